@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+)
+
+// bundleDir resolves a -bundle name to its directory: plain names live under
+// <data-dir>/bundles, anything containing a path separator is used as-is.
+func bundleDir(name, dataDir string) string {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return name
+	}
+	return filepath.Join(dataDir, "bundles", name)
+}
+
+// bundleGazetteerFile is the venue gazetteer's name inside a bundle (and its
+// replay scratch directory).
+const bundleGazetteerFile = "gazetteer.json"
+
+// setupBundle attaches a record/replay bundle to client. It returns the
+// directory the snapshot manager should use: recording snapshots the data dir's
+// snapshot into the bundle (it is part of what the build saw), and replay works
+// on a scratch copy of that snapshot so replaying never modifies the bundle.
+// The venue gazetteer at gazetteerPath (empty when disabled) is recorded and
+// replayed the same way: replay loads it from the scratch directory (see
+// bundleGazetteerFile).
+func setupBundle(client *fetch.Client, mode fetch.ClientMode, name, dataDir, gazetteerPath string, recordedAt time.Time) (bundle *fetch.Bundle, snapshotDir string, err error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("-fetch-mode %s requires -bundle NAME", mode)
+	}
+	dir := bundleDir(name, dataDir)
+
+	switch mode {
+	case fetch.RecordMode:
+		bundle, err = fetch.CreateBundle(dir, recordedAt)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := snapshot.NewManager(dataDir).CopyTo(dir); err != nil {
+			return nil, "", fmt.Errorf("recording snapshot: %w", err)
+		}
+		if gazetteerPath != "" {
+			if err := copyFile(gazetteerPath, filepath.Join(dir, bundleGazetteerFile)); err != nil {
+				return nil, "", fmt.Errorf("recording venue gazetteer: %w", err)
+			}
+		}
+		snapshotDir = dataDir
+
+	case fetch.ReplayMode:
+		bundle, err = fetch.OpenBundle(dir)
+		if err != nil {
+			return nil, "", err
+		}
+		snapshotDir, err = os.MkdirTemp("", "buildsite-replay-")
+		if err != nil {
+			return nil, "", fmt.Errorf("creating replay snapshot directory: %w", err)
+		}
+		if err := snapshot.NewManager(dir).CopyTo(snapshotDir); err != nil {
+			return nil, "", fmt.Errorf("loading recorded snapshot: %w", err)
+		}
+		if err := copyFile(filepath.Join(dir, bundleGazetteerFile), filepath.Join(snapshotDir, bundleGazetteerFile)); err != nil {
+			return nil, "", fmt.Errorf("loading recorded venue gazetteer: %w", err)
+		}
+
+	default:
+		return nil, "", fmt.Errorf("bundles are only used by record and replay modes, not %s", mode)
+	}
+
+	client.UseBundle(bundle)
+	return bundle, snapshotDir, nil
+}
+
+// copyFile copies src to dst. A missing src is not an error: the recorded
+// build had none.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// bundleHasWeather reports whether the recorded build fetched the AEMET
+// forecast (from the API or from the synthetic forecast cache).
+func bundleHasWeather(bundle *fetch.Bundle, aemetBaseURL string) bool {
+	for _, url := range bundle.URLs() {
+		if strings.HasPrefix(url, aemetBaseURL) || strings.HasPrefix(url, "aemet-forecast://") {
+			return true
+		}
+	}
+	return false
+}
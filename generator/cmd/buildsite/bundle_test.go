@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+)
+
+func TestSetupBundle_Gazetteer(t *testing.T) {
+	dataDir := t.TempDir()
+	gazPath := filepath.Join(dataDir, "gazetteer.json")
+	recorded := `{"venues": {"esmadrid:78001": {"latitude": 40.4233, "longitude": -3.7122}}}`
+	if err := os.WriteFile(gazPath, []byte(recorded), 0644); err != nil {
+		t.Fatal(err)
+	}
+	newClient := func(config fetch.ModeConfig) *fetch.Client {
+		client, err := fetch.NewClient(5*time.Second, config, t.TempDir())
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		return client
+	}
+
+	// Recording copies the gazetteer the build starts from into the bundle
+	if _, _, err := setupBundle(newClient(fetch.DefaultRecordConfig()), fetch.RecordMode, "b", dataDir, gazPath, time.Now()); err != nil {
+		t.Fatalf("setupBundle(record): %v", err)
+	}
+
+	// Later builds keep learning; replay still sees the recorded gazetteer
+	if err := os.WriteFile(gazPath, []byte(`{"venues": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, snapshotDir, err := setupBundle(newClient(fetch.DefaultReplayConfig()), fetch.ReplayMode, "b", dataDir, gazPath, time.Now())
+	if err != nil {
+		t.Fatalf("setupBundle(replay): %v", err)
+	}
+	defer os.RemoveAll(snapshotDir)
+	got, err := os.ReadFile(filepath.Join(snapshotDir, bundleGazetteerFile))
+	if err != nil || string(got) != recorded {
+		t.Errorf("replayed gazetteer = %q, %v, want the recorded %q", got, err, recorded)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+)
+
+// runCacheCommand implements "buildsite cache list|purge": inspecting and
+// clearing the HTTP cache under <data-dir>/http-cache without running a build.
+// Returns the process exit code.
+func runCacheCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "config.toml", "Path to TOML configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory holding http-cache (overrides config)")
+	fetchMode := fs.String("fetch-mode", "development", "Fetch mode whose cache TTL entries are checked against")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage:")
+		fmt.Fprintln(stderr, "  buildsite cache [options] list            List cached URLs with age, size and TTL")
+		fmt.Fprintln(stderr, "  buildsite cache [options] purge PATTERN   Remove entries whose URL contains PATTERN (\"*\" for all)")
+		fmt.Fprintln(stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.DefaultConfig()
+	if _, err := os.Stat(*configPath); err == nil {
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Snapshot.DataDir = *dataDir
+	}
+
+	cache, err := fetch.NewHTTPCache(filepath.Join(cfg.Snapshot.DataDir, "http-cache"), modeCacheTTL(fetch.ParseMode(*fetchMode)))
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open cache: %v\n", err)
+		return 1
+	}
+	for pattern, ttl := range cfg.Fetch.Cache.TTLOverrides {
+		cache.SetTTLOverride(pattern, ttl)
+	}
+
+	switch cmd := fs.Arg(0); cmd {
+	case "list":
+		entries, err := cache.Entries()
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read cache: %v\n", err)
+			return 1
+		}
+		writeCacheList(stdout, entries, time.Now())
+		return 0
+
+	case "purge":
+		if fs.NArg() != 2 {
+			fmt.Fprintln(stderr, "cache purge requires a URL pattern (\"*\" for all)")
+			return 2
+		}
+		pattern := fs.Arg(1)
+		if pattern == "*" {
+			pattern = ""
+		}
+		n, err := cache.Purge(pattern)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to purge cache: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "Purged %d cache entries\n", n)
+		return 0
+
+	default:
+		fmt.Fprintf(stderr, "Unknown cache command %q\n", cmd)
+		fs.Usage()
+		return 2
+	}
+}
+
+// modeCacheTTL returns the default cache TTL of a fetch mode.
+func modeCacheTTL(mode fetch.ClientMode) time.Duration {
+	switch mode {
+	case fetch.ProductionMode:
+		return fetch.DefaultProductionConfig().CacheTTL
+	case fetch.RecordMode:
+		return fetch.DefaultRecordConfig().CacheTTL
+	case fetch.ReplayMode:
+		return fetch.DefaultReplayConfig().CacheTTL
+	default:
+		return fetch.DefaultDevelopmentConfig().CacheTTL
+	}
+}
+
+// writeCacheList prints entries as a table, followed by a total.
+func writeCacheList(w io.Writer, entries []fetch.CacheInfo, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tAGE\tSIZE\tSTORED\tTTL\tSTATUS")
+
+	var stored int64
+	for _, e := range entries {
+		ttl := e.TTL.String()
+		if e.TTLOverride != "" {
+			ttl += " (" + e.TTLOverride + ")"
+		}
+		status := "fresh"
+		if !e.Fresh(now) {
+			status = "expired"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.URL, now.Sub(e.FetchedAt).Round(time.Second), formatBytes(int64(e.Size)), formatBytes(e.StoredSize), ttl, status)
+		stored += e.StoredSize
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d entries, %s on disk\n", len(entries), formatBytes(stored))
+}
+
+// formatBytes renders n in B, KB or MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
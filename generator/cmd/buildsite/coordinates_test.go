@@ -0,0 +1,136 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/gazetteer"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+)
+
+func TestFilterCityEvents_CoordinateFixes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Filter.Latitude, cfg.Filter.Longitude, cfg.Filter.RadiusKm = 40.42338, -3.71217, 0.35
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	start := now.Add(24 * time.Hour)
+
+	cityEvents := []event.CityEvent{
+		{ID: "swapped", Title: "Concierto", Latitude: -3.71217, Longitude: 40.42338, StartDate: start, EndDate: start},
+		{ID: "centroid", Title: "Taller", Latitude: 40.4167754, Longitude: -3.7037902, StartDate: start, EndDate: start},
+		{ID: "half-missing", Title: "Mercadillo en Plaza de España", Latitude: 40.42338, StartDate: start, EndDate: start},
+		{ID: "fine", Title: "Cine", Latitude: 40.42338, Longitude: -3.71217, StartDate: start, EndDate: start},
+	}
+
+	fixes := newCoordinateFixes(cfg.Filter.Coordinates, nil)
+	all, kept, err := filterCityEvents(cityEvents, cfg, now, &report.PipelineReport{}, fixes)
+	if err != nil {
+		t.Fatalf("filterCityEvents failed: %v", err)
+	}
+
+	want := map[string]struct {
+		fix  string
+		kept bool
+	}{
+		"swapped":      {"swapped", true},             // Corrected, inside the radius
+		"centroid":     {"municipal centroid", false}, // Cleared, no text match
+		"half-missing": {"sentinel value", true},      // Cleared, kept by text
+		"fine":         {"", true},
+	}
+	for _, evt := range all {
+		w := want[evt.ID]
+		if evt.FilterResult.CoordinateFix != w.fix || evt.FilterResult.Kept != w.kept {
+			t.Errorf("%s: fix %q, kept %v, want %q, %v", evt.ID, evt.FilterResult.CoordinateFix, evt.FilterResult.Kept, w.fix, w.kept)
+		}
+	}
+	if len(kept) != 3 {
+		t.Errorf("kept %d events, want 3", len(kept))
+	}
+	if got := all[0]; got.Latitude != 40.42338 || got.FilterResult.OriginalLatitude != -3.71217 {
+		t.Errorf("swapped event at %v, %v (originally %v), want corrected",
+			got.Latitude, got.Longitude, got.FilterResult.OriginalLatitude)
+	}
+
+	issues := fixes.issues()
+	types := make(map[string]int)
+	for _, issue := range issues {
+		types[issue.Type] = issue.Count
+	}
+	for _, typ := range []string{"COORDINATES_SWAPPED", "COORDINATES_CENTROID", "COORDINATES_SENTINEL"} {
+		if types[typ] != 1 {
+			t.Errorf("issue %s count = %d, want 1 (issues: %+v)", typ, types[typ], issues)
+		}
+	}
+	if len(issues) != 3 {
+		t.Errorf("got %d issues, want 3", len(issues))
+	}
+}
+
+func TestFilterCityEvents_Gazetteer(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Filter.Latitude, cfg.Filter.Longitude, cfg.Filter.RadiusKm = 40.42338, -3.71217, 1.0
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	start := now.Add(24 * time.Hour)
+
+	gaz, err := gazetteer.Load(filepath.Join(t.TempDir(), "gazetteer.json"), 0.15)
+	if err != nil {
+		t.Fatalf("gazetteer.Load: %v", err)
+	}
+	gaz.Learn(gazetteer.Place{Name: "Templo de Debod"}, 40.4240, -3.7178) // From an earlier build
+
+	// Events without coordinates come before the events placing their venue
+	cityEvents := []event.CityEvent{
+		{ID: "by-id", Title: "Mercadillo", VenueID: "78001", Venue: "Explanada", StartDate: start, EndDate: start},
+		{ID: "by-name", Title: "Visita guiada", Venue: "TEMPLO DE DEBOD", StartDate: start, EndDate: start},
+		{ID: "by-address", Title: "Taller", Venue: "Sala 2", Address: "Calle Ferraz, 2", StartDate: start, EndDate: start},
+		{ID: "sentinel", Title: "Concierto", VenueID: "78001", Latitude: 40.42338, StartDate: start, EndDate: start},
+		{ID: "unknown", Title: "Exposición", Venue: "Sala desconocida", StartDate: start, EndDate: start},
+		{ID: "plaza", Title: "Feria", VenueID: "78001", Venue: "Plaza de España", Latitude: 40.4233, Longitude: -3.7122, StartDate: start, EndDate: start},
+		{ID: "ferraz", Title: "Cine", Venue: "Otra sala", Address: "Calle Ferraz 2", Latitude: 40.4245, Longitude: -3.7150, StartDate: start, EndDate: start},
+	}
+
+	fixes := newCoordinateFixes(cfg.Filter.Coordinates, gaz)
+	all, _, err := filterCityEvents(cityEvents, cfg, now, &report.PipelineReport{}, fixes)
+	if err != nil {
+		t.Fatalf("filterCityEvents failed: %v", err)
+	}
+
+	want := map[string]struct {
+		geocoded string
+		key      string
+		kept     bool
+	}{
+		"by-id":      {"venue_id", "esmadrid:78001", true},
+		"by-name":    {"venue_name", "templo de debod", true},
+		"by-address": {"address", "calle ferraz 2", true},
+		"sentinel":   {"venue_id", "esmadrid:78001", true},
+		"unknown":    {"", "", false},
+		"plaza":      {"", "", true},
+		"ferraz":     {"", "", true},
+	}
+	for _, evt := range all {
+		w := want[evt.ID]
+		r := evt.FilterResult
+		if r.Geocoded != w.geocoded || r.GeocodedKey != w.key || r.Kept != w.kept {
+			t.Errorf("%s: geocoded %q (%q), kept %v, want %q (%q), %v", evt.ID, r.Geocoded, r.GeocodedKey, r.Kept, w.geocoded, w.key, w.kept)
+		}
+	}
+	if got := all[0]; got.Latitude != 40.4233 || got.Longitude != -3.7122 || !got.FilterResult.HasCoordinates {
+		t.Errorf("by-id event at %v, %v, want the venue's coordinates", got.Latitude, got.Longitude)
+	}
+	if cityEvents[0].Latitude != 0 {
+		t.Errorf("input event modified: %+v", cityEvents[0])
+	}
+
+	var geocoded int
+	for _, issue := range fixes.issues() {
+		if issue.Type == "COORDINATES_GEOCODED" {
+			geocoded = issue.Count
+		}
+	}
+	if geocoded != 4 {
+		t.Errorf("COORDINATES_GEOCODED count = %d, want 4", geocoded)
+	}
+}
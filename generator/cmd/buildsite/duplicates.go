@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/dedupe"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/source"
+)
+
+// mergeDuplicates finds the kept events both feeds list (see dedupe.Matcher)
+// and merges each city event into its cultural duplicate, so it renders as
+// one card. Returns the cultural events with the merged ones, the city events
+// without their duplicates, and the linked pairs (for the audit export).
+func mergeDuplicates(cultural []event.CulturalEvent, city []event.CityEvent, cfg config.DuplicatesConfig) ([]event.CulturalEvent, []event.CityEvent, []event.DuplicatePair) {
+	if !cfg.Enabled {
+		return cultural, city, nil
+	}
+	matcher := dedupe.Matcher{
+		MinTitleSimilarity: cfg.MinTitleSimilarity,
+		MaxDistanceKm:      cfg.MaxDistanceM / 1000,
+		CulturalSource:     source.DatosMadridName,
+		CitySource:         source.EsmadridName,
+		Priority:           cfg.Priority,
+	}
+	links := matcher.Match(cultural, city)
+	if len(links) == 0 {
+		return cultural, city, nil
+	}
+
+	merged := make([]event.CulturalEvent, len(cultural))
+	copy(merged, cultural)
+	linkedCity := make(map[int]bool, len(links))
+	pairs := make([]event.DuplicatePair, 0, len(links))
+	for _, l := range links {
+		merged[l.Cultural] = matcher.Merge(cultural[l.Cultural], city[l.City])
+		linkedCity[l.City] = true
+		pairs = append(pairs, l.DuplicatePair)
+		log.Printf("Duplicate: cultural %s %q = city %s %q (title similarity %.2f)",
+			l.CulturalID, l.CulturalTitle, l.CityID, l.CityTitle, l.TitleSimilarity)
+	}
+
+	remaining := make([]event.CityEvent, 0, len(city)-len(links))
+	for j, evt := range city {
+		if !linkedCity[j] {
+			remaining = append(remaining, evt)
+		}
+	}
+	log.Printf("Cross-feed duplicates: %d events listed in both feeds merged", len(links))
+	return merged, remaining, pairs
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/source"
+)
+
+// config validates duplicates.priority against its own copy of the feed names
+func TestDuplicateFeeds(t *testing.T) {
+	want := []string{source.DatosMadridName, source.EsmadridName}
+	if !slices.Equal(config.DuplicateFeeds, want) {
+		t.Errorf("config.DuplicateFeeds = %q, want %q", config.DuplicateFeeds, want)
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	start := time.Date(2025, 10, 20, 19, 0, 0, 0, time.UTC)
+	cultural := []event.CulturalEvent{
+		{ID: "c1", Title: "Concierto: Banda Sinfónica Municipal", StartTime: start, EndTime: start.Add(2 * time.Hour),
+			Latitude: 40.4240, Longitude: -3.7178},
+		{ID: "c2", Title: "Cuentacuentos", StartTime: start, EndTime: start.Add(time.Hour)},
+	}
+	city := []event.CityEvent{
+		{ID: "e1", Title: "Banda Sinfónica Municipal en concierto", StartDate: start, EndDate: start,
+			Latitude: 40.4242, Longitude: -3.7180},
+		{ID: "e2", Title: "Mercadillo", StartDate: start, EndDate: start, Latitude: 40.4242, Longitude: -3.7180},
+	}
+	cfg := config.DuplicatesConfig{
+		Enabled:            true,
+		MinTitleSimilarity: 0.6,
+		MaxDistanceM:       250,
+		Priority:           []string{"datos.madrid.es", "esmadrid.com"},
+	}
+
+	gotCultural, gotCity, pairs := mergeDuplicates(cultural, city, cfg)
+	if len(pairs) != 1 || pairs[0].CulturalID != "c1" || pairs[0].CityID != "e1" {
+		t.Fatalf("pairs = %+v, want c1-e1", pairs)
+	}
+	if len(gotCity) != 1 || gotCity[0].ID != "e2" {
+		t.Errorf("city events = %+v, want only e2", gotCity)
+	}
+	if len(gotCultural) != 2 || len(gotCultural[0].Listings) != 2 || gotCultural[1].Listings != nil {
+		t.Errorf("cultural events = %+v, want c1 merged and c2 as is", gotCultural)
+	}
+	if cultural[0].Listings != nil {
+		t.Error("mergeDuplicates modified its input")
+	}
+
+	cfg.Enabled = false
+	if _, gotCity, pairs := mergeDuplicates(cultural, city, cfg); len(gotCity) != 2 || pairs != nil {
+		t.Errorf("disabled: %d city events, pairs %+v, want all city events and no pairs", len(gotCity), pairs)
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/filter"
+	"github.com/ericphanson/plazaespana.info/internal/gazetteer"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/validate"
+)
+
+// newFilterEngine creates the filter engine for a pipeline's configured rules,
+// filling the settings a rule leaves unset from cfg and loading geofences.
+func newFilterEngine(cfg config.FilterConfig, rules []config.FilterRule, now time.Time) (*filter.Engine, error) {
+	engineRules := make([]filter.Rule, 0, len(rules))
+	for _, r := range rules {
+		rule := filter.Rule{
+			Kind:      filter.RuleKind(r.Type),
+			Next:      r.OnMiss == "next",
+			Distritos: r.Distritos,
+			Latitude:  cfg.Latitude,
+			Longitude: cfg.Longitude,
+			RadiusKm:  r.RadiusKm,
+			Text: filter.TextMatcher{
+				PlazaEspana: cfg.Text.PlazaEspana,
+				Keywords:    r.Keywords,
+				Exclude:     cfg.Text.Exclude,
+				Weights: filter.FieldWeights{
+					Title:       cfg.Text.Weights.Title,
+					Venue:       cfg.Text.Weights.Venue,
+					Address:     cfg.Text.Weights.Address,
+					Description: cfg.Text.Weights.Description,
+				},
+				Threshold:       cfg.Text.Threshold,
+				KeywordsInTitle: cfg.Text.KeywordsInTitle,
+			},
+			PastWeeks:  cfg.PastEventsWeeks,
+			ByEnd:      r.Date == "end",
+			Categories: r.Categories,
+		}
+		if rule.Distritos == nil {
+			rule.Distritos = cfg.Distritos
+		}
+		if rule.RadiusKm == 0 {
+			rule.RadiusKm = cfg.RadiusKm
+		}
+		if rule.Kind == filter.RulePolygon {
+			areas, err := ruleAreas(cfg, r)
+			if err != nil {
+				return nil, err
+			}
+			rule.Areas = areas
+		}
+		engineRules = append(engineRules, rule)
+	}
+	return filter.NewEngine(engineRules, now)
+}
+
+// ruleAreas returns a polygon rule's areas: its inline polygon, or the areas
+// of its geofence (by default cfg's).
+func ruleAreas(cfg config.FilterConfig, r config.FilterRule) ([]filter.Area, error) {
+	if len(r.Polygon) > 0 {
+		name := r.Name
+		if name == "" {
+			name = "polygon"
+		}
+		return []filter.Area{polygonArea(name, r.Polygon)}, nil
+	}
+	path := r.Geofence
+	if path == "" {
+		path = cfg.Geofence
+	}
+	return filter.LoadGeofence(path)
+}
+
+// polygonArea returns the area inside the [latitude, longitude] vertices.
+func polygonArea(name string, vertices [][]float64) filter.Area {
+	ring := make(filter.Ring, 0, len(vertices))
+	for _, v := range vertices {
+		ring = append(ring, [2]float64{v[0], v[1]})
+	}
+	return filter.Area{Name: name, Polygons: []filter.Polygon{{ring}}}
+}
+
+// newZones creates the configured zones (see config.ZoneConfig), centering
+// circles on [filter]'s coordinates unless a zone sets its own and loading
+// geofence areas.
+func newZones(cfg *config.Config) ([]filter.Zone, error) {
+	geofences := make(map[string][]filter.Area)
+	zones := make([]filter.Zone, 0, len(cfg.Zones))
+	for _, zc := range cfg.Zones {
+		z := filter.Zone{ID: zc.ID, Name: zc.Name, Aliases: zc.Aliases, Exclude: cfg.Filter.Text.Exclude}
+		switch {
+		case zc.RadiusM > 0:
+			z.Latitude, z.Longitude, z.RadiusKm = zc.Latitude, zc.Longitude, zc.RadiusM/1000
+			if z.Latitude == 0 && z.Longitude == 0 {
+				z.Latitude, z.Longitude = cfg.Filter.Latitude, cfg.Filter.Longitude
+			}
+		case zc.Area != "":
+			path := zc.Geofence
+			if path == "" {
+				path = cfg.Filter.Geofence
+			}
+			areas, ok := geofences[path]
+			if !ok {
+				var err error
+				if areas, err = filter.LoadGeofence(path); err != nil {
+					return nil, fmt.Errorf("zone %q: %w", zc.ID, err)
+				}
+				geofences[path] = areas
+			}
+			for _, a := range areas {
+				if a.Name == zc.Area {
+					z.Areas = append(z.Areas, a)
+				}
+			}
+			if z.Areas == nil {
+				return nil, fmt.Errorf("zone %q: geofence %s has no area %q", zc.ID, path, zc.Area)
+			}
+		case len(zc.Polygon) > 0:
+			z.Areas = []filter.Area{polygonArea(zc.Name, zc.Polygon)}
+		}
+		z.Prepare()
+		zones = append(zones, z)
+	}
+	return zones, nil
+}
+
+// filterCulturalEvents tags every cultural event with its filter decision
+// (see filter.Engine and [filter.rules]) and records the filter stats in pr.
+// Coordinates are checked first, and events without any are placed at their
+// venue (see coordinateFixes). Returns all events (for audit) and the kept
+// events sorted by start time (for rendering).
+func filterCulturalEvents(merged []event.CulturalEvent, cfg *config.Config, now time.Time, pr *report.PipelineReport, fixes *coordinateFixes) (allEvents, filteredEvents []event.CulturalEvent, err error) {
+	start := time.Now()
+	engine, err := newFilterEngine(cfg.Filter, cfg.Filter.Rules.Cultural, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cultural filter rules: %w", err)
+	}
+
+	// Check every event's coordinates and learn its venue's place before
+	// placing any, so events without coordinates find their venue wherever
+	// it comes in the feed
+	events := slices.Clone(merged)
+	results := make([]event.FilterResult, len(events))
+	for i := range events {
+		evt := &events[i]
+		fixes.check("cultural", evt.ID, evt.VenueName, &evt.Latitude, &evt.Longitude, &results[i])
+		fixes.learn(culturalPlace(*evt), evt.Latitude, evt.Longitude)
+	}
+
+	// Non-destructive: keep ALL events, tagged, for the audit
+	allEvents = make([]event.CulturalEvent, 0, len(events))
+	for i, evt := range events {
+		result := results[i]
+		fixes.geocode("cultural", evt.ID, culturalPlace(evt), &evt.Latitude, &evt.Longitude, &result)
+		engine.Evaluate(filter.Subject{
+			Distrito:    evt.Distrito,
+			Latitude:    evt.Latitude,
+			Longitude:   evt.Longitude,
+			Title:       evt.Title,
+			Venue:       evt.VenueName,
+			Address:     evt.Address,
+			Description: evt.Description,
+			Start:       evt.StartTime,
+			End:         evt.EndTime,
+		}, &result)
+		evt.FilterResult = result
+		allEvents = append(allEvents, evt)
+		if result.Kept {
+			filteredEvents = append(filteredEvents, evt)
+		}
+	}
+	engine.Report(&pr.Filtering, time.Since(start))
+
+	log.Printf("Filtered by distrito: %d, by radius: %d, by text: %d",
+		engine.Count(event.FilterKeptDistrito), engine.Count(event.FilterKeptRadius), engine.Count(event.FilterKeptText))
+	log.Printf("Cultural events after filtering: %d events", len(filteredEvents))
+
+	// Sort events by start time (upcoming events first)
+	sort.Slice(filteredEvents, func(i, j int) bool {
+		return filteredEvents[i].StartTime.Before(filteredEvents[j].StartTime)
+	})
+
+	return allEvents, filteredEvents, nil
+}
+
+// filterCityEvents tags every city event with its filter decision (see
+// filter.Engine and [filter.rules]) and records the filter stats in pr.
+// Coordinates are checked first, and events without any are placed at their
+// venue (see coordinateFixes). Returns all events (for audit) and the kept
+// events sorted by start date (for rendering).
+func filterCityEvents(cityEvents []event.CityEvent, cfg *config.Config, now time.Time, pr *report.PipelineReport, fixes *coordinateFixes) (allCityEvents, filteredCityEvents []event.CityEvent, err error) {
+	start := time.Now()
+	engine, err := newFilterEngine(cfg.Filter, cfg.Filter.Rules.City, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("city filter rules: %w", err)
+	}
+
+	// Check coordinates and learn venues first (see filterCulturalEvents)
+	events := slices.Clone(cityEvents)
+	results := make([]event.FilterResult, len(events))
+	for i := range events {
+		evt := &events[i]
+		fixes.check("city", evt.ID, evt.Venue, &evt.Latitude, &evt.Longitude, &results[i])
+		fixes.learn(cityPlace(*evt), evt.Latitude, evt.Longitude)
+	}
+
+	// Non-destructive: keep ALL events, tagged, for the audit
+	allCityEvents = make([]event.CityEvent, 0, len(events))
+	for i, evt := range events {
+		result := results[i]
+		fixes.geocode("city", evt.ID, cityPlace(evt), &evt.Latitude, &evt.Longitude, &result)
+		engine.Evaluate(filter.Subject{
+			Latitude:    evt.Latitude,
+			Longitude:   evt.Longitude,
+			Title:       evt.Title,
+			Venue:       evt.Venue,
+			Address:     evt.Address,
+			Description: evt.Description,
+			Category:    evt.Category,
+			Start:       evt.StartDate,
+			End:         evt.EndDate,
+		}, &result)
+		evt.FilterResult = result
+		allCityEvents = append(allCityEvents, evt)
+		if result.Kept {
+			filteredCityEvents = append(filteredCityEvents, evt)
+		}
+	}
+	engine.Report(&pr.Filtering, time.Since(start))
+
+	multiVenue := engine.Count(event.FilterKeptText)
+	log.Printf("City events after filtering: %d events (%d by geo, %d by Plaza de España text match)",
+		len(filteredCityEvents), len(filteredCityEvents)-multiVenue, multiVenue)
+
+	// Sort city events by start date
+	sort.Slice(filteredCityEvents, func(i, j int) bool {
+		return filteredCityEvents[i].StartDate.Before(filteredCityEvents[j].StartDate)
+	})
+
+	return allCityEvents, filteredCityEvents, nil
+}
+
+// coordinateFixes runs the coordinate quality checks (validate.CoordinateChecker)
+// for both event types, places events left without coordinates at their venue
+// (see gazetteer.Gazetteer), and tallies both for the build report.
+type coordinateFixes struct {
+	checker   validate.CoordinateChecker
+	fixed     map[validate.CoordinateFix][]string // Fix -> affected events, "kind ID (venue)"
+	gazetteer *gazetteer.Gazetteer                // nil: events without coordinates stay so
+	geocoded  map[gazetteer.Kind][]string         // How -> placed events, "kind ID (venue): key"
+}
+
+// newCoordinateFixes creates a tally checking against cfg's bounds and
+// placeholders and placing events with gaz (which may be nil).
+func newCoordinateFixes(cfg config.CoordinatesConfig, gaz *gazetteer.Gazetteer) *coordinateFixes {
+	return &coordinateFixes{
+		checker: validate.CoordinateChecker{
+			MinLatitude:  cfg.MinLatitude,
+			MaxLatitude:  cfg.MaxLatitude,
+			MinLongitude: cfg.MinLongitude,
+			MaxLongitude: cfg.MaxLongitude,
+			Placeholders: cfg.Placeholders,
+		},
+		fixed:     make(map[validate.CoordinateFix][]string),
+		gazetteer: gaz,
+		geocoded:  make(map[gazetteer.Kind][]string),
+	}
+}
+
+// gazetteerPath returns where the venue gazetteer configured in
+// [filter.gazetteer] lives, or "" if it is disabled.
+func gazetteerPath(cfg *config.Config) string {
+	gc := cfg.Filter.Gazetteer
+	if !gc.Enabled {
+		return ""
+	}
+	if filepath.IsAbs(gc.File) {
+		return gc.File
+	}
+	return filepath.Join(cfg.Snapshot.DataDir, gc.File)
+}
+
+// loadGazetteer loads the venue gazetteer at path (see gazetteerPath), or
+// returns nil if path is empty.
+func loadGazetteer(cfg *config.Config, path string) (*gazetteer.Gazetteer, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return gazetteer.Load(path, cfg.Filter.Gazetteer.MaxSpreadM/1000)
+}
+
+// culturalPlace is what places a cultural event in the gazetteer.
+func culturalPlace(evt event.CulturalEvent) gazetteer.Place {
+	return gazetteer.Place{VenueID: gazetteer.VenueKey("datos", evt.VenueID), Name: evt.VenueName, Address: evt.Address,
+		Event: "datos:" + evt.ID}
+}
+
+// cityPlace is what places a city event in the gazetteer.
+func cityPlace(evt event.CityEvent) gazetteer.Place {
+	return gazetteer.Place{VenueID: gazetteer.VenueKey("esmadrid", evt.VenueID), Name: evt.Venue, Address: evt.Address,
+		Event: "esmadrid:" + evt.ID}
+}
+
+// check corrects or clears an event's coordinates in place, recording the fix
+// and the original coordinates in result.
+func (f *coordinateFixes) check(kind, id, venue string, lat, lon *float64, result *event.FilterResult) {
+	newLat, newLon, fix := f.checker.Check(*lat, *lon)
+	if fix == validate.CoordinatesOK {
+		return
+	}
+	result.CoordinateFix = string(fix)
+	result.OriginalLatitude, result.OriginalLongitude = *lat, *lon
+	f.fixed[fix] = append(f.fixed[fix], fmt.Sprintf("%s %s (%s): %.5f, %.5f", kind, id, venue, *lat, *lon))
+	*lat, *lon = newLat, newLon
+}
+
+// learn records the place of an event with (checked) coordinates in the gazetteer.
+func (f *coordinateFixes) learn(p gazetteer.Place, lat, lon float64) {
+	if f.gazetteer != nil && lat != 0 && lon != 0 {
+		f.gazetteer.Learn(p, lat, lon)
+	}
+}
+
+// geocode gives an event without coordinates those of its place in the
+// gazetteer, if it has one, recording where they came from in result.
+func (f *coordinateFixes) geocode(kind, id string, p gazetteer.Place, lat, lon *float64, result *event.FilterResult) {
+	if f.gazetteer == nil || (*lat != 0 && *lon != 0) {
+		return
+	}
+	m, ok := f.gazetteer.Lookup(p)
+	if !ok {
+		return
+	}
+	result.Geocoded = string(m.Kind)
+	result.GeocodedKey = m.Key
+	result.GeocodedManual = m.Entry.Manual
+	f.geocoded[m.Kind] = append(f.geocoded[m.Kind], fmt.Sprintf("%s %s (%s): %s", kind, id, p.Name, m.Key))
+	*lat, *lon = m.Entry.Latitude, m.Entry.Longitude
+}
+
+// issues returns a data quality issue per kind of fix made.
+func (f *coordinateFixes) issues() []report.DataQualityIssue {
+	kinds := []struct {
+		fix         validate.CoordinateFix
+		issueType   string
+		severity    string
+		description string
+	}{
+		{validate.CoordinatesSwapped, "COORDINATES_SWAPPED", "INFO", "Events with latitude and longitude swapped (corrected)"},
+		{validate.CoordinatesSentinel, "COORDINATES_SENTINEL", "WARNING", "Events with placeholder coordinate values such as a lone zero (cleared)"},
+		{validate.CoordinatesOutOfBounds, "COORDINATES_OUT_OF_BOUNDS", "WARNING", "Events with coordinates outside Madrid (cleared)"},
+		{validate.CoordinatesPlaceholder, "COORDINATES_CENTROID", "WARNING", "Events located at the municipal centroid, i.e. geocoded to \"Madrid\" (cleared)"},
+	}
+
+	var issues []report.DataQualityIssue
+	for _, k := range kinds {
+		events := f.fixed[k.fix]
+		if len(events) == 0 {
+			continue
+		}
+		log.Printf("Coordinates %s: %d events", k.fix, len(events))
+		issue := report.DataQualityIssue{
+			Type:        k.issueType,
+			Severity:    k.severity,
+			Count:       len(events),
+			Description: k.description,
+			Examples:    events[:min(len(events), 5)],
+		}
+		if k.fix != validate.CoordinatesSwapped {
+			issue.Recommendation = "Cleared events are placed by distrito or venue text instead"
+		}
+		issues = append(issues, issue)
+	}
+
+	var geocoded []string
+	for _, kind := range []gazetteer.Kind{gazetteer.ByVenueID, gazetteer.ByName, gazetteer.ByAddress} {
+		if n := len(f.geocoded[kind]); n > 0 {
+			log.Printf("Coordinates from the gazetteer by %s: %d events", kind, n)
+			geocoded = append(geocoded, f.geocoded[kind]...)
+		}
+	}
+	if len(geocoded) > 0 {
+		issues = append(issues, report.DataQualityIssue{
+			Type:           "COORDINATES_GEOCODED",
+			Severity:       "INFO",
+			Count:          len(geocoded),
+			Description:    "Events without coordinates placed at their venue by the gazetteer",
+			Examples:       geocoded[:min(len(geocoded), 5)],
+			Recommendation: "Add manual entries to the gazetteer for venues placed wrongly or not at all",
+		})
+	}
+	return issues
+}
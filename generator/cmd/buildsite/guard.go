@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/guard"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/source"
+)
+
+// guardExitCode is the exit status of a build the publish guard kept from
+// publishing, distinct from log.Fatal's 1.
+const guardExitCode = 3
+
+// guardHistoryFile is the publish guard's history, in the data directory.
+const guardHistoryFile = "publish-history.json"
+
+// publishCounts names the counts the publish guard checks: events fetched
+// per source and events kept per pipeline kind.
+func publishCounts(fetched map[string]int, kept map[source.Kind]int) guard.Counts {
+	counts := make(guard.Counts, len(fetched)+len(kept))
+	for name, n := range fetched {
+		counts[name+" fetched"] = n
+	}
+	for kind, n := range kept {
+		counts[string(kind)+" kept"] = n
+	}
+	return counts
+}
+
+// runPublishGuard checks counts against the publish history in dataDir and
+// records the verdict in buildReport. skipReason, if set, skips the check and
+// leaves the history alone (e.g. for replayed builds). It returns whether to
+// publish and the history to add the build to once published (nil if the
+// build shouldn't be remembered).
+func runPublishGuard(cfg config.GuardConfig, dataDir string, counts guard.Counts, skipReason string, force bool,
+	buildReport *report.BuildReport) (publish bool, history *guard.History) {
+	if skipReason != "" {
+		log.Printf("Publish guard skipped: %s", skipReason)
+		buildReport.Guard = &report.GuardReport{Status: "SKIPPED", Reason: skipReason}
+		return true, nil
+	}
+
+	history, err := guard.LoadHistory(filepath.Join(dataDir, guardHistoryFile))
+	if err != nil {
+		log.Printf("Warning: publish guard skipped: %v", err)
+		buildReport.AddWarning("Publish guard skipped: %v", err)
+		buildReport.Guard = &report.GuardReport{Status: "SKIPPED", Reason: err.Error()}
+		return true, nil
+	}
+	if !cfg.Enabled {
+		buildReport.Guard = &report.GuardReport{Status: "SKIPPED", Reason: "disabled in config ([guard] enabled = false)"}
+		return true, history
+	}
+
+	checks := history.Check(counts, guard.Policy{
+		MaxDrop:     cfg.MaxDrop,
+		MinBaseline: cfg.MinBaseline,
+		MinHistory:  cfg.MinHistory,
+	})
+	buildReport.Guard = &report.GuardReport{Status: "PASSED", History: len(history.Builds)}
+	for _, c := range checks {
+		buildReport.Guard.Checks = append(buildReport.Guard.Checks, report.GuardCheck{
+			Name:      c.Name,
+			Count:     c.Count,
+			Baseline:  c.Baseline,
+			Drop:      c.Drop,
+			Anomalous: c.Anomalous,
+		})
+	}
+
+	anomalies := guard.Anomalies(checks)
+	if len(anomalies) == 0 {
+		log.Printf("Publish guard passed (%d counts against %d published builds)", len(checks), len(history.Builds))
+		return true, history
+	}
+
+	described := make([]string, len(anomalies))
+	for i, c := range anomalies {
+		described[i] = c.String()
+	}
+	buildReport.Guard.Reason = fmt.Sprintf("Event counts collapsed (more than %.0f%% below baseline, or zero): %s",
+		cfg.MaxDrop*100, strings.Join(described, "; "))
+
+	if force {
+		log.Printf("Warning: publish guard overridden by -force-publish: %s", buildReport.Guard.Reason)
+		buildReport.Guard.Status = "FORCED"
+		buildReport.AddWarning("Publish guard overridden by -force-publish: %s", strings.Join(described, "; "))
+		return true, history
+	}
+
+	log.Printf("ERROR: publish guard kept the previous output: %s", buildReport.Guard.Reason)
+	buildReport.Guard.Status = "BLOCKED"
+	buildReport.ExitStatus = "FAILED"
+	buildReport.AddWarning("Publish guard kept the previous index.html and events.json: %s", strings.Join(described, "; "))
+	buildReport.AddRecommendation("If the drop is real, rebuild with -force-publish to publish it and make it the new baseline")
+	return false, nil
+}
@@ -14,11 +14,12 @@ import (
 	"github.com/ericphanson/plazaespana.info/internal/config"
 	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/fetch"
-	"github.com/ericphanson/plazaespana.info/internal/filter"
+	"github.com/ericphanson/plazaespana.info/internal/guard"
 	"github.com/ericphanson/plazaespana.info/internal/pipeline"
 	"github.com/ericphanson/plazaespana.info/internal/render"
 	"github.com/ericphanson/plazaespana.info/internal/report"
 	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+	"github.com/ericphanson/plazaespana.info/internal/source"
 	"github.com/ericphanson/plazaespana.info/internal/version"
 	"github.com/ericphanson/plazaespana.info/internal/weather"
 )
@@ -48,10 +49,19 @@ func readBuildReportCSSHash(outDir string) string {
 const buildVersion = "2.0.0-dual-pipeline"
 
 func main() {
+	// Subcommands that don't run a build
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		os.Exit(runCacheCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		os.Exit(runSnapshotCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Initialize build report
 	buildReport := report.NewBuildReport()
 	var outputDir string
 	var reportBasePath string
+	exitCode := 0 // Set before returning from a build that should fail, once the report is written
 	defer func() {
 		buildReport.Duration = time.Since(buildReport.BuildTime)
 
@@ -63,6 +73,9 @@ func main() {
 			f.Close()
 			log.Println("Build report written to:", htmlReportPath)
 		}
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
 	}()
 
 	// Custom usage message
@@ -70,7 +83,9 @@ func main() {
 		log.Printf("Madrid Events Site Generator %s\n", buildVersion)
 		log.Println("\nDual pipeline: Fetches cultural events (datos.madrid.es) and city events (esmadrid.com)")
 		log.Println("\nUsage:")
-		log.Printf("  %s [options]\n\n", os.Args[0])
+		log.Printf("  %s [options]", os.Args[0])
+		log.Printf("  %s cache [options] list|purge PATTERN", os.Args[0])
+		log.Printf("  %s snapshot [options] list|show ID|restore ID\n\n", os.Args[0])
 		log.Println("Configuration:")
 		log.Println("  Use -config flag to specify TOML config file (recommended)")
 		log.Println("  Or use individual flags to override specific settings")
@@ -92,7 +107,10 @@ func main() {
 	lon := flag.Float64("lon", 0, "Reference longitude in decimal degrees (overrides config)")
 	radiusKm := flag.Float64("radius-km", 0, "Filter radius in kilometers (overrides config)")
 	timezone := flag.String("timezone", "Europe/Madrid", "Timezone for event times")
-	fetchMode := flag.String("fetch-mode", "development", "Fetch mode: production or development (affects caching/throttling)")
+	fetchMode := flag.String("fetch-mode", "development", "Fetch mode: production, development, record or replay (affects caching/throttling)")
+	bundleName := flag.String("bundle", "", "Bundle for record/replay fetch modes (name under <data-dir>/bundles, or a path)")
+	snapshotGeneration := flag.String("snapshot-generation", "", "Build from this snapshot generation without fetching sources (see snapshot list)")
+	forcePublish := flag.Bool("force-publish", false, "Publish even if the publish guard finds an event-count collapse")
 	templatePath := flag.String("template-path", "generator/templates/index.tmpl.html", "Path to HTML template file")
 	basePath := flag.String("base-path", "", "Base path for URLs (e.g., /previews/PR5 for preview deployments)")
 
@@ -119,6 +137,14 @@ func main() {
 		log.Printf("Loaded configuration from %s", *configPath)
 	}
 
+	// Fail before fetching if a pipeline's filter can't be built (e.g. a
+	// missing geofence)
+	for _, rules := range [][]config.FilterRule{cfg.Filter.Rules.Cultural, cfg.Filter.Rules.City} {
+		if _, err := newFilterEngine(cfg.Filter, rules, time.Now()); err != nil {
+			log.Fatalf("Invalid filter rules: %v", err)
+		}
+	}
+
 	// Override config with CLI flags if provided
 	if *jsonURL != "" {
 		cfg.CulturalEvents.JSONURL = *jsonURL
@@ -150,6 +176,12 @@ func main() {
 		cfg.Snapshot.DataDir = *dataDir
 	}
 
+	// Zones for the site's zone filters (after -lat/-lon, which center circles)
+	zones, err := newZones(cfg)
+	if err != nil {
+		log.Fatalf("Invalid zones: %v", err)
+	}
+
 	// Capture output directory and base path for deferred report writing
 	outputDir = filepath.Dir(cfg.Output.HTMLPath)
 	reportBasePath = *basePath
@@ -164,9 +196,14 @@ func main() {
 	// Parse fetch mode and get config
 	mode := fetch.ParseMode(*fetchMode)
 	var modeConfig fetch.ModeConfig
-	if mode == fetch.ProductionMode {
+	switch mode {
+	case fetch.ProductionMode:
 		modeConfig = fetch.DefaultProductionConfig()
-	} else {
+	case fetch.RecordMode:
+		modeConfig = fetch.DefaultRecordConfig()
+	case fetch.ReplayMode:
+		modeConfig = fetch.DefaultReplayConfig()
+	default:
 		modeConfig = fetch.DefaultDevelopmentConfig()
 	}
 
@@ -180,555 +217,312 @@ func main() {
 	}
 	log.Printf("Fetch mode: %s (cache TTL: %v, min delay: %v)", mode, modeConfig.CacheTTL, modeConfig.MinDelay)
 
-	// Set longer cache TTL for weather data (6 hours)
-	// Weather forecasts don't change often, and AEMET has rate limits
-	client.SetCacheTTLOverride("opendata.aemet.es", 6*time.Hour)
-	log.Printf("Weather cache TTL: 6h (overriding default %v)", modeConfig.CacheTTL)
-
-	snapMgr := snapshot.NewManager(cfg.Snapshot.DataDir)
-
-	// Initialize cultural pipeline report
-	buildReport.CulturalPipeline.Name = "Cultural Events"
-	buildReport.CulturalPipeline.Source = "datos.madrid.es"
-	culturalStart := time.Now()
-
-	// Create pipeline for multi-source fetching (cultural events from datos.madrid.es)
-	pipe := pipeline.NewPipeline(cfg.CulturalEvents.JSONURL, cfg.CulturalEvents.XMLURL, cfg.CulturalEvents.CSVURL, client, loc)
-
-	// Fetch from all three sources independently
-	log.Println("Fetching from all three sources (JSON, XML, CSV)...")
-	fetchStart := time.Now()
-	pipeResult := pipe.FetchAll()
-	buildReport.CulturalPipeline.Fetching.TotalDuration = time.Since(fetchStart)
-
-	// Track individual fetch attempts
-	buildReport.CulturalPipeline.Fetching.Attempts = []report.FetchAttempt{
-		createFetchAttempt("JSON", cfg.CulturalEvents.JSONURL, pipeResult.JSONEvents, pipeResult.JSONErrors),
-		createFetchAttempt("XML", cfg.CulturalEvents.XMLURL, pipeResult.XMLEvents, pipeResult.XMLErrors),
-		createFetchAttempt("CSV", cfg.CulturalEvents.CSVURL, pipeResult.CSVEvents, pipeResult.CSVErrors),
-	}
-
-	log.Printf("JSON: %d events, %d errors", len(pipeResult.JSONEvents), len(pipeResult.JSONErrors))
-	log.Printf("XML: %d events, %d errors", len(pipeResult.XMLEvents), len(pipeResult.XMLErrors))
-	log.Printf("CSV: %d events, %d errors", len(pipeResult.CSVEvents), len(pipeResult.CSVErrors))
-
-	// Merge and deduplicate
-	mergeStart := time.Now()
-	merged := pipe.Merge(pipeResult)
-	mergeDuration := time.Since(mergeStart)
-
-	// Calculate merge stats for cultural pipeline
-	mergeStats := report.MergeStats{
-		JSONEvents:       len(pipeResult.JSONEvents),
-		XMLEvents:        len(pipeResult.XMLEvents),
-		CSVEvents:        len(pipeResult.CSVEvents),
-		TotalBeforeMerge: len(pipeResult.JSONEvents) + len(pipeResult.XMLEvents) + len(pipeResult.CSVEvents),
-		UniqueEvents:     len(merged),
-		Duplicates:       (len(pipeResult.JSONEvents) + len(pipeResult.XMLEvents) + len(pipeResult.CSVEvents)) - len(merged),
-		Duration:         mergeDuration,
-	}
-
-	// Calculate source coverage
-	for _, evt := range merged {
-		switch len(evt.Sources) {
-		case 3:
-			mergeStats.InAllThree++
-		case 2:
-			mergeStats.InTwoSources++
-		case 1:
-			mergeStats.InOneSource++
-		}
+	// Retry transient upstream failures ([fetch] config), bounded by the build deadline
+	client.SetRetryPolicy("", retryPolicy(cfg.Fetch.Retry))
+	for host := range cfg.Fetch.Hosts {
+		client.SetRetryPolicy(host, retryPolicy(cfg.Fetch.RetryFor(host)))
 	}
+	if cfg.Fetch.BuildDeadline > 0 {
+		client.SetDeadline(buildReport.BuildTime.Add(cfg.Fetch.BuildDeadline))
+	}
+	log.Printf("Retries: up to %d attempts (build deadline: %v)", cfg.Fetch.Retry.MaxAttempts, cfg.Fetch.BuildDeadline)
 
-	buildReport.CulturalPipeline.Merging = &mergeStats
+	// Serve recently expired cache when upstream fails (stale-if-error)
+	client.SetMaxStale("", cfg.Fetch.MaxStale)
+	for host := range cfg.Fetch.Hosts {
+		client.SetMaxStale(host, cfg.Fetch.MaxStaleFor(host))
+	}
 
-	log.Printf("After merge: %d unique events from %d total (%.1f%% deduplication)",
-		len(merged),
-		mergeStats.TotalBeforeMerge,
-		float64(mergeStats.Duplicates)*100.0/float64(mergeStats.TotalBeforeMerge))
+	// Enforce per-URL request budgets across runs (MaxRequestRate per TimeWindow)
+	ledgerPath := filepath.Join(cfg.Snapshot.DataDir, "request-ledger.json")
+	if err := client.EnableRequestLedger(ledgerPath); err != nil {
+		// A corrupt ledger shouldn't block the build; the throttle still applies
+		log.Printf("Warning: request budget disabled: %v", err)
+		buildReport.AddWarning("Request budget disabled: %v", err)
+	} else {
+		log.Printf("Request budget: %d per URL per %v (ledger: %s)", modeConfig.MaxRequestRate, modeConfig.TimeWindow, ledgerPath)
+	}
 
-	// Handle snapshot fallback if ALL sources failed
-	if len(merged) == 0 && allSourcesFailed(pipeResult) {
-		log.Println("All sources failed, attempting to load snapshot...")
+	// Cache TTL overrides ([fetch.cache] ttl_overrides, e.g. 6h for AEMET)
+	for pattern, ttl := range cfg.Fetch.Cache.TTLOverrides {
+		client.SetCacheTTLOverride(pattern, ttl)
+		log.Printf("Cache TTL for %s: %v (overriding default %v)", pattern, ttl, modeConfig.CacheTTL)
+	}
 
-		// Load snapshot
-		snapshot, err := snapMgr.LoadSnapshot()
+	// Record/replay: every response goes to (or comes from) a bundle
+	snapshotDir := cfg.Snapshot.DataDir
+	gazPath := gazetteerPath(cfg)
+	var bundle *fetch.Bundle
+	if mode == fetch.RecordMode || mode == fetch.ReplayMode {
+		bundle, snapshotDir, err = setupBundle(client, mode, *bundleName, cfg.Snapshot.DataDir, gazPath, buildReport.BuildTime)
 		if err != nil {
-			log.Printf("Warning: Failed to load snapshot: %v", err)
-			buildReport.AddWarning("All fetch sources failed and no snapshot available")
-		} else {
-			log.Printf("Loaded snapshot with %d events", len(snapshot))
-
-			// Convert RawEvent back to CulturalEvent
-			snapshotEvents := make([]event.CulturalEvent, 0, len(snapshot))
-			for _, raw := range snapshot {
-				// Parse times
-				startTime, err := time.ParseInLocation("2006-01-02 15:04", raw.Fecha+" "+raw.Hora, loc)
-				if err != nil {
-					// Try without time if parsing fails
-					startTime, err = time.ParseInLocation("2006-01-02", raw.Fecha, loc)
-					if err != nil {
-						log.Printf("Warning: Failed to parse snapshot event %s time: %v", raw.IDEvento, err)
-						continue
-					}
-				}
-
-				endTime, err := time.ParseInLocation("2006-01-02", raw.FechaFin, loc)
-				if err != nil {
-					// Use start time if end time parsing fails
-					endTime = startTime
-				}
-
-				// Convert to CulturalEvent
-				canonical := event.CulturalEvent{
-					ID:          raw.IDEvento,
-					Title:       raw.Titulo,
-					Description: raw.Descripcion,
-					StartTime:   startTime,
-					EndTime:     endTime,
-					VenueName:   raw.NombreInstalacion,
-					Address:     raw.Direccion,
-					DetailsURL:  raw.ContentURL,
-					Latitude:    raw.Lat,
-					Longitude:   raw.Lon,
-					Sources:     []string{"SNAPSHOT"}, // Mark as from snapshot
-				}
-
-				snapshotEvents = append(snapshotEvents, canonical)
+			log.Fatalf("Failed to set up bundle: %v", err)
+		}
+		log.Printf("Bundle (%s): %s", mode, bundle.Dir())
+		if mode == fetch.ReplayMode {
+			defer os.RemoveAll(snapshotDir)
+			if gazPath != "" {
+				gazPath = filepath.Join(snapshotDir, bundleGazetteerFile) // The recorded build's
 			}
+			buildReport.AddWarning("Replay build: all upstream data served from bundle %s (recorded %s)",
+				bundle.Dir(), bundle.RecordedAt().Format(time.RFC3339))
+		}
+	}
+
+	snapMgr := snapshot.NewManager(snapshotDir)
+	snapMgr.SetBuild(snapshot.Build{Time: buildReport.BuildTime, Version: buildVersion, Commit: version.GitCommit})
+	// This build's snapshots replace the last good ones only once it publishes
+	// (see the publish guard below)
+	snapMgr.Hold()
 
-			log.Printf("Converted %d snapshot events to CulturalEvent", len(snapshotEvents))
-			merged = snapshotEvents
-			buildReport.AddWarning("Using snapshot data - all fetch attempts failed (snapshot has %d events)", len(snapshotEvents))
+	// Pinned build: sources and weather come from one snapshot generation
+	pinned := *snapshotGeneration
+	if pinned != "" {
+		if mode == fetch.RecordMode || mode == fetch.ReplayMode {
+			log.Fatalf("-snapshot-generation cannot be combined with -fetch-mode %s", mode)
 		}
-	} else if len(merged) > 0 {
-		// Save successful merge to snapshot
-		if err := snapMgr.SaveSnapshot(convertToRawEvents(merged)); err != nil {
-			log.Printf("Warning: failed to save snapshot: %v", err)
+		if err := snapMgr.Pin(pinned); err != nil {
+			log.Fatalf("Failed to pin snapshot generation: %v", err)
+		}
+		log.Printf("Pinned to snapshot generation %s: not fetching sources or weather", pinned)
+		buildReport.AddWarning("Pinned build: all upstream data from snapshot generation %s", pinned)
+		if missing, err := snapMgr.Missing(pinned); err == nil && len(missing) > 0 {
+			log.Printf("Warning: snapshot generation %s has no snapshot of %s", pinned, strings.Join(missing, ", "))
+			buildReport.AddWarning("Snapshot generation %s has no snapshot of %s: the pinned build goes without",
+				pinned, strings.Join(missing, ", "))
 		}
 	}
 
+	// Build the enabled event sources from the registry (config [sources])
+	sources, err := source.Build(cfg, source.Deps{
+		Client:    client,
+		Location:  loc,
+		Snapshots: snapMgr,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up event sources: %v", err)
+	}
+
 	// =====================================================================
-	// CULTURAL EVENTS PIPELINE: Tag events with filter decisions
+	// EVENT SOURCES: Fetch, parse and filter each registered source
 	// =====================================================================
-	log.Println("\n=== Cultural Events Pipeline ===")
 	now := time.Now().In(loc)
-	geoStart := time.Now()
-
-	// Target districts from config
-	targetDistricts := make(map[string]bool)
-	for _, distrito := range cfg.Filter.Distritos {
-		targetDistricts[distrito] = true
-	}
-
-	// Location keywords for text-based fallback (when no distrito or coords)
-	locationKeywords := []string{
-		"plaza de españa",
-		"plaza españa",
-		"templo de debod",
-		"parque del oeste",
-		"conde duque",
-	}
-
-	// Step 1: Evaluate all filters for all events and record results
-	// Non-destructive: Keep ALL events in memory
-	allEvents := make([]event.CulturalEvent, 0, len(merged))
-	for _, evt := range merged {
-		result := event.FilterResult{}
-
-		// Evaluate distrito filter
-		result.HasDistrito = (evt.Distrito != "")
-		result.Distrito = evt.Distrito
-		if result.HasDistrito {
-			result.DistritoMatched = targetDistricts[evt.Distrito]
-		}
-
-		// Evaluate GPS filter
-		result.HasCoordinates = (evt.Latitude != 0 && evt.Longitude != 0)
-		if result.HasCoordinates {
-			result.GPSDistanceKm = filter.HaversineDistance(
-				cfg.Filter.Latitude, cfg.Filter.Longitude,
-				evt.Latitude, evt.Longitude)
-			result.WithinRadius = (result.GPSDistanceKm <= cfg.Filter.RadiusKm)
-		}
-
-		// Evaluate text matching
-		result.TextMatched = filter.MatchesLocation(
-			evt.VenueName, evt.Address, evt.Description, locationKeywords)
-
-		// Evaluate time filter
-		result.StartDate = evt.StartTime
-		result.EndDate = evt.EndTime
-		result.DaysOld = int(now.Sub(evt.StartTime).Hours() / 24)
-		cutoffWeeksAgo := now.AddDate(0, 0, -7*cfg.Filter.PastEventsWeeks)
-		result.TooOld = evt.StartTime.Before(cutoffWeeksAgo)
-
-		// Decide if kept (priority order: distrito -> GPS -> time -> kept)
-		if result.HasDistrito && !result.DistritoMatched {
-			result.Kept = false
-			result.FilterReason = "outside target distrito"
-		} else if result.HasCoordinates && !result.WithinRadius && !result.HasDistrito {
-			result.Kept = false
-			result.FilterReason = "outside GPS radius"
-		} else if result.TooOld {
-			result.Kept = false
-			result.FilterReason = "event too old"
-		} else {
-			result.Kept = true
-			result.FilterReason = "kept"
-		}
-
-		evt.FilterResult = result
-		allEvents = append(allEvents, evt) // Keep ALL events
+	if mode == fetch.ReplayMode {
+		// Filter against the recorded build's clock so the output matches
+		now = bundle.RecordedAt().In(loc)
+		log.Printf("Replaying build recorded at %s", now.Format(time.RFC3339))
 	}
 
-	// Count events by filter reason (no double-counting, no mixing)
+	// =====================================================================
+	// WEATHER SETUP: Load the AEMET key and create the weather client
+	// =====================================================================
+	log.Println("\n=== Weather Setup ===")
 	var (
-		keptEvents         = 0
-		outsideDistrito    = 0
-		outsideRadius      = 0
-		missingCoords      = 0
-		tooOld             = 0
-		byDistrito         = 0
-		byRadius           = 0
-		byTextMatch        = 0
-		missingBothSources = 0
+		weatherClient *weather.Client // nil without an API key
+		weatherHost   string
 	)
 
-	for _, evt := range allEvents {
-		switch evt.FilterResult.FilterReason {
-		case "kept":
-			keptEvents++
-			// Count by location method (for logging)
-			if evt.FilterResult.HasDistrito && evt.FilterResult.DistritoMatched {
-				byDistrito++
-			} else if evt.FilterResult.HasCoordinates && evt.FilterResult.WithinRadius {
-				byRadius++
-			} else {
-				// No distrito, no coords - included by default
-				missingBothSources++
-				if evt.FilterResult.TextMatched {
-					byTextMatch++
-				}
-			}
-		case "outside target distrito":
-			outsideDistrito++
-		case "outside GPS radius":
-			outsideRadius++
-		case "missing location data":
-			missingCoords++
-		case "event too old":
-			tooOld++
-		}
+	// Initialize weather report
+	buildReport.Weather = &report.WeatherReport{
+		FetchTimestamp: time.Now(),
+		Municipality:   cfg.Weather.MunicipalityCode,
 	}
 
-	// Step 2: Separate kept events for rendering
-	var filteredEvents []event.CulturalEvent
-	for _, evt := range allEvents {
-		if evt.FilterResult.Kept {
-			filteredEvents = append(filteredEvents, evt)
+	// Get API key - try file first, then environment variable
+	var apiKey string
+
+	// Try reading from file first (preferred for production)
+	if cfg.Weather.APIKeyFile != "" {
+		keyBytes, err := os.ReadFile(cfg.Weather.APIKeyFile)
+		if err != nil {
+			log.Printf("Warning: Could not read API key file %s: %v", cfg.Weather.APIKeyFile, err)
+		} else {
+			apiKey = strings.TrimSpace(string(keyBytes))
+			log.Printf("Loaded AEMET API key from file: %s", cfg.Weather.APIKeyFile)
 		}
 	}
 
-	log.Printf("Filtered by distrito: %d, by radius: %d, by text: %d", byDistrito, byRadius, byTextMatch)
-
-	// Record filtering stats for cultural pipeline
-	geoDuration := time.Since(geoStart)
-
-	// Distrito filter stats (most events have distrito)
-	if len(cfg.Filter.Distritos) > 0 {
-		buildReport.CulturalPipeline.Filtering.DistrictoFilter = &report.DistrictoFilterStats{
-			AllowedDistricts: cfg.Filter.Distritos,
-			Input:            len(allEvents),
-			Filtered:         outsideDistrito, // FIXED: Only "outside target distrito" events
-			Kept:             keptEvents,      // FIXED: Only kept events
-			Duration:         geoDuration,
+	// Fall back to environment variable if file not available
+	if apiKey == "" && cfg.Weather.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.Weather.APIKeyEnv)
+		if apiKey != "" {
+			log.Printf("Loaded AEMET API key from environment: %s", cfg.Weather.APIKeyEnv)
 		}
 	}
 
-	// Geo filter stats (for events without distrito)
-	buildReport.CulturalPipeline.Filtering.GeoFilter = &report.GeoFilterStats{
-		RefLat:        cfg.Filter.Latitude,
-		RefLon:        cfg.Filter.Longitude,
-		Radius:        cfg.Filter.RadiusKm,
-		Input:         len(allEvents),
-		MissingCoords: missingCoords, // FIXED: Only events with "missing location data" reason
-		OutsideRadius: outsideRadius, // FIXED: Only "outside GPS radius" events
-		Kept:          keptEvents,    // FIXED: Only kept events
-		Duration:      geoDuration,
+	// Replay serves AEMET from the bundle and the key is only sent as a header,
+	// so a placeholder will do - but only if the recording fetched weather
+	if apiKey == "" && mode == fetch.ReplayMode {
+		recordedBaseURL := cfg.Weather.APIBaseURL
+		if *aemetBaseURL != "" {
+			recordedBaseURL = *aemetBaseURL
+		}
+		if bundleHasWeather(bundle, recordedBaseURL) {
+			apiKey = "replay"
+		}
 	}
 
-	// Log filtering method breakdown
-	if byTextMatch > 0 {
-		log.Printf("Text-based location matching: kept %d events", byTextMatch)
-	}
+	buildReport.Weather.APIKeyPresent = (apiKey != "")
 
-	// Time filter stats for cultural pipeline
-	buildReport.CulturalPipeline.Filtering.TimeFilter = &report.TimeFilterStats{
-		ReferenceTime: now,
-		Timezone:      *timezone,
-		Input:         len(allEvents),
-		ParseFailures: 0,          // No parse failures with CulturalEvent
-		PastEvents:    tooOld,     // FIXED: Only "event too old" events
-		Kept:          keptEvents, // FIXED: Only kept events
-		Duration:      0,          // Included in geo filter duration
-	}
+	if apiKey == "" {
+		var keySourceMsg string
+		if cfg.Weather.APIKeyFile != "" {
+			keySourceMsg = fmt.Sprintf("file %s or env %s", cfg.Weather.APIKeyFile, cfg.Weather.APIKeyEnv)
+		} else {
+			keySourceMsg = fmt.Sprintf("env %s", cfg.Weather.APIKeyEnv)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: AEMET API key not found (%s) - continuing without weather forecasts\n", keySourceMsg)
+		log.Printf("Warning: AEMET API key not found (%s) - continuing without weather forecasts", keySourceMsg)
+		buildReport.Weather.Error = fmt.Sprintf("API key not found (%s)", keySourceMsg)
+	} else {
+		// Determine AEMET base URL: flag overrides config
+		baseURL := cfg.Weather.APIBaseURL
+		if *aemetBaseURL != "" {
+			baseURL = *aemetBaseURL
+			log.Printf("Using AEMET base URL from flag: %s", baseURL)
+		} else {
+			log.Printf("Using AEMET base URL from config: %s", baseURL)
+		}
 
-	// Add warnings if needed
-	if len(filteredEvents) < len(merged)/100 { // Less than 1%
-		buildReport.AddWarning("Geographic radius very restrictive (%.2fkm) - only %.1f%% of events kept",
-			cfg.Filter.RadiusKm, float64(len(filteredEvents))*100/float64(len(merged)))
-		buildReport.AddRecommendation("Consider increasing filter.radius_km to 1.0-2.0 for better coverage")
+		// Create weather client
+		weatherClient = weather.NewClientWithBaseURL(apiKey, cfg.Weather.MunicipalityCode, client, baseURL)
+		weatherHost = fetch.HostOf(baseURL)
 	}
 
-	log.Printf("Cultural events after filtering: %d events", len(filteredEvents))
-
-	// Sort events by start time (upcoming events first)
-	sort.Slice(filteredEvents, func(i, j int) bool {
-		return filteredEvents[i].StartTime.Before(filteredEvents[j].StartTime)
-	})
-
-	// Set cultural pipeline totals
-	buildReport.CulturalPipeline.EventCount = len(filteredEvents)
-	buildReport.CulturalPipeline.Duration = time.Since(culturalStart)
-
 	// =====================================================================
-	// CITY EVENTS PIPELINE: Fetch and filter esmadrid.com events
+	// FETCH: Sources and weather run concurrently. The client's throttle
+	// serializes and spaces requests per host, so hosts proceed in parallel.
 	// =====================================================================
-	log.Println("\n=== City Events Pipeline ===")
-
-	// Initialize city pipeline report
-	buildReport.CityPipeline.Name = "City Events"
-	buildReport.CityPipeline.Source = "esmadrid.com"
-	cityStart := time.Now()
-
-	// Fetch ESMadrid XML events
-	log.Printf("Fetching ESMadrid events from: %s", cfg.CityEvents.XMLURL)
-	cityFetchStart := time.Now()
-	esmadridServices, err := fetch.FetchEsmadridEvents(cfg.CityEvents.XMLURL)
-	cityFetchDuration := time.Since(cityFetchStart)
-
-	// Track city events fetch attempt
-	cityFetchAttempt := report.FetchAttempt{
-		Source:   "XML",
-		URL:      cfg.CityEvents.XMLURL,
-		Duration: cityFetchDuration,
+	log.Println("\n=== Fetching ===")
+	scheduler := pipeline.NewScheduler()
+	results := make([]source.Result, len(sources))
+	for i, src := range sources {
+		scheduler.Go(src.Name(), src.Host(), func() {
+			results[i] = src.Fetch()
+		})
 	}
 
-	if err != nil {
-		log.Printf("Warning: Failed to fetch ESMadrid events: %v", err)
-		esmadridServices = []fetch.EsmadridService{} // Continue with empty list
-		cityFetchAttempt.Status = "FAILED"
-		cityFetchAttempt.Error = err.Error()
-	} else {
-		log.Printf("Fetched %d ESMadrid services", len(esmadridServices))
-		cityFetchAttempt.Status = "SUCCESS"
-		cityFetchAttempt.HTTPStatus = 200
-		cityFetchAttempt.EventCount = len(esmadridServices)
+	var (
+		forecast        *weather.Forecast
+		forecastErr     error
+		weatherDuration time.Duration
+	)
+	if weatherClient != nil && pinned == "" {
+		scheduler.Go("weather", weatherHost, func() {
+			log.Printf("Fetching 7-day forecast for municipality %s...", cfg.Weather.MunicipalityCode)
+			weatherStart := time.Now()
+			forecast, forecastErr = weatherClient.FetchForecast()
+			weatherDuration = time.Since(weatherStart)
+		})
 	}
 
-	buildReport.CityPipeline.Fetching.Attempts = []report.FetchAttempt{cityFetchAttempt}
-	buildReport.CityPipeline.Fetching.TotalDuration = cityFetchDuration
+	schedule := scheduler.Wait()
+	buildReport.Schedule = summarizeSchedule(schedule)
+	log.Printf("Fetch phase: %v wall (%v sequential), critical path %s (%v)",
+		buildReport.Schedule.Wall.Round(time.Millisecond), buildReport.Schedule.Serial.Round(time.Millisecond),
+		buildReport.Schedule.CriticalHost, buildReport.Schedule.CriticalPath.Round(time.Millisecond))
 
-	// Convert to CityEvent structs
-	var cityEvents []event.CityEvent
-	var cityParseErrors []event.ParseError
-	for i, svc := range esmadridServices {
-		cityEvent, err := svc.ToCityEvent()
-		if err != nil {
-			// Track parse error with details
-			cityParseErrors = append(cityParseErrors, event.ParseError{
-				Source:      "ESMadrid",
-				Index:       i,
-				RawData:     "", // ESMadrid services are complex, skip raw data
-				Error:       err,
-				RecoverType: "skipped",
-			})
-			continue
-		}
-		cityEvents = append(cityEvents, *cityEvent)
-	}
-	log.Printf("Parsed %d city events (%d parse errors)", len(cityEvents), len(cityParseErrors))
+	var (
+		allEvents          []event.CulturalEvent // Every cultural event, tagged (for audit)
+		filteredEvents     []event.CulturalEvent // Kept cultural events (for rendering)
+		allCityEvents      []event.CityEvent
+		filteredCityEvents []event.CityEvent
 
-	// Update fetch attempt with parsed event count (not fetched services count)
-	if err == nil { // Only update if fetch succeeded
-		cityFetchAttempt.EventCount = len(cityEvents)
+		culturalParseErrors = []event.ParseError{}
+		cityParseErrors     = []event.ParseError{}
+	)
 
-		// Add note if there were parse errors
-		if len(cityParseErrors) > 0 {
-			cityFetchAttempt.Error = fmt.Sprintf("Parsed %d/%d services successfully",
-				len(cityEvents), len(esmadridServices))
-		}
+	var snapshotNotices []render.SnapshotNotice // Sources shown from their snapshot
+	fetchedCounts := make(map[string]int)       // Per source, for the publish guard
+	keptCounts := make(map[source.Kind]int)     // Per pipeline kind, for the publish guard
 
-		// Update the report with corrected fetch attempt
-		buildReport.CityPipeline.Fetching.Attempts = []report.FetchAttempt{cityFetchAttempt}
-	}
-
-	// Track filtering start
-	cityFilterStart := time.Now()
-
-	// Step 1: Evaluate all filters for all city events and record results
-	// Non-destructive: Keep ALL events in memory
-	cutoffTime := now.Add(-time.Duration(cfg.Filter.PastEventsWeeks) * 7 * 24 * time.Hour)
-	allCityEvents := make([]event.CityEvent, 0, len(cityEvents))
-
-	// Stats counters for city events
-	cityOutsideRadius := 0
-	cityTooOld := 0
-	cityMissingCoords := 0
-	cityMultiVenueKept := 0
-
-	for _, evt := range cityEvents {
-		result := event.FilterResult{}
-
-		// Check if coordinates are actually present (not zero)
-		hasCoords := evt.Latitude != 0.0 && evt.Longitude != 0.0
-		result.HasCoordinates = hasCoords
-
-		// City events don't have distrito
-		result.HasDistrito = false
-
-		// Time filter
-		result.StartDate = evt.StartDate
-		result.EndDate = evt.EndDate
-		result.DaysOld = int(now.Sub(evt.EndDate).Hours() / 24)
-		result.TooOld = evt.EndDate.Before(cutoffTime)
-
-		// Check for Plaza de España text mention (city events only)
-		result.PlazaEspanaText = filter.MatchesPlazaEspana(
-			evt.Title,
-			evt.Venue,
-			evt.Address,
-			evt.Description,
-		)
-
-		// Decide if kept (priority: missing coords -> geo/text -> too old -> kept)
-		if !hasCoords {
-			// No coordinates - check text matching
-			if result.PlazaEspanaText {
-				if result.TooOld {
-					result.Kept = false
-					result.FilterReason = "event too old"
-					cityTooOld++
-				} else {
-					result.Kept = true
-					result.FilterReason = "kept (multi-venue: Plaza de España)"
-					result.MultiVenueKept = true
-					cityMultiVenueKept++
-				}
-			} else {
-				result.Kept = false
-				result.FilterReason = "missing location data"
-				cityMissingCoords++
-			}
-		} else {
-			// Have coordinates - check geo first, then text
-			result.GPSDistanceKm = filter.HaversineDistance(
-				cfg.Filter.Latitude, cfg.Filter.Longitude,
-				evt.Latitude, evt.Longitude)
-			result.WithinRadius = (result.GPSDistanceKm <= cfg.Filter.RadiusKm)
-
-			if result.WithinRadius {
-				// Kept by geo (preferred)
-				if result.TooOld {
-					result.Kept = false
-					result.FilterReason = "event too old"
-					cityTooOld++
-				} else {
-					result.Kept = true
-					result.FilterReason = "kept"
-				}
-			} else if result.PlazaEspanaText {
-				// Outside radius but mentions Plaza de España
-				if result.TooOld {
-					result.Kept = false
-					result.FilterReason = "event too old"
-					cityTooOld++
-				} else {
-					result.Kept = true
-					result.FilterReason = "kept (multi-venue: Plaza de España)"
-					result.MultiVenueKept = true
-					cityMultiVenueKept++
-				}
-			} else {
-				// Outside radius and no text match
-				result.Kept = false
-				result.FilterReason = "outside GPS radius"
-				cityOutsideRadius++
+	// Venue gazetteer: places events without coordinates ([filter.gazetteer])
+	gaz, err := loadGazetteer(cfg, gazPath)
+	if err != nil {
+		log.Printf("Warning: %v (events without coordinates stay unplaced)", err)
+		buildReport.AddWarning("Venue gazetteer not used: %v", err)
+	}
+	coordFixes := newCoordinateFixes(cfg.Filter.Coordinates, gaz)
+	for i, src := range sources {
+		log.Printf("\n=== Source: %s (%s events) ===", src.Name(), src.Kind())
+		pr := buildReport.AddPipeline()
+
+		result := results[i]
+		src.Report(result, pr)
+		fetchedCounts[src.Name()] = result.EventCount()
+		for _, warning := range result.Warnings {
+			buildReport.AddWarning("%s", warning)
+		}
+		for _, issue := range result.DataQuality {
+			buildReport.AddDataQualityIssue(issue)
+		}
+		for _, attempt := range result.Attempts {
+			if attempt.Snapshot {
+				label := fmt.Sprintf("%s (%s)", src.Name(), attempt.Source)
+				snapshotNotices = append(snapshotNotices, snapshotNotice(label, attempt.SnapshotAge, loc))
 			}
 		}
 
-		evt.FilterResult = result
-		allCityEvents = append(allCityEvents, evt) // Keep ALL events
-	}
-
-	// Step 2: Separate kept events for rendering
-	var filteredCityEvents []event.CityEvent
-	for _, evt := range allCityEvents {
-		if evt.FilterResult.Kept {
-			filteredCityEvents = append(filteredCityEvents, evt)
+		filterStart := time.Now()
+		switch src.Kind() {
+		case source.KindCultural:
+			all, kept, err := filterCulturalEvents(result.Cultural, cfg, now, pr, coordFixes)
+			if err != nil {
+				log.Fatalf("Filtering %s: %v", src.Name(), err)
+			}
+			allEvents = append(allEvents, all...)
+			filteredEvents = append(filteredEvents, kept...)
+			culturalParseErrors = append(culturalParseErrors, result.Errors...)
+			pr.EventCount = len(kept)
+
+			// Add warnings if needed
+			if len(result.Cultural) > 0 && len(kept) < len(result.Cultural)/100 { // Less than 1%
+				buildReport.AddWarning("Geographic radius very restrictive (%.2fkm) - only %.1f%% of events kept",
+					cfg.Filter.RadiusKm, float64(len(kept))*100/float64(len(result.Cultural)))
+				buildReport.AddRecommendation("Consider increasing filter.radius_km to 1.0-2.0 for better coverage")
+			}
+		case source.KindCity:
+			all, kept, err := filterCityEvents(result.City, cfg, now, pr, coordFixes)
+			if err != nil {
+				log.Fatalf("Filtering %s: %v", src.Name(), err)
+			}
+			allCityEvents = append(allCityEvents, all...)
+			filteredCityEvents = append(filteredCityEvents, kept...)
+			cityParseErrors = append(cityParseErrors, result.Errors...)
+			pr.EventCount = len(kept)
 		}
-	}
-
-	cityFilterDuration := time.Since(cityFilterStart)
 
-	log.Printf("City events after filtering: %d events (%d by geo, %d by Plaza de España text match)",
-		len(filteredCityEvents), len(filteredCityEvents)-cityMultiVenueKept, cityMultiVenueKept)
-
-	// Geo filter stats for city pipeline (FIXED: use correct counters)
-	buildReport.CityPipeline.Filtering.GeoFilter = &report.GeoFilterStats{
-		RefLat:         cfg.Filter.Latitude,
-		RefLon:         cfg.Filter.Longitude,
-		Radius:         cfg.Filter.RadiusKm,
-		Input:          len(allCityEvents),
-		MissingCoords:  cityMissingCoords, // FIXED: Track actual missing coordinates
-		OutsideRadius:  cityOutsideRadius, // FIXED: Only "outside GPS radius" events
-		Kept:           len(filteredCityEvents),
-		MultiVenueKept: cityMultiVenueKept, // NEW: Count of events kept via Plaza de España text match
-		Duration:       cityFilterDuration,
+		keptCounts[src.Kind()] += pr.EventCount
+		pr.Duration = result.Duration + time.Since(filterStart)
+		log.Printf("%s pipeline completed in %v", pr.Name, pr.Duration)
 	}
-
-	// Time filter stats for city pipeline (included in geo filter duration)
-	buildReport.CityPipeline.Filtering.TimeFilter = &report.TimeFilterStats{
-		ReferenceTime: now,
-		Timezone:      *timezone,
-		Input:         len(allCityEvents),
-		ParseFailures: 0,
-		PastEvents:    cityTooOld, // FIXED: Only "event too old" events
-		Kept:          len(filteredCityEvents),
-		Duration:      0, // Included in geo filter duration
+	for _, issue := range coordFixes.issues() {
+		buildReport.AddDataQualityIssue(issue)
 	}
 
-	// Category filter stats (currently disabled, but track for completeness)
-	// buildReport.CityPipeline.Filtering.CategoryFilter would go here if enabled
+	// Events both feeds list render as one card ([duplicates])
+	filteredEvents, filteredCityEvents, duplicates := mergeDuplicates(filteredEvents, filteredCityEvents, cfg.Duplicates)
 
-	// Sort city events by start date
-	sort.Slice(filteredCityEvents, func(i, j int) bool {
+	// Keep rendering order stable when several sources feed the same kind
+	sort.SliceStable(filteredEvents, func(i, j int) bool {
+		return filteredEvents[i].StartTime.Before(filteredEvents[j].StartTime)
+	})
+	sort.SliceStable(filteredCityEvents, func(i, j int) bool {
 		return filteredCityEvents[i].StartDate.Before(filteredCityEvents[j].StartDate)
 	})
 
-	// Set city pipeline totals
-	buildReport.CityPipeline.EventCount = len(filteredCityEvents)
-	buildReport.CityPipeline.Duration = time.Since(cityStart)
-	log.Printf("City events pipeline completed in %v", buildReport.CityPipeline.Duration)
-
 	// =====================================================================
 	// AUDIT EXPORT: Save complete audit trail with all events
 	// =====================================================================
 	log.Println("\n=== Exporting Audit Trail ===")
 
-	// Collect all cultural parse errors
-	culturalParseErrors := []event.ParseError{}
-	culturalParseErrors = append(culturalParseErrors, pipeResult.JSONErrors...)
-	culturalParseErrors = append(culturalParseErrors, pipeResult.XMLErrors...)
-	culturalParseErrors = append(culturalParseErrors, pipeResult.CSVErrors...)
-
 	auditPath := filepath.Join(cfg.Snapshot.DataDir, "audit-events.json")
 	auditErr := audit.SaveAuditJSON(
 		allEvents,
 		allCityEvents,
 		culturalParseErrors,
 		cityParseErrors,
+		duplicates,
 		auditPath,
 		buildReport.BuildTime,
 		buildReport.Duration,
@@ -747,81 +541,43 @@ func main() {
 	}
 
 	// =====================================================================
-	// WEATHER: Fetch weather forecast
+	// WEATHER: Build the weather map from the fetched forecast
 	// =====================================================================
 	var weatherMap map[string]*render.Weather
-	log.Println("\n=== Fetching Weather ===")
-	weatherStart := time.Now()
-
-	// Initialize weather report
-	buildReport.Weather = &report.WeatherReport{
-		FetchTimestamp: time.Now(),
-		Municipality:   cfg.Weather.MunicipalityCode,
-	}
-
-	// Get API key - try file first, then environment variable
-	var apiKey string
-
-	// Try reading from file first (preferred for production)
-	if cfg.Weather.APIKeyFile != "" {
-		keyBytes, err := os.ReadFile(cfg.Weather.APIKeyFile)
-		if err != nil {
-			log.Printf("Warning: Could not read API key file %s: %v", cfg.Weather.APIKeyFile, err)
-		} else {
-			apiKey = strings.TrimSpace(string(keyBytes))
-			log.Printf("Loaded AEMET API key from file: %s", cfg.Weather.APIKeyFile)
+	if weatherClient != nil {
+		if pinned != "" {
+			forecastErr = fmt.Errorf("pinned to snapshot generation %s", pinned)
 		}
-	}
-
-	// Fall back to environment variable if file not available
-	if apiKey == "" && cfg.Weather.APIKeyEnv != "" {
-		apiKey = os.Getenv(cfg.Weather.APIKeyEnv)
-		if apiKey != "" {
-			log.Printf("Loaded AEMET API key from environment: %s", cfg.Weather.APIKeyEnv)
-		}
-	}
-
-	buildReport.Weather.APIKeyPresent = (apiKey != "")
-
-	if apiKey == "" {
-		var keySourceMsg string
-		if cfg.Weather.APIKeyFile != "" {
-			keySourceMsg = fmt.Sprintf("file %s or env %s", cfg.Weather.APIKeyFile, cfg.Weather.APIKeyEnv)
-		} else {
-			keySourceMsg = fmt.Sprintf("env %s", cfg.Weather.APIKeyEnv)
-		}
-		fmt.Fprintf(os.Stderr, "Warning: AEMET API key not found (%s) - continuing without weather forecasts\n", keySourceMsg)
-		log.Printf("Warning: AEMET API key not found (%s) - continuing without weather forecasts", keySourceMsg)
-		buildReport.Weather.Error = fmt.Sprintf("API key not found (%s)", keySourceMsg)
-	} else {
-		// Determine AEMET base URL: flag overrides config
-		baseURL := cfg.Weather.APIBaseURL
-		if *aemetBaseURL != "" {
-			baseURL = *aemetBaseURL
-			log.Printf("Using AEMET base URL from flag: %s", baseURL)
+		if forecastErr == nil {
+			if err := snapMgr.SaveSource(weatherSnapshot, time.Now(), forecast); err != nil {
+				log.Printf("Warning: failed to save weather snapshot: %v", err)
+			}
 		} else {
-			log.Printf("Using AEMET base URL from config: %s", baseURL)
-		}
-
-		// Create weather client
-		weatherClient := weather.NewClientWithBaseURL(apiKey, cfg.Weather.MunicipalityCode, client, baseURL)
-
-		// Set longer cache TTL for weather forecasts (updates 3-4x daily, safe to cache 6 hours)
-		client.SetCacheTTLOverride("aemet-forecast://", 6*time.Hour)
-
-		// Wait 2 seconds before weather fetch (respectful delay)
-		log.Println("Waiting 2 seconds before weather fetch (respectful delay)...")
-		time.Sleep(2 * time.Second)
+			if pinned == "" {
+				fmt.Fprintf(os.Stderr, "ERROR: Weather fetch failed: %v\n", forecastErr)
+				log.Printf("ERROR: Weather fetch failed: %v", forecastErr)
+			}
+			buildReport.Weather.Error = forecastErr.Error()
 
-		// Fetch forecast
-		log.Printf("Fetching 7-day forecast for municipality %s...", cfg.Weather.MunicipalityCode)
-		forecast, err := weatherClient.FetchForecast()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: Weather fetch failed: %v\n", err)
-			log.Printf("ERROR: Weather fetch failed: %v", err)
-			buildReport.AddWarning("Weather fetch failed: %v", err)
-			buildReport.Weather.Error = err.Error()
-			log.Fatal("weather fetch is required but failed")
+			// Fall back to the last good forecast; without one, weather is required
+			var snapForecast weather.Forecast
+			snap, err := snapMgr.LoadSource(weatherSnapshot)
+			if err == nil {
+				err = snap.Decode(&snapForecast)
+			}
+			if err != nil {
+				buildReport.AddWarning("Weather fetch failed: %v", forecastErr)
+				log.Printf("No weather snapshot: %v", err)
+				log.Fatal("weather fetch is required but failed")
+			}
+			forecast = &snapForecast
+			buildReport.Weather.Snapshot = true
+			buildReport.Weather.SnapshotAge = time.Since(snap.FetchedAt)
+			if pinned == "" {
+				buildReport.AddWarning("Weather fetch failed, using its snapshot from %v ago: %v",
+					buildReport.Weather.SnapshotAge.Round(time.Minute), forecastErr)
+			}
+			snapshotNotices = append(snapshotNotices, snapshotNotice("AEMET", buildReport.Weather.SnapshotAge, loc))
 		}
 		log.Printf("Weather forecast received: %d days", len(forecast.Prediction.Days))
 		buildReport.Weather.DaysCovered = len(forecast.Prediction.Days)
@@ -830,7 +586,7 @@ func main() {
 		weatherMap = weather.BuildWeatherMap(forecast, *basePath)
 		log.Printf("Weather map built: %d dates", len(weatherMap))
 	}
-	buildReport.Weather.Duration = time.Since(weatherStart)
+	buildReport.Weather.Duration = weatherDuration
 
 	// =====================================================================
 	// RENDERING: Render both cultural and city events
@@ -838,9 +594,10 @@ func main() {
 	log.Println("\n=== Rendering Output ===")
 
 	// Group events by time (merged: city and cultural together)
-	mergedGroups, ongoingEvents, ongoingCityCount, ongoingPlaza, ongoingNearby, ongoingCityPlaza, ongoingCityNearby := render.GroupMixedEventsByTime(
+	mergedGroups, ongoing := render.GroupMixedEventsByTime(
 		filteredCityEvents, filteredEvents, now,
-		cfg.Filter.Latitude, cfg.Filter.Longitude, weatherMap)
+		cfg.Filter.Latitude, cfg.Filter.Longitude, zones, weatherMap)
+	ongoingEvents := ongoing.Events
 
 	// Count events with/without weather
 	if buildReport.Weather != nil {
@@ -877,6 +634,16 @@ func main() {
 			EndTime:    evt.EndTime.Format(time.RFC3339), // ADDED: Include end time
 			VenueName:  evt.VenueName,
 			DetailsURL: evt.DetailsURL,
+
+			Price:         evt.Price,
+			Free:          &evt.Free,
+			Audience:      evt.Audience,
+			Type:          evt.Type,
+			Barrio:        evt.Barrio,
+			PostalCode:    evt.PostalCode,
+			VenueID:       evt.VenueID,
+			Accessibility: evt.Accessibility,
+			Listings:      evt.Listings,
 		})
 	}
 
@@ -889,6 +656,7 @@ func main() {
 			EndTime:    evt.EndDate.Format(time.RFC3339), // ADDED: Include end time
 			VenueName:  evt.Venue,
 			DetailsURL: evt.WebURL,
+			Price:      render.TruncateText(evt.Price, 200), // Upstream sends HTML
 		})
 	}
 
@@ -912,18 +680,71 @@ func main() {
 		}
 	}
 
+	// Record final event count (total of both pipelines)
+	buildReport.TotalEvents = len(filteredEvents) + len(filteredCityEvents)
+
+	// Summarize upstream requests, flagging any the request budget suppressed
+	buildReport.Requests = summarizeRequests(client.Auditor().Records())
+	if n := len(buildReport.Requests.Suppressed); n > 0 {
+		buildReport.AddWarning("%d request(s) suppressed by the request budget - served from cache or snapshot", n)
+	}
+
+	// Keep the HTTP cache bounded ([fetch.cache] max_age and max_size_mb)
+	pruned, err := client.PruneCache(int64(cfg.Fetch.Cache.MaxSizeMB)<<20, cfg.Fetch.Cache.MaxAge)
+	if err != nil {
+		log.Printf("Warning: cache pruning failed: %v", err)
+		buildReport.AddWarning("HTTP cache pruning failed: %v", err)
+	} else {
+		log.Printf("HTTP cache: %d entries, %.1f MB (removed %d expired, %d least recently used)",
+			pruned.Remaining, float64(pruned.Size)/1024/1024, pruned.Expired, pruned.Evicted)
+		buildReport.Requests.CacheEntries = pruned.Remaining
+		buildReport.Requests.CacheBytes = pruned.Size
+		buildReport.Requests.CacheEvicted = pruned.Expired + pruned.Evicted
+	}
+
+	// Export request audit trail
+	requestAuditPath := filepath.Join(cfg.Snapshot.DataDir, "request-audit.json")
+	if err = client.Auditor().Export(requestAuditPath); err != nil {
+		log.Printf("Warning: failed to export audit trail: %v", err)
+	} else {
+		log.Printf("Request audit exported to: %s", requestAuditPath)
+	}
+
+	// =====================================================================
+	// PUBLISH GUARD: Keep the previous output if event counts collapsed
+	// =====================================================================
+	counts := publishCounts(fetchedCounts, keptCounts)
+	skipGuard := ""
+	switch {
+	case mode == fetch.ReplayMode:
+		skipGuard = "replay build"
+	case pinned != "":
+		skipGuard = "pinned to snapshot generation " + pinned
+	}
+	publish, publishHistory := runPublishGuard(cfg.Guard, cfg.Snapshot.DataDir, counts, skipGuard, *forcePublish, buildReport)
+	if !publish {
+		const kept = "previous output kept by the publish guard"
+		buildReport.Output.HTML = report.OutputFile{Path: cfg.Output.HTMLPath, Status: "SKIPPED", Error: kept}
+		buildReport.Output.JSON = report.OutputFile{Path: cfg.Output.JSONPath, Status: "SKIPPED", Error: kept}
+		if dropped := snapMgr.Discard(); len(dropped) > 0 {
+			log.Printf("Snapshots not saved, the last good ones kept: %s", strings.Join(dropped, ", "))
+		}
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", buildReport.Guard.Reason)
+		exitCode = guardExitCode
+		return
+	}
+
 	// Render outputs
 	outDirPath := filepath.Dir(cfg.Output.HTMLPath)
 	if err := os.MkdirAll(outDirPath, 0755); err != nil {
 		log.Fatalf("Failed to create output directory: %v", err)
 	}
 
-	// Calculate total distance-filtered counts across all groups
-	totalPlaza := ongoingPlaza
-	totalCityPlaza := ongoingCityPlaza
-	for _, group := range mergedGroups {
-		totalPlaza += group.CountPlaza
-		totalCityPlaza += group.CityPlaza
+	// Calculate total zone counts across all groups, and the zone filters' stylesheet
+	zoneTotals := render.ZoneTotals(append([]render.TimeGroup{ongoing}, mergedGroups...)...)
+	zonesCSSHash, err := render.WriteZoneCSS(zoneTotals, filepath.Join(outDirPath, "assets"))
+	if err != nil {
+		log.Fatalf("Failed to write zone stylesheet: %v", err)
 	}
 
 	// Render HTML with grouped events
@@ -941,15 +762,15 @@ func main() {
 		ShowCulturalDefault: true, // Cultural events shown by default
 		Groups:              mergedGroups,
 		OngoingEvents:       ongoingEvents,
-		OngoingCityCount:    ongoingCityCount,
-		OngoingPlaza:        ongoingPlaza,
-		OngoingNearby:       ongoingNearby,
-		OngoingCityPlaza:    ongoingCityPlaza,
-		OngoingCityNearby:   ongoingCityNearby,
-		TotalPlaza:          totalPlaza,
+		OngoingCityCount:    ongoing.CityCount,
+		OngoingNearby:       ongoing.CountNearby,
+		OngoingCityNearby:   ongoing.CityNearby,
+		OngoingZones:        ongoing.Zones,
 		TotalNearby:         totalCityEvents + totalCulturalEvents,
-		TotalCityPlaza:      totalCityPlaza,
 		TotalCityNearby:     totalCityEvents,
+		Zones:               zoneTotals,
+		ZonesCSSHash:        zonesCSSHash,
+		SnapshotNotices:     snapshotNotices,
 	}
 	htmlPath := cfg.Output.HTMLPath
 	htmlErr := htmlRenderer.RenderAny(htmlData, htmlPath)
@@ -1000,15 +821,52 @@ func main() {
 	}
 	log.Println("Generated:", jsonPath)
 
-	// Record final event count (total of both pipelines)
-	buildReport.TotalEvents = len(filteredEvents) + len(filteredCityEvents)
+	// Published: the counts join the publish guard's baseline
+	if publishHistory != nil {
+		publishHistory.Add(guard.Build{Time: buildReport.BuildTime, Counts: counts}, cfg.Guard.History)
+		if err := publishHistory.Save(); err != nil {
+			log.Printf("Warning: failed to save publish history: %v", err)
+			buildReport.AddWarning("Failed to save publish history: %v", err)
+		}
+	}
 
-	// Export request audit trail
-	requestAuditPath := filepath.Join(cfg.Snapshot.DataDir, "request-audit.json")
-	if err = client.Auditor().Export(requestAuditPath); err != nil {
-		log.Printf("Warning: failed to export audit trail: %v", err)
-	} else {
-		log.Printf("Request audit exported to: %s", requestAuditPath)
+	// Published: its snapshots become the last good ones, and its generation
+	// holds every source (those it saved none of keep their latest snapshot)
+	if saved, err := snapMgr.Commit(); err != nil {
+		log.Printf("Warning: failed to save snapshots: %v", err)
+		buildReport.AddWarning("Failed to save snapshots: %v", err)
+	} else if len(saved) > 0 {
+		log.Printf("Snapshots saved: %s", strings.Join(saved, ", "))
+	}
+	if copied, err := snapMgr.CompleteGeneration(); err != nil {
+		log.Printf("Warning: failed to complete snapshot generation: %v", err)
+		buildReport.AddWarning("Failed to complete snapshot generation: %v", err)
+	} else if len(copied) > 0 {
+		log.Printf("Snapshot generation: kept the latest snapshots of %s", strings.Join(copied, ", "))
+	}
+
+	// Published: what the gazetteer learned is kept (a blocked build's feeds
+	// may be broken, so it learns nothing from them). Replayed and pinned
+	// builds see old data, which isn't learned either, as with the publish
+	// guard's baseline.
+	if gaz != nil && skipGuard == "" {
+		if err := gaz.Save(); err != nil {
+			log.Printf("Warning: failed to save venue gazetteer: %v", err)
+			buildReport.AddWarning("Failed to save venue gazetteer: %v", err)
+		} else {
+			log.Printf("Venue gazetteer: %d entries", gaz.Len())
+		}
+	}
+
+	// Keep snapshot history bounded ([snapshot] keep_hourly and keep_daily)
+	if pinned == "" {
+		removed, err := snapMgr.Prune(cfg.Snapshot.KeepHourly, cfg.Snapshot.KeepDaily)
+		if err != nil {
+			log.Printf("Warning: snapshot pruning failed: %v", err)
+			buildReport.AddWarning("Snapshot history pruning failed: %v", err)
+		} else if len(removed) > 0 {
+			log.Printf("Snapshot history: removed %d generations (%s)", len(removed), strings.Join(removed, ", "))
+		}
 	}
 
 	// Final summary
@@ -1018,51 +876,3 @@ func main() {
 	log.Printf("Total events rendered: %d", len(filteredEvents)+len(filteredCityEvents))
 	log.Println("Build complete!")
 }
-
-// createFetchAttempt creates a FetchAttempt from pipeline results.
-func createFetchAttempt(source, url string, events []event.SourcedEvent, errors []event.ParseError) report.FetchAttempt {
-	attempt := report.FetchAttempt{
-		Source: source,
-		URL:    url,
-	}
-
-	if len(events) > 0 {
-		attempt.Status = "SUCCESS"
-		attempt.EventCount = len(events)
-		attempt.HTTPStatus = 200
-	} else if len(errors) > 0 {
-		attempt.Status = "FAILED"
-		attempt.Error = errors[0].Error.Error()
-	} else {
-		attempt.Status = "FAILED"
-		attempt.Error = "no events parsed"
-	}
-
-	return attempt
-}
-
-// allSourcesFailed returns true if all three sources failed to fetch events.
-func allSourcesFailed(result pipeline.PipelineResult) bool {
-	return len(result.JSONEvents) == 0 && len(result.XMLEvents) == 0 && len(result.CSVEvents) == 0
-}
-
-// convertToRawEvents converts CulturalEvents to RawEvents for snapshot compatibility.
-func convertToRawEvents(canonical []event.CulturalEvent) []fetch.RawEvent {
-	raw := make([]fetch.RawEvent, len(canonical))
-	for i, evt := range canonical {
-		raw[i] = fetch.RawEvent{
-			IDEvento:          evt.ID,
-			Titulo:            evt.Title,
-			Descripcion:       evt.Description,
-			Fecha:             evt.StartTime.Format("2006-01-02"),
-			FechaFin:          evt.EndTime.Format("2006-01-02"),
-			Hora:              evt.StartTime.Format("15:04"),
-			NombreInstalacion: evt.VenueName,
-			Direccion:         evt.Address,
-			ContentURL:        evt.DetailsURL,
-			Lat:               evt.Latitude,
-			Lon:               evt.Longitude,
-		}
-	}
-	return raw
-}
@@ -4,12 +4,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ericphanson/plazaespana.info/internal/config"
 	"github.com/ericphanson/plazaespana.info/internal/event"
-	"github.com/ericphanson/plazaespana.info/internal/filter"
+	"github.com/ericphanson/plazaespana.info/internal/report"
 )
 
-// TestMultiVenueFiltering validates the multi-venue Plaza de España filtering logic
-// These tests verify the filtering decision tree implemented in Phase 3
+// TestMultiVenueFiltering validates the multi-venue Plaza de España filtering
+// with the default city rules (radius, falling back to text, then time)
 func TestMultiVenueFiltering(t *testing.T) {
 	// Reference point: Plaza de España
 	refLat := 40.42338
@@ -18,9 +19,9 @@ func TestMultiVenueFiltering(t *testing.T) {
 
 	// Time references (using fixed time for reproducibility)
 	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
-	futureDate := now.Add(7 * 24 * time.Hour)  // 1 week future
-	oldDate := now.Add(-30 * 24 * time.Hour)   // 30 days ago (beyond cutoff)
-	cutoffTime := now.Add(-7 * 24 * time.Hour) // 1 week cutoff
+	futureDate := now.Add(7 * 24 * time.Hour) // 1 week future
+	oldDate := now.Add(-30 * 24 * time.Hour)  // 30 days ago (beyond cutoff)
+	pastWeeks := 1                            // 1 week cutoff
 
 	tests := []struct {
 		name                string
@@ -137,70 +138,18 @@ func TestMultiVenueFiltering(t *testing.T) {
 		},
 	}
 
+	cfg := config.DefaultConfig()
+	cfg.Filter.Latitude, cfg.Filter.Longitude, cfg.Filter.RadiusKm = refLat, refLon, radiusKm
+	cfg.Filter.PastEventsWeeks = pastWeeks
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Simulate the filtering logic from main.go
-			result := event.FilterResult{}
-
-			hasCoords := tt.evt.Latitude != 0.0 && tt.evt.Longitude != 0.0
-			result.HasCoordinates = hasCoords
-
-			result.StartDate = tt.evt.StartDate
-			result.EndDate = tt.evt.EndDate
-			result.DaysOld = int(now.Sub(tt.evt.EndDate).Hours() / 24)
-			result.TooOld = tt.evt.EndDate.Before(cutoffTime)
-
-			// Check for Plaza de España text mention
-			result.PlazaEspanaText = filter.MatchesPlazaEspana(
-				tt.evt.Title,
-				tt.evt.Venue,
-				tt.evt.Address,
-				tt.evt.Description,
-			)
-
-			// Filtering decision logic (from Phase 3)
-			if !hasCoords {
-				if result.PlazaEspanaText {
-					if result.TooOld {
-						result.Kept = false
-						result.FilterReason = "event too old"
-					} else {
-						result.Kept = true
-						result.FilterReason = "kept (multi-venue: Plaza de España)"
-						result.MultiVenueKept = true
-					}
-				} else {
-					result.Kept = false
-					result.FilterReason = "missing location data"
-				}
-			} else {
-				result.GPSDistanceKm = filter.HaversineDistance(
-					refLat, refLon,
-					tt.evt.Latitude, tt.evt.Longitude)
-				result.WithinRadius = (result.GPSDistanceKm <= radiusKm)
-
-				if result.WithinRadius {
-					if result.TooOld {
-						result.Kept = false
-						result.FilterReason = "event too old"
-					} else {
-						result.Kept = true
-						result.FilterReason = "kept"
-					}
-				} else if result.PlazaEspanaText {
-					if result.TooOld {
-						result.Kept = false
-						result.FilterReason = "event too old"
-					} else {
-						result.Kept = true
-						result.FilterReason = "kept (multi-venue: Plaza de España)"
-						result.MultiVenueKept = true
-					}
-				} else {
-					result.Kept = false
-					result.FilterReason = "outside GPS radius"
-				}
+			all, _, err := filterCityEvents([]event.CityEvent{tt.evt}, cfg, now, &report.PipelineReport{},
+				newCoordinateFixes(config.CoordinatesConfig{}, nil))
+			if err != nil {
+				t.Fatalf("filterCityEvents failed: %v", err)
 			}
+			result := all[0].FilterResult
 
 			// Validate results
 			if result.Kept != tt.wantKept {
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+)
+
+// summarizeRequests condenses the fetch client's audit trail for the build report.
+// Synthetic (cache-only) URLs are not upstream requests and are skipped.
+func summarizeRequests(records []fetch.RequestRecord) *report.RequestReport {
+	summary := &report.RequestReport{}
+	for _, r := range records {
+		if r.Synthetic {
+			continue
+		}
+		summary.Total++
+		if r.Attempt > 1 {
+			summary.Retries++
+		}
+
+		switch {
+		case r.Suppressed:
+			servedFrom := "none"
+			if r.CacheHit {
+				servedFrom = "cache"
+			}
+			summary.Suppressed = append(summary.Suppressed, report.SuppressedRequest{
+				URL:        r.URL,
+				ServedFrom: servedFrom,
+			})
+		case r.Stale:
+			summary.Stale++
+		case r.Revalidation == fetch.RevalidationNotModified:
+			summary.Network++
+			summary.NotModified++
+		case r.CacheHit:
+			summary.CacheHits++
+		default:
+			summary.Network++
+		}
+	}
+	return summary
+}
+
+// retryPolicy converts a [fetch] retry config to the fetch client's policy.
+func retryPolicy(r config.RetryConfig) fetch.RetryPolicy {
+	return fetch.RetryPolicy{
+		MaxAttempts: r.MaxAttempts,
+		BaseDelay:   r.BaseDelay,
+		MaxDelay:    r.MaxDelay,
+		Jitter:      r.Jitter,
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/ericphanson/plazaespana.info/internal/pipeline"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+)
+
+// summarizeSchedule converts the fetch phase's task timings for the build report.
+func summarizeSchedule(schedule pipeline.Schedule) *report.FetchSchedule {
+	host, span := schedule.CriticalPath()
+	summary := &report.FetchSchedule{
+		Wall:         schedule.Wall(),
+		Serial:       schedule.Serial(),
+		CriticalHost: host,
+		CriticalPath: span,
+	}
+	for _, t := range schedule.Tasks {
+		summary.Tasks = append(summary.Tasks, report.ScheduledTask{
+			Name:     t.Name,
+			Host:     t.Host,
+			Offset:   t.Start.Sub(schedule.Tasks[0].Start),
+			Duration: t.Duration(),
+		})
+	}
+	return summary
+}
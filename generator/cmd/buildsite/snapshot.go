@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/render"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+)
+
+// weatherSnapshot is the snapshot.Manager name of the AEMET forecast.
+const weatherSnapshot = "aemet"
+
+// snapshotNotice tells visitors that source is shown from a snapshot of its
+// data fetched age ago.
+func snapshotNotice(source string, age time.Duration, loc *time.Location) render.SnapshotNotice {
+	return render.SnapshotNotice{
+		Source:    source,
+		FetchedAt: time.Now().Add(-age).In(loc).Format("2006-01-02 15:04 MST"),
+	}
+}
+
+// runSnapshotCommand implements "buildsite snapshot list|show|restore":
+// inspecting the snapshot generations under <data-dir>/snapshots and rolling
+// the latest snapshots back to one of them without running a build.
+// Returns the process exit code.
+func runSnapshotCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "config.toml", "Path to TOML configuration file")
+	dataDir := fs.String("data-dir", "", "Data directory holding snapshots (overrides config)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage:")
+		fmt.Fprintln(stderr, "  buildsite snapshot [options] list          List snapshot generations, newest first")
+		fmt.Fprintln(stderr, "  buildsite snapshot [options] show ID       Show the snapshots generation ID holds")
+		fmt.Fprintln(stderr, "  buildsite snapshot [options] restore ID    Make generation ID's snapshots the latest ones")
+		fmt.Fprintln(stderr, "\nTo build from a generation without restoring it, use buildsite -snapshot-generation ID.")
+		fmt.Fprintln(stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg := config.DefaultConfig()
+	if _, err := os.Stat(*configPath); err == nil {
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to load config: %v\n", err)
+			return 1
+		}
+	}
+	if *dataDir != "" {
+		cfg.Snapshot.DataDir = *dataDir
+	}
+	mgr := snapshot.NewManager(cfg.Snapshot.DataDir)
+
+	switch cmd := fs.Arg(0); cmd {
+	case "list":
+		generations, err := mgr.Generations()
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to read snapshots: %v\n", err)
+			return 1
+		}
+		writeGenerationList(stdout, generations, time.Now())
+		return 0
+
+	case "show":
+		if fs.NArg() != 2 {
+			fmt.Fprintln(stderr, "snapshot show requires a generation ID (see snapshot list)")
+			return 2
+		}
+		gen, err := mgr.Generation(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return 1
+		}
+		if err := writeGeneration(stdout, mgr, gen, time.Now()); err != nil {
+			fmt.Fprintf(stderr, "Failed to read snapshots: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "restore":
+		if fs.NArg() != 2 {
+			fmt.Fprintln(stderr, "snapshot restore requires a generation ID (see snapshot list)")
+			return 2
+		}
+		missing, err := mgr.Missing(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to restore snapshots: %v\n", err)
+			return 1
+		}
+		restored, err := mgr.Restore(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to restore snapshots: %v\n", err)
+			return 1
+		}
+		if len(missing) > 0 {
+			fmt.Fprintf(stderr, "Warning: generation %s has no snapshot of %s, which keep their latest snapshot\n",
+				fs.Arg(1), strings.Join(missing, ", "))
+		}
+		fmt.Fprintf(stdout, "Restored %d snapshots from generation %s: %s\n",
+			len(restored), fs.Arg(1), strings.Join(restored, ", "))
+		return 0
+
+	default:
+		fmt.Fprintf(stderr, "Unknown snapshot command %q\n", cmd)
+		fs.Usage()
+		return 2
+	}
+}
+
+// writeGenerationList prints generations as a table, followed by a total.
+func writeGenerationList(w io.Writer, generations []snapshot.Generation, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAGE\tSOURCES")
+	for _, gen := range generations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", gen.ID, now.Sub(gen.Time).Round(time.Minute), strings.Join(gen.Sources, ", "))
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d generations\n", len(generations))
+}
+
+// writeGeneration prints each snapshot in gen: when it was fetched, the build
+// that saved it and how many items it holds.
+func writeGeneration(w io.Writer, mgr *snapshot.Manager, gen snapshot.Generation, now time.Time) error {
+	fmt.Fprintf(w, "Generation %s (%s ago)\n\n", gen.ID, now.Sub(gen.Time).Round(time.Minute))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tFETCHED\tVERSION\tBUILD\tITEMS")
+	for _, name := range gen.Sources {
+		snap, err := mgr.LoadGeneration(gen.ID, name)
+		if err != nil {
+			return err
+		}
+		build := "-"
+		if !snap.Build.Time.IsZero() {
+			build = strings.TrimSpace(snap.Build.Version + " " + snap.Build.Commit)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n",
+			name, snap.FetchedAt.Format(time.RFC3339), snap.Version, build, snapshotItems(snap))
+	}
+	return tw.Flush()
+}
+
+// snapshotItems counts the items of a snapshot holding a list (events), or
+// returns "-" for one holding a single document (the weather forecast).
+func snapshotItems(snap *snapshot.Snapshot) string {
+	var items []json.RawMessage
+	if err := snap.Decode(&items); err != nil {
+		return "-"
+	}
+	return fmt.Sprint(len(items))
+}
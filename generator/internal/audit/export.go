@@ -17,9 +17,10 @@ type AuditFile struct {
 	BuildDuration float64   `json:"build_duration_seconds"`
 	TotalEvents   int       `json:"total_events"`
 
-	CulturalEvents AuditPipeline    `json:"cultural_events"`
-	CityEvents     AuditPipeline    `json:"city_events"`
-	ParseErrors    ParseErrorsAudit `json:"parse_errors"` // NEW: Track parse failures
+	CulturalEvents AuditPipeline         `json:"cultural_events"`
+	CityEvents     AuditPipeline         `json:"city_events"`
+	ParseErrors    ParseErrorsAudit      `json:"parse_errors"` // NEW: Track parse failures
+	Duplicates     []event.DuplicatePair `json:"duplicates"`   // Events listed in both feeds, rendered as one
 }
 
 // AuditPipeline tracks all events and filtering decisions for one pipeline.
@@ -32,12 +33,14 @@ type AuditPipeline struct {
 }
 
 // SaveAuditJSON exports complete audit trail to JSON file.
-// Includes all events (kept + filtered) with filter decisions and parse errors.
+// Includes all events (kept + filtered) with filter decisions, parse errors
+// and the cross-feed duplicates merged for rendering.
 func SaveAuditJSON(
 	culturalEvents []event.CulturalEvent,
 	cityEvents []event.CityEvent,
 	culturalParseErrors []event.ParseError,
 	cityParseErrors []event.ParseError,
+	duplicates []event.DuplicatePair,
 	path string,
 	buildTime time.Time,
 	duration time.Duration,
@@ -57,6 +60,10 @@ func SaveAuditJSON(
 	// Process parse errors
 	parseErrorsAudit := processParseErrors(culturalParseErrors, cityParseErrors)
 
+	if duplicates == nil {
+		duplicates = []event.DuplicatePair{} // Export [] rather than null
+	}
+
 	// Build audit file
 	audit := AuditFile{
 		BuildTime:      buildTime,
@@ -65,6 +72,7 @@ func SaveAuditJSON(
 		CulturalEvents: culturalPipeline,
 		CityEvents:     cityPipeline,
 		ParseErrors:    parseErrorsAudit,
+		Duplicates:     duplicates,
 	}
 
 	// Marshal to JSON
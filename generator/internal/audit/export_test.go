@@ -59,8 +59,12 @@ func TestSaveAuditJSON(t *testing.T) {
 	culturalParseErrors := []event.ParseError{}
 	cityParseErrors := []event.ParseError{}
 
+	duplicates := []event.DuplicatePair{
+		{CulturalID: "event1", CityID: "city1", TitleSimilarity: 0.8, DistanceKm: 0.05, Primary: "datos.madrid.es"},
+	}
+
 	// Save audit JSON
-	err := SaveAuditJSON(culturalEvents, cityEvents, culturalParseErrors, cityParseErrors, auditPath, buildTime, duration)
+	err := SaveAuditJSON(culturalEvents, cityEvents, culturalParseErrors, cityParseErrors, duplicates, auditPath, buildTime, duration)
 	if err != nil {
 		t.Fatalf("SaveAuditJSON failed: %v", err)
 	}
@@ -90,6 +94,10 @@ func TestSaveAuditJSON(t *testing.T) {
 		t.Errorf("TotalEvents = %d, want 3", audit.TotalEvents)
 	}
 
+	if len(audit.Duplicates) != 1 || audit.Duplicates[0].CulturalID != "event1" || audit.Duplicates[0].CityID != "city1" {
+		t.Errorf("Duplicates = %+v, want event1 linked to city1", audit.Duplicates)
+	}
+
 	// Verify cultural events pipeline
 	if audit.CulturalEvents.Total != 2 {
 		t.Errorf("CulturalEvents.Total = %d, want 2", audit.CulturalEvents.Total)
@@ -223,7 +231,7 @@ func TestSaveAuditJSON_WithParseErrors(t *testing.T) {
 	}
 
 	// Save audit JSON
-	err := SaveAuditJSON(culturalEvents, cityEvents, culturalParseErrors, cityParseErrors, auditPath, buildTime, duration)
+	err := SaveAuditJSON(culturalEvents, cityEvents, culturalParseErrors, cityParseErrors, nil, auditPath, buildTime, duration)
 	if err != nil {
 		t.Fatalf("SaveAuditJSON failed: %v", err)
 	}
@@ -3,6 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -16,6 +21,11 @@ type Config struct {
 	Snapshot       SnapshotConfig       `toml:"snapshot"`
 	Server         ServerConfig         `toml:"server"`
 	Weather        WeatherConfig        `toml:"weather"`
+	Sources        SourcesConfig        `toml:"sources"`
+	Fetch          FetchConfig          `toml:"fetch"`
+	Guard          GuardConfig          `toml:"guard"`
+	Duplicates     DuplicatesConfig     `toml:"duplicates"`
+	Zones          []ZoneConfig         `toml:"zones"`
 }
 
 // CulturalEventsConfig holds configuration for datos.madrid.es cultural programming.
@@ -30,6 +40,75 @@ type CityEventsConfig struct {
 	XMLURL string `toml:"xml_url"`
 }
 
+// SourcesConfig selects which registered event sources run, in order.
+type SourcesConfig struct {
+	Enabled []string `toml:"enabled"` // Registry names, e.g. "datos.madrid.es", "esmadrid.com"
+}
+
+// FetchConfig holds retry and deadline settings for upstream requests.
+// (Caching and throttling come from the -fetch-mode flag, see fetch.ModeConfig.)
+type FetchConfig struct {
+	BuildDeadline time.Duration         `toml:"build_deadline"` // No retries are scheduled after the build has run this long
+	MaxStale      time.Duration         `toml:"max_stale"`      // Serve cache this long past expiry when upstream fails (0 = never)
+	Retry         RetryConfig           `toml:"retry"`          // Default retry policy
+	Hosts         map[string]HostConfig `toml:"hosts"`          // Per-host settings, keyed by hostname
+	Cache         CacheConfig           `toml:"cache"`
+}
+
+// CacheConfig bounds the HTTP cache (<data_dir>/http-cache) and overrides its TTL.
+// After each build, entries older than max_age are removed, then the least
+// recently used ones until the cache fits in max_size_mb. Zero disables a limit.
+type CacheConfig struct {
+	MaxSizeMB    int                      `toml:"max_size_mb"`   // Maximum size on disk, in MiB
+	MaxAge       time.Duration            `toml:"max_age"`       // Remove entries fetched longer ago than this
+	TTLOverrides map[string]time.Duration `toml:"ttl_overrides"` // URL substring -> TTL (longest match wins)
+}
+
+// RetryConfig describes retries with exponential backoff for one host.
+type RetryConfig struct {
+	MaxAttempts int           `toml:"max_attempts"` // Total attempts including the first
+	BaseDelay   time.Duration `toml:"base_delay"`   // Backoff before the first retry; doubles each retry
+	MaxDelay    time.Duration `toml:"max_delay"`    // Backoff cap; longer Retry-After values give up
+	Jitter      float64       `toml:"jitter"`       // Fraction of each backoff that is randomized (0-1)
+}
+
+// HostConfig overrides fetch settings for one host. Unset retry fields
+// inherit from [fetch.retry]; an unset max_stale inherits [fetch] max_stale.
+type HostConfig struct {
+	RetryConfig
+	MaxStale *time.Duration `toml:"max_stale"` // Pointer so "0s" can disable stale-if-error for one host
+}
+
+// MaxStaleFor returns the stale-if-error window for host.
+func (f FetchConfig) MaxStaleFor(host string) time.Duration {
+	if h, ok := f.Hosts[host]; ok && h.MaxStale != nil {
+		return *h.MaxStale
+	}
+	return f.MaxStale
+}
+
+// RetryFor returns the retry settings for host, filling unset fields from the default.
+func (f FetchConfig) RetryFor(host string) RetryConfig {
+	r := f.Retry
+	h, ok := f.Hosts[host]
+	if !ok {
+		return r
+	}
+	if h.MaxAttempts != 0 {
+		r.MaxAttempts = h.MaxAttempts
+	}
+	if h.BaseDelay != 0 {
+		r.BaseDelay = h.BaseDelay
+	}
+	if h.MaxDelay != 0 {
+		r.MaxDelay = h.MaxDelay
+	}
+	if h.Jitter != 0 {
+		r.Jitter = h.Jitter
+	}
+	return r
+}
+
 // FilterConfig holds event filtering criteria.
 type FilterConfig struct {
 	Latitude        float64  `toml:"latitude"`
@@ -37,6 +116,105 @@ type FilterConfig struct {
 	RadiusKm        float64  `toml:"radius_km"`
 	Distritos       []string `toml:"distritos"`
 	PastEventsWeeks int      `toml:"past_events_weeks"`
+
+	// Geofence is a GeoJSON file of named areas (Polygon or MultiPolygon
+	// features) for polygon rules, relative to the config file.
+	Geofence string `toml:"geofence"`
+
+	Coordinates CoordinatesConfig `toml:"coordinates"`
+	Gazetteer   GazetteerConfig   `toml:"gazetteer"`
+	Text        TextConfig        `toml:"text"`
+	Rules       FilterRulesConfig `toml:"rules"`
+}
+
+// TextConfig configures how text rules find places in an event's title,
+// venue, address and description. Phrases match whole words, ignoring case,
+// accents and punctuation; the words of an excluded phrase don't count as a
+// mention. A text rule matches when the weights of the fields it found a
+// phrase in add up to threshold.
+type TextConfig struct {
+	PlazaEspana []string    `toml:"plaza_espana"` // Spellings of "Plaza de España", searched by every text rule
+	Exclude     []string    `toml:"exclude"`      // E.g. "plaza de españa de leganés"; zone aliases honor them too
+	Weights     TextWeights `toml:"weights"`
+	Threshold   float64     `toml:"threshold"` // Zero means 1
+
+	// Search rules' keywords in the title too, not just plaza_espana. Off by
+	// default: titles often merely mention other places.
+	KeywordsInTitle bool `toml:"keywords_in_title"`
+}
+
+// TextWeights is how much a phrase found in each field counts. Zero skips the field.
+type TextWeights struct {
+	Title       float64 `toml:"title"`
+	Venue       float64 `toml:"venue"`
+	Address     float64 `toml:"address"`
+	Description float64 `toml:"description"`
+}
+
+// FilterRulesConfig holds each pipeline's ordered filter rules. Gate rules
+// (time, category) drop the events failing them; the first location rule
+// (distrito, radius, polygon, text) that matches keeps the event, and one that
+// doesn't drops it, or with on_miss = "next" leaves it to later location rules.
+type FilterRulesConfig struct {
+	Cultural []FilterRule `toml:"cultural"`
+	City     []FilterRule `toml:"city"`
+}
+
+// FilterRule is one filter rule. Settings it leaves unset come from [filter].
+type FilterRule struct {
+	Type       string      `toml:"type"`       // "distrito", "radius", "polygon", "text", "time" or "category"
+	OnMiss     string      `toml:"on_miss"`    // Location rules: "drop" (default) or "next"
+	Distritos  []string    `toml:"distritos"`  // distrito: default filter.distritos
+	RadiusKm   float64     `toml:"radius_km"`  // radius: around filter.latitude/longitude, default filter.radius_km
+	Geofence   string      `toml:"geofence"`   // polygon: GeoJSON file, default filter.geofence
+	Name       string      `toml:"name"`       // polygon: the area's name, for an inline polygon
+	Polygon    [][]float64 `toml:"polygon"`    // polygon: inline [latitude, longitude] vertices instead of a geofence
+	Keywords   []string    `toml:"keywords"`   // text: phrases besides filter.text.plaza_espana
+	Date       string      `toml:"date"`       // time: "start" (default) or "end", checked against filter.past_events_weeks
+	Categories []string    `toml:"categories"` // category: allowed categories (events without one pass)
+}
+
+// CoordinatesConfig holds the coordinate quality checks run before filtering.
+// Coordinates outside the bounding box (unless swapping latitude and longitude
+// brings them inside) or equal to a placeholder are cleared, so events fall
+// back to distrito and text matching. An all-zero box disables the bounds check.
+type CoordinatesConfig struct {
+	MinLatitude  float64     `toml:"min_latitude"`
+	MaxLatitude  float64     `toml:"max_latitude"`
+	MinLongitude float64     `toml:"min_longitude"`
+	MaxLongitude float64     `toml:"max_longitude"`
+	Placeholders [][]float64 `toml:"placeholders"` // [latitude, longitude] pairs geocoders return for "somewhere in Madrid"
+}
+
+// GazetteerConfig configures the venue gazetteer, which gives events without
+// coordinates (after the coordinate checks) those of their venue, looked up
+// by venue ID (esmadrid idrt, datos.madrid.es ID-INSTALACION), then venue
+// name, then address. Every build learns the places of the events that have
+// coordinates; entries marked "manual" in the file are added by hand and never
+// updated. A place whose events are more than max_spread_m apart is ambiguous
+// and isn't used.
+type GazetteerConfig struct {
+	Enabled    bool    `toml:"enabled"`
+	File       string  `toml:"file"`         // Relative to snapshot.data_dir
+	MaxSpreadM float64 `toml:"max_spread_m"` // Meters
+}
+
+// ZoneConfig is a named place the site lets visitors filter events by (the
+// "En Plaza" buttons). An event is in the first zone whose geometry contains
+// its coordinates, or else the first zone one of whose aliases its title,
+// venue, address or description mentions (ignoring case and accents).
+type ZoneConfig struct {
+	ID      string   `toml:"id"`      // Lowercase letters and digits, used in the page's HTML and CSS
+	Name    string   `toml:"name"`    // Button label, e.g. "En Plaza"
+	Aliases []string `toml:"aliases"` // Phrases naming the zone
+
+	// Geometry: at most one of a circle, a geofence area or an inline polygon
+	RadiusM   float64     `toml:"radius_m"`  // Circle radius in meters
+	Latitude  float64     `toml:"latitude"`  // Circle center, default filter.latitude
+	Longitude float64     `toml:"longitude"` // Circle center, default filter.longitude
+	Area      string      `toml:"area"`      // Name of an area in geofence
+	Geofence  string      `toml:"geofence"`  // GeoJSON file for area, default filter.geofence
+	Polygon   [][]float64 `toml:"polygon"`   // Inline [latitude, longitude] vertices
 }
 
 // OutputConfig holds output file paths.
@@ -45,11 +223,46 @@ type OutputConfig struct {
 	JSONPath string `toml:"json_path"`
 }
 
-// SnapshotConfig holds snapshot directory configuration.
+// SnapshotConfig holds snapshot directory configuration and how much
+// snapshot history (one generation per build) to keep.
 type SnapshotConfig struct {
-	DataDir string `toml:"data_dir"`
+	DataDir    string `toml:"data_dir"`
+	KeepHourly int    `toml:"keep_hourly"` // Newest generation of each of the last N hours
+	KeepDaily  int    `toml:"keep_daily"`  // Newest generation of each of the last N days
 }
 
+// GuardConfig configures the publish guard. When an event count (events
+// fetched per source, events kept per pipeline) falls by more than max_drop
+// below its median over recent published builds, or to zero, the build keeps
+// the previous index.html and events.json and exits non-zero. History lives
+// in <data_dir>/publish-history.json.
+type GuardConfig struct {
+	Enabled     bool    `toml:"enabled"`
+	MaxDrop     float64 `toml:"max_drop"`     // Fraction a count may fall below its baseline (0-1)
+	MinBaseline int     `toml:"min_baseline"` // Smaller baselines are only checked for a drop to zero
+	History     int     `toml:"history"`      // Published builds remembered
+	MinHistory  int     `toml:"min_history"`  // Builds needed before a count is checked
+}
+
+// DuplicatesConfig configures cross-feed duplicate detection. A kept
+// cultural event and a kept city event are the same event when their titles
+// are at least min_title_similarity alike (0-1, shared words), their dates
+// overlap, and they are within max_distance_m of each other (or, without
+// coordinates, at the same venue). Each pair renders as one card crediting
+// both feeds, with the fields of the feed first in priority; the audit export
+// lists the pairs.
+type DuplicatesConfig struct {
+	Enabled            bool     `toml:"enabled"`
+	MinTitleSimilarity float64  `toml:"min_title_similarity"`
+	MaxDistanceM       float64  `toml:"max_distance_m"`
+	Priority           []string `toml:"priority"` // Names from DuplicateFeeds, the first's fields winning
+}
+
+// DuplicateFeeds are the source names duplicates.priority may list: the two
+// feeds duplicates are found across (source.DatosMadridName and
+// source.EsmadridName, which config can't import).
+var DuplicateFeeds = []string{"datos.madrid.es", "esmadrid.com"}
+
 // ServerConfig holds development server settings.
 type ServerConfig struct {
 	Port int `toml:"port"`
@@ -65,6 +278,13 @@ type WeatherConfig struct {
 
 // DefaultConfig returns a Config with sensible default values.
 func DefaultConfig() *Config {
+	// Spellings of Plaza de España, abbreviations included (punctuation is ignored)
+	plazaEspana := []string{
+		"plaza de españa", "plaza españa",
+		"pza de españa", "pza españa",
+		"pl de españa", "pl españa",
+		"plz españa",
+	}
 	return &Config{
 		CulturalEvents: CulturalEventsConfig{
 			JSONURL: "https://datos.madrid.es/egob/catalogo/300107-0-agenda-actividades-eventos.json",
@@ -80,13 +300,54 @@ func DefaultConfig() *Config {
 			RadiusKm:        0.35,
 			Distritos:       []string{"CENTRO", "MONCLOA-ARAVACA"},
 			PastEventsWeeks: 2,
+			Coordinates: CoordinatesConfig{
+				// Municipality of Madrid, with a small margin
+				MinLatitude:  40.30,
+				MaxLatitude:  40.65,
+				MinLongitude: -3.90,
+				MaxLongitude: -3.50,
+				Placeholders: [][]float64{
+					{40.4167754, -3.7037902}, // Geocoded "Madrid" (Puerta del Sol)
+					{40.416775, -3.70379},
+				},
+			},
+			Gazetteer: GazetteerConfig{
+				Enabled:    true,
+				File:       "gazetteer.json",
+				MaxSpreadM: 150,
+			},
+			Text: TextConfig{
+				PlazaEspana: plazaEspana,
+				Exclude:     []string{"plaza de españa de leganés"},
+				Weights:     TextWeights{Title: 1, Venue: 1, Address: 1, Description: 1},
+				Threshold:   1,
+			},
+			Rules: FilterRulesConfig{
+				// Cultural events mostly have a distrito; those without one are
+				// placed by coordinates, then by venue text
+				Cultural: []FilterRule{
+					{Type: "distrito"},
+					{Type: "radius"},
+					{Type: "text", Keywords: []string{"templo de debod", "parque del oeste", "conde duque"}},
+					{Type: "time", Date: "start"},
+				},
+				// City events have coordinates, but multi-venue events list
+				// Plaza de España among venues elsewhere
+				City: []FilterRule{
+					{Type: "radius", OnMiss: "next"},
+					{Type: "text"},
+					{Type: "time", Date: "end"},
+				},
+			},
 		},
 		Output: OutputConfig{
 			HTMLPath: "public/index.html",
 			JSONPath: "public/events.json",
 		},
 		Snapshot: SnapshotConfig{
-			DataDir: "data",
+			DataDir:    "data",
+			KeepHourly: 24,
+			KeepDaily:  7,
 		},
 		Server: ServerConfig{
 			Port: 8080,
@@ -96,6 +357,49 @@ func DefaultConfig() *Config {
 			APIKeyEnv:        "AEMET_API_KEY",
 			MunicipalityCode: "28079", // Madrid
 		},
+		Sources: SourcesConfig{
+			Enabled: []string{"datos.madrid.es", "esmadrid.com"},
+		},
+		Fetch: FetchConfig{
+			BuildDeadline: 10 * time.Minute, // Well inside the hourly cron interval
+			MaxStale:      6 * time.Hour,    // Recent cache beats the coarser snapshot fallback
+			Retry: RetryConfig{
+				MaxAttempts: 3,
+				BaseDelay:   2 * time.Second,
+				MaxDelay:    30 * time.Second,
+				Jitter:      0.2,
+			},
+			Cache: CacheConfig{
+				MaxSizeMB: 50,
+				MaxAge:    7 * 24 * time.Hour, // Far beyond any TTL + max_stale
+				TTLOverrides: map[string]time.Duration{
+					// AEMET forecasts update 3-4x daily and AEMET rate limits aggressively
+					"opendata.aemet.es": 6 * time.Hour,
+					"aemet-forecast://": 6 * time.Hour,
+				},
+			},
+		},
+		Guard: GuardConfig{
+			Enabled:     true,
+			MaxDrop:     0.5,
+			MinBaseline: 10,
+			History:     24,
+			MinHistory:  3,
+		},
+		Duplicates: DuplicatesConfig{
+			Enabled:            true,
+			MinTitleSimilarity: 0.6,
+			MaxDistanceM:       250,
+			Priority:           []string{"datos.madrid.es", "esmadrid.com"},
+		},
+		Zones: []ZoneConfig{
+			{
+				ID:      "plaza",
+				Name:    "En Plaza",
+				RadiusM: 50,
+				Aliases: plazaEspana,
+			},
+		},
 	}
 }
 
@@ -107,10 +411,119 @@ func Load(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := toml.Unmarshal(data, &cfg); err != nil {
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Configs written before [sources] existed run every source
+	if len(cfg.Sources.Enabled) == 0 {
+		cfg.Sources.Enabled = DefaultConfig().Sources.Enabled
+	}
+
+	// Configs written before [fetch] retries existed get the default policy
+	defaults := DefaultConfig().Fetch
+	if cfg.Fetch.BuildDeadline == 0 {
+		cfg.Fetch.BuildDeadline = defaults.BuildDeadline
+	}
+	if cfg.Fetch.Retry == (RetryConfig{}) {
+		cfg.Fetch.Retry = defaults.Retry
+	}
+	if !md.IsDefined("fetch", "max_stale") {
+		cfg.Fetch.MaxStale = defaults.MaxStale
+	}
+	if !md.IsDefined("fetch", "cache", "max_size_mb") {
+		cfg.Fetch.Cache.MaxSizeMB = defaults.Cache.MaxSizeMB
+	}
+	if !md.IsDefined("fetch", "cache", "max_age") {
+		cfg.Fetch.Cache.MaxAge = defaults.Cache.MaxAge
+	}
+	if !md.IsDefined("fetch", "cache", "ttl_overrides") {
+		cfg.Fetch.Cache.TTLOverrides = defaults.Cache.TTLOverrides
+	}
+
+	// Configs written before snapshot history existed get the default retention
+	if !md.IsDefined("snapshot", "keep_hourly") {
+		cfg.Snapshot.KeepHourly = DefaultConfig().Snapshot.KeepHourly
+	}
+	if !md.IsDefined("snapshot", "keep_daily") {
+		cfg.Snapshot.KeepDaily = DefaultConfig().Snapshot.KeepDaily
+	}
+
+	// Configs written before [guard] existed get the default publish guard
+	if !md.IsDefined("guard") {
+		cfg.Guard = DefaultConfig().Guard
+	}
+
+	// Configs written before [duplicates] existed get duplicate detection
+	if !md.IsDefined("duplicates") {
+		cfg.Duplicates = DefaultConfig().Duplicates
+	}
+
+	// Configs written before [filter.coordinates] existed get Madrid's bounds
+	if !md.IsDefined("filter", "coordinates") {
+		cfg.Filter.Coordinates = DefaultConfig().Filter.Coordinates
+	}
+
+	// Configs written before [filter.gazetteer] existed get the gazetteer
+	if !md.IsDefined("filter", "gazetteer") {
+		cfg.Filter.Gazetteer = DefaultConfig().Filter.Gazetteer
+	}
+
+	// Configs written before [filter.text] existed match the same phrases in every field
+	defaultText := DefaultConfig().Filter.Text
+	if !md.IsDefined("filter", "text", "plaza_espana") {
+		cfg.Filter.Text.PlazaEspana = defaultText.PlazaEspana
+	}
+	if !md.IsDefined("filter", "text", "exclude") {
+		cfg.Filter.Text.Exclude = defaultText.Exclude
+	}
+	if !md.IsDefined("filter", "text", "threshold") {
+		cfg.Filter.Text.Threshold = defaultText.Threshold
+	}
+	weights := []struct {
+		key          string
+		value        *float64
+		defaultValue float64
+	}{
+		{"title", &cfg.Filter.Text.Weights.Title, defaultText.Weights.Title},
+		{"venue", &cfg.Filter.Text.Weights.Venue, defaultText.Weights.Venue},
+		{"address", &cfg.Filter.Text.Weights.Address, defaultText.Weights.Address},
+		{"description", &cfg.Filter.Text.Weights.Description, defaultText.Weights.Description},
+	}
+	for _, w := range weights {
+		if !md.IsDefined("filter", "text", "weights", w.key) {
+			*w.value = w.defaultValue
+		}
+	}
+
+	// Geofences are relative to the config file
+	dir := filepath.Dir(path)
+	cfg.Filter.Geofence = resolvePath(dir, cfg.Filter.Geofence)
+	for i := range cfg.Filter.Rules.Cultural {
+		cfg.Filter.Rules.Cultural[i].Geofence = resolvePath(dir, cfg.Filter.Rules.Cultural[i].Geofence)
+	}
+	for i := range cfg.Filter.Rules.City {
+		cfg.Filter.Rules.City[i].Geofence = resolvePath(dir, cfg.Filter.Rules.City[i].Geofence)
+	}
+
+	for i := range cfg.Zones {
+		cfg.Zones[i].Geofence = resolvePath(dir, cfg.Zones[i].Geofence)
+	}
+
+	// Pipelines without [[filter.rules.<pipeline>]] get the default rules
+	if !md.IsDefined("filter", "rules", "cultural") {
+		cfg.Filter.Rules.Cultural = DefaultConfig().Filter.Rules.Cultural
+	}
+	if !md.IsDefined("filter", "rules", "city") {
+		cfg.Filter.Rules.City = DefaultConfig().Filter.Rules.City
+	}
+
+	// Configs without [[zones]] get the "En Plaza" zone
+	if !md.IsDefined("zones") {
+		cfg.Zones = DefaultConfig().Zones
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
@@ -136,6 +549,48 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("city_events.xml_url must not be empty")
 	}
 
+	// Validate sources (names are checked against the registry at build time)
+	seenSources := make(map[string]bool)
+	for _, name := range c.Sources.Enabled {
+		if name == "" {
+			return fmt.Errorf("sources.enabled must not contain empty names")
+		}
+		if seenSources[name] {
+			return fmt.Errorf("sources.enabled lists %q more than once", name)
+		}
+		seenSources[name] = true
+	}
+
+	// Validate fetch retry settings
+	if c.Fetch.BuildDeadline < 0 {
+		return fmt.Errorf("fetch.build_deadline must not be negative, got %v", c.Fetch.BuildDeadline)
+	}
+	if err := validateRetry("fetch.retry", c.Fetch.Retry); err != nil {
+		return err
+	}
+	for host := range c.Fetch.Hosts {
+		if err := validateRetry(fmt.Sprintf("fetch.hosts.%q", host), c.Fetch.RetryFor(host)); err != nil {
+			return err
+		}
+		if c.Fetch.MaxStaleFor(host) < 0 {
+			return fmt.Errorf("fetch.hosts.%q.max_stale must not be negative", host)
+		}
+	}
+	if c.Fetch.MaxStale < 0 {
+		return fmt.Errorf("fetch.max_stale must not be negative, got %v", c.Fetch.MaxStale)
+	}
+	if c.Fetch.Cache.MaxSizeMB < 0 {
+		return fmt.Errorf("fetch.cache.max_size_mb must not be negative, got %d", c.Fetch.Cache.MaxSizeMB)
+	}
+	if c.Fetch.Cache.MaxAge < 0 {
+		return fmt.Errorf("fetch.cache.max_age must not be negative, got %v", c.Fetch.Cache.MaxAge)
+	}
+	for pattern, ttl := range c.Fetch.Cache.TTLOverrides {
+		if pattern == "" || ttl <= 0 {
+			return fmt.Errorf("fetch.cache.ttl_overrides: %q must be a non-empty pattern with a positive TTL, got %v", pattern, ttl)
+		}
+	}
+
 	// Validate coordinates
 	if c.Filter.Latitude < -90 || c.Filter.Latitude > 90 {
 		return fmt.Errorf("filter.latitude must be between -90 and 90, got %f", c.Filter.Latitude)
@@ -144,11 +599,61 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("filter.longitude must be between -180 and 180, got %f", c.Filter.Longitude)
 	}
 
+	coords := c.Filter.Coordinates
+	boxSet := coords.MinLatitude != 0 || coords.MaxLatitude != 0 || coords.MinLongitude != 0 || coords.MaxLongitude != 0
+	if boxSet && (coords.MinLatitude >= coords.MaxLatitude || coords.MinLongitude >= coords.MaxLongitude) {
+		return fmt.Errorf("filter.coordinates: bounding box is empty (latitude %v to %v, longitude %v to %v)",
+			coords.MinLatitude, coords.MaxLatitude, coords.MinLongitude, coords.MaxLongitude)
+	}
+	for _, p := range coords.Placeholders {
+		if len(p) != 2 {
+			return fmt.Errorf("filter.coordinates.placeholders: want [latitude, longitude] pairs, got %v", p)
+		}
+	}
+
+	if c.Filter.Gazetteer.Enabled {
+		if c.Filter.Gazetteer.File == "" {
+			return fmt.Errorf("filter.gazetteer.file must not be empty")
+		}
+		if c.Filter.Gazetteer.MaxSpreadM <= 0 {
+			return fmt.Errorf("filter.gazetteer.max_spread_m must be positive, got %f", c.Filter.Gazetteer.MaxSpreadM)
+		}
+	}
+
 	// Validate radius
 	if c.Filter.RadiusKm <= 0 {
 		return fmt.Errorf("filter.radius_km must be positive, got %f", c.Filter.RadiusKm)
 	}
 
+	// Validate text matching
+	text := c.Filter.Text
+	if text.Threshold < 0 {
+		return fmt.Errorf("filter.text.threshold must not be negative, got %f", text.Threshold)
+	}
+	if text.Weights.Title < 0 || text.Weights.Venue < 0 || text.Weights.Address < 0 || text.Weights.Description < 0 {
+		return fmt.Errorf("filter.text.weights must not be negative")
+	}
+	for _, phrases := range [][]string{text.PlazaEspana, text.Exclude} {
+		for _, p := range phrases {
+			if strings.TrimSpace(p) == "" {
+				return fmt.Errorf("filter.text phrases must not be empty")
+			}
+		}
+	}
+
+	// Validate filter rules
+	if err := validateRules("filter.rules.cultural", c.Filter.Rules.Cultural, c.Filter.Geofence); err != nil {
+		return err
+	}
+	if err := validateRules("filter.rules.city", c.Filter.Rules.City, c.Filter.Geofence); err != nil {
+		return err
+	}
+
+	// Validate zones
+	if err := validateZones(c.Zones, c.Filter.Geofence); err != nil {
+		return err
+	}
+
 	// Validate output paths
 	if c.Output.HTMLPath == "" {
 		return fmt.Errorf("output.html_path must not be empty")
@@ -161,6 +666,42 @@ func (c *Config) Validate() error {
 	if c.Snapshot.DataDir == "" {
 		return fmt.Errorf("snapshot.data_dir must not be empty")
 	}
+	if c.Snapshot.KeepHourly < 0 || c.Snapshot.KeepDaily < 0 {
+		return fmt.Errorf("snapshot.keep_hourly and snapshot.keep_daily must not be negative")
+	}
+
+	// Validate duplicate detection
+	if c.Duplicates.Enabled {
+		if c.Duplicates.MinTitleSimilarity <= 0 || c.Duplicates.MinTitleSimilarity > 1 {
+			return fmt.Errorf("duplicates.min_title_similarity must be between 0 (exclusive) and 1, got %v", c.Duplicates.MinTitleSimilarity)
+		}
+		if c.Duplicates.MaxDistanceM <= 0 {
+			return fmt.Errorf("duplicates.max_distance_m must be positive, got %v", c.Duplicates.MaxDistanceM)
+		}
+		seenPriority := make(map[string]bool)
+		for _, name := range c.Duplicates.Priority {
+			if !slices.Contains(DuplicateFeeds, name) {
+				return fmt.Errorf("duplicates.priority must list only %q, got %q", DuplicateFeeds, name)
+			}
+			if seenPriority[name] {
+				return fmt.Errorf("duplicates.priority must list source names at most once, got %q", c.Duplicates.Priority)
+			}
+			seenPriority[name] = true
+		}
+	}
+
+	// Validate publish guard
+	if c.Guard.Enabled {
+		if c.Guard.MaxDrop <= 0 || c.Guard.MaxDrop >= 1 {
+			return fmt.Errorf("guard.max_drop must be between 0 and 1 (exclusive)")
+		}
+		if c.Guard.MinBaseline < 0 {
+			return fmt.Errorf("guard.min_baseline must not be negative")
+		}
+		if c.Guard.MinHistory < 1 || c.Guard.History < c.Guard.MinHistory {
+			return fmt.Errorf("guard.min_history must be at least 1 and no more than guard.history")
+		}
+	}
 
 	// Validate server port
 	if c.Server.Port < 1 || c.Server.Port > 65535 {
@@ -177,3 +718,124 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// validateRules checks one pipeline's filter rules; section names them in
+// error messages and geofence is filter.geofence.
+func validateRules(section string, rules []FilterRule, geofence string) error {
+	for i, r := range rules {
+		name := fmt.Sprintf("%s[%d]", section, i)
+		switch r.Type {
+		case "distrito", "radius", "text":
+		case "polygon":
+			if len(r.Polygon) == 0 && r.Geofence == "" && geofence == "" {
+				return fmt.Errorf("%s: polygon rule needs a polygon, a geofence or filter.geofence", name)
+			}
+			if len(r.Polygon) > 0 && len(r.Polygon) < 3 {
+				return fmt.Errorf("%s: polygon needs at least 3 vertices", name)
+			}
+			for _, v := range r.Polygon {
+				if len(v) != 2 {
+					return fmt.Errorf("%s.polygon: want [latitude, longitude] pairs, got %v", name, v)
+				}
+			}
+		case "time":
+			if r.Date != "" && r.Date != "start" && r.Date != "end" {
+				return fmt.Errorf("%s.date must be \"start\" or \"end\", got %q", name, r.Date)
+			}
+		case "category":
+			if len(r.Categories) == 0 {
+				return fmt.Errorf("%s.categories must not be empty", name)
+			}
+		default:
+			return fmt.Errorf("%s.type must be distrito, radius, polygon, text, time or category, got %q", name, r.Type)
+		}
+		if r.OnMiss != "" && r.OnMiss != "drop" && r.OnMiss != "next" {
+			return fmt.Errorf("%s.on_miss must be \"drop\" or \"next\", got %q", name, r.OnMiss)
+		}
+		if r.RadiusKm < 0 {
+			return fmt.Errorf("%s.radius_km must not be negative, got %f", name, r.RadiusKm)
+		}
+	}
+	return nil
+}
+
+// zoneID is the form of zone ids, which the page's HTML attribute names use.
+var zoneID = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// validateZones checks the zones; geofence is filter.geofence.
+func validateZones(zones []ZoneConfig, geofence string) error {
+	seen := make(map[string]bool)
+	for i, z := range zones {
+		name := fmt.Sprintf("zones[%d]", i)
+		if !zoneID.MatchString(z.ID) {
+			return fmt.Errorf("%s.id must be lowercase letters and digits, got %q", name, z.ID)
+		}
+		if z.ID == "nearby" {
+			return fmt.Errorf("%s.id %q is reserved for the filter showing every event", name, z.ID)
+		}
+		if seen[z.ID] {
+			return fmt.Errorf("zones: id %q is used more than once", z.ID)
+		}
+		seen[z.ID] = true
+		if z.Name == "" {
+			return fmt.Errorf("%s.name must not be empty", name)
+		}
+
+		geometries := 0
+		if z.RadiusM != 0 {
+			geometries++
+		}
+		if z.Area != "" {
+			geometries++
+		}
+		if len(z.Polygon) > 0 {
+			geometries++
+		}
+		if geometries > 1 {
+			return fmt.Errorf("%s: set only one of radius_m, area and polygon", name)
+		}
+		if geometries == 0 && len(z.Aliases) == 0 {
+			return fmt.Errorf("%s: zone needs a geometry (radius_m, area or polygon) or aliases", name)
+		}
+		if z.RadiusM < 0 {
+			return fmt.Errorf("%s.radius_m must not be negative, got %f", name, z.RadiusM)
+		}
+		if z.Area != "" && z.Geofence == "" && geofence == "" {
+			return fmt.Errorf("%s: area needs a geofence or filter.geofence", name)
+		}
+		if len(z.Polygon) > 0 && len(z.Polygon) < 3 {
+			return fmt.Errorf("%s: polygon needs at least 3 vertices", name)
+		}
+		for _, v := range z.Polygon {
+			if len(v) != 2 {
+				return fmt.Errorf("%s.polygon: want [latitude, longitude] pairs, got %v", name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// resolvePath returns path relative to dir, unless it is empty or absolute.
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// validateRetry checks one retry policy; section names it in error messages.
+func validateRetry(section string, r RetryConfig) error {
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("%s.max_attempts must not be negative, got %d", section, r.MaxAttempts)
+	}
+	if r.BaseDelay < 0 || r.MaxDelay < 0 {
+		return fmt.Errorf("%s delays must not be negative", section)
+	}
+	if r.MaxDelay < r.BaseDelay {
+		return fmt.Errorf("%s.max_delay (%v) must be at least base_delay (%v)", section, r.MaxDelay, r.BaseDelay)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("%s.jitter must be between 0 and 1, got %f", section, r.Jitter)
+	}
+	return nil
+}
@@ -3,8 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 func TestLoad_ValidConfig(t *testing.T) {
@@ -98,6 +102,14 @@ municipality_code = "28079"
 	if cfg.Snapshot.DataDir != "data" {
 		t.Errorf("Snapshot.DataDir = %q, want %q", cfg.Snapshot.DataDir, "data")
 	}
+	if cfg.Snapshot.KeepHourly != 24 || cfg.Snapshot.KeepDaily != 7 {
+		t.Errorf("Snapshot keep_hourly/keep_daily = %d/%d, want defaults 24/7", cfg.Snapshot.KeepHourly, cfg.Snapshot.KeepDaily)
+	}
+
+	// Verify Guard (defaults: no [guard] section)
+	if cfg.Guard != DefaultConfig().Guard {
+		t.Errorf("Guard = %+v, want defaults %+v", cfg.Guard, DefaultConfig().Guard)
+	}
 
 	// Verify Server
 	if cfg.Server.Port != 8080 {
@@ -514,3 +526,773 @@ func TestValidate_InvalidServerPort(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_SourcesDefaultWhenMissing(t *testing.T) {
+	// Config without a [sources] section runs every default source
+	cfg := DefaultConfig()
+	cfg.Sources = SourcesConfig{}
+
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(cfg); err != nil {
+		t.Fatalf("encoding config: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(b.String()), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	loaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := DefaultConfig().Sources.Enabled
+	if strings.Join(loaded.Sources.Enabled, ",") != strings.Join(want, ",") {
+		t.Errorf("Sources.Enabled = %v, want %v", loaded.Sources.Enabled, want)
+	}
+}
+
+func TestValidate_DuplicateSources(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Sources.Enabled = []string{"esmadrid.com", "esmadrid.com"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() succeeded, want error for duplicate source")
+	}
+	if !strings.Contains(err.Error(), "sources.enabled") {
+		t.Errorf("Validate() error = %v, want error containing 'sources.enabled'", err)
+	}
+}
+
+func TestLoad_FetchRetry(t *testing.T) {
+	fetchTOML := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/agenda.xml"
+
+[filter]
+latitude = 40.42338
+longitude = -3.71217
+radius_km = 0.35
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[fetch]
+build_deadline = "5m"
+max_stale = "2h"
+
+[fetch.retry]
+max_attempts = 2
+base_delay = "1s"
+max_delay = "10s"
+
+[fetch.hosts."opendata.aemet.es"]
+max_attempts = 5
+max_delay = "1m"
+max_stale = "0s"
+`
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(fetchTOML), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	loaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if loaded.Fetch.BuildDeadline != 5*time.Minute {
+		t.Errorf("Fetch.BuildDeadline = %v, want 5m", loaded.Fetch.BuildDeadline)
+	}
+
+	tests := []struct {
+		host string
+		want RetryConfig
+	}{
+		{"datos.madrid.es", RetryConfig{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second}},
+		{"opendata.aemet.es", RetryConfig{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Minute}},
+	}
+	for _, tt := range tests {
+		if got := loaded.Fetch.RetryFor(tt.host); got != tt.want {
+			t.Errorf("RetryFor(%q) = %+v, want %+v", tt.host, got, tt.want)
+		}
+	}
+
+	// max_stale inherits unless the host sets it, even to zero
+	if got := loaded.Fetch.MaxStaleFor("datos.madrid.es"); got != 2*time.Hour {
+		t.Errorf("MaxStaleFor(datos.madrid.es) = %v, want 2h", got)
+	}
+	if got := loaded.Fetch.MaxStaleFor("opendata.aemet.es"); got != 0 {
+		t.Errorf("MaxStaleFor(opendata.aemet.es) = %v, want 0 (disabled)", got)
+	}
+}
+
+func TestLoad_FetchDefaultWhenMissing(t *testing.T) {
+	// Config without a [fetch] section gets the default retry and stale settings
+	var b strings.Builder
+	if err := toml.NewEncoder(&b).Encode(DefaultConfig()); err != nil {
+		t.Fatalf("encoding config: %v", err)
+	}
+	encoded := b.String()
+	if i := strings.Index(encoded, "[fetch"); i >= 0 {
+		encoded = encoded[:i] // Fetch is the last section
+	}
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(encoded), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	loaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := DefaultConfig().Fetch
+	if loaded.Fetch.BuildDeadline != want.BuildDeadline || loaded.Fetch.Retry != want.Retry || loaded.Fetch.MaxStale != want.MaxStale {
+		t.Errorf("Fetch = %+v, want defaults %+v", loaded.Fetch, want)
+	}
+	if loaded.Fetch.Cache.MaxSizeMB != want.Cache.MaxSizeMB || loaded.Fetch.Cache.MaxAge != want.Cache.MaxAge ||
+		len(loaded.Fetch.Cache.TTLOverrides) != len(want.Cache.TTLOverrides) {
+		t.Errorf("Fetch.Cache = %+v, want defaults %+v", loaded.Fetch.Cache, want.Cache)
+	}
+}
+
+func TestLoad_FetchCache(t *testing.T) {
+	configTOML := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[fetch.cache]
+max_size_mb = 0
+max_age = "24h"
+
+[fetch.cache.ttl_overrides]
+"example.com/slow" = "12h"
+`
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+
+	loaded, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	cache := loaded.Fetch.Cache
+	if cache.MaxSizeMB != 0 {
+		t.Errorf("MaxSizeMB = %d, want 0 (explicitly unlimited)", cache.MaxSizeMB)
+	}
+	if cache.MaxAge != 24*time.Hour {
+		t.Errorf("MaxAge = %v, want 24h", cache.MaxAge)
+	}
+	if len(cache.TTLOverrides) != 1 || cache.TTLOverrides["example.com/slow"] != 12*time.Hour {
+		t.Errorf("TTLOverrides = %v, want only example.com/slow = 12h", cache.TTLOverrides)
+	}
+}
+
+func TestValidate_InvalidRetry(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"negative deadline", func(c *Config) { c.Fetch.BuildDeadline = -time.Second }, "fetch.build_deadline"},
+		{"negative attempts", func(c *Config) { c.Fetch.Retry.MaxAttempts = -1 }, "fetch.retry.max_attempts"},
+		{"max below base", func(c *Config) { c.Fetch.Retry.MaxDelay = time.Second }, "fetch.retry.max_delay"},
+		{"jitter out of range", func(c *Config) { c.Fetch.Retry.Jitter = 1.5 }, "fetch.retry.jitter"},
+		{"negative max stale", func(c *Config) { c.Fetch.MaxStale = -time.Minute }, "fetch.max_stale"},
+		{"negative cache size", func(c *Config) { c.Fetch.Cache.MaxSizeMB = -1 }, "fetch.cache.max_size_mb"},
+		{"negative cache age", func(c *Config) { c.Fetch.Cache.MaxAge = -time.Hour }, "fetch.cache.max_age"},
+		{"zero ttl override", func(c *Config) {
+			c.Fetch.Cache.TTLOverrides = map[string]time.Duration{"example.org": 0}
+		}, "fetch.cache.ttl_overrides"},
+		{"host override", func(c *Config) {
+			c.Fetch.Hosts = map[string]HostConfig{"example.org": {RetryConfig: RetryConfig{Jitter: 2}}}
+		}, `fetch.hosts."example.org".jitter`},
+		{"negative snapshot retention", func(c *Config) { c.Snapshot.KeepDaily = -1 }, "snapshot.keep_daily"},
+		{"guard drop out of range", func(c *Config) { c.Guard.MaxDrop = 1 }, "guard.max_drop"},
+		{"guard history below minimum", func(c *Config) { c.Guard.History = 2 }, "guard.min_history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil {
+				t.Fatal("Validate() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_FilterCoordinates(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Configs without [filter.coordinates] get Madrid's bounds and placeholders
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	want := DefaultConfig().Filter.Coordinates
+	if got := loaded.Filter.Coordinates; got.MinLatitude != want.MinLatitude || got.MaxLongitude != want.MaxLongitude ||
+		len(got.Placeholders) != len(want.Placeholders) {
+		t.Errorf("Coordinates = %+v, want defaults %+v", got, want)
+	}
+
+	loaded, err = load(t, base+`
+[filter.coordinates]
+min_latitude = 40.0
+max_latitude = 41.0
+min_longitude = -4.0
+max_longitude = -3.0
+placeholders = [[40.5, -3.5]]
+`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Filter.Coordinates; got.MinLatitude != 40 || got.MaxLongitude != -3 ||
+		len(got.Placeholders) != 1 || got.Placeholders[0][0] != 40.5 {
+		t.Errorf("Coordinates = %+v, want the configured box and placeholder", got)
+	}
+
+	_, err = load(t, base+`
+[filter.coordinates]
+min_latitude = 41.0
+max_latitude = 40.0
+min_longitude = -4.0
+max_longitude = -3.0
+`)
+	if err == nil || !strings.Contains(err.Error(), "bounding box") {
+		t.Errorf("Load() error = %v, want an empty bounding box error", err)
+	}
+
+	_, err = load(t, base+`
+[filter.coordinates]
+placeholders = [[40.5]]
+`)
+	if err == nil || !strings.Contains(err.Error(), "placeholders") {
+		t.Errorf("Load() error = %v, want a placeholders error", err)
+	}
+}
+
+func TestLoad_FilterGazetteer(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Configs without [filter.gazetteer] get the gazetteer
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got, want := loaded.Filter.Gazetteer, DefaultConfig().Filter.Gazetteer; got != want {
+		t.Errorf("Gazetteer = %+v, want defaults %+v", got, want)
+	}
+
+	loaded, err = load(t, base+`
+[filter.gazetteer]
+enabled = false
+`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if loaded.Filter.Gazetteer.Enabled {
+		t.Errorf("Gazetteer.Enabled = true, want false")
+	}
+
+	_, err = load(t, base+`
+[filter.gazetteer]
+enabled = true
+file = "venues.json"
+`)
+	if err == nil || !strings.Contains(err.Error(), "max_spread_m") {
+		t.Errorf("Load() error = %v, want a max_spread_m error", err)
+	}
+}
+
+func TestLoad_FilterRules(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Pipelines without rules get the default ones
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got, want := len(loaded.Filter.Rules.Cultural), len(DefaultConfig().Filter.Rules.Cultural); got != want {
+		t.Errorf("len(Rules.Cultural) = %d, want %d", got, want)
+	}
+
+	loaded, err = load(t, base+`
+[[filter.rules.city]]
+type = "polygon"
+name = "Plaza"
+polygon = [[40.42, -3.72], [40.43, -3.72], [40.43, -3.71]]
+on_miss = "next"
+
+[[filter.rules.city]]
+type = "time"
+date = "end"
+`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	city := loaded.Filter.Rules.City
+	if len(city) != 2 || city[0].Type != "polygon" || len(city[0].Polygon) != 3 || city[0].OnMiss != "next" || city[1].Date != "end" {
+		t.Errorf("Rules.City = %+v, want the configured polygon and time rules", city)
+	}
+	if len(loaded.Filter.Rules.Cultural) == 0 {
+		t.Error("Rules.Cultural is empty, want the defaults alongside configured city rules")
+	}
+
+	// Geofences are relative to the config file
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(base+`geofence = "plaza.geojson"
+[[filter.rules.city]]
+type = "polygon"
+`), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	loaded, err = Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(configPath), "plaza.geojson"); loaded.Filter.Geofence != want {
+		t.Errorf("Geofence = %q, want %q", loaded.Filter.Geofence, want)
+	}
+
+	invalid := []struct {
+		rule string
+		want string
+	}{
+		{`type = "nearby"`, "type"},
+		{`type = "polygon"`, "geofence"},
+		{`type = "radius"` + "\n" + `on_miss = "keep"`, "on_miss"},
+		{`type = "polygon"` + "\n" + `polygon = [[40.42, -3.72], [40.43, -3.72]]`, "3 vertices"},
+		{`type = "time"` + "\n" + `date = "middle"`, "date"},
+		{`type = "category"`, "categories"},
+	}
+	for _, tt := range invalid {
+		_, err := load(t, base+"\n[[filter.rules.cultural]]\n"+tt.rule+"\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q) error = %v, want an error mentioning %q", tt.rule, err, tt.want)
+		}
+	}
+}
+
+func TestLoad_Zones(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Configs without zones get "En Plaza"
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(loaded.Zones) != 1 || loaded.Zones[0].ID != "plaza" || loaded.Zones[0].RadiusM != 50 || len(loaded.Zones[0].Aliases) == 0 {
+		t.Errorf("Zones = %+v, want the default plaza zone", loaded.Zones)
+	}
+
+	// An empty list turns the zone filters off
+	loaded, err = load(t, "zones = []\n"+base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(loaded.Zones) != 0 {
+		t.Errorf("Zones = %+v, want none", loaded.Zones)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(base+`
+[[zones]]
+id = "plaza"
+name = "En Plaza"
+radius_m = 50
+
+[[zones]]
+id = "debod"
+name = "Templo de Debod"
+area = "Templo de Debod"
+geofence = "zones.geojson"
+aliases = ["templo de debod"]
+`), 0644); err != nil {
+		t.Fatalf("Failed to write test config: %v", err)
+	}
+	loaded, err = Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(loaded.Zones) != 2 || loaded.Zones[1].Area != "Templo de Debod" || len(loaded.Zones[1].Aliases) != 1 {
+		t.Errorf("Zones = %+v, want the configured plaza and debod zones", loaded.Zones)
+	}
+	if want := filepath.Join(filepath.Dir(configPath), "zones.geojson"); loaded.Zones[1].Geofence != want {
+		t.Errorf("Zones[1].Geofence = %q, want %q", loaded.Zones[1].Geofence, want)
+	}
+
+	invalid := []struct {
+		zone string
+		want string
+	}{
+		{`id = "conde-duque"` + "\n" + `name = "Conde Duque"` + "\n" + `radius_m = 50`, "lowercase letters and digits"},
+		{`id = "nearby"` + "\n" + `name = "Cerca"` + "\n" + `radius_m = 50`, "reserved"},
+		{`id = "plaza"` + "\n" + `radius_m = 50`, "name"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"`, "geometry"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"` + "\n" + `radius_m = 50` + "\n" + `area = "Plaza de España"`, "only one"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"` + "\n" + `area = "Plaza de España"`, "geofence"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"` + "\n" + `radius_m = -50`, "negative"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"` + "\n" + `polygon = [[40.42, -3.72], [40.43, -3.72]]`, "3 vertices"},
+		{`id = "plaza"` + "\n" + `name = "En Plaza"` + "\n" + `radius_m = 50` + "\n[[zones]]\n" + `id = "plaza"` + "\n" + `name = "Plaza"` + "\n" + `radius_m = 80`, "more than once"},
+	}
+	for _, tt := range invalid {
+		_, err := load(t, base+"\n[[zones]]\n"+tt.zone+"\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q) error = %v, want an error mentioning %q", tt.zone, err, tt.want)
+		}
+	}
+}
+
+func TestLoad_FilterText(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Configs without [filter.text] get the default phrases and equal weights
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	defaults := DefaultConfig().Filter.Text
+	if !reflect.DeepEqual(loaded.Filter.Text, defaults) {
+		t.Errorf("Filter.Text = %+v, want the defaults %+v", loaded.Filter.Text, defaults)
+	}
+
+	// Unset weights keep their default
+	loaded, err = load(t, base+`
+[filter.text]
+plaza_espana = ["plaza de españa"]
+exclude = []
+threshold = 1.5
+keywords_in_title = true
+
+[filter.text.weights]
+description = 0.5
+`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	text := loaded.Filter.Text
+	if len(text.PlazaEspana) != 1 || len(text.Exclude) != 0 || text.Threshold != 1.5 || !text.KeywordsInTitle {
+		t.Errorf("Filter.Text = %+v, want the configured phrases, threshold and keywords_in_title", text)
+	}
+	if want := (TextWeights{Title: 1, Venue: 1, Address: 1, Description: 0.5}); text.Weights != want {
+		t.Errorf("Weights = %+v, want %+v", text.Weights, want)
+	}
+
+	invalid := []struct {
+		text string
+		want string
+	}{
+		{"threshold = -1", "threshold"},
+		{"[filter.text.weights]\ntitle = -1", "weights"},
+		{`exclude = [" "]`, "empty"},
+	}
+	for _, tt := range invalid {
+		_, err := load(t, base+"\n[filter.text]\n"+tt.text+"\n")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q) error = %v, want an error mentioning %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestLoad_Duplicates(t *testing.T) {
+	base := `
+[cultural_events]
+json_url = "https://example.com/events.json"
+xml_url = "https://example.com/events.xml"
+csv_url = "https://example.com/events.csv"
+
+[city_events]
+xml_url = "https://example.com/city.xml"
+
+[output]
+html_path = "public/index.html"
+json_path = "public/events.json"
+
+[snapshot]
+data_dir = "data"
+
+[server]
+port = 8080
+
+[weather]
+api_key_env = "AEMET_API_KEY"
+municipality_code = "28079"
+
+[filter]
+latitude = 40.42
+longitude = -3.71
+radius_km = 0.35
+`
+	load := func(t *testing.T, configTOML string) (*Config, error) {
+		t.Helper()
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		if err := os.WriteFile(configPath, []byte(configTOML), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+		return Load(configPath)
+	}
+
+	// Configs without [duplicates] get duplicate detection
+	loaded, err := load(t, base)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if want := DefaultConfig().Duplicates; !reflect.DeepEqual(loaded.Duplicates, want) {
+		t.Errorf("Duplicates = %+v, want defaults %+v", loaded.Duplicates, want)
+	}
+
+	loaded, err = load(t, base+`
+[duplicates]
+enabled = true
+min_title_similarity = 0.8
+max_distance_m = 100
+priority = ["esmadrid.com", "datos.madrid.es"]
+`)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Duplicates; got.MinTitleSimilarity != 0.8 || got.MaxDistanceM != 100 || got.Priority[0] != "esmadrid.com" {
+		t.Errorf("Duplicates = %+v, want the configured settings", got)
+	}
+
+	tests := []struct {
+		name    string
+		section string
+		wantErr string
+	}{
+		{"similarity above 1", "enabled = true\nmin_title_similarity = 1.5\nmax_distance_m = 100", "min_title_similarity"},
+		{"no distance", "enabled = true\nmin_title_similarity = 0.6", "max_distance_m"},
+		{"repeated source", "enabled = true\nmin_title_similarity = 0.6\nmax_distance_m = 100\npriority = [\"esmadrid.com\", \"esmadrid.com\"]", "priority"},
+		{"unknown source", "enabled = true\nmin_title_similarity = 0.6\nmax_distance_m = 100\npriority = [\"esmadrid\", \"datos.madrid.es\"]", "priority"},
+		{"empty source", "enabled = true\nmin_title_similarity = 0.6\nmax_distance_m = 100\npriority = [\"\"]", "priority"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, base+"\n[duplicates]\n"+tt.section+"\n")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want a %s error", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package dedupe links events listed in both feeds.
+//
+// A concert or festival datos.madrid.es lists as a cultural event often has
+// an esmadrid.com listing too, and would render twice. pipeline.Merge only
+// dedupes datos.madrid.es's formats by ID; the feeds share no IDs, so
+// duplicates across them are found by their titles, dates and places.
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/filter"
+)
+
+// Matcher finds and merges cross-feed duplicates.
+type Matcher struct {
+	MinTitleSimilarity float64 // See TitleSimilarity
+	MaxDistanceKm      float64 // Coordinates this close are the same place
+
+	CulturalSource, CitySource string   // Feed names, e.g. "datos.madrid.es" and "esmadrid.com"
+	Priority                   []string // Feed names, the first's fields winning; unlisted feeds rank last
+}
+
+// Link is a cultural event and a city event found to be the same.
+type Link struct {
+	Cultural, City int // Indexes into the slices given to Match
+	event.DuplicatePair
+}
+
+// Match links the cultural and city events that are the same event: titles
+// at least MinTitleSimilarity alike, dates overlapping (by day), and at the
+// same place: coordinates within MaxDistanceKm when both have coordinates,
+// or else the same venue name. Each event is linked at most once, the most
+// similar titles first. Links are in cultural event order.
+func (m Matcher) Match(cultural []event.CulturalEvent, city []event.CityEvent) []Link {
+	cityWords := make([][]string, len(city))
+	for j, c := range city {
+		cityWords[j] = titleWords(c.Title)
+	}
+
+	var candidates []Link
+	for i, c := range cultural {
+		words := titleWords(c.Title)
+		for j, ce := range city {
+			similarity := dice(words, cityWords[j])
+			if similarity < m.MinTitleSimilarity || !overlap(c.StartTime, c.EndTime, ce.StartDate, ce.EndDate) {
+				continue
+			}
+
+			distance := -1.0
+			if c.Latitude != 0 && c.Longitude != 0 && ce.Latitude != 0 && ce.Longitude != 0 {
+				distance = filter.HaversineDistance(c.Latitude, c.Longitude, ce.Latitude, ce.Longitude)
+			}
+			sameVenue := SameVenue(c.VenueName, ce.Venue)
+			if distance > m.MaxDistanceKm || (distance < 0 && !sameVenue) {
+				continue
+			}
+
+			candidates = append(candidates, Link{Cultural: i, City: j, DuplicatePair: event.DuplicatePair{
+				CulturalID:      c.ID,
+				CityID:          ce.ID,
+				CulturalTitle:   c.Title,
+				CityTitle:       ce.Title,
+				TitleSimilarity: similarity,
+				DistanceKm:      distance,
+				SameVenue:       sameVenue,
+				Primary:         m.primary(),
+			}})
+		}
+	}
+
+	// Best pairs first: an event listed twice in one feed links only once
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].TitleSimilarity > candidates[b].TitleSimilarity
+	})
+	linkedCultural := make(map[int]bool)
+	linkedCity := make(map[int]bool)
+	var links []Link
+	for _, l := range candidates {
+		if linkedCultural[l.Cultural] || linkedCity[l.City] {
+			continue
+		}
+		linkedCultural[l.Cultural], linkedCity[l.City] = true, true
+		links = append(links, l)
+	}
+	sort.Slice(links, func(a, b int) bool { return links[a].Cultural < links[b].Cultural })
+	return links
+}
+
+// Merge merges a city event into the cultural event it duplicates. Text and
+// coordinates both listings have come from the feed first in Priority;
+// fields one lacks come from the other. The dates and sessions are always the
+// cultural event's (the city feed has only a date range), and the event is
+// free when either listing says so. The result lists both listings.
+func (m Matcher) Merge(cultural event.CulturalEvent, city event.CityEvent) event.CulturalEvent {
+	cityFirst := m.primary() == m.CitySource
+	pick := func(fromCultural, fromCity string) string {
+		if fromCity != "" && (fromCultural == "" || cityFirst) {
+			return fromCity
+		}
+		return fromCultural
+	}
+
+	merged := cultural
+	merged.Title = pick(cultural.Title, city.Title)
+	merged.Description = pick(cultural.Description, city.Description)
+	merged.VenueName = pick(cultural.VenueName, city.Venue)
+	merged.Address = pick(cultural.Address, city.Address)
+	merged.DetailsURL = pick(cultural.DetailsURL, city.WebURL)
+	merged.Price = pick(cultural.Price, city.Price)
+	cityHasCoords := city.Latitude != 0 && city.Longitude != 0
+	if cityHasCoords && (cityFirst || cultural.Latitude == 0 || cultural.Longitude == 0) {
+		merged.Latitude, merged.Longitude = city.Latitude, city.Longitude
+	}
+	merged.Free = cultural.Free || cityFree(city)
+
+	culturalListing := event.Listing{Source: m.CulturalSource, ID: cultural.ID, Title: cultural.Title, URL: cultural.DetailsURL}
+	cityListing := event.Listing{Source: m.CitySource, ID: city.ID, Title: city.Title, URL: city.WebURL}
+	merged.Listings = []event.Listing{culturalListing, cityListing}
+	if cityFirst {
+		merged.Listings = []event.Listing{cityListing, culturalListing}
+	}
+	return merged
+}
+
+// primary returns the feed whose fields win: whichever of the two comes
+// first in Priority, the cultural feed if neither is listed.
+func (m Matcher) primary() string {
+	for _, name := range m.Priority {
+		if name == m.CulturalSource || name == m.CitySource {
+			return name
+		}
+	}
+	return m.CulturalSource
+}
+
+// freeWords are price texts saying an event is free (normalized, see
+// filter.NormalizeWords).
+var freeWords = []string{"gratis", "gratuito", "gratuita", "entrada libre", "acceso libre"}
+
+// cityFree reports whether a city event's price says it is free: the city
+// feed has no free flag.
+func cityFree(city event.CityEvent) bool {
+	price := " " + filter.NormalizeWords(city.Price) + " "
+	for _, w := range freeWords {
+		if strings.Contains(price, " "+w+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// stopWords don't count toward title similarity.
+var stopWords = map[string]bool{
+	"a": true, "al": true, "con": true, "de": true, "del": true, "e": true, "el": true,
+	"en": true, "la": true, "las": true, "los": true, "o": true, "para": true, "por": true,
+	"u": true, "un": true, "una": true, "y": true,
+}
+
+// titleWords returns a title's distinct words, normalized (see
+// filter.NormalizeWords), without stop words.
+func titleWords(title string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(filter.NormalizeWords(title)) {
+		if !stopWords[w] && !seen[w] {
+			seen[w] = true
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// TitleSimilarity returns how alike two titles are, from 0 to 1: the Dice
+// coefficient of their words, ignoring case, accents, punctuation and stop
+// words such as "de" and "la".
+func TitleSimilarity(a, b string) float64 {
+	return dice(titleWords(a), titleWords(b))
+}
+
+func dice(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inA := make(map[string]bool, len(a))
+	for _, w := range a {
+		inA[w] = true
+	}
+	shared := 0
+	for _, w := range b {
+		if inA[w] {
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(a)+len(b))
+}
+
+// SameVenue reports whether two venue names name the same venue: equal, or
+// one's words part of the other's ("Teatro Español" and "Teatro Español -
+// Sala Principal"), ignoring case, accents and punctuation.
+func SameVenue(a, b string) bool {
+	a, b = filter.NormalizeWords(a), filter.NormalizeWords(b)
+	if a == "" || b == "" {
+		return false
+	}
+	return strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ")
+}
+
+// overlap reports whether two events' days overlap. A zero end is the start.
+func overlap(start1, end1, start2, end2 time.Time) bool {
+	if end1.IsZero() {
+		end1 = start1
+	}
+	if end2.IsZero() {
+		end2 = start2
+	}
+	return day(start1) <= day(end2) && day(start2) <= day(end1)
+}
+
+// day returns t's date as "2006-01-02", in t's location.
+func day(t time.Time) string {
+	return t.Format("2006-01-02")
+}
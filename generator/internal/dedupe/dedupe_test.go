@@ -0,0 +1,167 @@
+package dedupe
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+var testMatcher = Matcher{
+	MinTitleSimilarity: 0.6,
+	MaxDistanceKm:      0.25,
+	CulturalSource:     "datos.madrid.es",
+	CitySource:         "esmadrid.com",
+	Priority:           []string{"datos.madrid.es", "esmadrid.com"},
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Concierto de la Banda Sinfónica", "CONCIERTO BANDA SINFONICA", 1},
+		{"Noche de los Libros", "La noche de los libros 2025", 0.8},
+		{"Mercadillo navideño", "Concierto de Navidad", 0},
+		{"", "Concierto", 0},
+	}
+	for _, tt := range tests {
+		if got := TitleSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TitleSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSameVenue(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Teatro Español", "TEATRO ESPANOL", true},
+		{"Teatro Español", "Teatro Español - Sala Principal", true},
+		{"Centro Cultural Conde Duque", "Conde Duque", true},
+		{"Teatro Español", "Teatro Real", false},
+		{"Teatro", "", false},
+	}
+	for _, tt := range tests {
+		if got := SameVenue(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameVenue(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2025, 10, d, hour, 0, 0, 0, time.UTC) }
+
+	cultural := []event.CulturalEvent{
+		{ID: "c-concert", Title: "Concierto: Banda Sinfónica Municipal", StartTime: day(20, 19), EndTime: day(20, 21),
+			VenueName: "Templo de Debod", Latitude: 40.4240, Longitude: -3.7178},
+		{ID: "c-far", Title: "Cuentacuentos en familia", StartTime: day(21, 11), EndTime: day(21, 12),
+			VenueName: "Biblioteca Eugenio Trías", Latitude: 40.4135, Longitude: -3.6795},
+		{ID: "c-no-coords", Title: "Festival de Otoño", StartTime: day(22, 20), EndTime: day(22, 22),
+			VenueName: "Teatro Español"},
+		{ID: "c-other-day", Title: "Cine de verano", StartTime: day(25, 22), EndTime: day(25, 23),
+			Latitude: 40.4233, Longitude: -3.7122},
+	}
+	city := []event.CityEvent{
+		{ID: "e-concert", Title: "Banda Sinfónica Municipal en concierto", StartDate: day(20, 0), EndDate: day(20, 0),
+			Venue: "Parque del Oeste", Latitude: 40.4242, Longitude: -3.7180},
+		{ID: "e-concert-2", Title: "Banda Sinfónica Municipal", StartDate: day(18, 0), EndDate: day(31, 0),
+			Latitude: 40.4242, Longitude: -3.7180},
+		{ID: "e-far", Title: "Cuentacuentos en familia", StartDate: day(21, 0), EndDate: day(21, 0),
+			Venue: "Biblioteca Eugenio Trías", Latitude: 40.4530, Longitude: -3.6880}, // Same title, another library
+		{ID: "e-no-coords", Title: "Festival de Otoño 2025", StartDate: day(1, 0), EndDate: day(30, 0),
+			Venue: "Teatro Español - Sala Principal", Latitude: 40.4146, Longitude: -3.7000},
+		{ID: "e-other-day", Title: "Cine de verano", StartDate: day(26, 0), EndDate: day(26, 0),
+			Latitude: 40.4233, Longitude: -3.7122},
+	}
+
+	links := testMatcher.Match(cultural, city)
+	want := []struct {
+		cultural, city string
+		sameVenue      bool
+		located        bool
+	}{
+		{"c-concert", "e-concert", false, true},
+		{"c-no-coords", "e-no-coords", true, false},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("Match() = %+v, want %d links", links, len(want))
+	}
+	for i, w := range want {
+		l := links[i]
+		if l.CulturalID != w.cultural || l.CityID != w.city || cultural[l.Cultural].ID != w.cultural || city[l.City].ID != w.city {
+			t.Errorf("link %d = %s-%s, want %s-%s", i, l.CulturalID, l.CityID, w.cultural, w.city)
+		}
+		if l.SameVenue != w.sameVenue || (l.DistanceKm >= 0) != w.located || l.Primary != "datos.madrid.es" {
+			t.Errorf("link %d = %+v, want same venue %v, located %v, primary datos.madrid.es", i, l.DuplicatePair, w.sameVenue, w.located)
+		}
+	}
+}
+
+func TestMatcher_Merge(t *testing.T) {
+	start := time.Date(2025, 10, 20, 19, 0, 0, 0, time.UTC)
+	cultural := event.CulturalEvent{
+		ID: "c1", Title: "Concierto: Banda Sinfónica", StartTime: start, EndTime: start.Add(2 * time.Hour),
+		VenueName: "Templo de Debod", Latitude: 40.4240, Longitude: -3.7178,
+		DetailsURL: "https://datos.madrid.es/c1", Free: true,
+		Recurrence: &event.Recurrence{Frequency: "WEEKLY", Weekdays: []time.Weekday{time.Monday}},
+	}
+	city := event.CityEvent{
+		ID: "e1", Title: "Banda Sinfónica en concierto", Description: "Un concierto al aire libre.",
+		StartDate: start.Truncate(24 * time.Hour), EndDate: start.Truncate(24 * time.Hour),
+		Venue: "Parque del Oeste", Address: "Calle Ferraz, 1", Latitude: 40.4242, Longitude: -3.7180,
+		WebURL: "https://www.esmadrid.com/e1",
+	}
+
+	t.Run("cultural first", func(t *testing.T) {
+		merged := testMatcher.Merge(cultural, city)
+		if merged.Title != cultural.Title || merged.VenueName != "Templo de Debod" || merged.DetailsURL != cultural.DetailsURL ||
+			merged.Latitude != cultural.Latitude || !merged.StartTime.Equal(start) || merged.Recurrence == nil {
+			t.Errorf("merged = %+v, want the cultural event's fields", merged)
+		}
+		if merged.Description != city.Description || merged.Address != city.Address || !merged.Free {
+			t.Errorf("merged = %+v, want missing fields from the city event", merged)
+		}
+		if len(merged.Listings) != 2 || merged.Listings[0].Source != "datos.madrid.es" || merged.Listings[1].URL != city.WebURL {
+			t.Errorf("Listings = %+v, want datos.madrid.es then esmadrid.com", merged.Listings)
+		}
+	})
+
+	t.Run("city first", func(t *testing.T) {
+		m := testMatcher
+		m.Priority = []string{"esmadrid.com"}
+		merged := m.Merge(cultural, city)
+		if merged.Title != city.Title || merged.VenueName != "Parque del Oeste" || merged.DetailsURL != city.WebURL ||
+			merged.Latitude != city.Latitude {
+			t.Errorf("merged = %+v, want the city event's fields", merged)
+		}
+		if merged.ID != "c1" || !merged.Free || !merged.StartTime.Equal(start) || !merged.EndTime.Equal(cultural.EndTime) || merged.Recurrence == nil {
+			t.Errorf("merged = %+v, want the cultural event's ID, free flag, times and sessions", merged)
+		}
+		if len(merged.Listings) != 2 || merged.Listings[0].Source != "esmadrid.com" || merged.Listings[1].ID != "c1" {
+			t.Errorf("Listings = %+v, want esmadrid.com then datos.madrid.es", merged.Listings)
+		}
+	})
+
+	t.Run("free by either listing", func(t *testing.T) {
+		notFree := cultural
+		notFree.Free = false
+		for _, tt := range []struct {
+			price string
+			want  bool
+		}{
+			{"", false},
+			{"16 euros", false},
+			{"Gratuito", true},
+			{"<p>Entrada libre hasta completar aforo</p>", true},
+		} {
+			freeCity := city
+			freeCity.Price = tt.price
+			if got := testMatcher.Merge(notFree, freeCity).Free; got != tt.want {
+				t.Errorf("Merge(not free, city price %q).Free = %v, want %v", tt.price, got, tt.want)
+			}
+		}
+	})
+}
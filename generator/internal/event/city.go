@@ -17,6 +17,7 @@ type CityEvent struct {
 	StartDate   time.Time
 	EndDate     time.Time
 	Venue       string
+	VenueID     string // idrt: the venue's ID in esmadrid.com
 	Address     string
 	Latitude    float64
 	Longitude   float64
@@ -30,11 +31,26 @@ type CityEvent struct {
 	FilterResult FilterResult
 }
 
+// CityParseResult tracks city events parsed from the esmadrid feed and the
+// services that failed to convert.
+type CityParseResult struct {
+	Events []CityEvent
+	Errors []ParseError
+	Stale  *StaleData // Set when served from an expired cache entry (see StaleData)
+}
+
 // EventType returns the type of this event.
 func (e CityEvent) EventType() string {
 	return "city"
 }
 
+// In returns the event with its times in loc (see CulturalEvent.In).
+func (e CityEvent) In(loc *time.Location) CityEvent {
+	e.StartDate = e.StartDate.In(loc)
+	e.EndDate = e.EndDate.In(loc)
+	return e
+}
+
 // Distance calculates the great-circle distance (in kilometers) from
 // this event's location to the given coordinates using the Haversine formula.
 func (e CityEvent) Distance(lat, lon float64) float64 {
@@ -11,21 +11,35 @@ type CulturalEvent struct {
 	Description string
 
 	// Time
-	StartTime time.Time
-	EndTime   time.Time
+	StartTime  time.Time
+	EndTime    time.Time
+	Recurrence *Recurrence // Sessions within StartTime..EndTime (nil: one continuous block)
 
 	// Location
-	Latitude  float64
-	Longitude float64
-	VenueName string
-	Address   string
-	Distrito  string // District where event takes place (e.g. "CENTRO", "MONCLOA-ARAVACA")
+	Latitude   float64
+	Longitude  float64
+	UTMX       float64 // COORDENADA-X: ETRS89 / UTM zone 30N easting in meters (0 if absent)
+	UTMY       float64 // COORDENADA-Y: northing in meters
+	VenueName  string
+	Address    string
+	Distrito   string // District where event takes place (e.g. "CENTRO", "MONCLOA-ARAVACA")
+	Barrio     string // Neighbourhood within the district (e.g. "UNIVERSIDAD")
+	PostalCode string
+	VenueID    string // ID-INSTALACION: the venue's ID in datos.madrid.es
 
 	// Metadata
 	DetailsURL string
 
+	// Attendance
+	Price         string   // Free-form price text (e.g. "16 euros"); often empty
+	Free          bool     // GRATUITO flag
+	Audience      []string // Intended audiences (e.g. "Niños", "Familias"); empty = everyone
+	Type          string   // Activity type (e.g. "TeatroPerformance", see TIPO)
+	Accessibility []string // The venue's ACCESIBILIDAD codes (e.g. "1", "6"), as given upstream
+
 	// Source tracking
-	Sources []string // ["JSON", "XML", "CSV"]
+	Sources  []string  // ["JSON", "XML", "CSV"]
+	Listings []Listing // Each feed's listing, when merged with its duplicate in another feed (priority order)
 
 	// Filter tracking (for audit trail)
 	FilterResult FilterResult
@@ -36,6 +50,26 @@ func (e CulturalEvent) EventType() string {
 	return "cultural"
 }
 
+// In returns the event with its times (including recurrence exclusions) in
+// loc, e.g. after decoding it from JSON, which keeps only UTC offsets.
+// Recurrence sessions are computed in wall-clock time, so this matters across
+// daylight saving changes.
+func (e CulturalEvent) In(loc *time.Location) CulturalEvent {
+	e.StartTime = e.StartTime.In(loc)
+	e.EndTime = e.EndTime.In(loc)
+	if e.Recurrence != nil {
+		r := *e.Recurrence
+		if len(r.Excluded) > 0 {
+			r.Excluded = make([]time.Time, len(e.Recurrence.Excluded))
+			for i, d := range e.Recurrence.Excluded {
+				r.Excluded[i] = d.In(loc)
+			}
+		}
+		e.Recurrence = &r
+	}
+	return e
+}
+
 // SourcedEvent wraps an event with its source.
 type SourcedEvent struct {
 	Event  CulturalEvent
@@ -46,6 +80,14 @@ type SourcedEvent struct {
 type ParseResult struct {
 	Events []SourcedEvent
 	Errors []ParseError
+	Stale  *StaleData // Set when the body was an expired cache entry served after an upstream failure
+}
+
+// StaleData describes a feed body served from an expired cache entry because
+// the upstream request failed (stale-if-error).
+type StaleData struct {
+	FetchedAt time.Time // When the cached body was originally fetched
+	Reason    string    // The upstream error that triggered the fallback
 }
 
 // ParseError records a single event that failed to parse.
@@ -0,0 +1,181 @@
+package event
+
+import "time"
+
+// Recurrence describes when a recurring event happens within its
+// StartTime..EndTime span, e.g. a weekly workshop on Mondays and Wednesdays.
+// Parsed from datos.madrid.es DIAS-SEMANA/DIAS-EXCLUIDOS or a JSON-LD rrule.
+type Recurrence struct {
+	Frequency string         // "DAILY", "WEEKLY" or "MONTHLY"
+	Interval  int            // Every Interval days/weeks/months (0 is treated as 1)
+	Weekdays  []time.Weekday // Days it happens on (empty: StartTime's weekday, or any day for DAILY)
+	Excluded  []time.Time    // Dates without a session (only the date part is used)
+}
+
+// Occurrence is one concrete session of an event.
+type Occurrence struct {
+	Start time.Time
+	End   time.Time
+}
+
+// DaysPerWeek returns roughly how many days a week the event happens.
+func (r *Recurrence) DaysPerWeek() float64 {
+	interval := float64(r.interval())
+	switch r.Frequency {
+	case "DAILY":
+		if len(r.Weekdays) > 0 {
+			return float64(len(r.Weekdays)) / interval
+		}
+		return 7 / interval
+	case "MONTHLY":
+		if len(r.Weekdays) > 0 {
+			return float64(len(r.Weekdays)) / interval
+		}
+		return 1 / (4.35 * interval)
+	default:
+		if len(r.Weekdays) > 0 {
+			return float64(len(r.Weekdays)) / interval
+		}
+		return 1 / interval
+	}
+}
+
+func (r *Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// Occurrences returns the sessions of e that overlap [from, to), in order.
+// An event without a Recurrence is a single session from StartTime to EndTime.
+//
+// Each session of a recurring event takes StartTime's time of day and ends at
+// EndTime's time of day (or at midnight if that isn't later, e.g. for events
+// upstream lists without a time).
+func (e CulturalEvent) Occurrences(from, to time.Time) []Occurrence {
+	if e.Recurrence == nil {
+		end := e.EndTime
+		if end.IsZero() {
+			end = e.StartTime
+		}
+		if e.StartTime.Before(to) && !end.Before(from) {
+			return []Occurrence{{Start: e.StartTime, End: end}}
+		}
+		return nil
+	}
+
+	r := e.Recurrence
+	loc := e.StartTime.Location()
+	first := dateOf(e.StartTime)
+	last := first
+	if !e.EndTime.IsZero() {
+		last = dateOf(e.EndTime.In(loc))
+	}
+
+	excluded := make(map[time.Time]bool, len(r.Excluded))
+	for _, d := range r.Excluded {
+		excluded[dateOf(d.In(loc))] = true
+	}
+	weekdays := make(map[time.Weekday]bool, len(r.Weekdays))
+	for _, wd := range r.Weekdays {
+		weekdays[wd] = true
+	}
+
+	// Session times of day
+	startClock := clockOf(e.StartTime)
+	endClock := 24 * time.Hour
+	if !e.EndTime.IsZero() {
+		if c := clockOf(e.EndTime.In(loc)); c > startClock {
+			endClock = c
+		}
+	}
+
+	// Only walk the days that can overlap [from, to)
+	day := first
+	if d := dateOf(from.In(loc)).AddDate(0, 0, -1); d.After(day) {
+		day = d
+	}
+	if d := dateOf(to.In(loc)); d.Before(last) {
+		last = d
+	}
+
+	var occurrences []Occurrence
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if excluded[day] || !r.matches(day, first, weekdays) {
+			continue
+		}
+		start := atClock(day, startClock)
+		end := atClock(day, endClock)
+		if start.Before(to) && end.After(from) {
+			occurrences = append(occurrences, Occurrence{Start: start, End: end})
+		}
+	}
+	return occurrences
+}
+
+// matches reports whether the recurrence has a session on day, counting
+// intervals from first (the event's start date).
+func (r *Recurrence) matches(day, first time.Time, weekdays map[time.Weekday]bool) bool {
+	interval := r.interval()
+	days := daysBetween(first, day)
+
+	switch r.Frequency {
+	case "DAILY":
+		if len(weekdays) > 0 && !weekdays[day.Weekday()] {
+			return false
+		}
+		return days%interval == 0
+
+	case "MONTHLY":
+		months := (day.Year()-first.Year())*12 + int(day.Month()-first.Month())
+		if months%interval != 0 {
+			return false
+		}
+		if len(weekdays) > 0 {
+			return weekdays[day.Weekday()]
+		}
+		return day.Day() == first.Day()
+
+	default: // WEEKLY
+		if len(weekdays) == 0 {
+			if day.Weekday() != first.Weekday() {
+				return false
+			}
+		} else if !weekdays[day.Weekday()] {
+			return false
+		}
+		// Weeks run Monday to Sunday, counted from the week of the first date
+		weeks := (days + mondayOffset(first)) / 7
+		return weeks%interval == 0
+	}
+}
+
+// dateOf returns midnight of t's date in t's location.
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clockOf returns t's wall-clock time of day.
+func clockOf(t time.Time) time.Duration {
+	h, m, sec := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec)*time.Second
+}
+
+// atClock returns the time clock after the start of day, by the wall clock
+// (so DST changes don't shift sessions).
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(clock/time.Second), 0, day.Location())
+}
+
+// daysBetween counts calendar days from a to b (both midnights), ignoring DST.
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return int(time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC).Sub(time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+// mondayOffset returns how many days t is after the Monday of its week.
+func mondayOffset(t time.Time) int {
+	return (int(t.Weekday()) + 6) % 7
+}
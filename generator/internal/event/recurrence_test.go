@@ -0,0 +1,138 @@
+package event
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCulturalEvent_Occurrences(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	date := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2025, month, day, hour, min, 0, 0, madrid)
+	}
+
+	// Mondays and Wednesdays 18:00-20:00 from Mon 6 Oct to Fri 31 Oct 2025
+	workshop := CulturalEvent{
+		StartTime: date(10, 6, 18, 0),
+		EndTime:   date(10, 31, 20, 0),
+	}
+
+	tests := []struct {
+		name       string
+		recurrence *Recurrence
+		from, to   time.Time
+		want       []time.Time // Session starts
+	}{
+		{
+			name:       "weekly on two days",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Monday, time.Wednesday}},
+			from:       date(10, 1, 0, 0),
+			to:         date(10, 16, 0, 0),
+			want:       []time.Time{date(10, 6, 18, 0), date(10, 8, 18, 0), date(10, 13, 18, 0), date(10, 15, 18, 0)},
+		},
+		{
+			name: "excluded dates",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Monday, time.Wednesday},
+				Excluded: []time.Time{date(10, 8, 0, 0), date(10, 13, 0, 0)}},
+			from: date(10, 1, 0, 0),
+			to:   date(10, 16, 0, 0),
+			want: []time.Time{date(10, 6, 18, 0), date(10, 15, 18, 0)},
+		},
+		{
+			name:       "every other week",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 2, Weekdays: []time.Weekday{time.Monday}},
+			from:       date(10, 1, 0, 0),
+			to:         date(11, 1, 0, 0),
+			want:       []time.Time{date(10, 6, 18, 0), date(10, 20, 18, 0)},
+		},
+		{
+			name:       "weekly without days uses the start weekday",
+			recurrence: &Recurrence{Frequency: "WEEKLY"},
+			from:       date(10, 1, 0, 0),
+			to:         date(10, 21, 0, 0),
+			want:       []time.Time{date(10, 6, 18, 0), date(10, 13, 18, 0), date(10, 20, 18, 0)},
+		},
+		{
+			name:       "daily every three days",
+			recurrence: &Recurrence{Frequency: "DAILY", Interval: 3},
+			from:       date(10, 1, 0, 0),
+			to:         date(10, 13, 0, 0),
+			want:       []time.Time{date(10, 6, 18, 0), date(10, 9, 18, 0), date(10, 12, 18, 0)},
+		},
+		{
+			name:       "window clips sessions",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Monday, time.Wednesday}},
+			from:       date(10, 20, 19, 0), // During the 20 Oct session
+			to:         date(10, 23, 0, 0),
+			want:       []time.Time{date(10, 20, 18, 0), date(10, 22, 18, 0)},
+		},
+		{
+			name:       "window after the event",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Monday}},
+			from:       date(11, 1, 0, 0),
+			to:         date(12, 1, 0, 0),
+			want:       nil,
+		},
+		{
+			name:       "sessions keep wall-clock time across DST",
+			recurrence: &Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Monday}},
+			from:       date(10, 20, 0, 0),
+			to:         date(10, 31, 0, 0), // Clocks go back on Sun 26 Oct
+			want:       []time.Time{date(10, 20, 18, 0), date(10, 27, 18, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evt := workshop
+			evt.Recurrence = tt.recurrence
+
+			got := evt.Occurrences(tt.from, tt.to)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, occ := range got {
+				if !occ.Start.Equal(tt.want[i]) {
+					t.Errorf("occurrence %d starts %v, want %v", i, occ.Start, tt.want[i])
+				}
+				wantEnd := time.Date(occ.Start.Year(), occ.Start.Month(), occ.Start.Day(), 20, 0, 0, 0, madrid)
+				if !occ.End.Equal(wantEnd) {
+					t.Errorf("occurrence %d ends %v, want %v", i, occ.End, wantEnd)
+				}
+			}
+		})
+	}
+}
+
+func TestCulturalEvent_Occurrences_NotRecurring(t *testing.T) {
+	start := time.Date(2025, 10, 6, 18, 0, 0, 0, time.UTC)
+	evt := CulturalEvent{StartTime: start, EndTime: start.Add(30 * 24 * time.Hour)}
+
+	got := evt.Occurrences(start.AddDate(0, 0, 10), start.AddDate(0, 0, 11))
+	if len(got) != 1 || !got[0].Start.Equal(evt.StartTime) || !got[0].End.Equal(evt.EndTime) {
+		t.Errorf("Occurrences = %v, want the whole event as one session", got)
+	}
+	if got := evt.Occurrences(start.AddDate(0, 0, 40), start.AddDate(0, 0, 41)); len(got) != 0 {
+		t.Errorf("Occurrences after the event = %v, want none", got)
+	}
+}
+
+func TestRecurrence_DaysPerWeek(t *testing.T) {
+	tests := []struct {
+		recurrence Recurrence
+		want       float64
+	}{
+		{Recurrence{Frequency: "WEEKLY", Weekdays: []time.Weekday{time.Monday, time.Wednesday}}, 2},
+		{Recurrence{Frequency: "WEEKLY", Interval: 2, Weekdays: []time.Weekday{time.Monday}}, 0.5},
+		{Recurrence{Frequency: "WEEKLY"}, 1},
+		{Recurrence{Frequency: "DAILY"}, 7},
+	}
+	for _, tt := range tests {
+		if got := tt.recurrence.DaysPerWeek(); got != tt.want {
+			t.Errorf("%+v.DaysPerWeek() = %v, want %v", tt.recurrence, got, tt.want)
+		}
+	}
+}
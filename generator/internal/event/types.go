@@ -1,6 +1,9 @@
 package event
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // FilterResult tracks all filter decisions for a single event.
 // This structure records the outcome of each filtering stage and
@@ -11,23 +14,78 @@ type FilterResult struct {
 	DistritoMatched bool   `json:"distrito_matched"` // if has distrito, did it match target?
 	Distrito        string `json:"distrito,omitempty"`
 
+	// Coordinate quality (checked before filtering)
+	CoordinateFix     string  `json:"coordinate_fix,omitempty"`     // "swapped", "outside Madrid", ... (see validate.CoordinateFix)
+	OriginalLatitude  float64 `json:"original_latitude,omitempty"`  // Coordinates as given upstream, when fixed
+	OriginalLongitude float64 `json:"original_longitude,omitempty"` // (the event's own are corrected or cleared)
+	Geocoded          string  `json:"geocoded,omitempty"`           // How the venue gazetteer placed an event without coordinates: "venue_id", "venue_name", "address"
+	GeocodedKey       string  `json:"geocoded_key,omitempty"`       // The gazetteer entry used, e.g. "esmadrid:78001" or "teatro espanol"
+	GeocodedManual    bool    `json:"geocoded_manual,omitempty"`    // The entry was added by hand
+
 	// Location filtering - GPS
 	HasCoordinates bool    `json:"has_coordinates"`
 	GPSDistanceKm  float64 `json:"gps_distance_km,omitempty"` // km from reference point
 	WithinRadius   bool    `json:"within_radius"`
+	Area           string  `json:"area,omitempty"` // geofence area containing the event (see filter.Area)
 
 	// Location filtering - text matching (fallback)
-	TextMatched     bool `json:"text_matched"`
-	PlazaEspanaText bool `json:"plaza_espana_text,omitempty"` // specifically matched Plaza de España mention
-	MultiVenueKept  bool `json:"multi_venue_kept,omitempty"`  // kept due to multi-venue Plaza de España mention
+	TextMatched     bool     `json:"text_matched"`
+	TextPhrases     []string `json:"text_phrases,omitempty"`      // phrases text rules found, as "field: phrase"
+	PlazaEspanaText bool     `json:"plaza_espana_text,omitempty"` // specifically matched Plaza de España mention
+	MultiVenueKept  bool     `json:"multi_venue_kept,omitempty"`  // kept by a text rule (e.g. a multi-venue mention)
 
 	// Time filtering
 	StartDate time.Time `json:"start_date,omitempty"`
 	EndDate   time.Time `json:"end_date,omitempty"`
-	DaysOld   int       `json:"days_old"` // days since start (negative = future)
-	TooOld    bool      `json:"too_old"`  // started more than cutoff days ago?
+	DaysOld   int       `json:"days_old"` // days since the date the time rule checks (negative = future)
+	TooOld    bool      `json:"too_old"`  // before the time rule's cutoff?
 
 	// Final decision
-	Kept         bool   `json:"kept"`          // true = will be rendered, false = filtered out
-	FilterReason string `json:"filter_reason"` // human-readable: "outside distrito", "too old", "kept", etc.
+	Kept         bool       `json:"kept"`          // true = will be rendered, false = filtered out
+	Reason       FilterCode `json:"reason"`        // structured reason (see FilterCode)
+	FilterReason string     `json:"filter_reason"` // human-readable: "outside distrito", "too old", "kept", etc.
+}
+
+// FilterCode is the structured reason for a filter decision. Kept events
+// have a code starting with "kept".
+type FilterCode string
+
+const (
+	FilterKept            FilterCode = "kept"              // No location rule configured
+	FilterKeptDistrito    FilterCode = "kept_distrito"     // In an allowed distrito
+	FilterKeptRadius      FilterCode = "kept_radius"       // Within the radius
+	FilterKeptArea        FilterCode = "kept_area"         // Inside a polygon
+	FilterKeptText        FilterCode = "kept_text"         // Mentions the place (e.g. multi-venue events)
+	FilterOutsideDistrito FilterCode = "outside_distrito"  // In another distrito
+	FilterOutsideRadius   FilterCode = "outside_radius"    // Coordinates beyond the radius
+	FilterOutsideArea     FilterCode = "outside_area"      // Coordinates outside the polygon
+	FilterMissingLocation FilterCode = "missing_location"  // Nothing placed the event
+	FilterTooOld          FilterCode = "too_old"           // Started (or ended) before the cutoff
+	FilterCategory        FilterCode = "category_excluded" // Category not allowed
+)
+
+// Kept reports whether the code is a reason to keep an event.
+func (c FilterCode) Kept() bool {
+	return strings.HasPrefix(string(c), string(FilterKept))
+}
+
+// Listing is one feed's listing of an event that several feeds list.
+type Listing struct {
+	Source string `json:"source"` // Feed, e.g. "esmadrid.com"
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+}
+
+// DuplicatePair is a cultural event and a city event found to be the same
+// event, listed in both feeds, and merged into one.
+type DuplicatePair struct {
+	CulturalID      string  `json:"cultural_id"`
+	CityID          string  `json:"city_id"`
+	CulturalTitle   string  `json:"cultural_title"`
+	CityTitle       string  `json:"city_title"`
+	TitleSimilarity float64 `json:"title_similarity"` // 0-1
+	DistanceKm      float64 `json:"distance_km"`      // -1 when either has no coordinates
+	SameVenue       bool    `json:"same_venue"`       // Venue names match
+	Primary         string  `json:"primary"`          // Feed whose fields won
 }
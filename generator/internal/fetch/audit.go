@@ -17,9 +17,30 @@ type RequestRecord struct {
 	DelayMs     int64     `json:"delay_ms"`
 	RateLimited bool      `json:"rate_limited"`
 	Synthetic   bool      `json:"synthetic,omitempty"` // True for cache-only synthetic URLs (e.g., aemet-forecast://)
-	Error       string    `json:"error,omitempty"`
+	// Revalidation is set when an expired cache entry was sent upstream with
+	// If-None-Match/If-Modified-Since: RevalidationNotModified or RevalidationModified.
+	Revalidation string `json:"revalidation,omitempty"`
+	// Attempt is the 1-based attempt number for this URL; RetryInMs is the
+	// backoff before the next attempt (0 when this attempt was the last).
+	Attempt   int   `json:"attempt,omitempty"`
+	RetryInMs int64 `json:"retry_in_ms,omitempty"`
+	// Stale is set when an expired cache entry was served because the upstream
+	// request failed (stale-if-error); Error holds that failure.
+	Stale bool `json:"stale,omitempty"`
+	// Replayed is set in ReplayMode: the response came from a recorded bundle.
+	Replayed bool `json:"replayed,omitempty"`
+	// Suppressed is set when the request ledger's budget blocked a network
+	// request; CacheHit tells whether a cached copy was served instead.
+	Suppressed bool   `json:"suppressed,omitempty"`
+	Error      string `json:"error,omitempty"`
 }
 
+// Revalidation outcomes recorded in RequestRecord.Revalidation.
+const (
+	RevalidationNotModified = "not-modified" // 304: cached body reused, entry refreshed
+	RevalidationModified    = "modified"     // 200: upstream changed, new body cached
+)
+
 // RequestAuditor collects request records.
 type RequestAuditor struct {
 	records []RequestRecord
@@ -0,0 +1,231 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+// bundleManifestFile is the manifest's name inside a bundle directory.
+const bundleManifestFile = "manifest.json"
+
+// Bundle is a directory of recorded fetch responses. RecordMode writes every
+// response the build used into it; ReplayMode serves exclusively from it, so a
+// build can be reproduced byte for byte.
+//
+// Layout:
+//
+//	<dir>/manifest.json          - BundleManifest (URL -> body file, in request order)
+//	<dir>/bodies/<hash>-<n>.body - Response bodies
+type Bundle struct {
+	dir      string
+	manifest BundleManifest
+	served   map[string]int // Replay: responses already served per URL
+	mu       sync.Mutex
+}
+
+// BundleManifest describes the responses recorded in a bundle.
+type BundleManifest struct {
+	RecordedAt time.Time     `json:"recorded_at"` // Build time of the recording (replay uses it as "now")
+	Entries    []BundleEntry `json:"entries"`
+}
+
+// BundleEntry is one recorded response. Failed fetches are recorded too, so
+// replay reproduces them.
+type BundleEntry struct {
+	URL            string    `json:"url"`
+	Body           string    `json:"body,omitempty"`   // File under bodies/, empty for errors
+	SHA256         string    `json:"sha256,omitempty"` // Of the body: replay refuses edited bodies
+	Size           int       `json:"size"`
+	Error          string    `json:"error,omitempty"`
+	Stale          bool      `json:"stale,omitempty"` // Body was served stale (see event.StaleData)
+	StaleFetchedAt time.Time `json:"stale_fetched_at,omitzero"`
+	StaleReason    string    `json:"stale_reason,omitempty"`
+}
+
+// CreateBundle starts a new bundle for recording, replacing any bundle in dir.
+func CreateBundle(dir string, recordedAt time.Time) (*Bundle, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("clearing bundle directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "bodies"), 0755); err != nil {
+		return nil, fmt.Errorf("creating bundle directory: %w", err)
+	}
+
+	b := &Bundle{
+		dir:      dir,
+		manifest: BundleManifest{RecordedAt: recordedAt, Entries: []BundleEntry{}},
+		served:   make(map[string]int),
+	}
+	if err := b.save(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// OpenBundle loads a recorded bundle for replay.
+func OpenBundle(dir string) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(dir, bundleManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle manifest: %w", err)
+	}
+
+	var manifest BundleManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing bundle manifest: %w", err)
+	}
+
+	return &Bundle{
+		dir:      dir,
+		manifest: manifest,
+		served:   make(map[string]int),
+	}, nil
+}
+
+// Dir returns the bundle directory.
+func (b *Bundle) Dir() string {
+	return b.dir
+}
+
+// RecordedAt returns the build time the bundle was recorded at.
+func (b *Bundle) RecordedAt() time.Time {
+	return b.manifest.RecordedAt
+}
+
+// Len returns the number of recorded responses.
+func (b *Bundle) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.manifest.Entries)
+}
+
+// URLs returns the distinct recorded URLs in first-recorded order.
+func (b *Bundle) URLs() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, entry := range b.manifest.Entries {
+		if !seen[entry.URL] {
+			seen[entry.URL] = true
+			urls = append(urls, entry.URL)
+		}
+	}
+	return urls
+}
+
+// Record appends a response (or the error that replaced it) and saves the manifest.
+func (b *Bundle) Record(url string, body []byte, stale *event.StaleData, fetchErr error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry := BundleEntry{URL: url, Size: len(body)}
+	if fetchErr != nil {
+		entry.Error = fetchErr.Error()
+	} else {
+		entry.SHA256 = fmt.Sprintf("%x", sha256.Sum256(body))
+		entry.Body = fmt.Sprintf("%s-%d.body", urlHash(url), b.count(url))
+		if err := os.WriteFile(filepath.Join(b.dir, "bodies", entry.Body), body, 0644); err != nil {
+			return fmt.Errorf("writing bundle body: %w", err)
+		}
+	}
+	if stale != nil {
+		entry.Stale = true
+		entry.StaleFetchedAt = stale.FetchedAt
+		entry.StaleReason = stale.Reason
+	}
+
+	b.manifest.Entries = append(b.manifest.Entries, entry)
+	return b.save()
+}
+
+// Replay returns the next recorded response for url, in recording order.
+// Requests that weren't recorded (or were requested more often than recorded) fail.
+func (b *Bundle) Replay(url string) ([]byte, *event.StaleData, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	want := b.served[url]
+	seen := 0
+	for _, entry := range b.manifest.Entries {
+		if entry.URL != url {
+			continue
+		}
+		if seen < want {
+			seen++
+			continue
+		}
+		b.served[url]++
+		return b.load(entry)
+	}
+
+	if want > 0 {
+		return nil, nil, fmt.Errorf("replay: %s requested %d times but recorded %d times", url, want+1, want)
+	}
+	return nil, nil, fmt.Errorf("replay: no recorded response for %s", url)
+}
+
+// load reads an entry's body and reconstructs its stale data or error.
+func (b *Bundle) load(entry BundleEntry) ([]byte, *event.StaleData, error) {
+	if entry.Error != "" {
+		return nil, nil, errors.New(entry.Error)
+	}
+
+	body, err := os.ReadFile(filepath.Join(b.dir, "bodies", entry.Body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("replay: reading recorded body for %s: %w", entry.URL, err)
+	}
+	if fmt.Sprintf("%x", sha256.Sum256(body)) != entry.SHA256 {
+		return nil, nil, fmt.Errorf("replay: body for %s does not match its recorded checksum", entry.URL)
+	}
+
+	var stale *event.StaleData
+	if entry.Stale {
+		stale = &event.StaleData{FetchedAt: entry.StaleFetchedAt, Reason: entry.StaleReason}
+	}
+	return body, stale, nil
+}
+
+// count returns how many responses are already recorded for url. Caller holds mu.
+func (b *Bundle) count(url string) int {
+	n := 0
+	for _, entry := range b.manifest.Entries {
+		if entry.URL == url {
+			n++
+		}
+	}
+	return n
+}
+
+// save writes the manifest atomically. Caller holds mu (or has exclusive access).
+func (b *Bundle) save() error {
+	data, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling bundle manifest: %w", err)
+	}
+
+	path := filepath.Join(b.dir, bundleManifestFile)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("writing bundle manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		return fmt.Errorf("renaming bundle manifest: %w", err)
+	}
+	return nil
+}
+
+// urlHash returns a short, filesystem-safe name for url. Bodies are also
+// numbered per URL, so a collision can't overwrite another URL's body.
+func urlHash(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return fmt.Sprintf("%x", hash[:8]) // First 8 bytes of hash
+}
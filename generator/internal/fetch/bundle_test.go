@@ -0,0 +1,168 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+func TestBundle_RecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bundle")
+	recordedAt := time.Date(2025, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	bundle, err := CreateBundle(dir, recordedAt)
+	if err != nil {
+		t.Fatalf("CreateBundle failed: %v", err)
+	}
+	stale := &event.StaleData{FetchedAt: recordedAt.Add(-time.Hour), Reason: "HTTP 503"}
+	recordings := []struct {
+		url   string
+		body  string
+		stale *event.StaleData
+		err   error
+	}{
+		{"https://example.com/a", "first", nil, nil},
+		{"https://example.com/b", "", nil, errors.New("HTTP 500: boom")},
+		{"https://example.com/a", "second", stale, nil},
+	}
+	for _, r := range recordings {
+		if err := bundle.Record(r.url, []byte(r.body), r.stale, r.err); err != nil {
+			t.Fatalf("Record(%s) failed: %v", r.url, err)
+		}
+	}
+
+	replay, err := OpenBundle(dir)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	if !replay.RecordedAt().Equal(recordedAt) || replay.Len() != 3 {
+		t.Errorf("RecordedAt = %v, Len = %d, want %v and 3", replay.RecordedAt(), replay.Len(), recordedAt)
+	}
+	if urls := replay.URLs(); len(urls) != 2 || urls[0] != "https://example.com/a" || urls[1] != "https://example.com/b" {
+		t.Errorf("URLs() = %v, want [https://example.com/a https://example.com/b]", urls)
+	}
+
+	// Repeated URLs replay in recording order
+	body, gotStale, err := replay.Replay("https://example.com/a")
+	if err != nil || string(body) != "first" || gotStale != nil {
+		t.Errorf("first replay = (%q, %+v, %v), want (first, nil, nil)", body, gotStale, err)
+	}
+	body, gotStale, err = replay.Replay("https://example.com/a")
+	if err != nil || string(body) != "second" || gotStale == nil || gotStale.Reason != "HTTP 503" {
+		t.Errorf("second replay = (%q, %+v, %v), want stale second", body, gotStale, err)
+	}
+
+	// Recorded failures replay as the same error
+	if _, _, err := replay.Replay("https://example.com/b"); err == nil || err.Error() != "HTTP 500: boom" {
+		t.Errorf("replay of failed fetch error = %v, want recorded error", err)
+	}
+
+	// Anything beyond the recording fails
+	if _, _, err := replay.Replay("https://example.com/a"); err == nil {
+		t.Error("third replay of a twice-recorded URL succeeded, want error")
+	}
+	if _, _, err := replay.Replay("https://example.com/never"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("unrecorded URL error = %v, want no recorded response", err)
+	}
+}
+
+func TestBundle_OpenMissing(t *testing.T) {
+	if _, err := OpenBundle(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("OpenBundle succeeded on missing bundle, want error")
+	}
+}
+
+func TestBundle_EditedBody(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "bundle")
+	bundle, err := CreateBundle(dir, time.Now())
+	if err != nil {
+		t.Fatalf("CreateBundle failed: %v", err)
+	}
+	if err := bundle.Record("https://example.com/a", []byte("recorded"), nil, nil); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	bodies, err := filepath.Glob(filepath.Join(dir, "bodies", "*"))
+	if err != nil || len(bodies) != 1 {
+		t.Fatalf("bodies = %v, %v, want one", bodies, err)
+	}
+	if err := os.WriteFile(bodies[0], []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := OpenBundle(dir)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	if body, _, err := replay.Replay("https://example.com/a"); err == nil || !strings.Contains(err.Error(), "recorded checksum") {
+		t.Errorf("replay of an edited body = (%q, %v), want a checksum error", body, err)
+	}
+}
+
+func TestClient_RecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("payload"))
+	}))
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	bundle, err := CreateBundle(dir, time.Now())
+	if err != nil {
+		t.Fatalf("CreateBundle failed: %v", err)
+	}
+
+	recordConfig := ModeConfig{Mode: RecordMode, CacheTTL: time.Hour}
+	recorder, err := NewClient(5*time.Second, recordConfig, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	recorder.UseBundle(bundle)
+
+	// Cache hits are recorded too: replay must see what the build saw
+	for i := 0; i < 2; i++ {
+		if _, _, err := recorder.fetch(server.URL); err != nil {
+			t.Fatalf("recording fetch %d failed: %v", i, err)
+		}
+	}
+	server.Close() // Replay must not need the network
+
+	replayBundle, err := OpenBundle(dir)
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	replayer, err := NewClient(5*time.Second, DefaultReplayConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	replayer.UseBundle(replayBundle)
+
+	for i := 0; i < 2; i++ {
+		body, _, err := replayer.fetch(server.URL)
+		if err != nil || string(body) != "payload" {
+			t.Errorf("replay fetch %d = (%q, %v), want payload", i, body, err)
+		}
+	}
+	if _, _, err := replayer.fetch("https://example.com/unrecorded"); err == nil {
+		t.Error("replay of unrecorded URL succeeded, want error")
+	}
+
+	records := replayer.Auditor().Records()
+	if len(records) != 3 || !records[0].Replayed {
+		t.Errorf("records = %+v, want 3 replayed records", records)
+	}
+}
+
+func TestClient_ReplayWithoutBundle(t *testing.T) {
+	client, err := NewClient(5*time.Second, DefaultReplayConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if _, err := client.FetchWithHeaders("https://example.com", nil, false); err == nil {
+		t.Error("replay without bundle succeeded, want error")
+	}
+}
@@ -1,12 +1,17 @@
 package fetch
 
 import (
+	"bytes"
+	"compress/gzip"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,27 +26,78 @@ type CacheEntry struct {
 }
 
 // HTTPCache manages persistent HTTP response caching.
+//
+// Each entry is two files named by the SHA-256 of its URL:
+//
+//	<hash>.json    - cacheMeta (URL, validators, fetch and last-use times, sizes)
+//	<hash>.body.gz - The gzip-compressed response body
+//
+// Entries are never removed implicitly; Prune evicts by age and size.
 type HTTPCache struct {
 	cacheDir     string
 	ttl          time.Duration
 	ttlOverrides map[string]time.Duration // URL pattern -> TTL overrides
+	mu           sync.Mutex               // Serializes metadata rewrites (last-use updates)
+}
+
+// cacheMeta is the on-disk metadata of a cache entry.
+type cacheMeta struct {
+	URL          string    `json:"url"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	LastUsed     time.Time `json:"last_used"` // Last Lookup (for LRU eviction)
+	StatusCode   int       `json:"status_code"`
+	Size         int       `json:"size"`        // Uncompressed body size
+	StoredSize   int64     `json:"stored_size"` // Compressed body size on disk
+}
+
+// CacheInfo describes a cache entry without its body (see Entries).
+type CacheInfo struct {
+	URL         string
+	FetchedAt   time.Time
+	LastUsed    time.Time
+	Size        int           // Uncompressed body size
+	StoredSize  int64         // Bytes on disk (compressed body and metadata)
+	TTL         time.Duration // TTL in effect for the URL
+	TTLOverride string        // Pattern of the override that set TTL ("" = default)
+}
+
+// Fresh reports whether the entry is within its TTL at now.
+func (i CacheInfo) Fresh(now time.Time) bool {
+	return now.Sub(i.FetchedAt) <= i.TTL
+}
+
+// PruneResult reports what Prune removed.
+type PruneResult struct {
+	Expired    int   // Entries removed for exceeding the maximum age
+	Evicted    int   // Least recently used entries removed to fit the size limit
+	FreedBytes int64 // Bytes on disk released
+	Remaining  int
+	Size       int64 // Bytes on disk used by remaining entries
 }
 
 // NewHTTPCache creates a cache with the given directory and TTL.
+// Entries in the previous single-file format are converted on open.
 func NewHTTPCache(cacheDir string, ttl time.Duration) (*HTTPCache, error) {
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return nil, fmt.Errorf("creating cache dir: %w", err)
 	}
-	return &HTTPCache{
+	c := &HTTPCache{
 		cacheDir:     cacheDir,
 		ttl:          ttl,
 		ttlOverrides: make(map[string]time.Duration),
-	}, nil
+	}
+	if err := c.migrateLegacy(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // SetTTLOverride sets a custom TTL for URLs containing the given pattern.
 // For example, SetTTLOverride("opendata.aemet.es", 6*time.Hour) makes AEMET requests
-// use a 6-hour cache TTL instead of the default.
+// use a 6-hour cache TTL instead of the default. When several patterns match,
+// the longest wins.
 func (c *HTTPCache) SetTTLOverride(urlPattern string, ttl time.Duration) {
 	c.ttlOverrides[urlPattern] = ttl
 }
@@ -49,76 +105,360 @@ func (c *HTTPCache) SetTTLOverride(urlPattern string, ttl time.Duration) {
 // Get retrieves cached entry if valid (not expired).
 // Returns nil if cache miss or expired.
 func (c *HTTPCache) Get(url string) (*CacheEntry, error) {
-	path := c.cachePath(url)
+	entry, fresh, err := c.Lookup(url)
+	if err != nil || !fresh {
+		return nil, err
+	}
+	return entry, nil
+}
 
-	data, err := os.ReadFile(path)
+// Lookup retrieves a cached entry whether or not it has expired, so callers can
+// revalidate stale entries with conditional requests. It counts as a use of the
+// entry for LRU eviction.
+// Returns (nil, false, nil) on cache miss; fresh reports whether the entry is within its TTL.
+func (c *HTTPCache) Lookup(url string) (entry *CacheEntry, fresh bool, err error) {
+	meta, err := c.readMeta(c.metaPath(url))
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil // Cache miss
+			return nil, false, nil // Cache miss
 		}
-		return nil, err
+		return nil, false, err
+	}
+	if meta.URL != url {
+		return nil, false, nil // Hash collision: not our entry
+	}
+
+	body, err := c.readBody(url)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil // Interrupted write or purge: treat as a miss
+		}
+		return nil, false, err
+	}
+
+	meta.LastUsed = time.Now()
+	if err := c.writeMeta(meta); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cache last-use update failed: %v\n", err)
 	}
 
-	var entry CacheEntry
-	if err := json.Unmarshal(data, &entry); err != nil {
-		return nil, fmt.Errorf("parsing cache entry: %w", err)
+	e := &CacheEntry{
+		URL:          meta.URL,
+		Body:         body,
+		LastModified: meta.LastModified,
+		ETag:         meta.ETag,
+		FetchedAt:    meta.FetchedAt,
+		StatusCode:   meta.StatusCode,
 	}
+	return e, time.Since(e.FetchedAt) <= c.ttlFor(url), nil
+}
 
-	// Determine TTL: check for URL pattern overrides first
-	ttl := c.ttl
+// ttlFor returns the TTL for url: the longest matching override, else the default.
+func (c *HTTPCache) ttlFor(url string) time.Duration {
+	_, ttl := c.ttlOverrideFor(url)
+	return ttl
+}
+
+// ttlOverrideFor returns the override pattern matching url (longest first) and
+// its TTL, or "" and the default TTL.
+func (c *HTTPCache) ttlOverrideFor(url string) (string, time.Duration) {
+	best, ttl := "", c.ttl
 	for pattern, overrideTTL := range c.ttlOverrides {
-		if strings.Contains(url, pattern) {
-			ttl = overrideTTL
-			break
+		if strings.Contains(url, pattern) && len(pattern) > len(best) {
+			best, ttl = pattern, overrideTTL
 		}
 	}
+	return best, ttl
+}
 
-	// Check if expired
-	if time.Since(entry.FetchedAt) > ttl {
-		return nil, nil // Cache expired
+// Refresh marks a revalidated entry (HTTP 304) as fresh again.
+// The cached body is kept; only FetchedAt and any new validators are updated.
+func (c *HTTPCache) Refresh(entry CacheEntry, lastModified, etag string) error {
+	if lastModified != "" {
+		entry.LastModified = lastModified
 	}
-
-	return &entry, nil
+	if etag != "" {
+		entry.ETag = etag
+	}
+	return c.Set(entry)
 }
 
 // Set stores response in cache with atomic write.
 func (c *HTTPCache) Set(entry CacheEntry) error {
 	entry.FetchedAt = time.Now()
+	return c.write(entry)
+}
+
+// write stores entry as given (FetchedAt included). The body is written
+// before the metadata, so readers never see metadata without its body.
+func (c *HTTPCache) write(entry CacheEntry) error {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(entry.Body); err != nil {
+		return fmt.Errorf("compressing cache body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compressing cache body: %w", err)
+	}
+	if err := writeFileAtomic(c.bodyPath(entry.URL), gz.Bytes()); err != nil {
+		return fmt.Errorf("writing cache: %w", err)
+	}
+
+	return c.writeMeta(cacheMeta{
+		URL:          entry.URL,
+		LastModified: entry.LastModified,
+		ETag:         entry.ETag,
+		FetchedAt:    entry.FetchedAt,
+		LastUsed:     entry.FetchedAt,
+		StatusCode:   entry.StatusCode,
+		Size:         len(entry.Body),
+		StoredSize:   int64(gz.Len()),
+	})
+}
+
+// Delete removes a cache entry for the given URL.
+// Returns nil if the entry doesn't exist (idempotent).
+func (c *HTTPCache) Delete(url string) error {
+	return c.remove(c.metaPath(url), c.bodyPath(url))
+}
 
-	data, err := json.MarshalIndent(entry, "", "  ")
+// Entries returns every cache entry's metadata, sorted by URL.
+func (c *HTTPCache) Entries() ([]CacheInfo, error) {
+	metas, err := c.metas()
 	if err != nil {
-		return fmt.Errorf("marshaling cache entry: %w", err)
+		return nil, err
 	}
 
-	path := c.cachePath(entry.URL)
+	infos := make([]CacheInfo, 0, len(metas))
+	for path, meta := range metas {
+		pattern, ttl := c.ttlOverrideFor(meta.URL)
+		infos = append(infos, CacheInfo{
+			URL:         meta.URL,
+			FetchedAt:   meta.FetchedAt,
+			LastUsed:    meta.LastUsed,
+			Size:        meta.Size,
+			StoredSize:  meta.StoredSize + fileSize(path),
+			TTL:         ttl,
+			TTLOverride: pattern,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].URL < infos[j].URL })
+	return infos, nil
+}
 
-	// Atomic write: temp file + rename
-	tempPath := path + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+// Purge removes every entry whose URL contains pattern and returns how many
+// were removed. An empty pattern purges the whole cache.
+func (c *HTTPCache) Purge(pattern string) (int, error) {
+	metas, err := c.metas()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for path, meta := range metas {
+		if !strings.Contains(meta.URL, pattern) {
+			continue
+		}
+		if err := c.remove(path, c.bodyPath(meta.URL)); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Prune evicts entries fetched more than maxAge before now, then the least
+// recently used entries until the cache takes at most maxBytes on disk.
+// A zero maxAge or maxBytes disables that limit. Synthetic entries (such as
+// aemet-forecast:// keys) are pruned like any other.
+func (c *HTTPCache) Prune(maxBytes int64, maxAge time.Duration, now time.Time) (PruneResult, error) {
+	var result PruneResult
+	metas, err := c.metas()
+	if err != nil {
+		return result, err
+	}
+
+	type stored struct {
+		path string
+		meta cacheMeta
+		size int64
+	}
+	var kept []stored
+	for path, meta := range metas {
+		size := meta.StoredSize + fileSize(path)
+		if maxAge > 0 && now.Sub(meta.FetchedAt) > maxAge {
+			if err := c.remove(path, c.bodyPath(meta.URL)); err != nil {
+				return result, err
+			}
+			result.Expired++
+			result.FreedBytes += size
+			continue
+		}
+		kept = append(kept, stored{path: path, meta: meta, size: size})
+		result.Size += size
+	}
+
+	// Least recently used first
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].meta.LastUsed.Before(kept[j].meta.LastUsed)
+	})
+	for len(kept) > 0 && maxBytes > 0 && result.Size > maxBytes {
+		victim := kept[0]
+		if err := c.remove(victim.path, c.bodyPath(victim.meta.URL)); err != nil {
+			return result, err
+		}
+		kept = kept[1:]
+		result.Evicted++
+		result.FreedBytes += victim.size
+		result.Size -= victim.size
+	}
+
+	result.Remaining = len(kept)
+	return result, nil
+}
+
+// metas reads every entry's metadata, keyed by metadata path.
+// Unreadable metadata files are skipped.
+func (c *HTTPCache) metas() (map[string]cacheMeta, error) {
+	files, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache dir: %w", err)
+	}
+
+	metas := make(map[string]cacheMeta)
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(c.cacheDir, f.Name())
+		meta, err := c.readMeta(path)
+		if err != nil {
+			continue
+		}
+		metas[path] = meta
+	}
+	return metas, nil
+}
+
+// readMeta reads one metadata file.
+func (c *HTTPCache) readMeta(path string) (cacheMeta, error) {
+	var meta cacheMeta
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return meta, fmt.Errorf("parsing cache entry: %w", err)
+	}
+	return meta, nil
+}
+
+// writeMeta writes an entry's metadata atomically.
+func (c *HTTPCache) writeMeta(meta cacheMeta) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling cache entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeFileAtomic(c.metaPath(meta.URL), data); err != nil {
 		return fmt.Errorf("writing cache: %w", err)
 	}
+	return nil
+}
 
-	if err := os.Rename(tempPath, path); err != nil {
-		return fmt.Errorf("renaming cache: %w", err)
+// readBody reads and decompresses url's body.
+func (c *HTTPCache) readBody(url string) ([]byte, error) {
+	f, err := os.Open(c.bodyPath(url))
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
 
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache body: %w", err)
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("reading cache body: %w", err)
+	}
+	return body, nil
+}
+
+// remove deletes an entry's files, metadata first so a half-removed entry
+// reads as a miss.
+func (c *HTTPCache) remove(metaPath, bodyPath string) error {
+	for _, path := range []string{metaPath, bodyPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
 	return nil
 }
 
-// Delete removes a cache entry for the given URL.
-// Returns nil if the entry doesn't exist (idempotent).
-func (c *HTTPCache) Delete(url string) error {
-	path := c.cachePath(url)
-	err := os.Remove(path)
-	if err != nil && os.IsNotExist(err) {
-		return nil // Already deleted, not an error
+// migrateLegacy converts entries from the previous format (one JSON file per
+// URL with an inline base64 body, named by 8 bytes of the hash). Unreadable
+// legacy files are dropped.
+func (c *HTTPCache) migrateLegacy() error {
+	files, err := os.ReadDir(c.cacheDir)
+	if err != nil {
+		return fmt.Errorf("reading cache dir: %w", err)
 	}
-	return err
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || filepath.Ext(name) != ".json" || len(name) != len("0123456789abcdef.json") {
+			continue
+		}
+		path := filepath.Join(c.cacheDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil && entry.URL != "" {
+			if err := c.write(entry); err != nil {
+				// Leave it for the next run; a cache problem shouldn't stop the build
+				fmt.Fprintf(os.Stderr, "Warning: migrating cache entry %s: %v\n", name, err)
+				continue
+			}
+		}
+		os.Remove(path)
+	}
+	return nil
+}
+
+// metaPath and bodyPath name an entry's files by the full SHA-256 of its URL.
+func (c *HTTPCache) metaPath(url string) string {
+	return filepath.Join(c.cacheDir, cacheKey(url)+".json")
+}
+
+func (c *HTTPCache) bodyPath(url string) string {
+	return filepath.Join(c.cacheDir, cacheKey(url)+".body.gz")
+}
+
+// cacheKey returns the hex SHA-256 of url.
+func cacheKey(url string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(url)))
 }
 
-// cachePath generates a safe filename from URL using SHA256 hash.
-func (c *HTTPCache) cachePath(url string) string {
-	hash := sha256.Sum256([]byte(url))
-	filename := fmt.Sprintf("%x.json", hash[:8]) // First 8 bytes of hash
-	return filepath.Join(c.cacheDir, filename)
+// fileSize returns path's size, or 0 if it can't be read.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// writeFileAtomic writes data to path via a temp file and rename.
+func writeFileAtomic(path string, data []byte) error {
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
 }
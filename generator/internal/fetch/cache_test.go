@@ -1,6 +1,8 @@
 package fetch
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
@@ -187,3 +189,265 @@ func TestHTTPCache_ETag(t *testing.T) {
 		t.Errorf("ETag = %q, want %q", entry.ETag, `"abc123"`)
 	}
 }
+
+func TestHTTPCache_LookupReturnsExpired(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	url := "https://example.com/expired"
+	if err := cache.Set(CacheEntry{URL: url, Body: []byte("old"), ETag: `"v1"`, StatusCode: 200}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// Get hides expired entries
+	if entry, _ := cache.Get(url); entry != nil {
+		t.Error("Get returned expired entry, want nil")
+	}
+
+	// Lookup returns them for revalidation
+	entry, fresh, err := cache.Lookup(url)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if entry == nil {
+		t.Fatal("Lookup returned nil for expired entry")
+	}
+	if fresh {
+		t.Error("Lookup fresh = true for expired entry")
+	}
+	if entry.ETag != `"v1"` {
+		t.Errorf("ETag = %q, want %q", entry.ETag, `"v1"`)
+	}
+}
+
+func TestHTTPCache_Refresh(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	url := "https://example.com/refresh"
+	if err := cache.Set(CacheEntry{URL: url, Body: []byte("body"), ETag: `"v1"`, StatusCode: 200}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	stale, _, _ := cache.Lookup(url)
+	if err := cache.Refresh(*stale, "", `"v2"`); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	entry, fresh, err := cache.Lookup(url)
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if !fresh {
+		t.Error("entry not fresh after Refresh")
+	}
+	if string(entry.Body) != "body" {
+		t.Errorf("Body = %q, want %q (kept from cache)", entry.Body, "body")
+	}
+	if entry.ETag != `"v2"` {
+		t.Errorf("ETag = %q, want %q", entry.ETag, `"v2"`)
+	}
+}
+
+func TestHTTPCache_CompressedStorage(t *testing.T) {
+	cacheDir := t.TempDir()
+	cache, err := NewHTTPCache(cacheDir, 1*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	url := "https://example.com/big.json"
+	body := bytes.Repeat([]byte(`{"titulo":"Concierto en Plaza de España"},`), 1000)
+	if err := cache.Set(CacheEntry{URL: url, Body: body, StatusCode: 200}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	info, err := os.Stat(cache.bodyPath(url))
+	if err != nil {
+		t.Fatalf("compressed body missing: %v", err)
+	}
+	if info.Size() >= int64(len(body))/10 {
+		t.Errorf("stored body = %d bytes, want well under %d (compressed)", info.Size(), len(body))
+	}
+
+	entry, err := cache.Get(url)
+	if err != nil || entry == nil {
+		t.Fatalf("Get = %v, %v; want entry", entry, err)
+	}
+	if !bytes.Equal(entry.Body, body) {
+		t.Errorf("Body round-trip mismatch: got %d bytes, want %d", len(entry.Body), len(body))
+	}
+}
+
+func TestHTTPCache_MigratesLegacyEntries(t *testing.T) {
+	cacheDir := t.TempDir()
+	url := "https://example.com/legacy"
+	legacy, _ := json.Marshal(CacheEntry{
+		URL:        url,
+		Body:       []byte("legacy body"),
+		ETag:       `"old"`,
+		FetchedAt:  time.Now(),
+		StatusCode: 200,
+	})
+	legacyPath := filepath.Join(cacheDir, "0123456789abcdef.json")
+	if err := os.WriteFile(legacyPath, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewHTTPCache(cacheDir, 1*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("legacy file still present (err = %v)", err)
+	}
+
+	entry, err := cache.Get(url)
+	if err != nil || entry == nil {
+		t.Fatalf("Get = %v, %v; want migrated entry", entry, err)
+	}
+	if string(entry.Body) != "legacy body" || entry.ETag != `"old"` {
+		t.Errorf("migrated entry = %q (ETag %q), want %q (ETag %q)", entry.Body, entry.ETag, "legacy body", `"old"`)
+	}
+}
+
+func TestHTTPCache_PruneByAge(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 1*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	now := time.Now()
+	cache.write(CacheEntry{URL: "https://example.com/old", Body: []byte("old"), FetchedAt: now.Add(-8 * 24 * time.Hour)})
+	cache.write(CacheEntry{URL: "aemet-forecast://28079", Body: []byte("old forecast"), FetchedAt: now.Add(-8 * 24 * time.Hour)})
+	cache.write(CacheEntry{URL: "https://example.com/new", Body: []byte("new"), FetchedAt: now.Add(-time.Hour)})
+
+	result, err := cache.Prune(0, 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result.Expired != 2 || result.Evicted != 0 || result.Remaining != 1 {
+		t.Errorf("Prune = %+v, want 2 expired, 0 evicted, 1 remaining", result)
+	}
+
+	entries, _ := cache.Entries()
+	if len(entries) != 1 || entries[0].URL != "https://example.com/new" {
+		t.Errorf("Entries after prune = %+v, want only /new", entries)
+	}
+}
+
+func TestHTTPCache_PruneLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 24*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	now := time.Now()
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+	for i, url := range urls {
+		// Fetched a then b then c...
+		cache.write(CacheEntry{URL: url, Body: []byte(url), FetchedAt: now.Add(time.Duration(i-10) * time.Minute)})
+	}
+	// ...but a was used most recently
+	if _, _, err := cache.Lookup(urls[0]); err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+
+	entries, _ := cache.Entries()
+	var total int64
+	for _, e := range entries {
+		total += e.StoredSize
+	}
+
+	// Room for two of the three entries: b (least recently used) goes
+	result, err := cache.Prune(total-1, 0, now)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if result.Evicted != 1 || result.Remaining != 2 {
+		t.Errorf("Prune = %+v, want 1 evicted, 2 remaining", result)
+	}
+	if result.Size > total-1 {
+		t.Errorf("Size after prune = %d, want <= %d", result.Size, total-1)
+	}
+	for _, url := range urls {
+		entry, _, _ := cache.Lookup(url)
+		if got, want := entry != nil, url != urls[1]; got != want {
+			t.Errorf("%s cached = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestHTTPCache_Purge(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 1*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+
+	for _, url := range []string{
+		"https://datos.madrid.es/a.json",
+		"https://datos.madrid.es/a.xml",
+		"https://www.esmadrid.com/opendata/agenda.xml",
+	} {
+		if err := cache.Set(CacheEntry{URL: url, Body: []byte("x")}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	n, err := cache.Purge("datos.madrid.es")
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Purge(datos.madrid.es) = %d, want 2", n)
+	}
+
+	n, err = cache.Purge("")
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Purge(\"\") = %d, want 1", n)
+	}
+	if entries, _ := cache.Entries(); len(entries) != 0 {
+		t.Errorf("Entries after purging all = %d, want 0", len(entries))
+	}
+}
+
+func TestHTTPCache_EntriesReportTTLOverride(t *testing.T) {
+	cache, err := NewHTTPCache(t.TempDir(), 1*time.Hour)
+	if err != nil {
+		t.Fatalf("NewHTTPCache failed: %v", err)
+	}
+	cache.SetTTLOverride("aemet-forecast://", 6*time.Hour)
+
+	cache.Set(CacheEntry{URL: "aemet-forecast://28079", Body: []byte("{}")})
+	cache.Set(CacheEntry{URL: "https://example.com/x", Body: []byte("{}")})
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatalf("Entries failed: %v", err)
+	}
+	want := map[string]struct {
+		ttl     time.Duration
+		pattern string
+	}{
+		"aemet-forecast://28079": {6 * time.Hour, "aemet-forecast://"},
+		"https://example.com/x":  {1 * time.Hour, ""},
+	}
+	for _, e := range entries {
+		w := want[e.URL]
+		if e.TTL != w.ttl || e.TTLOverride != w.pattern {
+			t.Errorf("%s: TTL = %v (%q), want %v (%q)", e.URL, e.TTL, e.TTLOverride, w.ttl, w.pattern)
+		}
+		if e.Size != 2 || e.StoredSize == 0 {
+			t.Errorf("%s: Size = %d, StoredSize = %d, want 2 and > 0", e.URL, e.Size, e.StoredSize)
+		}
+	}
+}
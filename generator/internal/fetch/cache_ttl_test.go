@@ -1,8 +1,6 @@
 package fetch
 
 import (
-	"encoding/json"
-	"os"
 	"testing"
 	"time"
 )
@@ -35,11 +33,9 @@ func TestHTTPCache_TTLOverride(t *testing.T) {
 		StatusCode: 200,
 	}
 
-	// Manually write cache entries with old timestamps
+	// Write cache entries with old timestamps (Set would stamp them now)
 	for _, entry := range []CacheEntry{madridEntry, aemetEntry} {
-		data, _ := json.MarshalIndent(entry, "", "  ")
-		path := cache.cachePath(entry.URL)
-		if err := os.WriteFile(path, data, 0644); err != nil {
+		if err := cache.write(entry); err != nil {
 			t.Fatalf("Failed to write cache entry: %v", err)
 		}
 	}
@@ -3,7 +3,6 @@ package fetch
 import (
 	"bytes"
 	"encoding/csv"
-	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -18,12 +17,22 @@ import (
 )
 
 // Client wraps an HTTP client for fetching Madrid event data.
+// Fetches are safe to run concurrently once the client is configured: the Set*
+// and Enable* methods must all be called before fetching starts.
 type Client struct {
 	httpClient *http.Client
 	userAgent  string
 	cache      *HTTPCache
 	throttle   *RequestThrottle
 	auditor    *RequestAuditor
+	ledger     *RequestLedger           // Optional: cross-run request budgets (nil = unlimited)
+	retry      RetryPolicy              // Default retry policy
+	hostRetry  map[string]RetryPolicy   // Per-host overrides, keyed by hostname
+	deadline   time.Time                // No retries are scheduled past this (zero = none)
+	maxStale   time.Duration            // Default stale-if-error window (0 = disabled)
+	hostStale  map[string]time.Duration // Per-host stale-if-error windows, keyed by hostname
+	sleep      func(time.Duration)      // Backoff sleep (replaced in tests)
+	bundle     *Bundle                  // Record/replay target (RecordMode, ReplayMode)
 	config     ModeConfig
 }
 
@@ -42,6 +51,10 @@ func NewClient(timeout time.Duration, config ModeConfig, cacheDir string) (*Clie
 		cache:     cache,
 		throttle:  NewRequestThrottle(config.MinDelay),
 		auditor:   NewRequestAuditor(),
+		retry:     NoRetry,
+		hostRetry: make(map[string]RetryPolicy),
+		hostStale: make(map[string]time.Duration),
+		sleep:     time.Sleep,
 		config:    config,
 	}, nil
 }
@@ -51,6 +64,52 @@ func (c *Client) Auditor() *RequestAuditor {
 	return c.auditor
 }
 
+// EnableRequestLedger enforces the mode's per-URL request budget
+// (MaxRequestRate per TimeWindow) across runs, persisting request times at path.
+// Requests over budget are served from cache (even if expired) or fail.
+func (c *Client) EnableRequestLedger(path string) error {
+	ledger, err := NewRequestLedger(path, c.config.MaxRequestRate, c.config.TimeWindow)
+	if err != nil {
+		return err
+	}
+	c.ledger = ledger
+	return nil
+}
+
+// SetRetryPolicy sets the retry policy for requests to host (a hostname such as
+// "opendata.aemet.es"). An empty host sets the default for all other hosts.
+func (c *Client) SetRetryPolicy(host string, policy RetryPolicy) {
+	if host == "" {
+		c.retry = policy
+		return
+	}
+	c.hostRetry[host] = policy
+}
+
+// UseBundle sets the bundle that RecordMode writes to and ReplayMode reads from.
+// It has no effect in other modes.
+func (c *Client) UseBundle(bundle *Bundle) {
+	c.bundle = bundle
+}
+
+// SetMaxStale enables stale-if-error for host: when requests fail with a
+// transient error (network error, 429 or 5xx, after retries), an expired cache
+// entry is served if it expired no more than maxStale ago. An empty host sets
+// the default for all other hosts; zero disables it.
+func (c *Client) SetMaxStale(host string, maxStale time.Duration) {
+	if host == "" {
+		c.maxStale = maxStale
+		return
+	}
+	c.hostStale[host] = maxStale
+}
+
+// SetDeadline bounds retries: a retry whose backoff would end after deadline is
+// not attempted and the last error is returned instead.
+func (c *Client) SetDeadline(deadline time.Time) {
+	c.deadline = deadline
+}
+
 // Config returns the client's mode configuration.
 func (c *Client) Config() ModeConfig {
 	return c.config
@@ -63,10 +122,23 @@ func (c *Client) SetCacheTTLOverride(urlPattern string, ttl time.Duration) {
 	c.cache.SetTTLOverride(urlPattern, ttl)
 }
 
+// PruneCache evicts cache entries older than maxAge, then the least recently
+// used ones until the cache takes at most maxBytes (see HTTPCache.Prune).
+// It does nothing in ReplayMode.
+func (c *Client) PruneCache(maxBytes int64, maxAge time.Duration) (PruneResult, error) {
+	if c.config.Mode == ReplayMode {
+		return PruneResult{}, nil // Replays must not change the real cache
+	}
+	return c.cache.Prune(maxBytes, maxAge, time.Now())
+}
+
 // CacheForecast manually writes data to the cache under a synthetic URL.
 // This is used by the weather client to cache forecast data independently of
 // the temporary AEMET URLs that expire.
 func (c *Client) CacheForecast(syntheticURL string, body []byte) {
+	if c.config.Mode == ReplayMode {
+		return // Replays must not change the real cache
+	}
 	entry := CacheEntry{
 		URL:        syntheticURL,
 		Body:       body,
@@ -80,6 +152,9 @@ func (c *Client) CacheForecast(syntheticURL string, body []byte) {
 // Useful for removing corrupted cache entries when parse errors occur.
 // This is idempotent - calling it on a non-existent entry is safe.
 func (c *Client) InvalidateCache(url string) error {
+	if c.config.Mode == ReplayMode {
+		return nil // Replays must not change the real cache
+	}
 	return c.cache.Delete(url)
 }
 
@@ -88,7 +163,8 @@ func (c *Client) InvalidateCache(url string) error {
 // Useful for APIs requiring authentication (e.g., AEMET API key header).
 // If skipCache is true, bypasses the cache for this request (but still caches the response for future use).
 func (c *Client) FetchWithHeaders(url string, headers map[string]string, skipCache bool) ([]byte, error) {
-	return c.fetchWithHeaders(url, headers, skipCache)
+	body, _, err := c.fetchWithHeaders(url, headers, skipCache)
+	return body, err
 }
 
 // FetchJSON fetches and decodes JSON from the given URL.
@@ -97,7 +173,7 @@ func (c *Client) FetchJSON(url string, loc *time.Location) event.ParseResult {
 	var result event.ParseResult
 
 	// Fetch data (supports both HTTP and file:// URLs)
-	body, err := c.fetch(url)
+	body, stale, err := c.fetch(url)
 	if err != nil {
 		result.Errors = append(result.Errors, event.ParseError{
 			Source:      "JSON",
@@ -107,22 +183,24 @@ func (c *Client) FetchJSON(url string, loc *time.Location) event.ParseResult {
 		return result
 	}
 
+	result.Stale = stale
+
 	// Preprocess JSON to escape literal newlines in string values
 	// Madrid's JSON sometimes contains unescaped newlines which are invalid JSON
 	body = fixJSONNewlines(body)
 
-	var response JSONResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		result.Errors = append(result.Errors, event.ParseError{
-			Source:      "JSON",
-			Error:       fmt.Errorf("decoding JSON: %w", err),
-			RecoverType: "skipped",
-		})
-		return result
-	}
+	// Decode the @graph through the document's own @context; nodes fail independently
+	err = decodeJSONLDEvents(body, func(i int, jsonEvent JSONEvent, err error) {
+		if err != nil {
+			result.Errors = append(result.Errors, event.ParseError{
+				Source:      "JSON",
+				Index:       i,
+				Error:       fmt.Errorf("decoding event: %w", err),
+				RecoverType: "skipped",
+			})
+			return
+		}
 
-	// Parse each event individually with error recovery
-	for i, jsonEvent := range response.Graph {
 		canonical, err := jsonEvent.ToCanonical(loc)
 		if err != nil {
 			// Log parse error but continue processing other events
@@ -133,25 +211,33 @@ func (c *Client) FetchJSON(url string, loc *time.Location) event.ParseResult {
 				Error:       err,
 				RecoverType: "skipped",
 			})
-			continue
+			return
 		}
 
 		result.Events = append(result.Events, event.SourcedEvent{
 			Event:  canonical,
 			Source: "JSON",
 		})
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, event.ParseError{
+			Source:      "JSON",
+			Error:       fmt.Errorf("decoding JSON: %w", err),
+			RecoverType: "skipped",
+		})
 	}
 
 	return result
 }
 
 // FetchXML fetches and decodes XML from the given URL.
+// Events are decoded one <contenido> at a time (see decodeXMLElements).
 // Returns ParseResult with successful events and individual parse errors.
 func (c *Client) FetchXML(url string, loc *time.Location) event.ParseResult {
 	var result event.ParseResult
 
 	// Fetch data (supports both HTTP and file:// URLs)
-	body, err := c.fetch(url)
+	body, stale, err := c.fetch(url)
 	if err != nil {
 		result.Errors = append(result.Errors, event.ParseError{
 			Source:      "XML",
@@ -161,18 +247,21 @@ func (c *Client) FetchXML(url string, loc *time.Location) event.ParseResult {
 		return result
 	}
 
-	var response XMLResponse
-	if err := xml.Unmarshal(body, &response); err != nil {
-		result.Errors = append(result.Errors, event.ParseError{
-			Source:      "XML",
-			Error:       fmt.Errorf("decoding XML: %w", err),
-			RecoverType: "skipped",
-		})
-		return result
-	}
+	result.Stale = stale
+
+	// Stream the <contenido> elements; each decodes and converts on its own
+	err = decodeXMLElements(body, "Contenidos", "contenido", func(i int, _ xml.StartElement, decode func(any) error) {
+		var xmlEvent XMLEvent
+		if err := decode(&xmlEvent); err != nil {
+			result.Errors = append(result.Errors, event.ParseError{
+				Source:      "XML",
+				Index:       i,
+				Error:       fmt.Errorf("decoding event: %w", err),
+				RecoverType: "skipped",
+			})
+			return
+		}
 
-	// Parse each event individually with error recovery
-	for i, xmlEvent := range response.Events {
 		canonical, err := xmlEvent.ToCanonical(loc)
 		if err != nil {
 			// Log parse error but continue processing other events
@@ -183,13 +272,20 @@ func (c *Client) FetchXML(url string, loc *time.Location) event.ParseResult {
 				Error:       err,
 				RecoverType: "skipped",
 			})
-			continue
+			return
 		}
 
 		result.Events = append(result.Events, event.SourcedEvent{
 			Event:  canonical,
 			Source: "XML",
 		})
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, event.ParseError{
+			Source:      "XML",
+			Error:       fmt.Errorf("decoding XML: %w", err),
+			RecoverType: "skipped",
+		})
 	}
 
 	return result
@@ -202,7 +298,7 @@ func (c *Client) FetchCSV(url string, loc *time.Location) event.ParseResult {
 	var result event.ParseResult
 
 	// Fetch data (supports both HTTP and file:// URLs)
-	body, err := c.fetch(url)
+	body, stale, err := c.fetch(url)
 	if err != nil {
 		result.Errors = append(result.Errors, event.ParseError{
 			Source:      "CSV",
@@ -218,6 +314,7 @@ func (c *Client) FetchCSV(url string, loc *time.Location) event.ParseResult {
 		// Fall back to comma
 		result = parseCSV(body, ',', loc)
 	}
+	result.Stale = stale
 
 	return result
 }
@@ -312,8 +409,18 @@ func parseCSVRow(row []string, headerMap map[string]int) CSVEvent {
 		NombreInstalacion: getField(row, headerMap, "NOMBRE-INSTALACION"),
 		Direccion:         getField(row, headerMap, "DIRECCION"),
 		Distrito:          getField(row, headerMap, "DISTRITO-INSTALACION"),
+		Barrio:            getField(row, headerMap, "BARRIO-INSTALACION"),
+		CodigoPostal:      getField(row, headerMap, "CODIGO-POSTAL-INSTALACION"),
+		IDInstalacion:     getField(row, headerMap, "ID-INSTALACION"),
+		Accesibilidad:     getField(row, headerMap, "ACCESIBILIDAD-INSTALACION"),
 		ContentURL:        getField(row, headerMap, "CONTENT-URL"),
 		Descripcion:       getField(row, headerMap, "DESCRIPCION"),
+		Precio:            getField(row, headerMap, "PRECIO"),
+		Gratuito:          getField(row, headerMap, "GRATUITO"),
+		Audiencia:         getField(row, headerMap, "AUDIENCIA"),
+		Tipo:              getField(row, headerMap, "TIPO"),
+		DiasSemana:        getField(row, headerMap, "DIAS-SEMANA"),
+		DiasExcluidos:     getField(row, headerMap, "DIAS-EXCLUIDOS"),
 	}
 
 	// Parse coordinates
@@ -323,6 +430,12 @@ func parseCSVRow(row []string, headerMap map[string]int) CSVEvent {
 	if lonStr := getField(row, headerMap, "LONGITUD"); lonStr != "" {
 		fmt.Sscanf(lonStr, "%f", &event.Longitud)
 	}
+	if xStr := getField(row, headerMap, "COORDENADA-X"); xStr != "" {
+		fmt.Sscanf(xStr, "%f", &event.CoordenadaX)
+	}
+	if yStr := getField(row, headerMap, "COORDENADA-Y"); yStr != "" {
+		fmt.Sscanf(yStr, "%f", &event.CoordenadaY)
+	}
 
 	return event
 }
@@ -337,20 +450,63 @@ func getField(row []string, headerMap map[string]int, fieldName string) string {
 
 // fetch retrieves data from a URL or local file.
 // Supports both HTTP(S) URLs and file:// URLs.
-// Uses HTTP caching with If-Modified-Since and throttling for respectful fetching.
-func (c *Client) fetch(url string) ([]byte, error) {
+// Uses HTTP caching with conditional revalidation and throttling for respectful fetching.
+// stale is non-nil when the body is an expired cache entry served after an upstream failure.
+func (c *Client) fetch(url string) (body []byte, stale *event.StaleData, err error) {
 	return c.fetchWithHeaders(url, nil, false)
 }
 
 // fetchWithHeaders retrieves data from a URL with custom HTTP headers.
+// In RecordMode every result is written to the bundle; in ReplayMode results
+// come only from the bundle. Otherwise (and for recording) see fetchLive.
+func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCache bool) ([]byte, *event.StaleData, error) {
+	switch c.config.Mode {
+	case ReplayMode:
+		return c.replay(url)
+	case RecordMode:
+		body, stale, err := c.fetchLive(url, headers, skipCache)
+		if c.bundle != nil {
+			if recErr := c.bundle.Record(url, body, stale, err); recErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: bundle record error: %v\n", recErr)
+			}
+		}
+		return body, stale, err
+	}
+	return c.fetchLive(url, headers, skipCache)
+}
+
+// replay serves url from the bundle, failing if it wasn't recorded.
+func (c *Client) replay(url string) ([]byte, *event.StaleData, error) {
+	if c.bundle == nil {
+		return nil, nil, fmt.Errorf("replay mode requires a bundle (fetching %s)", url)
+	}
+	body, stale, err := c.bundle.Replay(url)
+	record := RequestRecord{
+		URL:       url,
+		Timestamp: time.Now(),
+		CacheHit:  err == nil,
+		Replayed:  true,
+		Stale:     stale != nil,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	c.auditor.Record(record)
+	return body, stale, err
+}
+
+// fetchLive retrieves data from a URL with custom HTTP headers.
 // Supports both HTTP(S) URLs, file:// URLs, and synthetic URLs (cache-only).
-// Uses HTTP caching with If-Modified-Since and throttling for respectful fetching.
+// Uses HTTP caching with conditional revalidation (If-None-Match/If-Modified-Since)
+// and throttling for respectful fetching.
 // If skipCache is true, bypasses reading from cache (but still writes to cache for future use).
-func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCache bool) ([]byte, error) {
+// Transient failures may be answered from an expired cache entry (see SetMaxStale).
+func (c *Client) fetchLive(url string, headers map[string]string, skipCache bool) ([]byte, *event.StaleData, error) {
 	// Handle file:// URLs (no caching for local files)
 	if strings.HasPrefix(url, "file://") {
 		path := strings.TrimPrefix(url, "file://")
-		return os.ReadFile(path)
+		body, err := os.ReadFile(path)
+		return body, nil, err
 	}
 
 	// Handle synthetic URLs (cache-only, no network fetch)
@@ -359,7 +515,7 @@ func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCac
 		// Only check cache, never make network request
 		cached, err := c.cache.Get(url)
 		if err != nil || cached == nil {
-			return nil, fmt.Errorf("cache miss for synthetic URL: %s", url)
+			return nil, nil, fmt.Errorf("cache miss for synthetic URL: %s", url)
 		}
 		c.auditor.Record(RequestRecord{
 			URL:       url,
@@ -367,44 +523,159 @@ func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCac
 			CacheHit:  true,
 			Synthetic: true,
 		})
-		return cached.Body, nil
+		return cached.Body, nil, nil
 	}
 
 	// Strict test mode: block all external HTTP requests if PLAZAESPANA_NO_API is set
 	// Allow localhost/127.0.0.1 for test servers (httptest)
 	if os.Getenv("PLAZAESPANA_NO_API") != "" {
 		if !strings.Contains(url, "://localhost") && !strings.Contains(url, "://127.0.0.1") {
-			return nil, fmt.Errorf("BLOCKED: external API request to %s (PLAZAESPANA_NO_API is set - use mock servers in tests)", url)
+			return nil, nil, fmt.Errorf("BLOCKED: external API request to %s (PLAZAESPANA_NO_API is set - use mock servers in tests)", url)
 		}
 	}
 
-	// Check cache first (unless skipCache is true)
+	// Check cache first (unless skipCache is true).
+	// Expired entries are kept in cached so the request can revalidate them.
 	var cached *CacheEntry
 	if !skipCache {
-		var err error
-		cached, err = c.cache.Get(url)
+		entry, fresh, err := c.cache.Lookup(url)
 		if err != nil {
 			// Cache read error - log but continue to fetch
 			fmt.Fprintf(os.Stderr, "Warning: cache read error: %v\n", err)
 		}
 
-		if cached != nil {
+		if entry != nil && fresh {
 			// Cache hit! Use cached data
 			c.auditor.Record(RequestRecord{
 				URL:       url,
 				Timestamp: time.Now(),
 				CacheHit:  true,
 			})
-			return cached.Body, nil
+			return entry.Body, nil, nil
+		}
+		cached = entry
+	}
+
+	// Enforce the cross-run request budget before touching the network,
+	// counting the request whether or not it succeeds
+	if c.ledger != nil {
+		ok, nextAllowed, err := c.ledger.Reserve(url, time.Now())
+		if !ok {
+			body, err := c.suppress(url, cached, nextAllowed)
+			return body, nil, err
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: request ledger write error: %v\n", err)
 		}
 	}
 
-	// Cache miss - need to make HTTP request
+	// Cache miss - need to make HTTP request(s), retrying per the host's policy
+	policy := c.retryPolicyFor(url)
+	var result attemptResult
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && c.ledger != nil {
+			// Retries count against the budget too
+			if err := c.ledger.Record(url, time.Now()); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: request ledger write error: %v\n", err)
+			}
+		}
+		result = c.attempt(url, headers, cached)
+		result.record.Attempt = attempt
+
+		if result.err == nil || !result.retryable || attempt >= policy.MaxAttempts {
+			c.auditor.Record(result.record)
+			break
+		}
+
+		// Honour Retry-After: never come back sooner than the server asked,
+		// and give up if it asks for longer than we're willing to wait
+		wait := policy.backoff(attempt)
+		if result.retryAfter > policy.MaxDelay {
+			c.auditor.Record(result.record)
+			result.err = fmt.Errorf("%w (Retry-After %v exceeds max retry delay %v)", result.err, result.retryAfter, policy.MaxDelay)
+			break
+		}
+		if result.retryAfter > wait {
+			wait = result.retryAfter
+		}
+
+		if !c.deadline.IsZero() && time.Now().Add(wait).After(c.deadline) {
+			c.auditor.Record(result.record)
+			result.err = fmt.Errorf("%w (not retrying: build deadline reached)", result.err)
+			break
+		}
+
+		result.record.RetryInMs = wait.Milliseconds()
+		c.auditor.Record(result.record)
+		fmt.Fprintf(os.Stderr, "[%s] Attempt %d/%d for %s failed (%v), retrying in %v\n",
+			c.config.Mode, attempt, policy.MaxAttempts, url, result.err, wait.Round(time.Millisecond))
+		c.sleep(wait)
+	}
+
+	// Stale-if-error: a recently expired copy beats no data at all
+	if result.err != nil && result.retryable {
+		if body, stale := c.serveStale(url, cached, result.err); stale != nil {
+			return body, stale, nil
+		}
+	}
+	return result.body, nil, result.err
+}
+
+// serveStale returns an expired cached body after a transient upstream failure,
+// if the entry expired within the host's max-stale window. Returns nil stale
+// data when there is nothing suitable to serve.
+func (c *Client) serveStale(url string, cached *CacheEntry, cause error) ([]byte, *event.StaleData) {
+	maxStale := c.maxStaleFor(url)
+	if maxStale <= 0 {
+		return nil, nil
+	}
+	if cached == nil {
+		// skipCache requests still fall back to whatever is cached
+		cached, _, _ = c.cache.Lookup(url)
+		if cached == nil {
+			return nil, nil
+		}
+	}
+	if expiredFor := time.Since(cached.FetchedAt) - c.cache.ttlFor(url); expiredFor > maxStale {
+		return nil, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "[%s] Serving stale copy of %s from %s after upstream error: %v\n",
+		c.config.Mode, url, cached.FetchedAt.Format(time.RFC3339), cause)
+	c.auditor.Record(RequestRecord{
+		URL:       url,
+		Timestamp: time.Now(),
+		CacheHit:  true,
+		Stale:     true,
+		Error:     cause.Error(),
+	})
+	return cached.Body, &event.StaleData{FetchedAt: cached.FetchedAt, Reason: cause.Error()}
+}
+
+// attemptResult is the outcome of one HTTP request. The audit record is
+// returned rather than recorded so the retry loop can annotate it.
+type attemptResult struct {
+	body       []byte
+	err        error
+	record     RequestRecord
+	retryable  bool          // Transient failure: network error or retryable status
+	retryAfter time.Duration // Server-requested wait from Retry-After (0 if none)
+}
+
+// attempt makes a single throttled HTTP request, revalidating cached if set,
+// and updates the cache from the response. It holds the host's throttle slot
+// until the response is read, so requests to one host never overlap.
+func (c *Client) attempt(url string, headers map[string]string, cached *CacheEntry) attemptResult {
 	// Wait for throttle to allow request
-	delay, err := c.throttle.Wait(url)
+	delay, release, err := c.throttle.Acquire(url)
 	if err != nil {
-		return nil, fmt.Errorf("throttle error: %w", err)
+		return attemptResult{err: fmt.Errorf("throttle error: %w", err), record: RequestRecord{
+			URL:       url,
+			Timestamp: time.Now(),
+			Error:     err.Error(),
+		}}
 	}
+	defer release()
 
 	if delay > 0 {
 		// Log the delay so user knows why build is slow
@@ -412,10 +683,17 @@ func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCac
 			c.config.Mode, delay.Round(time.Millisecond), url)
 	}
 
+	record := RequestRecord{
+		URL:     url,
+		DelayMs: delay.Milliseconds(),
+	}
+
 	// Create HTTP request
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		record.Timestamp = time.Now()
+		record.Error = err.Error()
+		return attemptResult{err: fmt.Errorf("creating request: %w", err), record: record}
 	}
 	req.Header.Set("User-Agent", c.userAgent)
 
@@ -424,73 +702,69 @@ func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCac
 		req.Header.Set(key, value)
 	}
 
-	// Add If-Modified-Since header if we have cached data (even if expired)
-	if cached != nil && cached.LastModified != "" {
-		req.Header.Set("If-Modified-Since", cached.LastModified)
+	// Revalidate expired cache entries with conditional headers
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
 	}
 
 	// Make HTTP request
 	resp, err := c.httpClient.Do(req)
+	record.Timestamp = time.Now()
 	if err != nil {
-		c.auditor.Record(RequestRecord{
-			URL:       url,
-			Timestamp: time.Now(),
-			CacheHit:  false,
-			DelayMs:   delay.Milliseconds(),
-			Error:     err.Error(),
-		})
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		record.Error = err.Error()
+		return attemptResult{
+			err:       fmt.Errorf("HTTP request failed: %w", err),
+			record:    record,
+			retryable: true,
+		}
 	}
 	defer resp.Body.Close()
+	record.StatusCode = resp.StatusCode
 
-	// Handle 304 Not Modified - use cached data
+	// Handle 304 Not Modified - reuse cached body and mark the entry fresh again
 	if resp.StatusCode == http.StatusNotModified && cached != nil {
-		c.auditor.Record(RequestRecord{
-			URL:        url,
-			Timestamp:  time.Now(),
-			CacheHit:   true,
-			StatusCode: 304,
-			DelayMs:    delay.Milliseconds(),
-		})
-		return cached.Body, nil
+		if err := c.cache.Refresh(*cached, resp.Header.Get("Last-Modified"), resp.Header.Get("ETag")); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cache refresh error: %v\n", err)
+		}
+		record.CacheHit = true
+		record.Revalidation = RevalidationNotModified
+		return attemptResult{body: cached.Body, record: record}
 	}
 
 	// Check for rate limiting or errors
 	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusServiceUnavailable {
-		c.auditor.Record(RequestRecord{
-			URL:         url,
-			Timestamp:   time.Now(),
-			CacheHit:    false,
-			StatusCode:  resp.StatusCode,
-			DelayMs:     delay.Milliseconds(),
-			RateLimited: true,
-		})
-		return nil, fmt.Errorf("HTTP %d (rate limited): %s", resp.StatusCode, resp.Status)
+		record.RateLimited = true
+		return attemptResult{
+			err:        fmt.Errorf("HTTP %d (rate limited): %s", resp.StatusCode, resp.Status),
+			record:     record,
+			retryable:  retryableStatus(resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		c.auditor.Record(RequestRecord{
-			URL:        url,
-			Timestamp:  time.Now(),
-			CacheHit:   false,
-			StatusCode: resp.StatusCode,
-			DelayMs:    delay.Milliseconds(),
-		})
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		return attemptResult{
+			err:        fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status),
+			record:     record,
+			retryable:  retryableStatus(resp.StatusCode),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		c.auditor.Record(RequestRecord{
-			URL:        url,
-			Timestamp:  time.Now(),
-			CacheHit:   false,
-			StatusCode: resp.StatusCode,
-			DelayMs:    delay.Milliseconds(),
-			Error:      err.Error(),
-		})
-		return nil, fmt.Errorf("reading response: %w", err)
+		record.Error = err.Error()
+		return attemptResult{
+			err:       fmt.Errorf("reading response: %w", err),
+			record:    record,
+			retryable: true,
+		}
 	}
 
 	// Store in cache
@@ -507,15 +781,57 @@ func (c *Client) fetchWithHeaders(url string, headers map[string]string, skipCac
 	}
 
 	// Record successful fetch
+	if cached != nil {
+		record.Revalidation = RevalidationModified
+	}
+	return attemptResult{body: body, record: record}
+}
+
+// maxStaleFor returns the stale-if-error window for url's host, or the default.
+func (c *Client) maxStaleFor(url string) time.Duration {
+	if maxStale, ok := c.hostStale[HostOf(url)]; ok {
+		return maxStale
+	}
+	return c.maxStale
+}
+
+// retryPolicyFor returns the retry policy for url's host, or the default policy.
+func (c *Client) retryPolicyFor(url string) RetryPolicy {
+	if policy, ok := c.hostRetry[HostOf(url)]; ok {
+		return policy
+	}
+	return c.retry
+}
+
+// suppress handles a request whose budget is exhausted: it serves the cached
+// body if there is one (fresh or not), otherwise it fails so callers can fall
+// back to snapshots. Either way the request is audited as suppressed.
+func (c *Client) suppress(url string, cached *CacheEntry, nextAllowed time.Time) ([]byte, error) {
+	if cached == nil {
+		// skipCache requests still prefer cached data over breaking the budget
+		cached, _, _ = c.cache.Lookup(url)
+	}
+
+	if cached != nil {
+		fmt.Fprintf(os.Stderr, "[%s] Request budget exhausted for %s, serving cached copy from %s\n",
+			c.config.Mode, url, cached.FetchedAt.Format(time.RFC3339))
+		c.auditor.Record(RequestRecord{
+			URL:        url,
+			Timestamp:  time.Now(),
+			CacheHit:   true,
+			Suppressed: true,
+		})
+		return cached.Body, nil
+	}
+
+	err := fmt.Errorf("request budget exhausted for %s (next allowed at %s)", url, nextAllowed.Format(time.RFC3339))
 	c.auditor.Record(RequestRecord{
 		URL:        url,
 		Timestamp:  time.Now(),
-		CacheHit:   false,
-		StatusCode: resp.StatusCode,
-		DelayMs:    delay.Milliseconds(),
+		Suppressed: true,
+		Error:      err.Error(),
 	})
-
-	return body, nil
+	return nil, err
 }
 
 // fixJSONNewlines preprocesses JSON to escape literal newlines in string values.
@@ -1,8 +1,13 @@
 package fetch
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -199,3 +204,504 @@ CSV-002,CSV Event 2,2025-11-26 00:00:00.0,2025-11-26 23:59:00.0,18:00,Venue 2,40
 		t.Errorf("Expected Longitude -3.71, got %f", result.Events[0].Event.Longitude)
 	}
 }
+
+func TestClient_RevalidatesExpiredEntry(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 20 Oct 2025 10:00:00 GMT")
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond}
+	client, err := NewClient(5*time.Second, config, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	if _, _, err := client.fetch(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the entry expire
+
+	revalidatedAt := time.Now()
+	body, _, err := client.fetch(server.URL)
+	if err != nil {
+		t.Fatalf("revalidating fetch failed: %v", err)
+	}
+	if string(body) != "payload" {
+		t.Errorf("body = %q, want cached %q", body, "payload")
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("upstream requests = %d, want 2", len(requests))
+	}
+	if got := requests[1].Header.Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("If-None-Match = %q, want %q", got, `"v1"`)
+	}
+	if got := requests[1].Header.Get("If-Modified-Since"); got != "Mon, 20 Oct 2025 10:00:00 GMT" {
+		t.Errorf("If-Modified-Since = %q, want Last-Modified from first response", got)
+	}
+
+	records := client.Auditor().Records()
+	last := records[len(records)-1]
+	if last.Revalidation != RevalidationNotModified || last.StatusCode != 304 || !last.CacheHit {
+		t.Errorf("last record = %+v, want cache-hit 304 with revalidation %q", last, RevalidationNotModified)
+	}
+
+	// The 304 refreshed the entry, restarting its TTL. (Checking FetchedAt
+	// rather than freshness: a slow run can outlast the 10ms TTL again.)
+	entry, _, _ := client.cache.Lookup(server.URL)
+	if entry == nil || entry.FetchedAt.Before(revalidatedAt) {
+		t.Error("cache entry not refreshed after 304")
+	}
+}
+
+func TestClient_RevalidationModified(t *testing.T) {
+	version := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, version))
+		fmt.Fprintf(w, "payload v%d", version)
+	}))
+	defer server.Close()
+
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond}
+	client, err := NewClient(5*time.Second, config, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	client.fetch(server.URL)
+	time.Sleep(20 * time.Millisecond)
+	version = 2
+
+	body, _, err := client.fetch(server.URL)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if string(body) != "payload v2" {
+		t.Errorf("body = %q, want %q", body, "payload v2")
+	}
+
+	records := client.Auditor().Records()
+	if first := records[0]; first.Revalidation != "" {
+		t.Errorf("first record revalidation = %q, want empty (nothing cached)", first.Revalidation)
+	}
+	if last := records[len(records)-1]; last.Revalidation != RevalidationModified {
+		t.Errorf("last record revalidation = %q, want %q", last.Revalidation, RevalidationModified)
+	}
+}
+
+func TestClient_RequestBudgetServesCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond, MaxRequestRate: 1, TimeWindow: time.Hour}
+	client, err := NewClient(5*time.Second, config, filepath.Join(dir, "cache"))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	if err := client.EnableRequestLedger(filepath.Join(dir, "ledger.json")); err != nil {
+		t.Fatalf("EnableRequestLedger failed: %v", err)
+	}
+
+	if _, _, err := client.fetch(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the entry expire
+
+	body, _, err := client.fetch(server.URL)
+	if err != nil {
+		t.Fatalf("over-budget fetch failed: %v", err)
+	}
+	if string(body) != "payload" {
+		t.Errorf("body = %q, want cached %q", body, "payload")
+	}
+	if requests != 1 {
+		t.Errorf("upstream requests = %d, want 1", requests)
+	}
+
+	records := client.Auditor().Records()
+	if last := records[len(records)-1]; !last.Suppressed || !last.CacheHit {
+		t.Errorf("last record = %+v, want suppressed cache hit", last)
+	}
+}
+
+func TestClient_RequestBudgetAcrossClients(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	ledgerPath := filepath.Join(t.TempDir(), "ledger.json")
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: time.Hour, MaxRequestRate: 1, TimeWindow: time.Hour}
+	newClient := func() *Client {
+		// Fresh cache dir per client: only the ledger carries over
+		client, err := NewClient(5*time.Second, config, t.TempDir())
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		if err := client.EnableRequestLedger(ledgerPath); err != nil {
+			t.Fatalf("EnableRequestLedger failed: %v", err)
+		}
+		return client
+	}
+
+	if _, _, err := newClient().fetch(server.URL); err != nil {
+		t.Fatalf("first run fetch failed: %v", err)
+	}
+
+	second := newClient()
+	_, _, err := second.fetch(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "request budget exhausted") {
+		t.Errorf("second run error = %v, want request budget exhausted", err)
+	}
+	if requests != 1 {
+		t.Errorf("upstream requests = %d, want 1", requests)
+	}
+	records := second.Auditor().Records()
+	if len(records) != 1 || !records[0].Suppressed || records[0].CacheHit {
+		t.Errorf("records = %+v, want one suppressed record without cache hit", records)
+	}
+}
+
+// newRetryTestClient creates a client whose backoff sleeps are recorded, not slept.
+func newRetryTestClient(t *testing.T, policy RetryPolicy) (*Client, *[]time.Duration) {
+	t.Helper()
+	client, err := NewClient(5*time.Second, ModeConfig{Mode: DevelopmentMode, CacheTTL: time.Hour}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.SetRetryPolicy("", policy)
+	var sleeps []time.Duration
+	client.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return client, &sleeps
+}
+
+func TestClient_RetriesTransientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	client, sleeps := newRetryTestClient(t, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+
+	body, _, err := client.fetch(server.URL)
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if string(body) != "payload" {
+		t.Errorf("body = %q, want %q", body, "payload")
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second}; fmt.Sprint(*sleeps) != fmt.Sprint(want) {
+		t.Errorf("backoff sleeps = %v, want %v", *sleeps, want)
+	}
+
+	// Every attempt is audited, numbered, with the wait before the next one
+	records := client.Auditor().Records()
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	for i, r := range records {
+		if r.Attempt != i+1 {
+			t.Errorf("records[%d].Attempt = %d, want %d", i, r.Attempt, i+1)
+		}
+	}
+	if records[0].StatusCode != 502 || records[0].RetryInMs != 1000 {
+		t.Errorf("records[0] = %+v, want 502 retried in 1000ms", records[0])
+	}
+	if records[2].StatusCode != 200 || records[2].RetryInMs != 0 {
+		t.Errorf("records[2] = %+v, want final 200", records[2])
+	}
+}
+
+func TestClient_RetryGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    string
+		policy    RetryPolicy
+		deadline  time.Duration
+		wantCalls int
+	}{
+		{"attempts exhausted", 503, "", RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Second}, 0, 3},
+		{"not retryable", 404, "", RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Second}, 0, 1},
+		{"forbidden", 403, "", RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Second}, 0, 1},
+		{"retry-after too long", 429, "3600", RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}, 0, 1},
+		{"past deadline", 503, "", RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}, time.Second, 1},
+		{"default no retry", 503, "", NoRetry, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client, _ := newRetryTestClient(t, tt.policy)
+			if tt.deadline > 0 {
+				client.SetDeadline(time.Now().Add(tt.deadline))
+			}
+
+			if _, _, err := client.fetch(server.URL); err == nil {
+				t.Fatal("fetch succeeded, want error")
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if records := client.Auditor().Records(); len(records) != tt.wantCalls {
+				t.Errorf("audit records = %d, want %d", len(records), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_HonoursRetryAfter(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	client, sleeps := newRetryTestClient(t, RetryPolicy{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: 30 * time.Second})
+
+	if _, _, err := client.fetch(server.URL); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if len(*sleeps) != 1 || (*sleeps)[0] != 7*time.Second {
+		t.Errorf("backoff sleeps = %v, want [7s] from Retry-After", *sleeps)
+	}
+	if first := client.Auditor().Records()[0]; !first.RateLimited {
+		t.Errorf("first record = %+v, want rate limited", first)
+	}
+}
+
+func TestClient_PerHostRetryPolicy(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := newRetryTestClient(t, NoRetry)
+	client.SetRetryPolicy("127.0.0.1", RetryPolicy{MaxAttempts: 4})
+
+	client.fetch(server.URL)
+	if requests != 4 {
+		t.Errorf("upstream requests = %d, want 4 from host policy", requests)
+	}
+}
+
+func TestClient_ServesStaleOnError(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond}
+	client, err := NewClient(5*time.Second, config, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.SetMaxStale("", time.Hour)
+
+	if _, _, err := client.fetch(server.URL); err != nil {
+		t.Fatalf("first fetch failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond) // Let the entry expire
+	failing = true
+
+	body, stale, err := client.fetch(server.URL)
+	if err != nil {
+		t.Fatalf("fetch failed, want stale body: %v", err)
+	}
+	if string(body) != "payload" {
+		t.Errorf("body = %q, want stale %q", body, "payload")
+	}
+	if stale == nil || !strings.Contains(stale.Reason, "500") {
+		t.Errorf("stale = %+v, want stale data citing the 500", stale)
+	}
+
+	records := client.Auditor().Records()
+	if last := records[len(records)-1]; !last.Stale || !last.CacheHit || last.Error == "" {
+		t.Errorf("last record = %+v, want stale cache hit with error", last)
+	}
+}
+
+func TestClient_StaleIfErrorLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		maxStale time.Duration
+	}{
+		{"disabled", 500, 0},
+		{"too stale", 500, time.Millisecond},
+		{"not transient", 404, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if failing {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Write([]byte("payload"))
+			}))
+			defer server.Close()
+
+			config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond}
+			client, err := NewClient(5*time.Second, config, t.TempDir())
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			client.SetMaxStale("", tt.maxStale)
+
+			client.fetch(server.URL)
+			time.Sleep(30 * time.Millisecond)
+			failing = true
+
+			if _, stale, err := client.fetch(server.URL); err == nil || stale != nil {
+				t.Errorf("fetch = (stale %+v, err %v), want error without stale data", stale, err)
+			}
+		})
+	}
+}
+
+func TestClient_FetchJSON_Stale(t *testing.T) {
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"@graph":[{"id":"1","title":"Evento","dtstart":"2025-10-20 10:00:00.0","dtend":"2025-10-20 12:00:00.0"}]}`))
+	}))
+	defer server.Close()
+
+	config := ModeConfig{Mode: DevelopmentMode, CacheTTL: 10 * time.Millisecond}
+	client, err := NewClient(5*time.Second, config, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	client.SetMaxStale("", time.Hour)
+	loc, _ := time.LoadLocation("Europe/Madrid")
+
+	if result := client.FetchJSON(server.URL, loc); result.Stale != nil {
+		t.Errorf("fresh fetch Stale = %+v, want nil", result.Stale)
+	}
+	time.Sleep(20 * time.Millisecond)
+	failing = true
+
+	result := client.FetchJSON(server.URL, loc)
+	if result.Stale == nil {
+		t.Fatal("Stale = nil, want stale data after upstream 503")
+	}
+	if len(result.Events) != 1 {
+		t.Errorf("Events = %d, want 1 from stale body", len(result.Events))
+	}
+}
+
+func TestClient_ConcurrentFetchesSerializePerHost(t *testing.T) {
+	// Each server tracks how many of its requests are in flight at once, and
+	// holds its first request until the other server has one: with the hosts
+	// fetched one after the other, it would wait in vain
+	arrivedA, arrivedB := make(chan struct{}), make(chan struct{})
+	var overlapped atomic.Int32
+	newServer := func(maxInFlight *int, arrived, other chan struct{}) *httptest.Server {
+		var mu sync.Mutex
+		var once sync.Once
+		inFlight := 0
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			inFlight++
+			if inFlight > *maxInFlight {
+				*maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			once.Do(func() {
+				close(arrived)
+				select {
+				case <-other:
+					overlapped.Add(1)
+				case <-time.After(5 * time.Second):
+				}
+			})
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			w.Write([]byte("payload"))
+		}))
+	}
+	var maxA, maxB int
+	serverA, serverB := newServer(&maxA, arrivedA, arrivedB), newServer(&maxB, arrivedB, arrivedA)
+	defer serverA.Close()
+	defer serverB.Close()
+
+	client, err := NewClient(5*time.Second, ModeConfig{Mode: DevelopmentMode, CacheTTL: time.Hour}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		for _, server := range []*httptest.Server{serverA, serverB} {
+			wg.Add(1)
+			go func(url string) {
+				defer wg.Done()
+				if _, _, err := client.fetch(url); err != nil {
+					t.Errorf("fetch(%s) failed: %v", url, err)
+				}
+			}(fmt.Sprintf("%s/%d", server.URL, i))
+		}
+	}
+	wg.Wait()
+
+	if maxA != 1 || maxB != 1 {
+		t.Errorf("max in-flight requests per host = %d and %d, want 1", maxA, maxB)
+	}
+	if overlapped.Load() != 2 {
+		t.Error("hosts were not fetched in parallel")
+	}
+}
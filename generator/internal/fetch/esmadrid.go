@@ -4,8 +4,6 @@ import (
 	"encoding/xml"
 	"fmt"
 	"html"
-	"io"
-	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -192,6 +190,7 @@ func (e EsmadridService) ToCityEvent() (*event.CityEvent, error) {
 		StartDate:   startDate,
 		EndDate:     endDate,
 		Venue:       e.VenueName,
+		VenueID:     e.VenueID,
 		Address:     e.Address,
 		Latitude:    lat,
 		Longitude:   lon,
@@ -252,46 +251,71 @@ func parseEsmadridDate(dateStr string) (time.Time, error) {
 	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc), nil
 }
 
-// FetchEsmadridEvents fetches and parses ESMadrid events from the given URL.
-// Returns a slice of EsmadridService structs or an error if fetching/parsing fails.
-func FetchEsmadridEvents(url string) ([]EsmadridService, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
+// FetchEsmadrid fetches and decodes the ESMadrid agenda XML from the given URL.
+// Goes through the same cache, throttle and audit path as FetchJSON/FetchXML/FetchCSV.
+// Services are decoded one at a time (see decodeXMLElements).
+// Returns CityParseResult with successful events and individual parse errors.
+func (c *Client) FetchEsmadrid(url string) event.CityParseResult {
+	var result event.CityParseResult
 
-	// Create HTTP request
-	req, err := http.NewRequest("GET", url, nil)
+	// Fetch data (supports both HTTP and file:// URLs)
+	body, stale, err := c.fetch(url)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		result.Errors = append(result.Errors, event.ParseError{
+			Source:      "ESMadrid",
+			Error:       err,
+			RecoverType: "skipped",
+		})
+		return result
 	}
+	result.Stale = stale
+
+	// Stream the <service> elements; one malformed service doesn't fail the feed
+	err = decodeXMLElements(body, "serviceList", "service", func(i int, start xml.StartElement, decode func(any) error) {
+		rawData := fmt.Sprintf("ID=%s", attrValue(start, "id"))
+
+		var svc EsmadridService
+		if err := decode(&svc); err != nil {
+			result.Errors = append(result.Errors, event.ParseError{
+				Source:      "ESMadrid",
+				Index:       i,
+				RawData:     rawData,
+				Error:       fmt.Errorf("decoding service: %w", err),
+				RecoverType: "skipped",
+			})
+			return
+		}
 
-	// Set User-Agent header (matching pattern from client.go)
-	req.Header.Set("User-Agent", "plazaespana-info-site-generator/1.0 (https://github.com/ericphanson/plazaespana.info)")
-
-	// Execute request
-	resp, err := client.Do(req)
+		cityEvent, err := svc.ToCityEvent()
+		if err != nil {
+			result.Errors = append(result.Errors, event.ParseError{
+				Source:      "ESMadrid",
+				Index:       i,
+				RawData:     rawData,
+				Error:       err,
+				RecoverType: "skipped",
+			})
+			return
+		}
+		result.Events = append(result.Events, *cityEvent)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		result.Errors = append(result.Errors, event.ParseError{
+			Source:      "ESMadrid",
+			Error:       fmt.Errorf("decoding XML: %w", err),
+			RecoverType: "skipped",
+		})
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
+	return result
+}
 
-	// Parse XML
-	var serviceList EsmadridServiceList
-	if err := xml.Unmarshal(body, &serviceList); err != nil {
-		return nil, fmt.Errorf("parsing XML: %w", err)
+// attrValue returns the value of start's attribute name, or "".
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
 	}
-
-	return serviceList.Services, nil
+	return ""
 }
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -169,6 +171,9 @@ func TestToCityEvent(t *testing.T) {
 	if evt.Venue != "Réplika Teatro" {
 		t.Errorf("Expected Venue 'Réplika Teatro', got '%s'", evt.Venue)
 	}
+	if evt.VenueID != "78001" {
+		t.Errorf("Expected VenueID '78001', got '%s'", evt.VenueID)
+	}
 	if evt.Address != "de la Explanada, 14" {
 		t.Errorf("Expected Address 'de la Explanada, 14', got '%s'", evt.Address)
 	}
@@ -284,8 +289,8 @@ func TestParseFullFixture(t *testing.T) {
 	}
 }
 
-// TestFetchEsmadridEvents_Success tests successful HTTP fetch and parse
-func TestFetchEsmadridEvents_Success(t *testing.T) {
+// TestClient_FetchEsmadrid_Success tests successful HTTP fetch and parse
+func TestClient_FetchEsmadrid_Success(t *testing.T) {
 	// Mock ESMadrid XML response
 	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
 <serviceList>
@@ -380,9 +385,10 @@ func TestFetchEsmadridEvents_Success(t *testing.T) {
 	defer server.Close()
 
 	// Fetch events
-	events, err := FetchEsmadridEvents(server.URL)
-	if err != nil {
-		t.Fatalf("FetchEsmadridEvents failed: %v", err)
+	client := newEsmadridTestClient(t)
+	result := client.FetchEsmadrid(server.URL)
+	if len(result.Errors) > 0 {
+		t.Fatalf("FetchEsmadrid failed: %v", result.Errors[0].Error)
 	}
 
 	// Verify User-Agent was set
@@ -392,29 +398,26 @@ func TestFetchEsmadridEvents_Success(t *testing.T) {
 	}
 
 	// Verify we got 2 events
-	if len(events) != 2 {
-		t.Fatalf("Expected 2 events, got %d", len(events))
+	if len(result.Events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(result.Events))
 	}
 
 	// Verify first event
-	event1 := events[0]
+	event1 := result.Events[0]
 	if event1.ID != "12345" {
 		t.Errorf("Expected ID '12345', got '%s'", event1.ID)
 	}
-	if event1.Name != "Exposición de Arte" {
-		t.Errorf("Expected Name 'Exposición de Arte', got '%s'", event1.Name)
-	}
 	if event1.Title != "Arte Contemporáneo" {
 		t.Errorf("Expected Title 'Arte Contemporáneo', got '%s'", event1.Title)
 	}
-	if event1.VenueName != "Museo Reina Sofía" {
-		t.Errorf("Expected VenueName 'Museo Reina Sofía', got '%s'", event1.VenueName)
+	if event1.Venue != "Museo Reina Sofía" {
+		t.Errorf("Expected Venue 'Museo Reina Sofía', got '%s'", event1.Venue)
 	}
-	if event1.Latitude != "40.4085" {
-		t.Errorf("Expected Latitude '40.4085', got '%s'", event1.Latitude)
+	if event1.Latitude != 40.4085 {
+		t.Errorf("Expected Latitude 40.4085, got %f", event1.Latitude)
 	}
-	if event1.Longitude != "-3.6936" {
-		t.Errorf("Expected Longitude '-3.6936', got '%s'", event1.Longitude)
+	if event1.Longitude != -3.6936 {
+		t.Errorf("Expected Longitude -3.6936, got %f", event1.Longitude)
 	}
 	if event1.Category != "Cultura" {
 		t.Errorf("Expected Category 'Cultura', got '%s'", event1.Category)
@@ -422,23 +425,23 @@ func TestFetchEsmadridEvents_Success(t *testing.T) {
 	if event1.Subcategory != "Exposiciones" {
 		t.Errorf("Expected Subcategory 'Exposiciones', got '%s'", event1.Subcategory)
 	}
-	if event1.StartDate != "20/10/2025" {
-		t.Errorf("Expected StartDate '20/10/2025', got '%s'", event1.StartDate)
+	if got := event1.StartDate.Format("02/01/2006"); got != "20/10/2025" {
+		t.Errorf("Expected StartDate '20/10/2025', got '%s'", got)
 	}
-	if event1.EndDate != "30/11/2025" {
-		t.Errorf("Expected EndDate '30/11/2025', got '%s'", event1.EndDate)
+	if got := event1.EndDate.Format("02/01/2006"); got != "30/11/2025" {
+		t.Errorf("Expected EndDate '30/11/2025', got '%s'", got)
 	}
 	if event1.Price != "Gratuito" {
 		t.Errorf("Expected Price 'Gratuito', got '%s'", event1.Price)
 	}
 
 	// Verify second event
-	event2 := events[1]
+	event2 := result.Events[1]
 	if event2.ID != "67890" {
 		t.Errorf("Expected ID '67890', got '%s'", event2.ID)
 	}
-	if event2.Name != "Concierto de Jazz" {
-		t.Errorf("Expected Name 'Concierto de Jazz', got '%s'", event2.Name)
+	if event2.Title != "Jazz en vivo" {
+		t.Errorf("Expected Title 'Jazz en vivo', got '%s'", event2.Title)
 	}
 	if event2.Category != "Música" {
 		t.Errorf("Expected Category 'Música', got '%s'", event2.Category)
@@ -448,8 +451,18 @@ func TestFetchEsmadridEvents_Success(t *testing.T) {
 	}
 }
 
-// TestFetchEsmadridEvents_HTTPError tests handling of HTTP errors
-func TestFetchEsmadridEvents_HTTPError(t *testing.T) {
+// newEsmadridTestClient creates a development-mode client with a temporary cache.
+func newEsmadridTestClient(t *testing.T) *Client {
+	t.Helper()
+	client, err := NewClient(5*time.Second, DefaultDevelopmentConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return client
+}
+
+// TestClient_FetchEsmadrid_HTTPError tests handling of HTTP errors
+func TestClient_FetchEsmadrid_HTTPError(t *testing.T) {
 	// Create server that returns 404
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
@@ -457,15 +470,18 @@ func TestFetchEsmadridEvents_HTTPError(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Fetch should return error
-	_, err := FetchEsmadridEvents(server.URL)
-	if err == nil {
-		t.Fatal("Expected error for 404 response, got nil")
+	// Fetch should return a feed-level error
+	result := newEsmadridTestClient(t).FetchEsmadrid(server.URL)
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected error for 404 response, got none")
+	}
+	if len(result.Events) != 0 {
+		t.Errorf("Expected 0 events, got %d", len(result.Events))
 	}
 }
 
-// TestFetchEsmadridEvents_InvalidXML tests handling of invalid XML
-func TestFetchEsmadridEvents_InvalidXML(t *testing.T) {
+// TestClient_FetchEsmadrid_InvalidXML tests handling of invalid XML
+func TestClient_FetchEsmadrid_InvalidXML(t *testing.T) {
 	// Create server that returns invalid XML
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/xml")
@@ -474,15 +490,15 @@ func TestFetchEsmadridEvents_InvalidXML(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Fetch should return error
-	_, err := FetchEsmadridEvents(server.URL)
-	if err == nil {
-		t.Fatal("Expected error for invalid XML, got nil")
+	// Fetch should return a feed-level error
+	result := newEsmadridTestClient(t).FetchEsmadrid(server.URL)
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected error for invalid XML, got none")
 	}
 }
 
-// TestFetchEsmadridEvents_EmptyResponse tests handling of empty service list
-func TestFetchEsmadridEvents_EmptyResponse(t *testing.T) {
+// TestClient_FetchEsmadrid_EmptyResponse tests handling of empty service list
+func TestClient_FetchEsmadrid_EmptyResponse(t *testing.T) {
 	// Create server that returns empty service list
 	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
 <serviceList>
@@ -496,31 +512,145 @@ func TestFetchEsmadridEvents_EmptyResponse(t *testing.T) {
 	defer server.Close()
 
 	// Fetch should succeed with empty list
-	events, err := FetchEsmadridEvents(server.URL)
-	if err != nil {
-		t.Fatalf("Expected success for empty list, got error: %v", err)
+	result := newEsmadridTestClient(t).FetchEsmadrid(server.URL)
+	if len(result.Errors) > 0 {
+		t.Fatalf("Expected success for empty list, got error: %v", result.Errors[0].Error)
 	}
-	if len(events) != 0 {
-		t.Errorf("Expected 0 events, got %d", len(events))
+	if len(result.Events) != 0 {
+		t.Errorf("Expected 0 events, got %d", len(result.Events))
 	}
 }
 
-// TestFetchEsmadridEvents_Timeout verifies timeout is set
-func TestFetchEsmadridEvents_Timeout(t *testing.T) {
-	// Create server that responds normally
+// TestClient_FetchEsmadrid_PerServiceErrors verifies that one bad service
+// doesn't fail the whole feed.
+func TestClient_FetchEsmadrid_PerServiceErrors(t *testing.T) {
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<serviceList>
+	<service id="1">
+		<basicData><title>Good</title></basicData>
+		<extradata><fechas><rango><inicio>01/11/2025</inicio><fin>02/11/2025</fin></rango></fechas></extradata>
+	</service>
+	<service id="2">
+		<basicData><title>No dates</title></basicData>
+	</service>
+</serviceList>`
+
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(xmlData))
+	}))
+	defer server.Close()
+
+	result := newEsmadridTestClient(t).FetchEsmadrid(server.URL)
+	if len(result.Events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(result.Events))
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 parse error, got %d", len(result.Errors))
+	}
+	parseErr := result.Errors[0]
+	if parseErr.Source != "ESMadrid" {
+		t.Errorf("Source = %q, want %q", parseErr.Source, "ESMadrid")
+	}
+	if parseErr.Index != 1 {
+		t.Errorf("Index = %d, want 1", parseErr.Index)
+	}
+	if parseErr.RawData != "ID=2" {
+		t.Errorf("RawData = %q, want %q", parseErr.RawData, "ID=2")
+	}
+}
+
+// TestClient_FetchEsmadrid_MalformedService verifies that a service with broken
+// markup is skipped with its own error while the rest of the feed decodes.
+func TestClient_FetchEsmadrid_MalformedService(t *testing.T) {
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<serviceList>
+	<service id="1">
+		<basicData><title>Before</title></basicData>
+		<extradata><fechas><rango><inicio>01/11/2025</inicio></rango></fechas></extradata>
+	</service>
+	<service id="2">
+		<basicData><title>Broken</titel></basicData>
+	</service>
+	<service id="3">
+		<basicData><title>After</title></basicData>
+		<extradata><fechas><rango><inicio>03/11/2025</inicio></rango></fechas></extradata>
+	</service>
+</serviceList>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(xmlData))
+	}))
+	defer server.Close()
+
+	result := newEsmadridTestClient(t).FetchEsmadrid(server.URL)
+	if len(result.Events) != 2 || result.Events[0].ID != "1" || result.Events[1].ID != "3" {
+		t.Fatalf("Events = %+v, want services 1 and 3", result.Events)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected 1 parse error, got %d: %+v", len(result.Errors), result.Errors)
+	}
+	if parseErr := result.Errors[0]; parseErr.Index != 1 || parseErr.RawData != "ID=2" {
+		t.Errorf("parse error Index = %d, RawData = %q, want 1 and ID=2", parseErr.Index, parseErr.RawData)
+	}
+}
+
+// TestClient_FetchEsmadrid_UsesCacheAndAudit verifies the feed goes through
+// the client's cache and request audit.
+func TestClient_FetchEsmadrid_UsesCacheAndAudit(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
 		w.Write([]byte(`<?xml version="1.0"?><serviceList></serviceList>`))
 	}))
 	defer server.Close()
 
-	// This test just verifies the function works with a timeout
-	// (testing actual timeout would require waiting 30+ seconds)
-	events, err := FetchEsmadridEvents(server.URL)
-	if err != nil {
-		t.Fatalf("Expected success, got error: %v", err)
+	client := newEsmadridTestClient(t)
+	client.FetchEsmadrid(server.URL)
+	client.FetchEsmadrid(server.URL)
+
+	if requests != 1 {
+		t.Errorf("Expected 1 upstream request (second served from cache), got %d", requests)
+	}
+
+	records := client.Auditor().Records()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d", len(records))
+	}
+	if records[0].CacheHit {
+		t.Error("First request should not be a cache hit")
+	}
+	if !records[1].CacheHit {
+		t.Error("Second request should be a cache hit")
+	}
+}
+
+// TestClient_FetchEsmadrid_FileURL verifies file:// URLs are supported.
+func TestClient_FetchEsmadrid_FileURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agenda.xml")
+	xmlData := `<?xml version="1.0"?><serviceList><service id="9"><basicData><title>Local</title></basicData>` +
+		`<extradata><fechas><rango><inicio>05/11/2025</inicio></rango></fechas></extradata></service></serviceList>`
+	if err := os.WriteFile(path, []byte(xmlData), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	result := newEsmadridTestClient(t).FetchEsmadrid("file://" + path)
+	if len(result.Errors) > 0 {
+		t.Fatalf("FetchEsmadrid failed: %v", result.Errors[0].Error)
+	}
+	if len(result.Events) != 1 || result.Events[0].ID != "9" {
+		t.Errorf("Expected single event with ID 9, got %+v", result.Events)
+	}
+}
+
+// TestClient_FetchEsmadrid_StrictMode verifies PLAZAESPANA_NO_API blocks external requests.
+func TestClient_FetchEsmadrid_StrictMode(t *testing.T) {
+	t.Setenv("PLAZAESPANA_NO_API", "1")
+
+	result := newEsmadridTestClient(t).FetchEsmadrid("https://www.esmadrid.com/opendata/agenda_v1_es.xml")
+	if len(result.Errors) == 0 {
+		t.Fatal("Expected external request to be blocked")
 	}
-	if len(events) != 0 {
-		t.Errorf("Expected 0 events, got %d", len(events))
+	if !strings.Contains(result.Errors[0].Error.Error(), "BLOCKED") {
+		t.Errorf("Expected BLOCKED error, got: %v", result.Errors[0].Error)
 	}
 }
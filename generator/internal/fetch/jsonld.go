@@ -0,0 +1,205 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonLDTerms are the terms JSONEvent's field tags use, with the IRIs the
+// datos.madrid.es @context maps them to. A feed that names a property
+// differently (but maps it to the same IRI) still decodes.
+var jsonLDTerms = map[string]string{
+	"id":                "http://purl.org/dc/terms/identifier",
+	"title":             "http://www.w3.org/2006/vcard/ns#fn",
+	"description":       "http://www.w3.org/2002/12/cal#summary",
+	"relation":          "http://purl.org/dc/terms/relation",
+	"address":           "http://www.w3.org/2006/vcard/ns#adr",
+	"area":              "http://purl.org/ctic/infraestructuras/localizacion#barrio",
+	"district":          "http://purl.org/ctic/infraestructuras/localizacion#distrito",
+	"locality":          "http://www.w3.org/2006/vcard/ns#locality",
+	"postal-code":       "http://www.w3.org/2006/vcard/ns#postal-code",
+	"street-address":    "http://www.w3.org/2006/vcard/ns#street-address",
+	"location":          "http://www.w3.org/2006/vcard/ns#geo",
+	"latitude":          "http://www.w3.org/2003/01/geo/wgs84_pos#lat",
+	"longitude":         "http://www.w3.org/2003/01/geo/wgs84_pos#long",
+	"organization":      "http://www.w3.org/2006/vcard/ns#org",
+	"accesibility":      "http://purl.org/ctic/infraestructuras/organizacion#accesibilidad",
+	"organization-name": "http://www.w3.org/2006/vcard/ns#organization-name",
+	"link":              "http://www.w3.org/2002/12/cal#url",
+	"dtstart":           "http://www.w3.org/2002/12/cal#dtstart",
+	"dtend":             "http://www.w3.org/2002/12/cal#dtend",
+	"time":              "http://www.w3.org/2002/12/cal#time",
+	"excluded-days":     "http://www.w3.org/2002/12/cal#exdate",
+	"event-location":    "http://www.w3.org/2002/12/cal#location",
+	"free":              "https://schema.org/free",
+	"price":             "http://www.w3.org/2002/12/cal#resource",
+	"recurrence":        "http://www.w3.org/2002/12/cal#rrule",
+	"days":              "http://www.w3.org/2002/12/cal#byday",
+	"frequency":         "http://www.w3.org/2002/12/cal#freq",
+	"interval":          "http://www.w3.org/2002/12/cal#interval",
+	"audience":          "dc:audience", // Upstream never defines the "dc" prefix
+}
+
+// termsByIRI inverts jsonLDTerms.
+var termsByIRI = func() map[string]string {
+	m := make(map[string]string, len(jsonLDTerms))
+	for term, iri := range jsonLDTerms {
+		m[iri] = term
+	}
+	return m
+}()
+
+// jsonLDContext maps the terms of a document's @context to the IRIs they
+// stand for, with compact IRIs ("vcard:fn") expanded.
+type jsonLDContext map[string]string
+
+// parseJSONLDContext reads an @context: an object of term definitions
+// ("title": "vcard:fn" or "title": {"@id": "vcard:fn"}), or an array of them.
+// Remote contexts (strings) and keywords other than @vocab are ignored.
+func parseJSONLDContext(raw json.RawMessage) (jsonLDContext, error) {
+	ctx := make(jsonLDContext)
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return ctx, nil
+	}
+
+	var defs []json.RawMessage
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &defs); err != nil {
+			return nil, err
+		}
+	} else {
+		defs = []json.RawMessage{raw}
+	}
+
+	for _, def := range defs {
+		if def = bytes.TrimSpace(def); len(def) == 0 || def[0] != '{' {
+			continue // Remote context
+		}
+		var terms map[string]json.RawMessage
+		if err := json.Unmarshal(def, &terms); err != nil {
+			return nil, err
+		}
+		for term, value := range terms {
+			var iri string
+			if err := json.Unmarshal(value, &iri); err != nil {
+				var expanded struct {
+					ID string `json:"@id"`
+				}
+				if err := json.Unmarshal(value, &expanded); err != nil {
+					return nil, fmt.Errorf("term %q: %w", term, err)
+				}
+				iri = expanded.ID
+			}
+			if iri != "" {
+				ctx[term] = iri
+			}
+		}
+	}
+
+	// Expand compact IRIs once every prefix is known
+	for term, iri := range ctx {
+		ctx[term] = ctx.expandIRI(iri)
+	}
+	return ctx, nil
+}
+
+// expandIRI expands a compact IRI whose prefix the context defines:
+// "vcard:fn" -> "http://www.w3.org/2006/vcard/ns#fn". Anything else is
+// returned unchanged.
+func (ctx jsonLDContext) expandIRI(iri string) string {
+	prefix, suffix, ok := strings.Cut(iri, ":")
+	if !ok || strings.HasPrefix(suffix, "//") {
+		return iri
+	}
+	if base, ok := ctx[prefix]; ok {
+		return base + suffix
+	}
+	return iri
+}
+
+// term returns the JSONEvent term for a property key of the document: the key
+// is expanded through the context (terms, compact IRIs and @vocab) and looked
+// up in jsonLDTerms. Keys the context doesn't map to a known IRI are kept.
+func (ctx jsonLDContext) term(key string) string {
+	if strings.HasPrefix(key, "@") {
+		return key
+	}
+	iri, ok := ctx[key]
+	if !ok {
+		if vocab, hasVocab := ctx["@vocab"]; hasVocab && !strings.Contains(key, ":") {
+			iri = vocab + key
+		} else {
+			iri = ctx.expandIRI(key)
+		}
+	}
+	if term, ok := termsByIRI[iri]; ok {
+		return term
+	}
+	return key
+}
+
+// normalize renames the keys of a decoded node (and of the nodes nested in it)
+// to JSONEvent's terms.
+func (ctx jsonLDContext) normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			out[ctx.term(key)] = ctx.normalize(value)
+		}
+		return out
+	case []any:
+		for i := range v {
+			v[i] = ctx.normalize(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// jsonLDDocument is a JSON-LD document with its @graph nodes left undecoded.
+type jsonLDDocument struct {
+	Context json.RawMessage   `json:"@context"`
+	Graph   []json.RawMessage `json:"@graph"`
+}
+
+// decodeJSONLDEvents decodes the @graph of a datos.madrid.es JSON-LD document,
+// resolving property names through its @context. Nodes fail independently:
+// fn receives each node's index and either its event or its decode error. A
+// document that isn't JSON or has a malformed @context fails as a whole.
+func decodeJSONLDEvents(body []byte, fn func(index int, e JSONEvent, err error)) error {
+	var doc jsonLDDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return err
+	}
+	ctx, err := parseJSONLDContext(doc.Context)
+	if err != nil {
+		return fmt.Errorf("parsing @context: %w", err)
+	}
+
+	for i, raw := range doc.Graph {
+		var e JSONEvent
+		err := decodeJSONLDNode(raw, ctx, &e)
+		fn(i, e, err)
+	}
+	return nil
+}
+
+// decodeJSONLDNode decodes one @graph node into e.
+func decodeJSONLDNode(raw json.RawMessage, ctx jsonLDContext, e *JSONEvent) error {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber() // Keep coordinates exactly as written
+	var node any
+	if err := d.Decode(&node); err != nil {
+		return err
+	}
+	normalized, err := json.Marshal(ctx.normalize(node))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(normalized, e)
+}
@@ -0,0 +1,164 @@
+package fetch
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+func TestFetchJSON_PopulatesEventFromJSONAlone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	client, err := NewClient(5*time.Second, DefaultDevelopmentConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	jsonResult := client.FetchJSON(getFixturePath(t, "madrid-events.json"), loc)
+	if len(jsonResult.Errors) > 0 {
+		t.Errorf("FetchJSON errors = %v", jsonResult.Errors)
+	}
+	byID := make(map[string]event.CulturalEvent)
+	for _, sourced := range jsonResult.Events {
+		byID[sourced.Event.ID] = sourced.Event
+	}
+
+	course, ok := byID["50043503"]
+	if !ok {
+		t.Fatalf("event 50043503 not found")
+	}
+	if course.Distrito != "ARGANZUELA" {
+		t.Errorf("Distrito = %q, want ARGANZUELA", course.Distrito)
+	}
+	if course.Address != "PLAZA LEGAZPI 8" {
+		t.Errorf("Address = %q, want %q", course.Address, "PLAZA LEGAZPI 8")
+	}
+	if course.Latitude != 40.39130985242181 || course.Longitude != -3.6958028442054074 {
+		t.Errorf("coordinates = %v, %v, want 40.39130985242181, -3.6958028442054074", course.Latitude, course.Longitude)
+	}
+	if course.VenueName != "Matadero Madrid" {
+		t.Errorf("VenueName = %q, want Matadero Madrid", course.VenueName)
+	}
+
+	// The JSON should know what the XML knows about an event. Upstream's feeds
+	// occasionally disagree on the venue (or the JSON lacks the barrio node
+	// that holds the street address), so only most venues have to match.
+	xmlResult := client.FetchXML(getFixturePath(t, "madrid-events.xml"), loc)
+	compared, venueMismatches := 0, 0
+	for _, sourced := range xmlResult.Events {
+		want := sourced.Event
+		got, ok := byID[want.ID]
+		if !ok {
+			continue
+		}
+		compared++
+		if want.Distrito != "" && got.Distrito != want.Distrito {
+			t.Errorf("%s: Distrito = %q, XML has %q", want.ID, got.Distrito, want.Distrito)
+		}
+		if !got.StartTime.Equal(want.StartTime) {
+			t.Errorf("%s: StartTime = %v, XML has %v", want.ID, got.StartTime, want.StartTime)
+		}
+		if got.Address != want.Address || got.PostalCode != want.PostalCode ||
+			math.Abs(got.Latitude-want.Latitude) > 1e-6 || math.Abs(got.Longitude-want.Longitude) > 1e-6 {
+			venueMismatches++
+		}
+	}
+	if compared < 500 {
+		t.Errorf("only %d events in both feeds, want most of them", compared)
+	}
+	if venueMismatches > compared/50 {
+		t.Errorf("%d of %d events have a different address, postal code or coordinates than in the XML", venueMismatches, compared)
+	}
+}
+
+func TestDecodeJSONLDEvents_ResolvesContext(t *testing.T) {
+	// Terms renamed, a prefix renamed, an {"@id": ...} definition and an
+	// array-valued @context: the same IRIs as datos.madrid.es's own @context
+	doc := `{
+		"@context": [
+			"https://example.com/remote-context.jsonld",
+			{
+				"ical": "http://www.w3.org/2002/12/cal#",
+				"vcard": "http://www.w3.org/2006/vcard/ns#",
+				"geo": "http://www.w3.org/2003/01/geo/wgs84_pos#",
+				"loc": "http://purl.org/ctic/infraestructuras/localizacion#",
+				"dcterms": "http://purl.org/dc/terms/",
+				"identificador": "dcterms:identifier",
+				"nombre": {"@id": "vcard:fn"},
+				"inicio": "ical:dtstart",
+				"direccion": "vcard:adr",
+				"distrito": "loc:distrito",
+				"coordenadas": "vcard:geo",
+				"lat": "geo:lat",
+				"lon": "geo:long"
+			}
+		],
+		"@graph": [
+			{
+				"identificador": "1",
+				"nombre": "Concierto",
+				"inicio": "2025-10-25 19:00:00.0",
+				"ical:dtend": "2025-10-25 21:00:00.0",
+				"direccion": {"distrito": {"@id": "https://datos.madrid.es/egob/kos/Provincia/Madrid/Municipio/Madrid/Distrito/Moncloa-Aravaca"}},
+				"coordenadas": {"lat": 40.4238, "lon": -3.7122}
+			},
+			{"identificador": "2", "nombre": ["not", "a", "string"]}
+		]
+	}`
+
+	var events []JSONEvent
+	var errs []int
+	err := decodeJSONLDEvents([]byte(doc), func(i int, e JSONEvent, err error) {
+		if err != nil {
+			errs = append(errs, i)
+			return
+		}
+		events = append(events, e)
+	})
+	if err != nil {
+		t.Fatalf("decodeJSONLDEvents() error = %v", err)
+	}
+	if len(events) != 1 || len(errs) != 1 || errs[0] != 1 {
+		t.Fatalf("decoded %d events with errors at %v, want 1 event and an error at node 1", len(events), errs)
+	}
+
+	canonical, err := events[0].ToCanonical(time.UTC)
+	if err != nil {
+		t.Fatalf("ToCanonical() error = %v", err)
+	}
+	if canonical.ID != "1" || canonical.Title != "Concierto" {
+		t.Errorf("ID, Title = %q, %q, want 1, Concierto", canonical.ID, canonical.Title)
+	}
+	if want := time.Date(2025, 10, 25, 21, 0, 0, 0, time.UTC); !canonical.EndTime.Equal(want) {
+		t.Errorf("EndTime = %v, want %v", canonical.EndTime, want)
+	}
+	if canonical.Distrito != "MONCLOA-ARAVACA" {
+		t.Errorf("Distrito = %q, want MONCLOA-ARAVACA", canonical.Distrito)
+	}
+	if canonical.Latitude != 40.4238 || canonical.Longitude != -3.7122 {
+		t.Errorf("coordinates = %v, %v, want 40.4238, -3.7122", canonical.Latitude, canonical.Longitude)
+	}
+}
+
+func TestSlugName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://datos.madrid.es/egob/kos/Provincia/Madrid/Municipio/Madrid/Distrito/Arganzuela", "ARGANZUELA"},
+		{".../Distrito/PuenteDeVallecas", "PUENTE DE VALLECAS"},
+		{".../Distrito/Fuencarral-ElPardo", "FUENCARRAL-EL PARDO"},
+		{".../Distrito/SanBlas-Canillejas", "SAN BLAS-CANILLEJAS"},
+		{".../Barrio/PuertaBonita", "PUERTA BONITA"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := slugName(tt.in); got != tt.want {
+			t.Errorf("slugName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RequestLedger persists upstream request times per URL so that request budgets
+// (ModeConfig.MaxRequestRate per TimeWindow) hold across process runs.
+// RequestThrottle only spaces requests within one run; the ledger is what stops
+// back-to-back builds from hitting the same URL again.
+type RequestLedger struct {
+	path        string
+	maxRequests int
+	window      time.Duration
+	requests    map[string][]time.Time // URL -> request times within the window
+	mu          sync.Mutex
+}
+
+// ledgerFile is the on-disk format of the request ledger.
+type ledgerFile struct {
+	Requests map[string][]time.Time `json:"requests"`
+}
+
+// NewRequestLedger loads (or creates) the ledger at path.
+// A maxRequests or window of zero disables budgeting: every request is allowed.
+func NewRequestLedger(path string, maxRequests int, window time.Duration) (*RequestLedger, error) {
+	l := &RequestLedger{
+		path:        path,
+		maxRequests: maxRequests,
+		window:      window,
+		requests:    make(map[string][]time.Time),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("reading request ledger: %w", err)
+	}
+
+	var file ledgerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing request ledger: %w", err)
+	}
+	if file.Requests != nil {
+		l.requests = file.Requests
+	}
+	return l, nil
+}
+
+// Reserve checks whether a request to url fits in its budget at time now and,
+// if it does, records it and persists the ledger. Checking and recording under
+// one lock keeps concurrent fetches from overrunning the budget together.
+// When the request doesn't fit, nextAllowed is when the oldest request in the
+// window expires. A write error is returned with ok still true: the request
+// was counted in memory.
+func (l *RequestLedger) Reserve(url string, now time.Time) (ok bool, nextAllowed time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recent := l.recent(url, now)
+	if l.maxRequests > 0 && l.window > 0 && len(recent) >= l.maxRequests {
+		return false, recent[len(recent)-l.maxRequests].Add(l.window), nil
+	}
+	l.requests[url] = append(recent, now)
+	return true, time.Time{}, l.save(now)
+}
+
+// Record notes a request to url at time at and persists the ledger, whatever
+// the budget (e.g. retries of a request already reserved).
+func (l *RequestLedger) Record(url string, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests[url] = append(l.recent(url, at), at)
+	return l.save(at)
+}
+
+// recent returns url's request times still inside the window. Caller holds mu.
+func (l *RequestLedger) recent(url string, now time.Time) []time.Time {
+	var kept []time.Time
+	for _, t := range l.requests[url] {
+		if now.Sub(t) < l.window {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// save prunes expired entries and writes the ledger atomically. Caller holds mu.
+func (l *RequestLedger) save(now time.Time) error {
+	for url := range l.requests {
+		if recent := l.recent(url, now); len(recent) > 0 {
+			l.requests[url] = recent
+		} else {
+			delete(l.requests, url)
+		}
+	}
+
+	data, err := json.MarshalIndent(ledgerFile{Requests: l.requests}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling request ledger: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("creating ledger directory: %w", err)
+	}
+
+	// Atomic write: temp file + rename
+	tempPath := l.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("writing request ledger: %w", err)
+	}
+	if err := os.Rename(tempPath, l.path); err != nil {
+		return fmt.Errorf("renaming request ledger: %w", err)
+	}
+	return nil
+}
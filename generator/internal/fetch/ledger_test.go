@@ -0,0 +1,126 @@
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestLedger_Budget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	ledger, err := NewRequestLedger(path, 2, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("NewRequestLedger failed: %v", err)
+	}
+
+	url := "https://example.com/events.json"
+	start := time.Date(2025, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"first request", start, true},
+		{"second request", start.Add(time.Minute), true},
+		{"over budget", start.Add(2 * time.Minute), false},
+		{"oldest expired", start.Add(5 * time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		ok, next, err := ledger.Reserve(url, tt.at)
+		if err != nil {
+			t.Fatalf("%s: Reserve failed: %v", tt.name, err)
+		}
+		if ok != tt.want {
+			t.Fatalf("%s: Reserve = %v, want %v", tt.name, ok, tt.want)
+		}
+		if !ok {
+			if want := start.Add(5 * time.Minute); !next.Equal(want) {
+				t.Errorf("%s: nextAllowed = %v, want %v", tt.name, next, want)
+			}
+		}
+	}
+
+	// Other URLs have their own budget
+	if ok, _, _ := ledger.Reserve("https://example.com/other.json", start.Add(2*time.Minute)); !ok {
+		t.Error("budget for one URL should not affect another")
+	}
+}
+
+func TestRequestLedger_PersistsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	url := "https://example.com/events.json"
+	now := time.Now()
+
+	first, err := NewRequestLedger(path, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRequestLedger failed: %v", err)
+	}
+	if err := first.Record(url, now); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// A new process loads the same ledger
+	second, err := NewRequestLedger(path, 1, time.Hour)
+	if err != nil {
+		t.Fatalf("reloading ledger failed: %v", err)
+	}
+	if ok, _, _ := second.Reserve(url, now.Add(time.Minute)); ok {
+		t.Error("reloaded ledger allowed a request over budget")
+	}
+	if ok, _, _ := second.Reserve(url, now.Add(time.Hour)); !ok {
+		t.Error("reloaded ledger blocked a request after the window")
+	}
+}
+
+func TestRequestLedger_Disabled(t *testing.T) {
+	ledger, err := NewRequestLedger(filepath.Join(t.TempDir(), "ledger.json"), 0, 0)
+	if err != nil {
+		t.Fatalf("NewRequestLedger failed: %v", err)
+	}
+	now := time.Now()
+	ledger.Record("https://example.com", now)
+	if ok, _, _ := ledger.Reserve("https://example.com", now); !ok {
+		t.Error("ledger without a budget should allow every request")
+	}
+}
+
+func TestRequestLedger_ConcurrentReserve(t *testing.T) {
+	ledger, err := NewRequestLedger(filepath.Join(t.TempDir(), "ledger.json"), 3, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRequestLedger failed: %v", err)
+	}
+	url := "https://example.com/events.json"
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _, _ := ledger.Reserve(url, now); ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 3 {
+		t.Errorf("%d concurrent requests reserved, want the budget of 3", got)
+	}
+}
+
+func TestRequestLedger_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRequestLedger(path, 1, time.Hour); err == nil {
+		t.Error("NewRequestLedger succeeded on corrupt file, want error")
+	}
+}
@@ -8,15 +8,25 @@ type ClientMode string
 const (
 	// ProductionMode: Normal operation (hourly cron)
 	// - Respectful delays between requests
-	// - HTTP caching with If-Modified-Since
+	// - HTTP caching with ETag/If-Modified-Since revalidation
 	// - Cache TTL: 30 minutes (cache expires if data < 30 min old)
+	// - Max 1 request per URL per 55 minutes (persisted across runs)
 	ProductionMode ClientMode = "production"
 
 	// DevelopmentMode: Testing/debugging (frequent builds)
 	// - Aggressive local caching (cache TTL: 1 hour)
 	// - WARNING printed to console if making real request
-	// - Max 1 request per URL per 5 minutes
+	// - Max 1 request per URL per 5 minutes (persisted across runs)
 	DevelopmentMode ClientMode = "development"
+
+	// RecordMode: Production behavior, plus every response the build uses
+	// (network, cache, file:// and synthetic) is written to a Bundle
+	RecordMode ClientMode = "record"
+
+	// ReplayMode: Serve exclusively from a recorded Bundle
+	// - No network, cache, throttling or request budget
+	// - Requests that weren't recorded fail
+	ReplayMode ClientMode = "replay"
 )
 
 // ModeConfig holds mode-specific configuration.
@@ -35,7 +45,9 @@ func DefaultProductionConfig() ModeConfig {
 		CacheTTL:       30 * time.Minute,
 		MinDelay:       2 * time.Second,
 		MaxRequestRate: 1, // 1 request per time window
-		TimeWindow:     1 * time.Hour,
+		// Slightly under the hourly cron interval so scheduling jitter
+		// doesn't make every other run fall back to the cache.
+		TimeWindow: 55 * time.Minute,
 	}
 }
 
@@ -50,6 +62,23 @@ func DefaultDevelopmentConfig() ModeConfig {
 	}
 }
 
+// DefaultRecordConfig returns record mode configuration.
+// Recording a build should fetch exactly like production does.
+func DefaultRecordConfig() ModeConfig {
+	config := DefaultProductionConfig()
+	config.Mode = RecordMode
+	return config
+}
+
+// DefaultReplayConfig returns replay mode configuration.
+// Nothing touches the network, so no delays or budgets apply.
+func DefaultReplayConfig() ModeConfig {
+	return ModeConfig{
+		Mode:     ReplayMode,
+		CacheTTL: DefaultProductionConfig().CacheTTL,
+	}
+}
+
 // ParseMode converts a string to ClientMode.
 func ParseMode(s string) ClientMode {
 	switch s {
@@ -57,6 +86,10 @@ func ParseMode(s string) ClientMode {
 		return ProductionMode
 	case "development", "dev":
 		return DevelopmentMode
+	case "record":
+		return RecordMode
+	case "replay":
+		return ReplayMode
 	default:
 		return ProductionMode // Safe default
 	}
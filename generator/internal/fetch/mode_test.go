@@ -20,8 +20,8 @@ func TestDefaultProductionConfig(t *testing.T) {
 	if config.MaxRequestRate != 1 {
 		t.Errorf("MaxRequestRate = %d, want 1", config.MaxRequestRate)
 	}
-	if config.TimeWindow != 1*time.Hour {
-		t.Errorf("TimeWindow = %v, want 1h", config.TimeWindow)
+	if config.TimeWindow != 55*time.Minute {
+		t.Errorf("TimeWindow = %v, want 55m", config.TimeWindow)
 	}
 }
 
@@ -54,6 +54,8 @@ func TestParseMode(t *testing.T) {
 		{"prod", ProductionMode},
 		{"development", DevelopmentMode},
 		{"dev", DevelopmentMode},
+		{"record", RecordMode},
+		{"replay", ReplayMode},
 		{"invalid", ProductionMode}, // Safe default
 		{"", ProductionMode},        // Safe default
 	}
@@ -0,0 +1,97 @@
+package fetch
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+// spanishWeekdays maps DIAS-SEMANA letters (CSV and XML) to weekdays.
+var spanishWeekdays = map[string]time.Weekday{
+	"L": time.Monday,
+	"M": time.Tuesday,
+	"X": time.Wednesday,
+	"J": time.Thursday,
+	"V": time.Friday,
+	"S": time.Saturday,
+	"D": time.Sunday,
+}
+
+// icalWeekdays maps JSON-LD rrule BYDAY codes to weekdays.
+var icalWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// jsonRecurrence is the rrule of a JSON-LD event.
+type jsonRecurrence struct {
+	Days      string      `json:"days"`      // BYDAY codes, e.g. "MO,WE"
+	Frequency string      `json:"frequency"` // e.g. "WEEKLY"
+	Interval  json.Number `json:"interval"`
+}
+
+// toRecurrence converts the rrule, with the event's excluded-days list.
+// Returns nil for an rrule without a frequency.
+func (r *jsonRecurrence) toRecurrence(excludedDays string, loc *time.Location) *event.Recurrence {
+	if r == nil || r.Frequency == "" {
+		return nil
+	}
+	interval, _ := strconv.Atoi(r.Interval.String())
+	return &event.Recurrence{
+		Frequency: strings.ToUpper(r.Frequency),
+		Interval:  interval,
+		Weekdays:  parseWeekdays(r.Days, icalWeekdays),
+		Excluded:  parseExcludedDays(excludedDays, loc),
+	}
+}
+
+// parseDiasSemana builds the weekly recurrence of a CSV or XML event from
+// DIAS-SEMANA ("L,X") and DIAS-EXCLUIDOS ("6/10/2025;10/10/2025;").
+// Returns nil if the event has no DIAS-SEMANA (it doesn't recur).
+func parseDiasSemana(diasSemana, diasExcluidos string, loc *time.Location) *event.Recurrence {
+	weekdays := parseWeekdays(diasSemana, spanishWeekdays)
+	if len(weekdays) == 0 {
+		return nil
+	}
+	return &event.Recurrence{
+		Frequency: "WEEKLY",
+		Interval:  1,
+		Weekdays:  weekdays,
+		Excluded:  parseExcludedDays(diasExcluidos, loc),
+	}
+}
+
+// parseWeekdays parses a comma-separated list of day codes. Unknown codes are skipped.
+func parseWeekdays(s string, codes map[string]time.Weekday) []time.Weekday {
+	var weekdays []time.Weekday
+	for _, code := range strings.Split(s, ",") {
+		if wd, ok := codes[strings.ToUpper(strings.TrimSpace(code))]; ok {
+			weekdays = append(weekdays, wd)
+		}
+	}
+	return weekdays
+}
+
+// parseExcludedDays parses a ";"-separated list of d/m/yyyy dates.
+// Unparseable entries are skipped.
+func parseExcludedDays(s string, loc *time.Location) []time.Time {
+	var days []time.Time
+	for _, field := range strings.Split(s, ";") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if d, err := time.ParseInLocation("2/1/2006", field, loc); err == nil {
+			days = append(days, d)
+		}
+	}
+	return days
+}
@@ -0,0 +1,103 @@
+package fetch
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+func TestFetch_Recurrence(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	client, err := NewClient(5*time.Second, DefaultDevelopmentConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	results := map[string]event.ParseResult{
+		"JSON": client.FetchJSON(getFixturePath(t, "madrid-events.json"), loc),
+		"XML":  client.FetchXML(getFixturePath(t, "madrid-events.xml"), loc),
+		"CSV":  client.FetchCSV(getFixturePath(t, "madrid-events.csv"), loc),
+	}
+
+	// Thursdays 18:00 from 2 Oct to 13 Nov 2025, except 16, 23 and 30 Oct
+	wantSessions := []time.Time{
+		time.Date(2025, 10, 2, 18, 0, 0, 0, loc),
+		time.Date(2025, 10, 9, 18, 0, 0, 0, loc),
+		time.Date(2025, 11, 6, 18, 0, 0, 0, loc),
+		time.Date(2025, 11, 13, 18, 0, 0, 0, loc),
+	}
+
+	for source, result := range results {
+		byID := make(map[string]event.CulturalEvent)
+		for _, sourced := range result.Events {
+			byID[sourced.Event.ID] = sourced.Event
+		}
+
+		workshop, ok := byID["50093233"]
+		if !ok {
+			t.Fatalf("%s: event 50093233 not found", source)
+		}
+		r := workshop.Recurrence
+		if r == nil {
+			t.Fatalf("%s: 50093233 Recurrence = nil", source)
+		}
+		if r.Frequency != "WEEKLY" || !reflect.DeepEqual(r.Weekdays, []time.Weekday{time.Thursday}) {
+			t.Errorf("%s: 50093233 Recurrence = %s on %v, want WEEKLY on [Thursday]", source, r.Frequency, r.Weekdays)
+		}
+
+		var got []time.Time
+		for _, occ := range workshop.Occurrences(time.Date(2025, 10, 1, 0, 0, 0, 0, loc), time.Date(2025, 12, 1, 0, 0, 0, 0, loc)) {
+			got = append(got, occ.Start)
+		}
+		if len(got) != len(wantSessions) {
+			t.Fatalf("%s: 50093233 sessions = %v, want %v", source, got, wantSessions)
+		}
+		for i := range got {
+			if !got[i].Equal(wantSessions[i]) {
+				t.Errorf("%s: 50093233 session %d = %v, want %v", source, i, got[i], wantSessions[i])
+			}
+		}
+
+		// No DIAS-SEMANA means no recurrence
+		if film := byID["50066046"]; film.Recurrence != nil {
+			t.Errorf("%s: 50066046 Recurrence = %+v, want nil", source, film.Recurrence)
+		}
+	}
+}
+
+func TestParseDiasSemana(t *testing.T) {
+	tests := []struct {
+		diasSemana, diasExcluidos string
+		wantDays                  []time.Weekday
+		wantExcluded              []time.Time
+	}{
+		{"", "", nil, nil},
+		{"L,X", "", []time.Weekday{time.Monday, time.Wednesday}, nil},
+		{"j", "23/9/2025;30/9/2025;", []time.Weekday{time.Thursday},
+			[]time.Time{time.Date(2025, 9, 23, 0, 0, 0, 0, time.UTC), time.Date(2025, 9, 30, 0, 0, 0, 0, time.UTC)}},
+		{"V,?", "junk;1/10/2025", []time.Weekday{time.Friday}, []time.Time{time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tt := range tests {
+		r := parseDiasSemana(tt.diasSemana, tt.diasExcluidos, time.UTC)
+		if tt.wantDays == nil {
+			if r != nil {
+				t.Errorf("parseDiasSemana(%q, %q) = %+v, want nil", tt.diasSemana, tt.diasExcluidos, r)
+			}
+			continue
+		}
+		if r == nil {
+			t.Fatalf("parseDiasSemana(%q, %q) = nil", tt.diasSemana, tt.diasExcluidos)
+		}
+		if !reflect.DeepEqual(r.Weekdays, tt.wantDays) {
+			t.Errorf("parseDiasSemana(%q) Weekdays = %v, want %v", tt.diasSemana, r.Weekdays, tt.wantDays)
+		}
+		if !reflect.DeepEqual(r.Excluded, tt.wantExcluded) {
+			t.Errorf("parseDiasSemana(%q) Excluded = %v, want %v", tt.diasExcluidos, r.Excluded, tt.wantExcluded)
+		}
+	}
+}
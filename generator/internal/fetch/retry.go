@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests to a host are retried.
+// Network errors and 429/500/502/503/504 responses are retried; anything else
+// (including 403, which AEMET uses for quota errors) fails immediately.
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first (1 = no retries)
+	BaseDelay   time.Duration // Backoff before the second attempt; doubles each retry
+	MaxDelay    time.Duration // Cap on backoff; a longer Retry-After gives up instead
+	Jitter      float64       // Fraction of each backoff that is randomized (0-1)
+}
+
+// NoRetry is the policy a new Client starts with: every request is tried once.
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the delay before the next attempt, after failed attempts so far.
+// Jitter shortens the delay by up to Jitter*delay so concurrent builds spread out.
+func (p RetryPolicy) backoff(failed int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < failed && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
+
+// retryableStatus reports whether an HTTP status is worth retrying.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header (delay in seconds or an HTTP date).
+// Returns 0 if the header is absent, malformed or already in the past.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// HostOf returns the hostname (without port) of rawURL, or "" if it doesn't parse.
+func HostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
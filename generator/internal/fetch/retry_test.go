@@ -0,0 +1,60 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	tests := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second}, // Capped at MaxDelay
+		{10, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.backoff(tt.failed); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failed, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_BackoffJitter(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(2)
+		if got < time.Second || got > 2*time.Second {
+			t.Fatalf("backoff(2) with 50%% jitter = %v, want within [1s, 2s]", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"soon", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 )
 
 // getFixturePath returns the absolute file:// URL for a fixture file
-func getFixturePath(t *testing.T, filename string) string {
+func getFixturePath(t testing.TB, filename string) string {
 	// Try multiple possible paths since go test working directory can vary
 	possiblePaths := []string{
 		filepath.Join("testdata", "fixtures", filename),             // From project root
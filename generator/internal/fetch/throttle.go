@@ -7,9 +7,12 @@ import (
 )
 
 // RequestThrottle enforces minimum delays between requests to the same host.
+// Requests to one host are serialized (see Acquire); different hosts never
+// wait on each other, so callers can fetch several hosts concurrently.
 type RequestThrottle struct {
 	minDelay time.Duration
 	lastReq  map[string]time.Time
+	hosts    map[string]*sync.Mutex // Per-host slot, held for the whole request
 	mu       sync.Mutex
 }
 
@@ -18,37 +21,63 @@ func NewRequestThrottle(minDelay time.Duration) *RequestThrottle {
 	return &RequestThrottle{
 		minDelay: minDelay,
 		lastReq:  make(map[string]time.Time),
+		hosts:    make(map[string]*sync.Mutex),
 	}
 }
 
 // Wait blocks until enough time has passed since the last request to this host.
 // Returns the actual delay waited.
 func (r *RequestThrottle) Wait(targetURL string) (time.Duration, error) {
-	u, err := url.Parse(targetURL)
+	delay, release, err := r.Acquire(targetURL)
 	if err != nil {
 		return 0, err
 	}
+	release()
+	return delay, nil
+}
+
+// Acquire takes the host's request slot, waiting for any in-flight request to
+// the host to release it and then for the minimum delay since the last request.
+// The caller makes its request and then calls release. Returns the delay waited
+// for spacing (not the time spent queued behind other requests).
+func (r *RequestThrottle) Acquire(targetURL string) (delay time.Duration, release func(), err error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return 0, nil, err
+	}
 
 	host := u.Host
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	slot := r.slot(host)
+	slot.Lock()
 
+	r.mu.Lock()
 	lastReq, exists := r.lastReq[host]
-	if !exists {
-		// First request to this host
-		r.lastReq[host] = time.Now()
-		return 0, nil
-	}
+	r.mu.Unlock()
 
-	elapsed := time.Since(lastReq)
-	if elapsed < r.minDelay {
-		waitTime := r.minDelay - elapsed
-		time.Sleep(waitTime)
-		r.lastReq[host] = time.Now()
-		return waitTime, nil
+	if exists {
+		if elapsed := time.Since(lastReq); elapsed < r.minDelay {
+			delay = r.minDelay - elapsed
+			time.Sleep(delay)
+		}
 	}
 
+	r.mu.Lock()
 	r.lastReq[host] = time.Now()
-	return 0, nil
+	r.mu.Unlock()
+
+	return delay, slot.Unlock, nil
+}
+
+// slot returns host's request slot, creating it on first use.
+func (r *RequestThrottle) slot(host string) *sync.Mutex {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slot, ok := r.hosts[host]
+	if !ok {
+		slot = &sync.Mutex{}
+		r.hosts[host] = slot
+	}
+	return slot
 }
@@ -113,3 +113,55 @@ func TestRequestThrottle_InvalidURL(t *testing.T) {
 		t.Error("Expected error for invalid URL, got nil")
 	}
 }
+
+func TestRequestThrottle_SerializesSameHost(t *testing.T) {
+	throttle := NewRequestThrottle(0)
+
+	_, release, err := throttle.Acquire("https://example.com/a")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	// A second request to the host must wait for the first to release its slot
+	acquired := make(chan struct{})
+	go func() {
+		_, release2, err := throttle.Acquire("https://example.com/b")
+		if err == nil {
+			release2()
+		}
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second request to example.com acquired while the first was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second request to example.com never acquired after release")
+	}
+}
+
+func TestRequestThrottle_HostsDoNotBlockEachOther(t *testing.T) {
+	throttle := NewRequestThrottle(200 * time.Millisecond)
+
+	if _, err := throttle.Wait("https://example.com/api"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+
+	// example.com now sleeps ~200ms; different.com must not queue behind it
+	go throttle.Wait("https://example.com/api")
+	time.Sleep(10 * time.Millisecond)
+
+	start := time.Now()
+	if _, err := throttle.Wait("https://different.com/api"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("different.com waited %v behind example.com, want no wait", elapsed)
+	}
+}
@@ -3,7 +3,9 @@ package fetch
 import (
 	"encoding/xml"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/validate"
@@ -26,17 +28,100 @@ type RawEvent struct {
 }
 
 // JSONEvent represents Madrid's JSON-LD event structure.
-// Uses JSON-LD field names as mapped in @context.
+// Uses JSON-LD field names as mapped in @context (see jsonLDTerms); feeds are
+// decoded through decodeJSONLDEvents, which resolves their own @context.
 type JSONEvent struct {
-	ID          string  `json:"id"`
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	StartTime   string  `json:"dtstart"`
-	EndTime     string  `json:"dtend"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	Location    string  `json:"event-location"`
-	Link        string  `json:"link"`
+	ID           string           `json:"id"`
+	Type         string           `json:"@type"` // Activity type URI (.../actividades/TeatroPerformance)
+	Title        string           `json:"title"`
+	Description  string           `json:"description"`
+	StartTime    string           `json:"dtstart"`
+	EndTime      string           `json:"dtend"`
+	Time         string           `json:"time"`      // "19:00"; usually already part of dtstart
+	Geo          jsonGeo          `json:"location"`  // Coordinates as published: nested under "location"
+	Latitude     float64          `json:"latitude"`  // Flat form, used when Geo is empty
+	Longitude    float64          `json:"longitude"` // Flat form, used when Geo is empty
+	Location     string           `json:"event-location"`
+	Link         string           `json:"link"`
+	Free         jsonFlag         `json:"free"`
+	Price        string           `json:"price"`
+	Audience     string           `json:"audience"` // Comma-separated (e.g. "Niños,Familias")
+	Relation     jsonRef          `json:"relation"` // The venue's entity URI (.../4703422-centro-cultural-....json)
+	Address      jsonAddress      `json:"address"`
+	Organization jsonOrganization `json:"organization"`
+	Recurrence   *jsonRecurrence  `json:"recurrence"`
+	ExcludedDays string           `json:"excluded-days"` // "6/10/2025;10/10/2025;"
+}
+
+// jsonRef is a JSON-LD node reference ({"@id": "..."}).
+type jsonRef struct {
+	ID string `json:"@id"`
+}
+
+// jsonGeo is the "location" node of a JSON-LD event.
+type jsonGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// jsonAddress is the venue address of a JSON-LD event. The district and barrio
+// are only given as URI slugs (.../Distrito/Arganzuela/Barrio/PuertaBonita).
+// Upstream nests the street address in the barrio node; the vCard placement
+// directly on the address is accepted too.
+type jsonAddress struct {
+	District jsonRef `json:"district"`
+	Area     struct {
+		ID string `json:"@id"`
+		jsonStreetAddress
+	} `json:"area"`
+	jsonStreetAddress
+}
+
+// jsonStreetAddress holds the vCard street address properties.
+type jsonStreetAddress struct {
+	Locality      string `json:"locality"`
+	PostalCode    string `json:"postal-code"`
+	StreetAddress string `json:"street-address"`
+}
+
+// street returns the address's street address properties, preferring the
+// barrio node's.
+func (a jsonAddress) street() jsonStreetAddress {
+	s := a.Area.jsonStreetAddress
+	if s.StreetAddress == "" {
+		s.StreetAddress = a.StreetAddress
+	}
+	if s.PostalCode == "" {
+		s.PostalCode = a.PostalCode
+	}
+	if s.Locality == "" {
+		s.Locality = a.Locality
+	}
+	return s
+}
+
+// slugName turns a district or barrio URI slug into the upper-case spelling
+// the XML and CSV feeds use: "Fuencarral-ElPardo" -> "FUENCARRAL-EL PARDO".
+// (Accents are lost in the slug: "Chamberi" for CHAMBERÍ, so distrito rules
+// compare without accents; see filter.RuleDistrito.)
+func slugName(uri string) string {
+	return strings.ToUpper(splitCamelCase(lastSegment(uri)))
+}
+
+// jsonOrganization describes the venue of a JSON-LD event.
+type jsonOrganization struct {
+	Name          string `json:"organization-name"`
+	Accessibility string `json:"accesibility"` // Comma-separated codes (upstream spelling)
+}
+
+// jsonFlag is a JSON-LD boolean that upstream sends as 1/0, "1"/"0" or true/false.
+// Anything unrecognized decodes as false rather than failing the whole feed.
+type jsonFlag bool
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (f *jsonFlag) UnmarshalJSON(data []byte) error {
+	*f = jsonFlag(parseFlag(strings.Trim(string(data), `"`)))
+	return nil
 }
 
 // JSONResponse wraps the Madrid API JSON-LD structure.
@@ -80,6 +165,13 @@ func (e JSONEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error)
 		return event.CulturalEvent{}, fmt.Errorf("parsing start time: %w", err)
 	}
 
+	// A date-only dtstart takes its time from "time", as XML's FECHA takes HORA
+	if startTime.Hour() == 0 && startTime.Minute() == 0 && e.Time != "" {
+		if t, err := time.Parse("15:04", strings.TrimSpace(e.Time)); err == nil {
+			startTime = time.Date(startTime.Year(), startTime.Month(), startTime.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		}
+	}
+
 	// Parse end time (format: "2025-10-25 23:59:00.0")
 	endTime, err := parseJSONTime(e.EndTime, loc)
 	if err != nil {
@@ -87,17 +179,34 @@ func (e JSONEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error)
 		endTime = time.Time{}
 	}
 
+	lat, lon := e.Geo.Latitude, e.Geo.Longitude
+	if lat == 0 && lon == 0 {
+		lat, lon = e.Latitude, e.Longitude
+	}
+	street := e.Address.street()
+
 	canonical := event.CulturalEvent{
-		ID:          e.ID,
-		Title:       e.Title,
-		Description: e.Description,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Latitude:    e.Latitude,
-		Longitude:   e.Longitude,
-		VenueName:   e.Location,
-		DetailsURL:  e.Link,
-		Sources:     []string{"JSON"},
+		ID:            e.ID,
+		Title:         e.Title,
+		Description:   e.Description,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Recurrence:    e.Recurrence.toRecurrence(e.ExcludedDays, loc),
+		Latitude:      lat,
+		Longitude:     lon,
+		VenueName:     e.Location,
+		Address:       street.StreetAddress,
+		Distrito:      slugName(e.Address.District.ID),
+		Barrio:        slugName(e.Address.Area.ID),
+		PostalCode:    street.PostalCode,
+		VenueID:       jsonVenueID(e.Relation.ID),
+		DetailsURL:    e.Link,
+		Price:         e.Price,
+		Free:          bool(e.Free),
+		Audience:      splitList(e.Audience),
+		Type:          lastSegment(e.Type),
+		Accessibility: splitList(e.Organization.Accessibility),
+		Sources:       []string{"JSON"},
 	}
 
 	// Sanitize and validate
@@ -112,18 +221,30 @@ func (e JSONEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error)
 // XMLEvent represents Madrid's XML event structure.
 // XML uses different field names than CSV (e.g., FECHA-EVENTO vs FECHA).
 type XMLEvent struct {
-	IDEvento    string
-	Titulo      string
-	Descripcion string
-	Fecha       string
-	FechaFin    string
-	Hora        string
-	Latitud     float64
-	Longitud    float64
-	Instalacion string
-	Direccion   string
-	Distrito    string
-	ContentURL  string
+	IDEvento      string
+	Titulo        string
+	Descripcion   string
+	Fecha         string
+	FechaFin      string
+	Hora          string
+	Latitud       float64
+	Longitud      float64
+	CoordenadaX   float64 // UTM (see event.CulturalEvent.UTMX)
+	CoordenadaY   float64
+	Instalacion   string
+	Direccion     string
+	Distrito      string
+	Barrio        string
+	CodigoPostal  string
+	IDInstalacion string
+	Accesibilidad string
+	ContentURL    string
+	Precio        string
+	Gratuito      string
+	Audiencia     string
+	Tipo          string
+	DiasSemana    string
+	DiasExcluidos string
 }
 
 // xmlAtributo represents a single attribute in Madrid's XML structure.
@@ -162,7 +283,17 @@ func (e *XMLEvent) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	e.Instalacion = attrs["NOMBRE-INSTALACION"]
 	e.Direccion = attrs["DIRECCION-INSTALACION"]
 	e.Distrito = attrs["DISTRITO"]
+	e.Barrio = attrs["BARRIO"]
+	e.CodigoPostal = attrs["CODIGO-POSTAL"]
+	e.IDInstalacion = attrs["ID-INSTALACION"]
+	e.Accesibilidad = attrs["ACCESIBILIDAD"]
 	e.ContentURL = attrs["CONTENT-URL"]
+	e.Precio = attrs["PRECIO"]
+	e.Gratuito = attrs["GRATUITO"]
+	e.Audiencia = attrs["AUDIENCIA"]
+	e.Tipo = attrs["TIPO"]
+	e.DiasSemana = attrs["DIAS-SEMANA"]
+	e.DiasExcluidos = attrs["DIAS-EXCLUIDOS-TEXT"]
 
 	// Parse coordinates
 	if latStr := attrs["LATITUD"]; latStr != "" {
@@ -171,6 +302,12 @@ func (e *XMLEvent) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	if lonStr := attrs["LONGITUD"]; lonStr != "" {
 		fmt.Sscanf(lonStr, "%f", &e.Longitud)
 	}
+	if xStr := attrs["COORDENADA-X"]; xStr != "" {
+		fmt.Sscanf(xStr, "%f", &e.CoordenadaX)
+	}
+	if yStr := attrs["COORDENADA-Y"]; yStr != "" {
+		fmt.Sscanf(yStr, "%f", &e.CoordenadaY)
+	}
 
 	return nil
 }
@@ -212,18 +349,29 @@ func (e XMLEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error) {
 	}
 
 	canonical := event.CulturalEvent{
-		ID:          e.IDEvento,
-		Title:       e.Titulo,
-		Description: e.Descripcion,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Latitude:    e.Latitud,
-		Longitude:   e.Longitud,
-		VenueName:   e.Instalacion,
-		Address:     e.Direccion,
-		Distrito:    e.Distrito,
-		DetailsURL:  e.ContentURL,
-		Sources:     []string{"XML"},
+		ID:            e.IDEvento,
+		Title:         e.Titulo,
+		Description:   e.Descripcion,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Recurrence:    parseDiasSemana(e.DiasSemana, e.DiasExcluidos, loc),
+		Latitude:      e.Latitud,
+		Longitude:     e.Longitud,
+		UTMX:          e.CoordenadaX,
+		UTMY:          e.CoordenadaY,
+		VenueName:     e.Instalacion,
+		Address:       e.Direccion,
+		Distrito:      e.Distrito,
+		Barrio:        e.Barrio,
+		PostalCode:    e.CodigoPostal,
+		VenueID:       e.IDInstalacion,
+		DetailsURL:    e.ContentURL,
+		Price:         e.Precio,
+		Free:          parseFlag(e.Gratuito),
+		Audience:      splitList(e.Audiencia),
+		Type:          lastSegment(e.Tipo),
+		Accessibility: splitList(e.Accesibilidad),
+		Sources:       []string{"XML"},
 	}
 
 	// Sanitize and validate
@@ -287,10 +435,22 @@ type CSVEvent struct {
 	Hora              string
 	Latitud           float64
 	Longitud          float64
+	CoordenadaX       float64 // UTM (see event.CulturalEvent.UTMX)
+	CoordenadaY       float64
 	NombreInstalacion string
 	Direccion         string
 	Distrito          string
+	Barrio            string
+	CodigoPostal      string
+	IDInstalacion     string
+	Accesibilidad     string
 	ContentURL        string
+	Precio            string
+	Gratuito          string
+	Audiencia         string
+	Tipo              string
+	DiasSemana        string
+	DiasExcluidos     string
 }
 
 // ToCanonical converts CSVEvent to CulturalEvent.
@@ -310,18 +470,29 @@ func (e CSVEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error) {
 	}
 
 	canonical := event.CulturalEvent{
-		ID:          e.IDEvento,
-		Title:       e.Titulo,
-		Description: e.Descripcion,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Latitude:    e.Latitud,
-		Longitude:   e.Longitud,
-		VenueName:   e.NombreInstalacion,
-		Address:     e.Direccion,
-		Distrito:    e.Distrito,
-		DetailsURL:  e.ContentURL,
-		Sources:     []string{"CSV"},
+		ID:            e.IDEvento,
+		Title:         e.Titulo,
+		Description:   e.Descripcion,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		Recurrence:    parseDiasSemana(e.DiasSemana, e.DiasExcluidos, loc),
+		Latitude:      e.Latitud,
+		Longitude:     e.Longitud,
+		UTMX:          e.CoordenadaX,
+		UTMY:          e.CoordenadaY,
+		VenueName:     e.NombreInstalacion,
+		Address:       e.Direccion,
+		Distrito:      e.Distrito,
+		Barrio:        e.Barrio,
+		PostalCode:    e.CodigoPostal,
+		VenueID:       e.IDInstalacion,
+		DetailsURL:    e.ContentURL,
+		Price:         e.Precio,
+		Free:          parseFlag(e.Gratuito),
+		Audience:      splitList(e.Audiencia),
+		Type:          lastSegment(e.Tipo),
+		Accessibility: splitList(e.Accesibilidad),
+		Sources:       []string{"CSV"},
 	}
 
 	// Sanitize and validate
@@ -332,3 +503,62 @@ func (e CSVEvent) ToCanonical(loc *time.Location) (event.CulturalEvent, error) {
 
 	return canonical, nil
 }
+
+// parseFlag parses upstream boolean fields such as GRATUITO ("1"/"0").
+func parseFlag(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "s", "si", "sí":
+		return true
+	}
+	return false
+}
+
+// lastSegment returns the part of a taxonomy path or URI after its last slash:
+// "/contenido/actividades/TeatroPerformance" -> "TeatroPerformance".
+func lastSegment(s string) string {
+	s = strings.TrimSpace(s)
+	return s[strings.LastIndex(s, "/")+1:]
+}
+
+// splitList splits a comma-separated upstream list, reducing taxonomy paths to
+// their last segment: "/usuario/Niños,/usuario/Familias" -> [Niños Familias].
+// Returns nil for an empty list.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = lastSegment(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitCamelCase spaces out a CamelCase URI slug: "PuertaBonita" -> "Puerta Bonita",
+// "SanBlas-Canillejas" -> "San Blas-Canillejas".
+func splitCamelCase(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// jsonVenueID extracts ID-INSTALACION from a JSON-LD venue URI:
+// ".../entidadesyorganismos/4703422-centro-cultural-....json" -> "4703422".
+func jsonVenueID(uri string) string {
+	slug := lastSegment(uri)
+	if i := strings.IndexByte(slug, '-'); i > 0 {
+		slug = slug[:i]
+	}
+	for _, r := range slug {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+	return slug
+}
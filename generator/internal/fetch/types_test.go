@@ -2,7 +2,11 @@ package fetch
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
 )
 
 func TestEvent_UnmarshalJSON(t *testing.T) {
@@ -73,3 +77,126 @@ func TestRawEvent_Fields(t *testing.T) {
 		t.Errorf("NombreInstalacion mismatch")
 	}
 }
+
+func TestFetch_AttendanceAndVenueFields(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	client, err := NewClient(5*time.Second, DefaultDevelopmentConfig(), t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+
+	results := map[string]event.ParseResult{
+		"JSON": client.FetchJSON(getFixturePath(t, "madrid-events.json"), loc),
+		"XML":  client.FetchXML(getFixturePath(t, "madrid-events.xml"), loc),
+		"CSV":  client.FetchCSV(getFixturePath(t, "madrid-events.csv"), loc),
+	}
+
+	for source, result := range results {
+		byID := make(map[string]event.CulturalEvent)
+		for _, sourced := range result.Events {
+			byID[sourced.Event.ID] = sourced.Event
+		}
+
+		// Free film screening
+		film, ok := byID["50066046"]
+		if !ok {
+			t.Fatalf("%s: event 50066046 not found", source)
+		}
+		if !film.Free {
+			t.Errorf("%s: 50066046 Free = false, want true", source)
+		}
+		if film.Type != "CineActividadesAudiovisuales" {
+			t.Errorf("%s: 50066046 Type = %q, want %q", source, film.Type, "CineActividadesAudiovisuales")
+		}
+
+		// Paid course for older people
+		course, ok := byID["50043503"]
+		if !ok {
+			t.Fatalf("%s: event 50043503 not found", source)
+		}
+		if course.Free {
+			t.Errorf("%s: 50043503 Free = true, want false", source)
+		}
+		if course.Price != "Actividad gratuita con inscripción previa" {
+			t.Errorf("%s: 50043503 Price = %q", source, course.Price)
+		}
+		if !reflect.DeepEqual(course.Audience, []string{"Mayores"}) {
+			t.Errorf("%s: 50043503 Audience = %q, want [Mayores]", source, course.Audience)
+		}
+		if course.Type != "ProgramacionDestacadaAgendaCultura" {
+			t.Errorf("%s: 50043503 Type = %q", source, course.Type)
+		}
+		if !reflect.DeepEqual(course.Accessibility, []string{"1"}) {
+			t.Errorf("%s: 50043503 Accessibility = %q, want [1]", source, course.Accessibility)
+		}
+		if course.PostalCode != "28045" {
+			t.Errorf("%s: 50043503 PostalCode = %q, want 28045", source, course.PostalCode)
+		}
+
+		// Venue IDs differ by format (the CSV has no ID-INSTALACION)
+		wantVenue := "4684678"
+		if source == "CSV" {
+			wantVenue = ""
+		}
+		if course.Barrio != "CHOPERA" {
+			t.Errorf("%s: 50043503 Barrio = %q, want CHOPERA", source, course.Barrio)
+		}
+		if course.VenueID != wantVenue {
+			t.Errorf("%s: 50043503 VenueID = %q, want %q", source, course.VenueID, wantVenue)
+		}
+
+		// COORDENADA-X/Y (UTM) are only in the XML and CSV
+		wantX, wantY := 440946.0, 4471422.0
+		if source == "JSON" {
+			wantX, wantY = 0, 0
+		}
+		if course.UTMX != wantX || course.UTMY != wantY {
+			t.Errorf("%s: 50043503 UTM = %v, %v, want %v, %v", source, course.UTMX, course.UTMY, wantX, wantY)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"Niños,Familias", []string{"Niños", "Familias"}},
+		{"/usuario/Niños,/usuario/Familias", []string{"Niños", "Familias"}},
+		{"1, 6", []string{"1", "6"}},
+		{"/usuario/Jovenes,", []string{"Jovenes"}},
+	}
+	for _, tt := range tests {
+		if got := splitList(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitList(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSONFlag(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{`1`, true},
+		{`0`, false},
+		{`"1"`, true},
+		{`true`, true},
+		{`false`, false},
+		{`null`, false},
+	}
+	for _, tt := range tests {
+		var f jsonFlag
+		if err := json.Unmarshal([]byte(tt.in), &f); err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.in, err)
+			continue
+		}
+		if bool(f) != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, f, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// xmlElementFunc handles one repeated element of a streamed XML document.
+// index counts elements in document order (including ones that fail), start is
+// the element's start tag and decode unmarshals the element into v (call it at
+// most once). decode fails if the element is malformed; the stream carries on
+// with the next one.
+type xmlElementFunc func(index int, start xml.StartElement, decode func(v any) error)
+
+// decodeXMLElements streams the children named element of the root element
+// root, calling fn for each as it is read, so no document tree or slice of
+// every element is ever built. (The body itself is still in memory: it is
+// cached and recorded as bytes.)
+//
+// Elements fail independently: if one is malformed (bad markup as well as bad
+// values), fn receives its error and decoding resumes at the next <element>
+// start tag. A missing or unexpected root fails the whole document, as do
+// syntax errors between elements that can't be skipped.
+func decodeXMLElements(body []byte, root, element string, fn xmlElementFunc) error {
+	d := xml.NewDecoder(bytes.NewReader(body))
+
+	// Find the root element
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return fmt.Errorf("no <%s> element found", root)
+		}
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != root {
+				return fmt.Errorf("expected element type <%s> but have <%s>", root, start.Name.Local)
+			}
+			break
+		}
+	}
+
+	s := &xmlStream{
+		body:    body,
+		root:    root,
+		element: element,
+		startRe: regexp.MustCompile(`<` + regexp.QuoteMeta(element) + `[\s/>]`),
+		fn:      fn,
+	}
+	return s.run(d, 0)
+}
+
+// xmlStream is the state of one decodeXMLElements call.
+type xmlStream struct {
+	body    []byte
+	root    string
+	element string
+	startRe *regexp.Regexp // Matches an element start tag, for resyncing
+	fn      xmlElementFunc
+	index   int
+}
+
+// run reads the root's children from d, whose input begins at body[base:]
+// (less any synthetic prefix, see resume), until the root closes.
+func (s *xmlStream) run(d *xml.Decoder, base int64) error {
+	for {
+		offset := base + d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF // The root never closed
+			}
+			// Between elements: skip ahead if there is anything left to read
+			return s.resume(offset, fmt.Errorf("decoding XML at byte %d: %w", offset, err))
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != s.element {
+				if err := d.Skip(); err != nil {
+					return s.resume(offset, fmt.Errorf("decoding XML at byte %d: %w", offset, err))
+				}
+				continue
+			}
+
+			index := s.index
+			s.index++
+			called := false
+			var decodeErr error
+			s.fn(index, t, func(v any) error {
+				called = true
+				decodeErr = d.DecodeElement(v, &t)
+				return decodeErr
+			})
+			if !called {
+				decodeErr = d.Skip()
+			}
+			if decodeErr != nil {
+				// The decoder stopped somewhere inside the element: start afresh after it
+				return s.resume(offset, nil)
+			}
+
+		case xml.EndElement:
+			return nil // The root closed
+		}
+	}
+}
+
+// resume continues after an error at offset with a new decoder, from the next
+// element start tag after it. cause is returned if there is none (nil: stop
+// quietly) and joined with any later error otherwise.
+func (s *xmlStream) resume(offset int64, cause error) error {
+	from := int(offset) + 1
+	if from >= len(s.body) {
+		return cause
+	}
+	loc := s.startRe.FindIndex(s.body[from:])
+	if loc == nil {
+		return cause
+	}
+	next := from + loc[0]
+
+	// Re-open the root so the new decoder accepts its closing tag
+	prefix := "<" + s.root + ">"
+	d := xml.NewDecoder(io.MultiReader(bytes.NewReader([]byte(prefix)), bytes.NewReader(s.body[next:])))
+	if _, err := d.Token(); err != nil {
+		return errors.Join(cause, err)
+	}
+	err := s.run(d, int64(next-len(prefix)))
+	if cause != nil {
+		return errors.Join(cause, err)
+	}
+	return err
+}
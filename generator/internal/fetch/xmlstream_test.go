@@ -0,0 +1,302 @@
+package fetch
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+// streamIDs decodes every <item id="..."> child of <root> and returns the
+// decoded ids and the indexes of elements that failed.
+func streamIDs(t *testing.T, doc string) (ids []string, failed []int, err error) {
+	t.Helper()
+	err = decodeXMLElements([]byte(doc), "root", "item", func(i int, _ xml.StartElement, decode func(any) error) {
+		var item struct {
+			ID   string `xml:"id,attr"`
+			Name string `xml:"name"`
+		}
+		if err := decode(&item); err != nil {
+			failed = append(failed, i)
+			return
+		}
+		ids = append(ids, item.ID)
+	})
+	return ids, failed, err
+}
+
+func TestDecodeXMLElements(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		wantIDs    []string
+		wantFailed []int
+		wantErr    bool
+	}{
+		{
+			name:    "well formed",
+			doc:     `<?xml version="1.0"?><root><meta>skipped</meta><item id="1"><name>a</name></item><item id="2"/></root>`,
+			wantIDs: []string{"1", "2"},
+		},
+		{
+			name:       "mismatched tag in one element",
+			doc:        `<root><item id="1"><name>a</name></item><item id="2"><name>b</nmae></item><item id="3"><name>c</name></item></root>`,
+			wantIDs:    []string{"1", "3"},
+			wantFailed: []int{1},
+		},
+		{
+			name:       "unescaped ampersand in one element",
+			doc:        `<root><item id="1"><name>a & b</name></item><item id="2"><name>c</name></item></root>`,
+			wantIDs:    []string{"2"},
+			wantFailed: []int{0},
+		},
+		{
+			name:       "several malformed elements",
+			doc:        `<root><item id="1"><x></item><item id="2"><name>ok</name></item><item id="3"><y></item><item id="4"/></root>`,
+			wantIDs:    []string{"2", "4"},
+			wantFailed: []int{0, 2},
+		},
+		{
+			name:       "truncated document",
+			doc:        `<root><item id="1"><name>a</name></item><item id="2"><na`,
+			wantIDs:    []string{"1"},
+			wantFailed: []int{1},
+		},
+		{
+			name:    "root never closed",
+			doc:     `<root><item id="1"/>`,
+			wantIDs: []string{"1"},
+			wantErr: true,
+		},
+		{
+			name:    "junk between elements",
+			doc:     `<root><item id="1"/></junk><item id="2"/></root>`,
+			wantIDs: []string{"1", "2"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong root",
+			doc:     `<html><item id="1"/></html>`,
+			wantErr: true,
+		},
+		{
+			name:    "not XML",
+			doc:     `This is not valid XML`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, failed, err := streamIDs(t, tt.doc)
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantIDs) {
+				t.Errorf("decoded ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if fmt.Sprint(failed) != fmt.Sprint(tt.wantFailed) {
+				t.Errorf("failed indexes = %v, want %v", failed, tt.wantFailed)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDecodeXMLElements_MatchesUnmarshal checks streaming decodes the datos.madrid.es
+// fixture exactly as unmarshaling the whole document does.
+func TestDecodeXMLElements_MatchesUnmarshal(t *testing.T) {
+	body := readFixture(t, "madrid-events.xml")
+
+	var response XMLResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		t.Fatalf("xml.Unmarshal failed: %v", err)
+	}
+
+	var streamed []XMLEvent
+	err := decodeXMLElements(body, "Contenidos", "contenido", func(i int, _ xml.StartElement, decode func(any) error) {
+		var e XMLEvent
+		if err := decode(&e); err != nil {
+			t.Errorf("element %d failed: %v", i, err)
+			return
+		}
+		streamed = append(streamed, e)
+	})
+	if err != nil {
+		t.Fatalf("decodeXMLElements failed: %v", err)
+	}
+
+	if len(streamed) != len(response.Events) {
+		t.Fatalf("streamed %d events, unmarshaled %d", len(streamed), len(response.Events))
+	}
+	for i := range streamed {
+		if streamed[i] != response.Events[i] {
+			t.Errorf("event %d = %+v, want %+v", i, streamed[i], response.Events[i])
+			break
+		}
+	}
+}
+
+// readFixture reads a testdata fixture's bytes.
+func readFixture(tb testing.TB, filename string) []byte {
+	tb.Helper()
+	path := strings.TrimPrefix(getFixturePath(tb, filename), "file://")
+	body, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("reading fixture: %v", err)
+	}
+	return body
+}
+
+// esmadridBenchmarkFeed builds an ESMadrid feed of n services. There is no
+// esmadrid.com fixture, so the services are copies of a representative one.
+func esmadridBenchmarkFeed(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<serviceList>\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `<service id="%d" fechaActualizacion="2025-10-20">
+	<basicData><name><![CDATA[Concierto &aacute; %d]]></name><title><![CDATA[Concierto %d]]></title>
+	<body><![CDATA[<p>Descripci&oacute;n del evento con bastante texto para parecerse al feed real.</p>]]></body>
+	<web>https://www.esmadrid.com/agenda/evento-%d</web><idrt>%d</idrt><nombrert><![CDATA[Teatro]]></nombrert></basicData>
+	<geoData><address>Plaza de España, 1</address><latitude>40.4233</latitude><longitude>-3.7122</longitude></geoData>
+	<multimedia><media><url>https://www.esmadrid.com/img/%d.jpg</url></media></multimedia>
+	<extradata><item name="Servicios de pago">15 €</item>
+		<categorias><categoria><item name="Categoria">Música</item><subcategorias><subcategoria><item name="SubCategoria">Clásica</item></subcategoria></subcategorias></categoria></categorias>
+		<fechas><rango><inicio>01/11/2025</inicio><fin>30/11/2025</fin></rango></fechas></extradata>
+</service>
+`, i, i, i, i, i, i)
+	}
+	b.WriteString("</serviceList>\n")
+	return []byte(b.String())
+}
+
+// liveHeap returns the bytes of heap in use after a full GC.
+func liveHeap() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// reportLiveHeap reports peak-live-B: the most heap the benchmark kept live
+// at its high-water mark (measured by the benchmark body), above baseline.
+func reportLiveHeap(b *testing.B, baseline, peak uint64) {
+	if peak > baseline {
+		b.ReportMetric(float64(peak-baseline), "peak-live-B")
+	}
+}
+
+// The Unmarshal benchmarks measure the previous approach: the whole document
+// decoded into a slice before converting. Their high-water mark is after
+// conversion, with every decoded element and every event live at once; the
+// streaming ones only ever hold the events (and the element being decoded).
+
+func BenchmarkXMLDecode_Unmarshal(b *testing.B) {
+	body := readFixture(b, "madrid-events.xml")
+	loc := time.UTC
+	baseline, peak := liveHeap(), uint64(0)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		var response XMLResponse
+		if err := xml.Unmarshal(body, &response); err != nil {
+			b.Fatal(err)
+		}
+		var events []event.CulturalEvent
+		for _, e := range response.Events {
+			if canonical, err := e.ToCanonical(loc); err == nil {
+				events = append(events, canonical)
+			}
+		}
+		b.StopTimer()
+		peak = max(peak, liveHeap())
+		runtime.KeepAlive(response)
+		runtime.KeepAlive(events)
+		b.StartTimer()
+	}
+	reportLiveHeap(b, baseline, peak)
+}
+
+func BenchmarkXMLDecode_Stream(b *testing.B) {
+	body := readFixture(b, "madrid-events.xml")
+	loc := time.UTC
+	baseline, peak := liveHeap(), uint64(0)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		var events []event.CulturalEvent
+		err := decodeXMLElements(body, "Contenidos", "contenido", func(_ int, _ xml.StartElement, decode func(any) error) {
+			var e XMLEvent
+			if decode(&e) != nil {
+				return
+			}
+			if canonical, err := e.ToCanonical(loc); err == nil {
+				events = append(events, canonical)
+			}
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		peak = max(peak, liveHeap())
+		runtime.KeepAlive(events)
+		b.StartTimer()
+	}
+	reportLiveHeap(b, baseline, peak)
+}
+
+func BenchmarkEsmadridDecode_Unmarshal(b *testing.B) {
+	body := esmadridBenchmarkFeed(2000)
+	baseline, peak := liveHeap(), uint64(0)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		var serviceList EsmadridServiceList
+		if err := xml.Unmarshal(body, &serviceList); err != nil {
+			b.Fatal(err)
+		}
+		var events []event.CityEvent
+		for _, svc := range serviceList.Services {
+			if cityEvent, err := svc.ToCityEvent(); err == nil {
+				events = append(events, *cityEvent)
+			}
+		}
+		b.StopTimer()
+		peak = max(peak, liveHeap())
+		runtime.KeepAlive(serviceList)
+		runtime.KeepAlive(events)
+		b.StartTimer()
+	}
+	reportLiveHeap(b, baseline, peak)
+}
+
+func BenchmarkEsmadridDecode_Stream(b *testing.B) {
+	body := esmadridBenchmarkFeed(2000)
+	baseline, peak := liveHeap(), uint64(0)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		var events []event.CityEvent
+		err := decodeXMLElements(body, "serviceList", "service", func(_ int, _ xml.StartElement, decode func(any) error) {
+			var svc EsmadridService
+			if decode(&svc) != nil {
+				return
+			}
+			if cityEvent, err := svc.ToCityEvent(); err == nil {
+				events = append(events, *cityEvent)
+			}
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		peak = max(peak, liveHeap())
+		runtime.KeepAlive(events)
+		b.StartTimer()
+	}
+	reportLiveHeap(b, baseline, peak)
+}
@@ -0,0 +1,411 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+)
+
+// RuleKind is what a filter rule checks.
+type RuleKind string
+
+const (
+	// Location rules place an event: the first that matches keeps it.
+	RuleDistrito RuleKind = "distrito" // Distrito is one of Distritos (ignoring case and accents)
+	RuleRadius   RuleKind = "radius"   // Coordinates within RadiusKm of Latitude, Longitude
+	RulePolygon  RuleKind = "polygon"  // Coordinates inside one of Areas
+	RuleText     RuleKind = "text"     // Text matches Text (see TextMatcher)
+
+	// Gate rules drop the events that fail them, placed or not.
+	RuleTime     RuleKind = "time"     // Start (or end) no more than PastWeeks ago
+	RuleCategory RuleKind = "category" // Category is one of Categories
+)
+
+// Rule is one step of an Engine's rule list. Only the fields of its Kind are used.
+type Rule struct {
+	Kind RuleKind
+
+	// Next leaves an event a location rule doesn't match to later location
+	// rules instead of dropping it. If none matches, the event is dropped with
+	// the first miss's reason. Text rules always leave misses to later rules.
+	Next bool
+
+	Distritos []string // distrito
+
+	Latitude, Longitude, RadiusKm float64 // radius
+
+	Areas []Area // polygon: geofence areas (see LoadGeofence)
+
+	Text TextMatcher // text
+
+	PastWeeks int  // time
+	ByEnd     bool // time: check the end date rather than the start date
+
+	Categories []string // category; events without a category pass
+}
+
+// location reports whether r is a location rule.
+func (r Rule) location() bool {
+	switch r.Kind {
+	case RuleDistrito, RuleRadius, RulePolygon, RuleText:
+		return true
+	}
+	return false
+}
+
+// Subject is what the rules see of an event, whatever its type.
+type Subject struct {
+	Distrito            string
+	Latitude, Longitude float64 // Zero when unknown
+	Title, Venue        string
+	Address             string
+	Description         string
+	Category            string
+	Start, End          time.Time
+}
+
+// Engine decides which events to keep by evaluating an ordered rule list.
+//
+// Rules run in order, and an event's reason is the first one found:
+//   - a gate rule (time, category) the event fails drops it;
+//   - the first location rule (distrito, radius, polygon, text) that matches
+//     places the event, and later location rules are skipped;
+//   - a location rule that doesn't match drops the event, unless the rule is
+//     Next (or text), leaving the event to later location rules.
+//
+// Location rules that can't judge an event (no distrito, no coordinates)
+// pass it on. An event no location rule places is dropped for the first
+// location rule it missed, or else as missing location data; that reason comes
+// ahead of any gate after the location rules, so an old event nowhere near is
+// reported as out of place, not as too old. Without location rules, every
+// event passing the gates is kept.
+//
+// Every rule is evaluated for every event, so the FilterResult records all
+// observations (distance, text match, age) even when an earlier rule decided.
+type Engine struct {
+	rules     []Rule
+	sets      []map[string]bool // Per rule: Distritos or Categories as a set
+	now       time.Time
+	locations bool // Has location rules
+
+	input     int
+	counts    map[event.FilterCode]int
+	areas     map[string]*report.AreaStats
+	areaOrder []string
+}
+
+// NewEngine creates an engine evaluating rules in order at time now.
+func NewEngine(rules []Rule, now time.Time) (*Engine, error) {
+	rules = slices.Clone(rules)
+	e := &Engine{
+		rules:  rules,
+		sets:   make([]map[string]bool, len(rules)),
+		now:    now,
+		counts: make(map[event.FilterCode]int),
+		areas:  make(map[string]*report.AreaStats),
+	}
+	for i, r := range rules {
+		switch r.Kind {
+		case RuleDistrito:
+			// Compared without case or accents: JSON-LD distritos come from
+			// URI slugs, which lose their accents ("CHAMBERI")
+			e.sets[i] = toSet(r.Distritos, normalizeText)
+		case RuleCategory:
+			e.sets[i] = toSet(r.Categories, nil)
+		case RuleRadius:
+			if r.RadiusKm <= 0 {
+				return nil, fmt.Errorf("filter rule %d (radius): radius must be positive", i+1)
+			}
+		case RulePolygon:
+			if len(r.Areas) == 0 {
+				return nil, fmt.Errorf("filter rule %d (polygon): no areas", i+1)
+			}
+			for _, a := range r.Areas {
+				if _, seen := e.areas[a.Name]; !seen {
+					e.areas[a.Name] = &report.AreaStats{Name: a.Name}
+					e.areaOrder = append(e.areaOrder, a.Name)
+				}
+			}
+		case RuleText:
+			rules[i].Text = r.Text.prepared()
+		case RuleTime:
+		default:
+			return nil, fmt.Errorf("filter rule %d: unknown kind %q", i+1, r.Kind)
+		}
+		e.locations = e.locations || r.location()
+	}
+	return e, nil
+}
+
+func toSet(values []string, normalize func(string) string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if normalize != nil {
+			v = normalize(v)
+		}
+		set[v] = true
+	}
+	return set
+}
+
+// verdict is one rule's view of an event.
+type verdict int
+
+const (
+	pass  verdict = iota // Doesn't apply, or a gate passed
+	match                // Location rule placed the event
+	miss                 // Location rule didn't place the event
+	fail                 // Gate failed
+)
+
+// Evaluate decides whether to keep s, recording the observations and the
+// decision in result (which may already hold coordinate fixes).
+func (e *Engine) Evaluate(s Subject, result *event.FilterResult) {
+	result.HasDistrito = s.Distrito != ""
+	result.Distrito = s.Distrito
+	result.HasCoordinates = s.Latitude != 0 && s.Longitude != 0
+	result.StartDate = s.Start
+	result.EndDate = s.End
+
+	var (
+		decided   event.FilterCode // First drop
+		firstMiss event.FilterCode
+		placed    = !e.locations
+		keptBy    = event.FilterKept
+		located   bool // A location rule has run
+	)
+	unplaced := func() event.FilterCode {
+		if firstMiss != "" {
+			return firstMiss
+		}
+		return event.FilterMissingLocation
+	}
+	for i, r := range e.rules {
+		v, code := e.apply(i, r, s, result)
+		isLocation := r.location()
+		if decided != "" {
+			continue
+		}
+		if !isLocation && located && !placed {
+			// The location rules are done without placing the event
+			decided = unplaced()
+			continue
+		}
+		located = located || isLocation
+		switch v {
+		case match:
+			if !placed {
+				placed, keptBy = true, code
+			}
+		case miss:
+			switch {
+			case placed:
+			case r.Next || r.Kind == RuleText:
+				if firstMiss == "" {
+					firstMiss = code
+				}
+			default:
+				decided = code
+			}
+		case fail:
+			decided = code
+		}
+	}
+
+	switch {
+	case decided != "":
+	case !placed:
+		decided = unplaced()
+	default:
+		decided = keptBy
+	}
+
+	result.Reason = decided
+	result.Kept = decided.Kept()
+	result.MultiVenueKept = decided == event.FilterKeptText
+	result.FilterReason = describe(decided, result)
+	e.input++
+	e.counts[decided]++
+	if a := e.areas[result.Area]; a != nil {
+		a.Events++
+		if result.Kept {
+			a.Kept++
+		}
+	}
+}
+
+// apply evaluates rule i, recording its observations in result.
+func (e *Engine) apply(i int, r Rule, s Subject, result *event.FilterResult) (verdict, event.FilterCode) {
+	switch r.Kind {
+	case RuleDistrito:
+		if s.Distrito == "" {
+			return pass, ""
+		}
+		result.DistritoMatched = e.sets[i][normalizeText(s.Distrito)]
+		if result.DistritoMatched {
+			return match, event.FilterKeptDistrito
+		}
+		return miss, event.FilterOutsideDistrito
+
+	case RuleRadius:
+		if !result.HasCoordinates {
+			return pass, ""
+		}
+		result.GPSDistanceKm = HaversineDistance(r.Latitude, r.Longitude, s.Latitude, s.Longitude)
+		result.WithinRadius = result.GPSDistanceKm <= r.RadiusKm
+		if result.WithinRadius {
+			return match, event.FilterKeptRadius
+		}
+		return miss, event.FilterOutsideRadius
+
+	case RulePolygon:
+		if !result.HasCoordinates {
+			return pass, ""
+		}
+		for _, a := range r.Areas {
+			if a.Contains(s.Latitude, s.Longitude) {
+				if result.Area == "" {
+					result.Area = a.Name
+				}
+				return match, event.FilterKeptArea
+			}
+		}
+		return miss, event.FilterOutsideArea
+
+	case RuleText:
+		m := r.Text.Match(s.Title, s.Venue, s.Address, s.Description)
+		result.PlazaEspanaText = result.PlazaEspanaText || m.PlazaEspana
+		for _, p := range m.Phrases {
+			if !slices.Contains(result.TextPhrases, p) {
+				result.TextPhrases = append(result.TextPhrases, p)
+			}
+		}
+		if m.Matched {
+			result.TextMatched = true
+			return match, event.FilterKeptText
+		}
+		return miss, ""
+
+	case RuleTime:
+		at := s.Start
+		if r.ByEnd {
+			at = s.End
+		}
+		result.DaysOld = int(e.now.Sub(at).Hours() / 24)
+		result.TooOld = at.Before(e.now.AddDate(0, 0, -7*r.PastWeeks))
+		if result.TooOld {
+			return fail, event.FilterTooOld
+		}
+		return pass, ""
+
+	case RuleCategory:
+		if s.Category == "" || e.sets[i][s.Category] {
+			return pass, ""
+		}
+		return fail, event.FilterCategory
+	}
+	return pass, ""
+}
+
+// describe returns the human-readable reason for code, as used in the audit
+// export's breakdowns.
+func describe(code event.FilterCode, result *event.FilterResult) string {
+	switch code {
+	case event.FilterKeptText:
+		if result.PlazaEspanaText {
+			return "kept (multi-venue: Plaza de España)"
+		}
+		return "kept (text match)"
+	case event.FilterOutsideDistrito:
+		return "outside target distrito"
+	case event.FilterOutsideRadius:
+		return "outside GPS radius"
+	case event.FilterOutsideArea:
+		return "outside geofence"
+	case event.FilterMissingLocation:
+		return "missing location data"
+	case event.FilterTooOld:
+		return "event too old"
+	case event.FilterCategory:
+		return "category not allowed"
+	}
+	return "kept"
+}
+
+// Count returns how many evaluated events got code.
+func (e *Engine) Count(code event.FilterCode) int {
+	return e.counts[code]
+}
+
+// Kept returns how many evaluated events were kept.
+func (e *Engine) Kept() int {
+	kept := 0
+	for code, n := range e.counts {
+		if code.Kept() {
+			kept += n
+		}
+	}
+	return kept
+}
+
+// Report fills the filter stats in f for the kinds of rules the engine has,
+// from the events evaluated so far. duration is the time spent filtering.
+func (e *Engine) Report(f *report.PipelineFilterReport, duration time.Duration) {
+	kept := e.Kept()
+	for _, r := range e.rules {
+		switch r.Kind {
+		case RuleDistrito:
+			if f.DistrictoFilter == nil {
+				f.DistrictoFilter = &report.DistrictoFilterStats{
+					AllowedDistricts: r.Distritos,
+					Input:            e.input,
+					Filtered:         e.counts[event.FilterOutsideDistrito],
+					Kept:             kept,
+					Duration:         duration,
+				}
+			}
+		case RuleRadius, RulePolygon, RuleText:
+			if f.GeoFilter == nil {
+				f.GeoFilter = &report.GeoFilterStats{
+					Input:          e.input,
+					MissingCoords:  e.counts[event.FilterMissingLocation],
+					OutsideRadius:  e.counts[event.FilterOutsideRadius],
+					OutsideArea:    e.counts[event.FilterOutsideArea],
+					Kept:           kept,
+					MultiVenueKept: e.counts[event.FilterKeptText],
+					Duration:       duration,
+				}
+			}
+			if r.Kind == RulePolygon && f.GeoFilter.Areas == nil {
+				for _, name := range e.areaOrder {
+					f.GeoFilter.Areas = append(f.GeoFilter.Areas, *e.areas[name])
+				}
+			}
+			if r.Kind == RuleRadius && f.GeoFilter.Radius == 0 {
+				f.GeoFilter.RefLat, f.GeoFilter.RefLon, f.GeoFilter.Radius = r.Latitude, r.Longitude, r.RadiusKm
+			}
+		case RuleTime:
+			if f.TimeFilter == nil {
+				f.TimeFilter = &report.TimeFilterStats{
+					ReferenceTime: e.now,
+					Timezone:      e.now.Location().String(),
+					Input:         e.input,
+					PastEvents:    e.counts[event.FilterTooOld],
+					Kept:          kept,
+				}
+			}
+		case RuleCategory:
+			if f.CategoryFilter == nil {
+				f.CategoryFilter = &report.CategoryFilterStats{
+					AllowedCategories: r.Categories,
+					Input:             e.input,
+					Filtered:          e.counts[event.FilterCategory],
+					Kept:              kept,
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+)
+
+func TestEngine_Evaluate(t *testing.T) {
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(24 * time.Hour)
+	old := now.Add(-30 * 24 * time.Hour)
+
+	plaza := [2]float64{40.42338, -3.71217}
+	plazaMayor := [2]float64{40.41794, -3.70736}
+	square := [][2]float64{{40.4220, -3.7140}, {40.4250, -3.7140}, {40.4250, -3.7100}, {40.4220, -3.7100}}
+
+	cultural := []Rule{
+		{Kind: RuleDistrito, Distritos: []string{"CENTRO", "MONCLOA-ARAVACA", "CHAMBERÍ"}},
+		{Kind: RuleRadius, Latitude: plaza[0], Longitude: plaza[1], RadiusKm: 0.35},
+		{Kind: RuleText, Text: TextMatcher{PlazaEspana: testPlazaEspana, Keywords: []string{"templo de debod"}, Weights: EqualWeights}},
+		{Kind: RuleTime, PastWeeks: 2},
+	}
+	city := []Rule{
+		{Kind: RuleRadius, Latitude: plaza[0], Longitude: plaza[1], RadiusKm: 0.35, Next: true},
+		{Kind: RuleText, Text: plazaText},
+		{Kind: RuleTime, PastWeeks: 2, ByEnd: true},
+	}
+	gated := []Rule{
+		{Kind: RuleCategory, Categories: []string{"Música"}},
+		{Kind: RulePolygon, Areas: []Area{{Name: "Plaza", Polygons: []Polygon{{square}}}}},
+	}
+
+	tests := []struct {
+		name       string
+		rules      []Rule
+		subject    Subject
+		wantReason event.FilterCode
+		wantText   string
+	}{
+		{"distrito placed, far coordinates", cultural,
+			Subject{Distrito: "CENTRO", Latitude: plazaMayor[0], Longitude: plazaMayor[1], Start: soon},
+			event.FilterKeptDistrito, "kept"},
+		{"distrito from a JSON-LD slug, without its accent", cultural,
+			Subject{Distrito: "CHAMBERI", Latitude: plazaMayor[0], Longitude: plazaMayor[1], Start: soon},
+			event.FilterKeptDistrito, "kept"},
+		{"other distrito", cultural,
+			Subject{Distrito: "RETIRO", Latitude: plaza[0], Longitude: plaza[1], Start: soon},
+			event.FilterOutsideDistrito, "outside target distrito"},
+		{"other distrito and old: rule order decides", cultural,
+			Subject{Distrito: "RETIRO", Start: old},
+			event.FilterOutsideDistrito, "outside target distrito"},
+		{"no distrito, within radius", cultural,
+			Subject{Latitude: plaza[0], Longitude: plaza[1], Start: soon},
+			event.FilterKeptRadius, "kept"},
+		{"outside radius drops before text", cultural,
+			Subject{Latitude: plazaMayor[0], Longitude: plazaMayor[1], Venue: "Plaza de España", Start: soon},
+			event.FilterOutsideRadius, "outside GPS radius"},
+		{"text keyword", cultural,
+			Subject{Venue: "Templo de Debod", Start: soon},
+			event.FilterKeptText, "kept (text match)"},
+		{"keyword only in the title", cultural,
+			Subject{Title: "Paseo hasta el Templo de Debod", Start: soon},
+			event.FilterMissingLocation, "missing location data"},
+		{"no location at all", cultural,
+			Subject{Title: "Taller", Start: soon},
+			event.FilterMissingLocation, "missing location data"},
+		{"no location at all, and old", cultural,
+			Subject{Title: "Taller", Start: old},
+			event.FilterMissingLocation, "missing location data"},
+		{"placed but too old", cultural,
+			Subject{Distrito: "CENTRO", Start: old},
+			event.FilterTooOld, "event too old"},
+		{"outside radius falls through to text", city,
+			Subject{Latitude: plazaMayor[0], Longitude: plazaMayor[1], Description: "Plaza Mayor y Plaza de España", Start: soon, End: soon},
+			event.FilterKeptText, "kept (multi-venue: Plaza de España)"},
+		{"outside radius, no text: first miss", city,
+			Subject{Latitude: plazaMayor[0], Longitude: plazaMayor[1], Start: soon, End: soon},
+			event.FilterOutsideRadius, "outside GPS radius"},
+		{"old, no location: the location miss comes first", city,
+			Subject{Title: "Taller", Start: old, End: old},
+			event.FilterMissingLocation, "missing location data"},
+		{"old, outside radius, no text: the location miss comes first", city,
+			Subject{Latitude: plazaMayor[0], Longitude: plazaMayor[1], Start: old, End: old},
+			event.FilterOutsideRadius, "outside GPS radius"},
+		{"old, placed by text", city,
+			Subject{Venue: "Plaza de España", Start: old, End: old},
+			event.FilterTooOld, "event too old"},
+		{"started long ago, ends soon", city,
+			Subject{Latitude: plaza[0], Longitude: plaza[1], Start: old, End: soon},
+			event.FilterKeptRadius, "kept"},
+		{"inside polygon", gated,
+			Subject{Latitude: 40.4230, Longitude: -3.7120, Category: "Música"},
+			event.FilterKeptArea, "kept"},
+		{"outside polygon", gated,
+			Subject{Latitude: plazaMayor[0], Longitude: plazaMayor[1]},
+			event.FilterOutsideArea, "outside geofence"},
+		{"category not allowed", gated,
+			Subject{Latitude: 40.4230, Longitude: -3.7120, Category: "Deportes"},
+			event.FilterCategory, "category not allowed"},
+		{"gates only", []Rule{{Kind: RuleTime, PastWeeks: 2}},
+			Subject{Start: soon},
+			event.FilterKept, "kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine(tt.rules, now)
+			if err != nil {
+				t.Fatalf("NewEngine failed: %v", err)
+			}
+			var result event.FilterResult
+			engine.Evaluate(tt.subject, &result)
+			if result.Reason != tt.wantReason || result.FilterReason != tt.wantText {
+				t.Errorf("reason = %q (%q), want %q (%q)", result.Reason, result.FilterReason, tt.wantReason, tt.wantText)
+			}
+			if tt.wantReason == event.FilterKeptArea && result.Area != "Plaza" {
+				t.Errorf("Area = %q, want Plaza", result.Area)
+			}
+			if result.Kept != tt.wantReason.Kept() {
+				t.Errorf("Kept = %v, want %v", result.Kept, tt.wantReason.Kept())
+			}
+		})
+	}
+}
+
+func TestEngine_RecordsAllObservations(t *testing.T) {
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	engine, err := NewEngine([]Rule{
+		{Kind: RuleDistrito, Distritos: []string{"CENTRO"}},
+		{Kind: RuleRadius, Latitude: 40.42338, Longitude: -3.71217, RadiusKm: 0.35},
+		{Kind: RuleText, Text: plazaText},
+		{Kind: RuleTime, PastWeeks: 2},
+	}, now)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+
+	// The distrito decides, but later rules still record what they saw
+	var result event.FilterResult
+	engine.Evaluate(Subject{
+		Distrito: "RETIRO", Latitude: 40.42338, Longitude: -3.71217,
+		Title: "Concierto en Plaza de España", Start: now.Add(-3 * 24 * time.Hour),
+	}, &result)
+	if !result.HasDistrito || result.DistritoMatched || !result.WithinRadius || !result.PlazaEspanaText || result.DaysOld != 3 {
+		t.Errorf("result = %+v, want every rule's observations", result)
+	}
+	if want := []string{"title: plaza de españa"}; !reflect.DeepEqual(result.TextPhrases, want) {
+		t.Errorf("TextPhrases = %v, want %v", result.TextPhrases, want)
+	}
+}
+
+func TestEngine_Report(t *testing.T) {
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	engine, err := NewEngine([]Rule{
+		{Kind: RuleDistrito, Distritos: []string{"CENTRO"}},
+		{Kind: RuleRadius, Latitude: 40.42338, Longitude: -3.71217, RadiusKm: 0.35},
+		{Kind: RuleText, Text: plazaText},
+		{Kind: RuleTime, PastWeeks: 2},
+	}, now)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	soon := now.Add(time.Hour)
+	for _, s := range []Subject{
+		{Distrito: "CENTRO", Start: soon},
+		{Distrito: "RETIRO", Start: soon},
+		{Latitude: 40.41794, Longitude: -3.70736, Start: soon},
+		{Venue: "Pza. España", Start: soon},
+		{Start: soon},
+		{Distrito: "CENTRO", Start: now.AddDate(0, 0, -30)},
+	} {
+		engine.Evaluate(s, &event.FilterResult{})
+	}
+
+	var f report.PipelineFilterReport
+	engine.Report(&f, time.Second)
+	if f.DistrictoFilter == nil || f.GeoFilter == nil || f.TimeFilter == nil || f.CategoryFilter != nil {
+		t.Fatalf("report = %+v, want distrito, geo and time stats only", f)
+	}
+	if got := *f.DistrictoFilter; got.Input != 6 || got.Filtered != 1 || got.Kept != 2 {
+		t.Errorf("DistrictoFilter = %+v, want 6 in, 1 filtered, 2 kept", got)
+	}
+	if got := *f.GeoFilter; got.Radius != 0.35 || got.OutsideRadius != 1 || got.MissingCoords != 1 || got.MultiVenueKept != 1 {
+		t.Errorf("GeoFilter = %+v, want 1 outside, 1 missing, 1 by text", got)
+	}
+	if got := *f.TimeFilter; got.PastEvents != 1 || got.Timezone != "UTC" {
+		t.Errorf("TimeFilter = %+v, want 1 past event in UTC", got)
+	}
+}
+
+func TestNewEngine_InvalidRules(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{Kind: "nearby"}},
+		{{Kind: RuleRadius}},
+		{{Kind: RulePolygon}},
+	} {
+		if _, err := NewEngine(rules, time.Now()); err == nil {
+			t.Errorf("NewEngine(%+v) succeeded, want error", rules)
+		}
+	}
+}
+
+func TestEngine_ReportAreas(t *testing.T) {
+	areas, err := ParseGeofence([]byte(testGeofence), "test")
+	if err != nil {
+		t.Fatalf("ParseGeofence failed: %v", err)
+	}
+	now := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	engine, err := NewEngine([]Rule{
+		{Kind: RulePolygon, Areas: areas},
+		{Kind: RuleTime, PastWeeks: 2},
+	}, now)
+	if err != nil {
+		t.Fatalf("NewEngine failed: %v", err)
+	}
+	soon := now.Add(time.Hour)
+	for _, s := range []Subject{
+		{Latitude: 40.4234, Longitude: -3.7122, Start: soon},
+		{Latitude: 40.4234, Longitude: -3.7122, Start: now.AddDate(0, 0, -30)},
+		{Latitude: 40.442, Longitude: -3.717, Start: soon},
+		{Latitude: 40.410, Longitude: -3.700, Start: soon},
+	} {
+		engine.Evaluate(s, &event.FilterResult{})
+	}
+
+	var f report.PipelineFilterReport
+	engine.Report(&f, 0)
+	want := []report.AreaStats{{Name: "Plaza", Events: 2, Kept: 1}, {Name: "Parque", Events: 1, Kept: 1}}
+	if f.GeoFilter == nil || !reflect.DeepEqual(f.GeoFilter.Areas, want) || f.GeoFilter.OutsideArea != 1 {
+		t.Errorf("GeoFilter = %+v, want areas %+v and 1 outside", f.GeoFilter, want)
+	}
+}
@@ -25,3 +25,20 @@ func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
 func WithinRadius(lat1, lon1, lat2, lon2, radiusKm float64) bool {
 	return HaversineDistance(lat1, lon1, lat2, lon2) <= radiusKm
 }
+
+// InPolygon reports whether a point is inside the polygon with the given
+// [latitude, longitude] vertices (ray casting; the ring needn't be closed).
+// Over the few hundred meters of a city square, treating degrees as planar
+// coordinates is accurate enough.
+func InPolygon(lat, lon float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lonI := polygon[i][0], polygon[i][1]
+		latJ, lonJ := polygon[j][0], polygon[j][1]
+		if (latI > lat) != (latJ > lat) &&
+			lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+			inside = !inside
+		}
+	}
+	return inside
+}
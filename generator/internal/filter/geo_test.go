@@ -81,3 +81,30 @@ func TestWithinRadius(t *testing.T) {
 		})
 	}
 }
+
+func TestInPolygon(t *testing.T) {
+	// A square around Plaza de España, and an L shape
+	square := [][2]float64{{40.4220, -3.7140}, {40.4250, -3.7140}, {40.4250, -3.7100}, {40.4220, -3.7100}}
+	ell := [][2]float64{{0, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 2}, {0, 2}}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		polygon  [][2]float64
+		want     bool
+	}{
+		{"center of square", 40.42338, -3.71217, square, true},
+		{"north of square", 40.4260, -3.7120, square, false},
+		{"east of square", 40.4230, -3.7090, square, false},
+		{"in the L's foot", 1.5, 0.5, ell, true},
+		{"in the L's notch", 1.5, 1.5, ell, false},
+		{"too few vertices", 0.5, 0.5, ell[:2], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InPolygon(tt.lat, tt.lon, tt.polygon); got != tt.want {
+				t.Errorf("InPolygon(%v, %v) = %v, want %v", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Ring is a closed line of [latitude, longitude] vertices (the closing vertex
+// may be repeated or not).
+type Ring [][2]float64
+
+// Polygon is an outer ring followed by any holes.
+type Polygon []Ring
+
+// Area is a named geofence: one or more polygons.
+type Area struct {
+	Name     string
+	Polygons []Polygon
+}
+
+// Contains reports whether the point is inside one of the area's polygons
+// (inside the outer ring and outside its holes).
+func (a Area) Contains(lat, lon float64) bool {
+	for _, p := range a.Polygons {
+		if len(p) == 0 || !InPolygon(lat, lon, p[0]) {
+			continue
+		}
+		inHole := false
+		for _, hole := range p[1:] {
+			if InPolygon(lat, lon, hole) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// geoJSON is the subset of a GeoJSON object (RFC 7946) geofences use:
+// a FeatureCollection, Feature or bare geometry.
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Features    []geoJSON       `json:"features"`   // FeatureCollection
+	Geometry    *geoJSON        `json:"geometry"`   // Feature
+	Properties  map[string]any  `json:"properties"` // Feature
+	Geometries  []geoJSON       `json:"geometries"` // GeometryCollection
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LoadGeofence reads the areas of a GeoJSON file (see ParseGeofence).
+func LoadGeofence(path string) ([]Area, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading geofence: %w", err)
+	}
+	name := filepath.Base(path)
+	areas, err := ParseGeofence(data, name[:len(name)-len(filepath.Ext(name))])
+	if err != nil {
+		return nil, fmt.Errorf("geofence %s: %w", path, err)
+	}
+	return areas, nil
+}
+
+// ParseGeofence parses GeoJSON Polygon and MultiPolygon geometries into
+// areas, one per feature (or bare geometry), named by the feature's "name"
+// property or else after fallback ("fallback", "fallback 2", ...). Other
+// geometry types are an error: a point or line can't contain anything.
+func ParseGeofence(data []byte, fallback string) ([]Area, error) {
+	var root geoJSON
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing GeoJSON: %w", err)
+	}
+
+	features := []geoJSON{root}
+	if root.Type == "FeatureCollection" {
+		features = root.Features
+	}
+
+	var areas []Area
+	for i, f := range features {
+		area := Area{Name: fallback}
+		if i > 0 {
+			area.Name = fmt.Sprintf("%s %d", fallback, i+1)
+		}
+		geometry := f
+		if f.Type == "Feature" {
+			if name, ok := f.Properties["name"].(string); ok && name != "" {
+				area.Name = name
+			}
+			if f.Geometry == nil {
+				return nil, fmt.Errorf("area %q has no geometry", area.Name)
+			}
+			geometry = *f.Geometry
+		}
+
+		polygons, err := parsePolygons(geometry)
+		if err != nil {
+			return nil, fmt.Errorf("area %q: %w", area.Name, err)
+		}
+		area.Polygons = polygons
+		areas = append(areas, area)
+	}
+	if len(areas) == 0 {
+		return nil, fmt.Errorf("no areas")
+	}
+	return areas, nil
+}
+
+// parsePolygons converts a Polygon, MultiPolygon or GeometryCollection of
+// them from GeoJSON's [longitude, latitude] positions.
+func parsePolygons(g geoJSON) ([]Polygon, error) {
+	switch g.Type {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("parsing Polygon coordinates: %w", err)
+		}
+		p, err := toPolygon(rings)
+		if err != nil {
+			return nil, err
+		}
+		return []Polygon{p}, nil
+
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, fmt.Errorf("parsing MultiPolygon coordinates: %w", err)
+		}
+		var out []Polygon
+		for _, rings := range polygons {
+			p, err := toPolygon(rings)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, p)
+		}
+		return out, nil
+
+	case "GeometryCollection":
+		var out []Polygon
+		for _, member := range g.Geometries {
+			polygons, err := parsePolygons(member)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, polygons...)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %q (want Polygon or MultiPolygon)", g.Type)
+}
+
+func toPolygon(rings [][][]float64) (Polygon, error) {
+	if len(rings) == 0 {
+		return nil, fmt.Errorf("polygon has no rings")
+	}
+	p := make(Polygon, 0, len(rings))
+	for _, positions := range rings {
+		if len(positions) < 3 {
+			return nil, fmt.Errorf("ring needs at least 3 positions, got %d", len(positions))
+		}
+		ring := make(Ring, 0, len(positions))
+		for _, pos := range positions {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("position %v needs longitude and latitude", pos)
+			}
+			ring = append(ring, [2]float64{pos[1], pos[0]})
+		}
+		p = append(p, ring)
+	}
+	return p, nil
+}
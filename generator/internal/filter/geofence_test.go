@@ -0,0 +1,111 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A square plaza, and a park of two parts, the first with a pond (hole)
+const testGeofence = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "properties": {"name": "Plaza"},
+     "geometry": {"type": "Polygon", "coordinates": [[[-3.714, 40.422], [-3.710, 40.422], [-3.710, 40.425], [-3.714, 40.425], [-3.714, 40.422]]]}},
+    {"type": "Feature", "properties": {"name": "Parque"},
+     "geometry": {"type": "MultiPolygon", "coordinates": [
+       [[[-3.730, 40.426], [-3.720, 40.426], [-3.720, 40.436], [-3.730, 40.436], [-3.730, 40.426]],
+        [[-3.726, 40.430], [-3.724, 40.430], [-3.724, 40.432], [-3.726, 40.432], [-3.726, 40.430]]],
+       [[[-3.720, 40.440], [-3.715, 40.440], [-3.715, 40.445], [-3.720, 40.445], [-3.720, 40.440]]]
+     ]}}
+  ]
+}`
+
+func TestParseGeofence(t *testing.T) {
+	areas, err := ParseGeofence([]byte(testGeofence), "test")
+	if err != nil {
+		t.Fatalf("ParseGeofence failed: %v", err)
+	}
+	if len(areas) != 2 || areas[0].Name != "Plaza" || areas[1].Name != "Parque" || len(areas[1].Polygons) != 2 {
+		t.Fatalf("areas = %+v, want Plaza and a two-part Parque", areas)
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string // Area containing the point, "" for none
+	}{
+		{"plaza", 40.4234, -3.7122, "Plaza"},
+		{"park", 40.428, -3.728, "Parque"},
+		{"pond", 40.431, -3.725, ""},
+		{"park's second part", 40.442, -3.717, "Parque"},
+		{"between", 40.438, -3.718, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			for _, a := range areas {
+				if a.Contains(tt.lat, tt.lon) {
+					got = a.Name
+					break
+				}
+			}
+			if got != tt.want {
+				t.Errorf("area containing (%v, %v) = %q, want %q", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGeofence_Names(t *testing.T) {
+	// Bare geometries and unnamed features are named after the file
+	bare := `{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}`
+	areas, err := ParseGeofence([]byte(bare), "plaza")
+	if err != nil || len(areas) != 1 || areas[0].Name != "plaza" {
+		t.Errorf("ParseGeofence(bare) = %+v, %v, want one area named plaza", areas, err)
+	}
+
+	unnamed := `{"type": "FeatureCollection", "features": [
+	  {"type": "Feature", "properties": {}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}},
+	  {"type": "Feature", "properties": null, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}}
+	]}`
+	areas, err = ParseGeofence([]byte(unnamed), "plaza")
+	if err != nil || len(areas) != 2 || areas[0].Name != "plaza" || areas[1].Name != "plaza 2" {
+		t.Errorf("ParseGeofence(unnamed) = %+v, %v, want plaza and plaza 2", areas, err)
+	}
+}
+
+func TestParseGeofence_Invalid(t *testing.T) {
+	tests := []struct {
+		name, geojson, want string
+	}{
+		{"not JSON", `{`, "parsing GeoJSON"},
+		{"point", `{"type": "Point", "coordinates": [-3.71, 40.42]}`, "unsupported geometry"},
+		{"short ring", `{"type": "Polygon", "coordinates": [[[0, 0], [1, 0]]]}`, "at least 3"},
+		{"feature without geometry", `{"type": "Feature", "properties": {"name": "Plaza"}}`, "no geometry"},
+		{"empty collection", `{"type": "FeatureCollection", "features": []}`, "no areas"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGeofence([]byte(tt.geojson), "test")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseGeofence error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadGeofence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plaza-espana.geojson")
+	if err := os.WriteFile(path, []byte(`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1]]]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	areas, err := LoadGeofence(path)
+	if err != nil || len(areas) != 1 || areas[0].Name != "plaza-espana" {
+		t.Errorf("LoadGeofence = %+v, %v, want one area named plaza-espana", areas, err)
+	}
+	if _, err := LoadGeofence(filepath.Join(t.TempDir(), "missing.geojson")); err == nil {
+		t.Error("LoadGeofence(missing) succeeded, want error")
+	}
+}
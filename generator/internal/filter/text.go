@@ -9,20 +9,143 @@ import (
 	"golang.org/x/text/unicode/norm"
 )
 
-// MatchesLocation checks if event text mentions target location.
-// Used as fallback when coordinates are missing.
-func MatchesLocation(venueName, address, description string, keywords []string) bool {
-	// Combine all text fields
-	text := strings.ToLower(venueName + " " + address + " " + description)
-
-	// Check if any keyword appears
-	for _, keyword := range keywords {
-		if strings.Contains(text, strings.ToLower(keyword)) {
-			return true
+// FieldWeights is how much a phrase found in each of an event's text fields
+// counts toward a TextMatcher's threshold. Fields weighing zero aren't searched.
+type FieldWeights struct {
+	Title, Venue, Address, Description float64
+}
+
+// EqualWeights counts a phrase in any field the same.
+var EqualWeights = FieldWeights{Title: 1, Venue: 1, Address: 1, Description: 1}
+
+// TextMatcher looks for phrases naming a place in an event's text fields.
+//
+// Phrases match whole words, ignoring case, accents and punctuation: "pza de
+// españa" matches "Pza. de Espana" but not "Pza. de Españas". Words that are
+// part of an excluded phrase don't count, so excluding "plaza de españa de
+// leganés" leaves other mentions of Plaza de España to match. An event matches
+// when the weights of the fields with a phrase in them add up to Threshold.
+// Keywords are only searched in the title with KeywordsInTitle: titles often
+// merely mention other places ("Ruta de Conde Duque a Sol").
+type TextMatcher struct {
+	PlazaEspana     []string // Spellings of "Plaza de España" (see TextMatch.PlazaEspana)
+	Keywords        []string // Other phrases
+	Exclude         []string // Phrases whose words don't count as a mention
+	Weights         FieldWeights
+	Threshold       float64 // Zero means 1
+	KeywordsInTitle bool
+
+	normalized *textPhrases // The phrases, normalized once (see prepared)
+}
+
+// textPhrases are a TextMatcher's phrases, normalized with NormalizeWords.
+type textPhrases struct {
+	plazaEspana, keywords, exclude []string
+}
+
+// prepared returns m with its phrases normalized, for matching many events
+// without normalizing them for each one. Its phrases must not change after.
+func (m TextMatcher) prepared() TextMatcher {
+	m.normalized = m.phrases()
+	return m
+}
+
+// phrases returns m's phrases normalized.
+func (m TextMatcher) phrases() *textPhrases {
+	if m.normalized != nil {
+		return m.normalized
+	}
+	return &textPhrases{
+		plazaEspana: normalizePhrases(m.PlazaEspana),
+		keywords:    normalizePhrases(m.Keywords),
+		exclude:     normalizePhrases(m.Exclude),
+	}
+}
+
+// TextMatch is what a TextMatcher found in an event.
+type TextMatch struct {
+	Matched     bool
+	Score       float64  // Sum of the weights of the fields with a phrase in them
+	Phrases     []string // Each phrase found, as "field: phrase" (e.g. "venue: plaza de españa")
+	PlazaEspana bool     // One of the Plaza de España spellings was found
+}
+
+// Match searches the event's text fields for the matcher's phrases.
+func (m TextMatcher) Match(title, venue, address, description string) TextMatch {
+	phrases := m.phrases()
+
+	fields := []struct {
+		name     string
+		text     string
+		weight   float64
+		keywords bool // Search Keywords, not just the Plaza de España spellings
+	}{
+		{"title", title, m.Weights.Title, m.KeywordsInTitle},
+		{"venue", venue, m.Weights.Venue, true},
+		{"address", address, m.Weights.Address, true},
+		{"description", description, m.Weights.Description, true},
+	}
+
+	var match TextMatch
+	for _, f := range fields {
+		if f.weight <= 0 || f.text == "" {
+			continue
 		}
+		text := " " + NormalizeWords(f.text) + " "
+		for _, ex := range phrases.exclude {
+			text = removePhrase(text, ex)
+		}
+
+		found := false
+		for i, p := range phrases.plazaEspana {
+			if containsPhrase(text, p) {
+				match.Phrases = append(match.Phrases, f.name+": "+m.PlazaEspana[i])
+				match.PlazaEspana = true
+				found = true
+			}
+		}
+		for i, p := range phrases.keywords {
+			if f.keywords && containsPhrase(text, p) {
+				match.Phrases = append(match.Phrases, f.name+": "+m.Keywords[i])
+				found = true
+			}
+		}
+		if found {
+			match.Score += f.weight
+		}
+	}
+
+	threshold := m.Threshold
+	if threshold == 0 {
+		threshold = 1
+	}
+	match.Matched = match.Phrases != nil && match.Score >= threshold
+	return match
+}
+
+// normalizePhrases normalizes phrases with NormalizeWords, keeping their
+// order ("" for phrases without any words, which never match).
+func normalizePhrases(phrases []string) []string {
+	normalized := make([]string, len(phrases))
+	for i, p := range phrases {
+		normalized[i] = NormalizeWords(p)
 	}
+	return normalized
+}
 
-	return false
+// containsPhrase reports whether padded (normalized text between spaces)
+// contains the normalized phrase as whole words.
+func containsPhrase(padded, phrase string) bool {
+	return phrase != "" && strings.Contains(padded, " "+phrase+" ")
+}
+
+// removePhrase replaces the phrase's occurrences in padded with a separator,
+// so they match no other phrase.
+func removePhrase(padded, phrase string) string {
+	for containsPhrase(padded, phrase) {
+		padded = strings.Replace(padded, " "+phrase+" ", " | ", 1)
+	}
+	return padded
 }
 
 // normalizeText removes accents, converts to lowercase, collapses whitespace.
@@ -41,45 +164,14 @@ func normalizeText(s string) string {
 	return result
 }
 
-// plazaEspanaVariants returns all normalized variants of "Plaza de España" to search for.
-// Includes abbreviated forms (Pza., Pl., etc.) commonly used in Spanish.
-func plazaEspanaVariants() []string {
-	return []string{
-		"plaza de espana",
-		"plaza espana",
-		"pza espana",
-		"pza de espana",
-		"pl espana",
-		"pl de espana",
-		"plz espana",
-		"pza. espana",
-		"pza. de espana",
-		"pl. espana",
-		"pl. de espana",
-	}
-}
-
-// MatchesPlazaEspana checks if any field mentions "Plaza de España" (accent-insensitive).
-// Searches across Title, Venue, Address, and Description fields.
-// Returns true if any variant of "Plaza de España" is found.
-//
-// This function is used for multi-venue city events that explicitly list
-// Plaza de España as one of their venues, even if their canonical coordinates
-// point to a different location.
-func MatchesPlazaEspana(title, venue, address, description string) bool {
-	// Combine all fields into searchable text
-	combined := strings.Join([]string{title, venue, address, description}, " ")
-
-	// Normalize (remove accents, lowercase, collapse spaces)
-	normalized := normalizeText(combined)
-
-	// Check all variants
-	variants := plazaEspanaVariants()
-	for _, variant := range variants {
-		if strings.Contains(normalized, variant) {
-			return true
+// NormalizeWords is normalizeText with punctuation turned into spaces, leaving
+// words separated by single spaces: the form phrases are matched in.
+func NormalizeWords(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
 		}
-	}
-
-	return false
+		return ' '
+	}, normalizeText(s))
+	return strings.Join(strings.Fields(s), " ")
 }
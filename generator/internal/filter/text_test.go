@@ -1,8 +1,22 @@
 package filter
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
-func TestMatchesLocation(t *testing.T) {
+// Spellings of Plaza de España, as in the default config
+var testPlazaEspana = []string{
+	"plaza de españa", "plaza españa",
+	"pza de españa", "pza españa",
+	"pl de españa", "pl españa",
+	"plz españa",
+}
+
+// plazaText matches Plaza de España's spellings in any field
+var plazaText = TextMatcher{PlazaEspana: testPlazaEspana, Weights: EqualWeights}
+
+func TestTextMatcher_Keywords(t *testing.T) {
 	tests := []struct {
 		name        string
 		venueName   string
@@ -23,6 +37,12 @@ func TestMatchesLocation(t *testing.T) {
 			keywords:  []string{"plaza de españa"},
 			want:      true,
 		},
+		{
+			name:      "accent insensitive match",
+			venueName: "Plaza de Espana",
+			keywords:  []string{"plaza de españa"},
+			want:      true,
+		},
 		{
 			name:      "partial match in venue name",
 			venueName: "Auditorio Plaza de España",
@@ -66,13 +86,29 @@ func TestMatchesLocation(t *testing.T) {
 			keywords: []string{"parque del oeste"},
 			want:     true,
 		},
+		{
+			name:      "whole words only",
+			venueName: "Centro Cultural Conde Duquesa",
+			keywords:  []string{"conde duque"},
+			want:      false,
+		},
+		{
+			name:        "punctuation between words",
+			description: "Cuartel del Conde-Duque",
+			keywords:    []string{"conde duque"},
+			want:        true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := MatchesLocation(tt.venueName, tt.address, tt.description, tt.keywords)
-			if got != tt.want {
-				t.Errorf("MatchesLocation() = %v, want %v", got, tt.want)
+			m := TextMatcher{Keywords: tt.keywords, Weights: EqualWeights}
+			got := m.Match("", tt.venueName, tt.address, tt.description)
+			if got.Matched != tt.want {
+				t.Errorf("Match() = %+v, want matched %v", got, tt.want)
+			}
+			if got.PlazaEspana {
+				t.Errorf("PlazaEspana = true, want false for keywords")
 			}
 		})
 	}
@@ -99,7 +135,7 @@ func TestNormalizeText(t *testing.T) {
 	}
 }
 
-func TestMatchesPlazaEspana(t *testing.T) {
+func TestTextMatcher_PlazaEspana(t *testing.T) {
 	tests := []struct {
 		name        string
 		title       string
@@ -180,43 +216,122 @@ func TestMatchesPlazaEspana(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := MatchesPlazaEspana(tt.title, tt.venue, tt.address, tt.description)
-			if got != tt.want {
-				t.Errorf("MatchesPlazaEspana() = %v, want %v", got, tt.want)
+			got := plazaText.Match(tt.title, tt.venue, tt.address, tt.description)
+			if got.Matched != tt.want || got.PlazaEspana != tt.want {
+				t.Errorf("Match() = %+v, want matched %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestPlazaEspanaVariants(t *testing.T) {
-	variants := plazaEspanaVariants()
+func TestNormalizeWords(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Pza. de España", "pza de espana"},
+		{"Conde-Duque, 9", "conde duque 9"},
+		{"«Plaza de España»", "plaza de espana"},
+		{" ... ", ""},
+	}
 
-	// Verify all variants are normalized (lowercase, no accents)
-	for _, variant := range variants {
-		normalized := normalizeText(variant)
-		if normalized != variant {
-			t.Errorf("Variant %q is not normalized (got %q)", variant, normalized)
+	for _, tt := range tests {
+		if got := NormalizeWords(tt.input); got != tt.want {
+			t.Errorf("NormalizeWords(%q) = %q, want %q", tt.input, got, tt.want)
 		}
 	}
+}
 
-	// Verify expected variants are present
-	expectedVariants := []string{
-		"plaza de espana",
-		"plaza espana",
-		"pza espana",
-		"pl espana",
+func TestTextMatcher_Exclude(t *testing.T) {
+	m := plazaText
+	m.Exclude = []string{"plaza de españa de leganés"}
+
+	tests := []struct {
+		name        string
+		description string
+		want        bool
+	}{
+		{"excluded", "Feria en la Plaza de España de Leganés", false},
+		{"excluded without accent", "PLAZA DE ESPANA DE LEGANES", false},
+		{"excluded and another mention", "Leganés (Plaza de España de Leganés) y Madrid (Plaza de España)", true},
+		{"not excluded", "Plaza de España de Madrid", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Match("", "", "", tt.description); got.Matched != tt.want {
+				t.Errorf("Match(%q) = %+v, want matched %v", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextMatcher_Weights(t *testing.T) {
+	m := TextMatcher{
+		PlazaEspana: testPlazaEspana,
+		Keywords:    []string{"templo de debod"},
+		Weights:     FieldWeights{Title: 1, Venue: 1, Address: 0, Description: 0.5},
+		Threshold:   1,
+
+		KeywordsInTitle: true,
 	}
 
-	for _, expected := range expectedVariants {
-		found := false
-		for _, variant := range variants {
-			if variant == expected {
-				found = true
-				break
+	tests := []struct {
+		name                               string
+		title, venue, address, description string
+		wantMatched                        bool
+		wantScore                          float64
+		wantPhrases                        []string
+	}{
+		{"venue", "", "Plaza de España", "", "", true, 1, []string{"venue: plaza de españa"}},
+		{"description alone is not enough", "", "", "", "Varios puntos, entre ellos Plaza de España", false, 0.5,
+			[]string{"description: plaza de españa"}},
+		{"title and description", "Concierto en Pza. España", "", "", "Junto al Templo de Debod", true, 1.5,
+			[]string{"title: pza españa", "description: templo de debod"}},
+		{"zero weight field is skipped", "", "", "Plaza de España, 1", "", false, 0, nil},
+		{"one field counts once", "Plaza de España y Templo de Debod", "", "", "", true, 1,
+			[]string{"title: plaza de españa", "title: templo de debod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Match(tt.title, tt.venue, tt.address, tt.description)
+			if got.Matched != tt.wantMatched || got.Score != tt.wantScore || !reflect.DeepEqual(got.Phrases, tt.wantPhrases) {
+				t.Errorf("Match() = %+v, want matched %v, score %v, phrases %v", got, tt.wantMatched, tt.wantScore, tt.wantPhrases)
 			}
-		}
-		if !found {
-			t.Errorf("Expected variant %q not found in plazaEspanaVariants()", expected)
+		})
+	}
+}
+
+func TestTextMatcher_KeywordsInTitle(t *testing.T) {
+	m := TextMatcher{PlazaEspana: testPlazaEspana, Keywords: []string{"conde duque"}, Weights: EqualWeights}
+
+	// A title merely mentioning a keyword's place doesn't place the event
+	if got := m.Match("Ruta de Conde Duque a Sol", "Puerta del Sol", "", ""); got.Matched {
+		t.Errorf("Match(keyword in title) = %+v, want no match", got)
+	}
+	// The Plaza de España spellings are searched in the title all the same
+	if got := m.Match("Concierto en Plaza de España", "", "", ""); !got.Matched {
+		t.Errorf("Match(Plaza de España in title) = %+v, want a match", got)
+	}
+	if got := m.Match("", "Centro Cultural Conde Duque", "", ""); !got.Matched {
+		t.Errorf("Match(keyword in venue) = %+v, want a match", got)
+	}
+
+	m.KeywordsInTitle = true
+	if got := m.Match("Ruta de Conde Duque a Sol", "Puerta del Sol", "", ""); !got.Matched {
+		t.Errorf("Match(keyword in title) with KeywordsInTitle = %+v, want a match", got)
+	}
+}
+
+func TestTextMatcher_Prepared(t *testing.T) {
+	m := TextMatcher{PlazaEspana: testPlazaEspana, Keywords: []string{"Templo de Debod"},
+		Exclude: []string{"plaza de españa de leganés"}, Weights: EqualWeights}
+	prepared := m.prepared()
+	if prepared.normalized == nil || prepared.normalized.keywords[0] != "templo de debod" {
+		t.Fatalf("prepared phrases = %+v, want them normalized", prepared.normalized)
+	}
+	for _, venue := range []string{"Templo de Debod", "Plaza de España de Leganés", "Plaza de España"} {
+		if got, want := prepared.Match("", venue, "", ""), m.Match("", venue, "", ""); !reflect.DeepEqual(got, want) {
+			t.Errorf("prepared Match(%q) = %+v, want %+v", venue, got, want)
 		}
 	}
 }
@@ -0,0 +1,66 @@
+package filter
+
+// Zone is a named place events can be at, such as Plaza de España itself,
+// for the site's zone filters. Its geometry is a circle (when RadiusKm is
+// positive) or Areas; Aliases are phrases naming it, matched like a
+// TextMatcher's in any text field.
+type Zone struct {
+	ID   string // Used in the page's HTML ids and CSS classes
+	Name string
+
+	Latitude, Longitude, RadiusKm float64 // Circle
+	Areas                         []Area  // Polygons
+
+	Aliases []string
+	Exclude []string // Phrases whose words don't count as an alias
+
+	text *TextMatcher // The aliases' matcher (see Prepare)
+}
+
+// Prepare readies the zone for placing many events: its aliases are
+// normalized once rather than for each event. Its aliases must not change after.
+func (z *Zone) Prepare() {
+	m := z.matcher().prepared()
+	z.text = &m
+}
+
+// matcher returns the matcher of the zone's aliases, which are searched in
+// every field, the title included.
+func (z *Zone) matcher() TextMatcher {
+	if z.text != nil {
+		return *z.text
+	}
+	return TextMatcher{Keywords: z.Aliases, Exclude: z.Exclude, Weights: EqualWeights, KeywordsInTitle: true}
+}
+
+// Contains reports whether the point is inside the zone's circle or areas.
+func (z Zone) Contains(lat, lon float64) bool {
+	if z.RadiusKm > 0 && HaversineDistance(z.Latitude, z.Longitude, lat, lon) <= z.RadiusKm {
+		return true
+	}
+	for _, a := range z.Areas {
+		if a.Contains(lat, lon) {
+			return true
+		}
+	}
+	return false
+}
+
+// ZoneOf returns the zone an event is at: the first zone containing its
+// coordinates (if it has any), or else the first whose aliases its text
+// mentions. It returns nil if the event is in no zone.
+func ZoneOf(zones []Zone, lat, lon float64, title, venue, address, description string) *Zone {
+	if lat != 0 || lon != 0 {
+		for i := range zones {
+			if zones[i].Contains(lat, lon) {
+				return &zones[i]
+			}
+		}
+	}
+	for i := range zones {
+		if zones[i].matcher().Match(title, venue, address, description).Matched {
+			return &zones[i]
+		}
+	}
+	return nil
+}
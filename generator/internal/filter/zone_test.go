@@ -0,0 +1,44 @@
+package filter
+
+import "testing"
+
+func TestZoneOf(t *testing.T) {
+	areas, err := ParseGeofence([]byte(testGeofence), "test")
+	if err != nil {
+		t.Fatalf("ParseGeofence failed: %v", err)
+	}
+	zones := []Zone{
+		{ID: "plaza", Latitude: 40.4234, Longitude: -3.7122, RadiusKm: 0.05, Aliases: []string{"plaza de españa", "pza. de españa"}},
+		{ID: "parque", Areas: areas[1:], Aliases: []string{"parque del oeste"}},
+		{ID: "debod", Aliases: []string{"Templo de Debod"}},
+	}
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		venue    string
+		want     string // Zone ID, "" for none
+	}{
+		{"in circle", 40.4236, -3.7122, "", "plaza"},
+		{"outside circle", 40.4240, -3.7122, "", ""},
+		{"in area", 40.428, -3.728, "", "parque"},
+		{"in area's hole", 40.431, -3.725, "", ""},
+		{"coordinates beat aliases", 40.428, -3.728, "Plaza de España", "parque"},
+		{"alias without coordinates", 0, 0, "PZA. DE ESPAÑA", "plaza"},
+		{"alias outside geometry", 40.45, -3.70, "Parque del Oeste", "parque"},
+		{"alias only zone", 0, 0, "Templo de Debod", "debod"},
+		{"accents ignored", 0, 0, "Plaza de Espana", "plaza"},
+		{"no zone", 0, 0, "Matadero", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if z := ZoneOf(zones, tt.lat, tt.lon, "", tt.venue, "", ""); z != nil {
+				got = z.ID
+			}
+			if got != tt.want {
+				t.Errorf("ZoneOf(%v, %v, %q) = %q, want %q", tt.lat, tt.lon, tt.venue, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+// Package gazetteer places events that come without coordinates at their venue.
+//
+// Many esmadrid services and some cultural events name their venue but give
+// no coordinates. The gazetteer remembers where venues are, by venue ID, by
+// venue name and by address, learning from the events that do have
+// coordinates. It lives in a JSON file in the data directory, so what one
+// build learns serves the next, and entries can be added by hand.
+package gazetteer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/ericphanson/plazaespana.info/internal/filter"
+)
+
+// Entry is where a place is.
+type Entry struct {
+	Name      string  `json:"name,omitempty"` // As first seen (or as written by hand), for people reading the file
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+
+	Manual    bool       `json:"manual,omitempty"`    // Added by hand: never updated by learning
+	Seen      int        `json:"seen,omitempty"`      // Distinct events learned from
+	Events    []Sighting `json:"events,omitempty"`    // The last maxSightings of them, oldest first; the coordinates are their mean
+	SpreadKm  float64    `json:"spread_km,omitempty"` // How far apart Events are, at most
+	Ambiguous bool       `json:"ambiguous,omitempty"` // Events too far apart to tell where the place is: not used
+}
+
+// Sighting is where one event said a place is.
+type Sighting struct {
+	Event     string  `json:"event,omitempty"` // The event's ID, with its source (see Place)
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// maxSightings is how many events an entry remembers. Older events age out,
+// so a place made ambiguous by a wrong coordinate recovers once it has been
+// corrected upstream or enough newer events agree.
+const maxSightings = 20
+
+// Kind is which of an event's fields found its place.
+type Kind string
+
+const (
+	ByVenueID Kind = "venue_id"
+	ByName    Kind = "venue_name"
+	ByAddress Kind = "address"
+)
+
+// Place is what identifies an event's place, any of which may be empty.
+type Place struct {
+	VenueID string // With its source (see VenueKey)
+	Name    string
+	Address string
+
+	// Event is the event's ID, with its source (e.g. "esmadrid:1234"): each
+	// event counts once however many builds learn from it. Empty counts
+	// every time.
+	Event string
+}
+
+// VenueKey returns the gazetteer key of a venue ID from source (e.g.
+// "esmadrid:78001"), as venue IDs of different sources don't share a
+// namespace. It returns "" for an empty ID.
+func VenueKey(source, id string) string {
+	if id == "" {
+		return ""
+	}
+	return source + ":" + id
+}
+
+// Match is the entry a lookup found.
+type Match struct {
+	Kind  Kind
+	Key   string // Venue key, or the normalized name or address
+	Entry Entry
+}
+
+// Gazetteer maps venue IDs, venue names and addresses to coordinates. Names
+// and addresses are keyed in normalized form (see filter.NormalizeWords), so
+// "Teatro Español" and "TEATRO ESPANOL" are the same place.
+type Gazetteer struct {
+	path        string
+	maxSpreadKm float64
+
+	Venues    map[string]*Entry `json:"venues"`    // By VenueKey
+	Names     map[string]*Entry `json:"names"`     // By normalized venue name
+	Addresses map[string]*Entry `json:"addresses"` // By normalized address
+}
+
+// Load loads the gazetteer at path. A missing file is an empty gazetteer.
+// Names and addresses written by hand are normalized. Places whose events
+// are more than maxSpreadKm apart become ambiguous as the gazetteer learns.
+func Load(path string, maxSpreadKm float64) (*Gazetteer, error) {
+	g := &Gazetteer{path: path, maxSpreadKm: maxSpreadKm}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading gazetteer: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, g); err != nil {
+			return nil, fmt.Errorf("parsing gazetteer: %w", err)
+		}
+	}
+	if g.Venues == nil {
+		g.Venues = make(map[string]*Entry)
+	}
+	g.Names = normalizeKeys(g.Names)
+	g.Addresses = normalizeKeys(g.Addresses)
+	return g, nil
+}
+
+// normalizeKeys rekeys entries by their normalized key, keeping the manual
+// entry when several keys normalize the same.
+func normalizeKeys(entries map[string]*Entry) map[string]*Entry {
+	normalized := make(map[string]*Entry, len(entries))
+	for key, e := range entries {
+		if e == nil {
+			continue
+		}
+		if e.Name == "" {
+			e.Name = key
+		}
+		key = filter.NormalizeWords(key)
+		if prev := normalized[key]; key == "" || (prev != nil && prev.Manual && !e.Manual) {
+			continue
+		}
+		normalized[key] = e
+	}
+	return normalized
+}
+
+// Len returns the number of entries.
+func (g *Gazetteer) Len() int {
+	return len(g.Venues) + len(g.Names) + len(g.Addresses)
+}
+
+// Learn records that the event at p has the given coordinates, under each of
+// p's keys. Zero coordinates are ignored. Manual entries are left alone;
+// others move to the mean of their recent events (an event learned again
+// replaces its earlier sighting), and are ambiguous while those events are
+// more than the gazetteer's spread apart.
+func (g *Gazetteer) Learn(p Place, lat, lon float64) {
+	if lat == 0 && lon == 0 {
+		return
+	}
+	s := Sighting{Event: p.Event, Latitude: lat, Longitude: lon}
+	learn(g.Venues, p.VenueID, p.Name, s, g.maxSpreadKm)
+	learn(g.Names, filter.NormalizeWords(p.Name), p.Name, s, g.maxSpreadKm)
+	learn(g.Addresses, filter.NormalizeWords(p.Address), p.Address, s, g.maxSpreadKm)
+}
+
+func learn(entries map[string]*Entry, key, name string, s Sighting, maxSpreadKm float64) {
+	if key == "" {
+		return
+	}
+	e := entries[key]
+	if e == nil {
+		e = &Entry{Name: name}
+		entries[key] = e
+	}
+	if e.Manual {
+		return
+	}
+
+	i := -1
+	if s.Event != "" {
+		i = slices.IndexFunc(e.Events, func(seen Sighting) bool { return seen.Event == s.Event })
+	}
+	if i >= 0 {
+		e.Events = slices.Delete(e.Events, i, i+1)
+	} else {
+		e.Seen++
+	}
+	e.Events = append(e.Events, s)
+	if len(e.Events) > maxSightings {
+		e.Events = e.Events[len(e.Events)-maxSightings:]
+	}
+	e.locate(maxSpreadKm)
+}
+
+// locate places e at the mean of its events and measures their spread.
+func (e *Entry) locate(maxSpreadKm float64) {
+	var lat, lon float64
+	for _, s := range e.Events {
+		lat += s.Latitude
+		lon += s.Longitude
+	}
+	e.Latitude = lat / float64(len(e.Events))
+	e.Longitude = lon / float64(len(e.Events))
+
+	e.SpreadKm = 0
+	for i, a := range e.Events {
+		for _, b := range e.Events[i+1:] {
+			e.SpreadKm = max(e.SpreadKm, filter.HaversineDistance(a.Latitude, a.Longitude, b.Latitude, b.Longitude))
+		}
+	}
+	e.Ambiguous = e.SpreadKm > maxSpreadKm
+}
+
+// Lookup finds p's place: by venue ID, then by venue name, then by address.
+// Ambiguous entries are skipped.
+func (g *Gazetteer) Lookup(p Place) (Match, bool) {
+	lookups := []struct {
+		kind    Kind
+		entries map[string]*Entry
+		key     string
+	}{
+		{ByVenueID, g.Venues, p.VenueID},
+		{ByName, g.Names, filter.NormalizeWords(p.Name)},
+		{ByAddress, g.Addresses, filter.NormalizeWords(p.Address)},
+	}
+	for _, l := range lookups {
+		if l.key == "" {
+			continue
+		}
+		if e := l.entries[l.key]; e != nil && !e.Ambiguous {
+			return Match{Kind: l.kind, Key: l.key, Entry: *e}, true
+		}
+	}
+	return Match{}, false
+}
+
+// Save writes the gazetteer atomically.
+func (g *Gazetteer) Save() error {
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling gazetteer: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(g.path), 0755); err != nil {
+		return fmt.Errorf("creating gazetteer directory: %w", err)
+	}
+
+	// Atomic write: temp file + rename
+	tempPath := g.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("writing gazetteer: %w", err)
+	}
+	if err := os.Rename(tempPath, g.path); err != nil {
+		return fmt.Errorf("renaming gazetteer: %w", err)
+	}
+	return nil
+}
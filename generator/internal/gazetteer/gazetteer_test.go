@@ -0,0 +1,180 @@
+package gazetteer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGazetteer_Lookup(t *testing.T) {
+	g, err := Load(filepath.Join(t.TempDir(), "gazetteer.json"), 0.15)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	g.Learn(Place{VenueID: "esmadrid:78001", Name: "Plaza de España", Address: "Plaza de España, s/n"}, 40.4233, -3.7122)
+	g.Learn(Place{Name: "Teatro Español", Address: "Calle del Príncipe, 25"}, 40.4146, -3.7000)
+	g.Learn(Place{VenueID: "", Name: "Conde Duque"}, 0, 0) // No coordinates: not learned
+
+	tests := []struct {
+		name     string
+		place    Place
+		wantKind Kind
+		wantKey  string
+		wantLat  float64
+		wantOK   bool
+	}{
+		{"venue id", Place{VenueID: "esmadrid:78001", Name: "Otro nombre"}, ByVenueID, "esmadrid:78001", 40.4233, true},
+		{"venue id from another source", Place{VenueID: "datos:78001"}, "", "", 0, false},
+		{"venue name", Place{Name: "TEATRO ESPANOL"}, ByName, "teatro espanol", 40.4146, true},
+		{"address", Place{Name: "Sala 2", Address: "calle del principe 25"}, ByAddress, "calle del principe 25", 40.4146, true},
+		{"unknown venue id falls back to name", Place{VenueID: "esmadrid:1", Name: "Teatro Español"}, ByName, "teatro espanol", 40.4146, true},
+		{"not learned", Place{Name: "Conde Duque"}, "", "", 0, false},
+		{"nothing", Place{}, "", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := g.Lookup(tt.place)
+			if ok != tt.wantOK || m.Kind != tt.wantKind || m.Key != tt.wantKey || m.Entry.Latitude != tt.wantLat {
+				t.Errorf("Lookup(%+v) = %+v, %v, want %s %q at %v, %v", tt.place, m, ok, tt.wantKind, tt.wantKey, tt.wantLat, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGazetteer_Learn(t *testing.T) {
+	g, err := Load(filepath.Join(t.TempDir(), "gazetteer.json"), 0.15)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Nearby events average out
+	g.Learn(Place{Name: "Templo de Debod"}, 40.4240, -3.7178)
+	g.Learn(Place{Name: "Templo de Debod"}, 40.4242, -3.7176)
+	m, ok := g.Lookup(Place{Name: "Templo de Debod"})
+	if !ok || m.Entry.Seen != 2 || math.Abs(m.Entry.Latitude-40.4241) > 1e-9 || math.Abs(m.Entry.Longitude+3.7177) > 1e-9 {
+		t.Errorf("after two nearby events: %+v, %v, want seen 2 at 40.4241, -3.7177", m.Entry, ok)
+	}
+
+	// A name used across town is no place at all
+	g.Learn(Place{Name: "Varios espacios"}, 40.4240, -3.7178)
+	g.Learn(Place{Name: "Varios espacios"}, 40.4531, -3.6883)
+	if m, ok := g.Lookup(Place{Name: "Varios espacios"}); ok {
+		t.Errorf("Lookup(ambiguous name) = %+v, want no match", m)
+	}
+	if e := g.Names["varios espacios"]; e == nil || !e.Ambiguous || e.SpreadKm < 3 {
+		t.Errorf("entry = %+v, want ambiguous with the spread that made it so", e)
+	}
+}
+
+func TestGazetteer_LearnEachEventOnce(t *testing.T) {
+	g, err := Load(filepath.Join(t.TempDir(), "gazetteer.json"), 0.15)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Every build learns the same events again: they count once
+	debod := Place{Name: "Templo de Debod", Event: "esmadrid:1"}
+	for range 5 {
+		g.Learn(debod, 40.4240, -3.7178)
+	}
+	g.Learn(Place{Name: "Templo de Debod", Event: "esmadrid:2"}, 40.4242, -3.7176)
+	e := g.Names["templo de debod"]
+	if e.Seen != 2 || len(e.Events) != 2 || math.Abs(e.Latitude-40.4241) > 1e-9 {
+		t.Errorf("entry = %+v, want seen 2 at 40.4241", e)
+	}
+
+	// An event whose coordinate is wrong makes its place ambiguous...
+	g.Learn(Place{Name: "Templo de Debod", Event: "datos:3"}, 40.4531, -3.6883)
+	if m, ok := g.Lookup(Place{Name: "Templo de Debod"}); ok {
+		t.Errorf("Lookup(with a wrong coordinate) = %+v, want no match", m)
+	}
+
+	// ...until it is corrected upstream
+	g.Learn(Place{Name: "Templo de Debod", Event: "datos:3"}, 40.4241, -3.7177)
+	m, ok := g.Lookup(Place{Name: "Templo de Debod"})
+	if !ok || m.Entry.Seen != 3 || m.Entry.Ambiguous || math.Abs(m.Entry.Latitude-40.4241) > 1e-9 {
+		t.Errorf("after the correction: %+v, %v, want seen 3 at 40.4241", m.Entry, ok)
+	}
+
+	// ...or ages out behind newer events that agree
+	g.Learn(Place{Name: "Templo de Debod", Event: "datos:4"}, 40.4531, -3.6883)
+	if _, ok := g.Lookup(Place{Name: "Templo de Debod"}); ok {
+		t.Errorf("Lookup(with another wrong coordinate) = ok, want no match")
+	}
+	for i := range maxSightings {
+		g.Learn(Place{Name: "Templo de Debod", Event: fmt.Sprintf("esmadrid:%d", 100+i)}, 40.4241, -3.7177)
+	}
+	if m, ok := g.Lookup(Place{Name: "Templo de Debod"}); !ok || len(m.Entry.Events) != maxSightings || m.Entry.Seen != 4+maxSightings {
+		t.Errorf("after %d agreeing events: %+v, %v, want a match from the last of them", maxSightings, m.Entry, ok)
+	}
+}
+
+func TestGazetteer_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "gazetteer.json")
+
+	// Entries written by hand: names and addresses as people write them
+	manual := `{
+  "venues": {"datos:1234": {"latitude": 40.4270, "longitude": -3.7110, "manual": true}},
+  "names": {
+    "Centro Cultural Conde Duque": {"latitude": 40.4273, "longitude": -3.7108, "manual": true},
+    "centro cultural conde duque": {"latitude": 40.5, "longitude": -3.6, "seen": 3}
+  }
+}`
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(manual), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := Load(path, 0.15)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	m, ok := g.Lookup(Place{Name: "CENTRO CULTURAL CONDE DUQUE"})
+	if !ok || !m.Entry.Manual || m.Entry.Latitude != 40.4273 || m.Entry.Name != "Centro Cultural Conde Duque" {
+		t.Errorf("Lookup(manual name) = %+v, %v, want the manual entry", m, ok)
+	}
+
+	// Learning leaves manual entries alone
+	g.Learn(Place{VenueID: "datos:1234"}, 40.4000, -3.7000)
+	g.Learn(Place{VenueID: "datos:99"}, 40.4100, -3.7100)
+	if e := g.Venues["datos:1234"]; e.Latitude != 40.4270 || e.Seen != 0 {
+		t.Errorf("manual entry after learning = %+v, want unchanged", e)
+	}
+	if err := g.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path, 0.15)
+	if err != nil {
+		t.Fatalf("Load after Save: %v", err)
+	}
+	if loaded.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", loaded.Len())
+	}
+	if m, ok := loaded.Lookup(Place{VenueID: "datos:99"}); !ok || m.Entry.Seen != 1 || m.Entry.Manual {
+		t.Errorf("Lookup(learned venue) = %+v, %v, want a learned entry", m, ok)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gazetteer.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, 0.15); err == nil {
+		t.Error("Load(invalid JSON) succeeded, want error")
+	}
+}
+
+func TestVenueKey(t *testing.T) {
+	if got := VenueKey("esmadrid", "78001"); got != "esmadrid:78001" {
+		t.Errorf("VenueKey = %q, want %q", got, "esmadrid:78001")
+	}
+	if got := VenueKey("datos", ""); got != "" {
+		t.Errorf("VenueKey(empty id) = %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,155 @@
+// Package guard keeps a build whose event counts collapse from publishing.
+//
+// A truncated upstream feed can still parse, and filtering it yields a
+// perfectly valid, nearly empty site. The publish guard compares a build's
+// counts (events fetched per source, events kept per pipeline) against the
+// median of recent published builds, remembered in a history file in the data
+// directory, and reports the counts that fell too far.
+package guard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Counts maps a count's name (e.g. "datos.madrid.es fetched") to its value.
+type Counts map[string]int
+
+// Build is the counts of one published build.
+type Build struct {
+	Time   time.Time `json:"time"`
+	Counts Counts    `json:"counts"`
+}
+
+// History is the counts of recent published builds, oldest first.
+type History struct {
+	path   string
+	Builds []Build `json:"builds"`
+}
+
+// LoadHistory loads the history at path. A missing file is an empty history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return nil, fmt.Errorf("reading publish history: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("parsing publish history: %w", err)
+	}
+	return h, nil
+}
+
+// Add appends a published build, keeping only the newest keep builds.
+func (h *History) Add(build Build, keep int) {
+	h.Builds = append(h.Builds, build)
+	if keep > 0 && len(h.Builds) > keep {
+		h.Builds = h.Builds[len(h.Builds)-keep:]
+	}
+}
+
+// Save writes the history atomically.
+func (h *History) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling publish history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("creating publish history directory: %w", err)
+	}
+
+	// Atomic write: temp file + rename
+	tempPath := h.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("writing publish history: %w", err)
+	}
+	if err := os.Rename(tempPath, h.path); err != nil {
+		return fmt.Errorf("renaming publish history: %w", err)
+	}
+	return nil
+}
+
+// Policy is when a count counts as a collapse.
+type Policy struct {
+	MaxDrop     float64 // Fraction a count may fall below its baseline (0.5: half)
+	MinBaseline int     // Smaller baselines are only checked for a drop to zero
+	MinHistory  int     // Builds with the count needed before it is checked
+}
+
+// Check is the outcome of checking one count.
+type Check struct {
+	Name      string
+	Count     int
+	Baseline  int     // Median over the history; -1 if too little history to check
+	Drop      float64 // Fraction of the baseline lost (negative for a rise)
+	Anomalous bool
+}
+
+// String describes the check, e.g. "esmadrid.com fetched: 0 (baseline 412, -100%)".
+func (c Check) String() string {
+	if c.Baseline < 0 {
+		return fmt.Sprintf("%s: %d (not enough history)", c.Name, c.Count)
+	}
+	return fmt.Sprintf("%s: %d (baseline %d, %+.0f%%)", c.Name, c.Count, c.Baseline, -c.Drop*100)
+}
+
+// Check compares counts with the history under policy p, returning one check
+// per count, sorted by name. A count is anomalous when it is below its
+// baseline by more than p.MaxDrop, or zero while its baseline isn't.
+func (h *History) Check(counts Counts, p Policy) []Check {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]Check, 0, len(names))
+	for _, name := range names {
+		c := Check{Name: name, Count: counts[name], Baseline: -1}
+		var past []int
+		for _, b := range h.Builds {
+			if n, ok := b.Counts[name]; ok {
+				past = append(past, n)
+			}
+		}
+		if len(past) >= p.MinHistory && len(past) > 0 {
+			c.Baseline = median(past)
+		}
+		if c.Baseline > 0 {
+			c.Drop = 1 - float64(c.Count)/float64(c.Baseline)
+			switch {
+			case c.Count == 0:
+				c.Anomalous = true
+			case c.Baseline >= p.MinBaseline && c.Drop > p.MaxDrop:
+				c.Anomalous = true
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// Anomalies returns the anomalous checks.
+func Anomalies(checks []Check) []Check {
+	var anomalous []Check
+	for _, c := range checks {
+		if c.Anomalous {
+			anomalous = append(anomalous, c)
+		}
+	}
+	return anomalous
+}
+
+// median returns the median of values (the lower middle one for an even count).
+func median(values []int) int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted[(len(sorted)-1)/2]
+}
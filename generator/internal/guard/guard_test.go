@@ -0,0 +1,96 @@
+package guard
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory_Check(t *testing.T) {
+	history := &History{Builds: []Build{
+		{Counts: Counts{"datos.madrid.es fetched": 1000, "esmadrid.com fetched": 400, "city kept": 4, "cultural kept": 30}},
+		{Counts: Counts{"datos.madrid.es fetched": 1100, "esmadrid.com fetched": 410, "city kept": 5, "cultural kept": 32}},
+		{Counts: Counts{"datos.madrid.es fetched": 950, "esmadrid.com fetched": 390, "city kept": 3, "cultural kept": 28}},
+	}}
+	policy := Policy{MaxDrop: 0.5, MinBaseline: 10, MinHistory: 3}
+
+	tests := []struct {
+		name          string
+		counts        Counts
+		wantAnomalies []string
+	}{
+		{"normal", Counts{"datos.madrid.es fetched": 980, "esmadrid.com fetched": 400, "city kept": 4, "cultural kept": 29}, nil},
+		{"rise", Counts{"datos.madrid.es fetched": 3000}, nil},
+		{"drop within limit", Counts{"datos.madrid.es fetched": 600}, nil},
+		{"truncated feed", Counts{"datos.madrid.es fetched": 120, "cultural kept": 2}, []string{"cultural kept", "datos.madrid.es fetched"}},
+		{"zero city events", Counts{"esmadrid.com fetched": 0, "city kept": 0}, []string{"city kept", "esmadrid.com fetched"}},
+		{"small baseline drop not checked", Counts{"city kept": 1}, nil},
+		{"new count", Counts{"example.org fetched": 0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range Anomalies(history.Check(tt.counts, policy)) {
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.wantAnomalies) {
+				t.Errorf("anomalies = %v, want %v", got, tt.wantAnomalies)
+			}
+		})
+	}
+}
+
+func TestHistory_Check_Baseline(t *testing.T) {
+	history := &History{Builds: []Build{
+		{Counts: Counts{"cultural kept": 30}},
+		{Counts: Counts{"cultural kept": 2}}, // An outlier doesn't move the median
+		{Counts: Counts{"cultural kept": 32}},
+	}}
+
+	checks := history.Check(Counts{"cultural kept": 15}, Policy{MaxDrop: 0.4, MinBaseline: 10, MinHistory: 3})
+	if len(checks) != 1 {
+		t.Fatalf("checks = %+v, want one", checks)
+	}
+	c := checks[0]
+	if c.Baseline != 30 || c.Drop != 0.5 || !c.Anomalous {
+		t.Errorf("check = %+v, want baseline 30, drop 0.5, anomalous", c)
+	}
+	if got, want := c.String(), "cultural kept: 15 (baseline 30, -50%)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	// Too little history: nothing is judged
+	checks = history.Check(Counts{"cultural kept": 0}, Policy{MaxDrop: 0.4, MinHistory: 4})
+	if checks[0].Baseline != -1 || checks[0].Anomalous {
+		t.Errorf("check = %+v, want no baseline with 3 of 4 builds", checks[0])
+	}
+}
+
+func TestHistory_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "publish-history.json")
+
+	history, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory(missing) failed: %v", err)
+	}
+	if len(history.Builds) != 0 {
+		t.Fatalf("missing history has %d builds, want 0", len(history.Builds))
+	}
+
+	start := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		history.Add(Build{Time: start.Add(time.Duration(i) * time.Hour), Counts: Counts{"city kept": i}}, 3)
+	}
+	if err := history.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory failed: %v", err)
+	}
+	if len(loaded.Builds) != 3 || loaded.Builds[0].Counts["city kept"] != 2 || !loaded.Builds[2].Time.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("loaded %+v, want the newest 3 builds", loaded.Builds)
+	}
+}
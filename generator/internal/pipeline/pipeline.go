@@ -3,20 +3,21 @@ package pipeline
 import (
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/fetch"
 )
 
-// Pipeline coordinates sequential data source fetching with respectful delays.
+// Pipeline coordinates data source fetching with respectful delays.
 type Pipeline struct {
 	jsonURL   string
 	xmlURL    string
 	csvURL    string
 	client    *fetch.Client
 	loc       *time.Location
-	minDelay  time.Duration // Minimum delay between format fetches
+	minDelay  time.Duration // Minimum delay between requests (enforced by the client)
 	fetchMode string        // For logging ("production" or "development")
 }
 
@@ -43,49 +44,55 @@ type PipelineResult struct {
 	JSONErrors []event.ParseError
 	XMLErrors  []event.ParseError
 	CSVErrors  []event.ParseError
+
+	// Set per format when it was served from stale cache (stale-if-error)
+	JSONStale *event.StaleData
+	XMLStale  *event.StaleData
+	CSVStale  *event.StaleData
 }
 
-// FetchAll fetches from all three sources sequentially with respectful delays.
+// FetchAll fetches from all three sources concurrently.
 // Each source is isolated - errors in one don't affect others.
-// Delays between formats prevent overwhelming upstream servers.
+// All three formats live on datos.madrid.es, so the client's RequestThrottle
+// serializes them and spaces them by the mode's MinDelay.
 func (p *Pipeline) FetchAll() PipelineResult {
-	var result PipelineResult
+	var (
+		result                           PipelineResult
+		jsonResult, xmlResult, csvResult event.ParseResult
+		wg                               sync.WaitGroup
+	)
+
+	log.Printf("[Pipeline] Fetching JSON, XML and CSV from datos.madrid.es (min delay %v between requests)...", p.minDelay)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		jsonResult = p.fetchJSONIsolated()
+	}()
+	go func() {
+		defer wg.Done()
+		xmlResult = p.fetchXMLIsolated()
+	}()
+	go func() {
+		defer wg.Done()
+		csvResult = p.fetchCSVIsolated()
+	}()
+	wg.Wait()
 
-	// Fetch JSON (isolated - errors captured, don't crash)
-	log.Printf("[Pipeline] Fetching JSON from datos.madrid.es...")
-	result.JSONEvents, result.JSONErrors = p.fetchJSONIsolated()
+	result.JSONEvents, result.JSONErrors, result.JSONStale = jsonResult.Events, jsonResult.Errors, jsonResult.Stale
 	log.Printf("[Pipeline] JSON: %d events, %d errors", len(result.JSONEvents), len(result.JSONErrors))
-
-	// Wait before next format (respectful to upstream)
-	if p.minDelay > 0 {
-		log.Printf("[Pipeline] Waiting %v before fetching next format (respectful delay)...", p.minDelay)
-		time.Sleep(p.minDelay)
-	}
-
-	// Fetch XML (isolated - JSON failure doesn't prevent this)
-	log.Printf("[Pipeline] Fetching XML from datos.madrid.es...")
-	result.XMLEvents, result.XMLErrors = p.fetchXMLIsolated()
+	result.XMLEvents, result.XMLErrors, result.XMLStale = xmlResult.Events, xmlResult.Errors, xmlResult.Stale
 	log.Printf("[Pipeline] XML: %d events, %d errors", len(result.XMLEvents), len(result.XMLErrors))
-
-	// Wait before next format (respectful to upstream)
-	if p.minDelay > 0 {
-		log.Printf("[Pipeline] Waiting %v before fetching next format (respectful delay)...", p.minDelay)
-		time.Sleep(p.minDelay)
-	}
-
-	// Fetch CSV (isolated - previous failures don't prevent this)
-	log.Printf("[Pipeline] Fetching CSV from datos.madrid.es...")
-	result.CSVEvents, result.CSVErrors = p.fetchCSVIsolated()
+	result.CSVEvents, result.CSVErrors, result.CSVStale = csvResult.Events, csvResult.Errors, csvResult.Stale
 	log.Printf("[Pipeline] CSV: %d events, %d errors", len(result.CSVEvents), len(result.CSVErrors))
 
 	return result
 }
 
 // fetchJSONIsolated fetches JSON with panic recovery.
-func (p *Pipeline) fetchJSONIsolated() (events []event.SourcedEvent, errors []event.ParseError) {
+func (p *Pipeline) fetchJSONIsolated() (result event.ParseResult) {
 	defer func() {
 		if r := recover(); r != nil {
-			errors = append(errors, event.ParseError{
+			result.Errors = append(result.Errors, event.ParseError{
 				Source:      "JSON",
 				Error:       fmt.Errorf("JSON fetch panic: %v", r),
 				RecoverType: "skipped",
@@ -93,15 +100,14 @@ func (p *Pipeline) fetchJSONIsolated() (events []event.SourcedEvent, errors []ev
 		}
 	}()
 
-	result := p.client.FetchJSON(p.jsonURL, p.loc)
-	return result.Events, result.Errors
+	return p.client.FetchJSON(p.jsonURL, p.loc)
 }
 
 // fetchXMLIsolated fetches XML with panic recovery.
-func (p *Pipeline) fetchXMLIsolated() (events []event.SourcedEvent, errors []event.ParseError) {
+func (p *Pipeline) fetchXMLIsolated() (result event.ParseResult) {
 	defer func() {
 		if r := recover(); r != nil {
-			errors = append(errors, event.ParseError{
+			result.Errors = append(result.Errors, event.ParseError{
 				Source:      "XML",
 				Error:       fmt.Errorf("XML fetch panic: %v", r),
 				RecoverType: "skipped",
@@ -109,15 +115,14 @@ func (p *Pipeline) fetchXMLIsolated() (events []event.SourcedEvent, errors []eve
 		}
 	}()
 
-	result := p.client.FetchXML(p.xmlURL, p.loc)
-	return result.Events, result.Errors
+	return p.client.FetchXML(p.xmlURL, p.loc)
 }
 
 // fetchCSVIsolated fetches CSV with panic recovery.
-func (p *Pipeline) fetchCSVIsolated() (events []event.SourcedEvent, errors []event.ParseError) {
+func (p *Pipeline) fetchCSVIsolated() (result event.ParseResult) {
 	defer func() {
 		if r := recover(); r != nil {
-			errors = append(errors, event.ParseError{
+			result.Errors = append(result.Errors, event.ParseError{
 				Source:      "CSV",
 				Error:       fmt.Errorf("CSV fetch panic: %v", r),
 				RecoverType: "skipped",
@@ -125,8 +130,7 @@ func (p *Pipeline) fetchCSVIsolated() (events []event.SourcedEvent, errors []eve
 		}
 	}()
 
-	result := p.client.FetchCSV(p.csvURL, p.loc)
-	return result.Events, result.Errors
+	return p.client.FetchCSV(p.csvURL, p.loc)
 }
 
 // Merge combines events from all sources and deduplicates.
@@ -140,16 +144,27 @@ func (p *Pipeline) Merge(result PipelineResult) []event.CulturalEvent {
 
 	// Deduplicate by ID, tracking sources
 	seen := make(map[string]*event.CulturalEvent)
+	namesFromJSON := make(map[string]bool) // Event ID -> Distrito/Barrio are still JSON's slug forms
 
 	for _, sourced := range all {
 		if existing, found := seen[sourced.Event.ID]; found {
 			// Event already exists, add this source
 			existing.Sources = append(existing.Sources, sourced.Source)
 
-			// Merge distrito if the new source has it but existing doesn't
-			if existing.Distrito == "" && sourced.Event.Distrito != "" {
+			// Merge distrito if the new source has it but existing doesn't.
+			// JSON only has district and barrio names as URI slugs, which lose
+			// accents ("NINO JESUS" for "NIÑO JESÚS"), so XML/CSV's spelling
+			// replaces them.
+			replaceNames := namesFromJSON[existing.ID] && sourced.Source != "JSON"
+			if sourced.Event.Distrito != "" && (existing.Distrito == "" || replaceNames) {
 				existing.Distrito = sourced.Event.Distrito
 			}
+			if sourced.Event.Barrio != "" && (existing.Barrio == "" || replaceNames) {
+				existing.Barrio = sourced.Event.Barrio
+			}
+			if replaceNames && (sourced.Event.Distrito != "" || sourced.Event.Barrio != "") {
+				namesFromJSON[existing.ID] = false
+			}
 
 			// Merge other missing fields as needed
 			if existing.VenueName == "" && sourced.Event.VenueName != "" {
@@ -167,10 +182,38 @@ func (p *Pipeline) Merge(result PipelineResult) []event.CulturalEvent {
 			if existing.Longitude == 0 && sourced.Event.Longitude != 0 {
 				existing.Longitude = sourced.Event.Longitude
 			}
+			if existing.UTMX == 0 && existing.UTMY == 0 {
+				existing.UTMX, existing.UTMY = sourced.Event.UTMX, sourced.Event.UTMY
+			}
+
+			// Venue and attendance details. Free is taken from the first
+			// source: every format carries GRATUITO, so there's nothing to fill.
+			if existing.PostalCode == "" && sourced.Event.PostalCode != "" {
+				existing.PostalCode = sourced.Event.PostalCode
+			}
+			if existing.VenueID == "" && sourced.Event.VenueID != "" {
+				existing.VenueID = sourced.Event.VenueID
+			}
+			if existing.Price == "" && sourced.Event.Price != "" {
+				existing.Price = sourced.Event.Price
+			}
+			if existing.Type == "" && sourced.Event.Type != "" {
+				existing.Type = sourced.Event.Type
+			}
+			if len(existing.Audience) == 0 && len(sourced.Event.Audience) > 0 {
+				existing.Audience = sourced.Event.Audience
+			}
+			if len(existing.Accessibility) == 0 && len(sourced.Event.Accessibility) > 0 {
+				existing.Accessibility = sourced.Event.Accessibility
+			}
+			if existing.Recurrence == nil && sourced.Event.Recurrence != nil {
+				existing.Recurrence = sourced.Event.Recurrence
+			}
 		} else {
 			// New event
 			evt := sourced.Event
 			seen[evt.ID] = &evt
+			namesFromJSON[evt.ID] = sourced.Source == "JSON"
 		}
 	}
 
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/fetch"
 )
 
@@ -42,7 +43,7 @@ func getFixturePath(t *testing.T, filename string) string {
 }
 
 func TestPipeline_FetchAll_Sequential(t *testing.T) {
-	// This test uses real fixtures to verify sequential fetching works
+	// This test uses real fixtures to verify fetching all three formats works
 	loc, err := time.LoadLocation("Europe/Madrid")
 	if err != nil {
 		t.Fatalf("loading timezone: %v", err)
@@ -333,3 +334,57 @@ func TestPipeline_Merge_DeduplicatesSources(t *testing.T) {
 
 	t.Logf("Verified %d events have no duplicate sources", len(merged))
 }
+
+func TestPipeline_Merge_ReconcilesDetails(t *testing.T) {
+	p := &Pipeline{}
+	result := PipelineResult{
+		JSONEvents: []event.SourcedEvent{{Source: "JSON", Event: event.CulturalEvent{
+			ID:       "1",
+			Distrito: "CHAMARTIN", // URI slug forms
+			Barrio:   "NINO JESUS",
+			Free:     true,
+			Audience: []string{"Niños"},
+			Type:     "TeatroPerformance",
+		}}},
+		XMLEvents: []event.SourcedEvent{{Source: "XML", Event: event.CulturalEvent{
+			ID:            "1",
+			Distrito:      "CHAMARTÍN",
+			Barrio:        "NIÑO JESÚS",
+			VenueID:       "4703422",
+			Free:          true,
+			Audience:      []string{"Familias"},
+			Accessibility: []string{"1", "6"},
+		}}},
+		CSVEvents: []event.SourcedEvent{{Source: "CSV", Event: event.CulturalEvent{
+			ID:         "1",
+			Barrio:     "NIÑO JESUS",
+			PostalCode: "28025",
+			Price:      "3 euros",
+		}}},
+	}
+
+	merged := p.Merge(result)
+	if len(merged) != 1 {
+		t.Fatalf("len(merged) = %d, want 1", len(merged))
+	}
+	got := merged[0]
+
+	if got.Distrito != "CHAMARTÍN" || got.Barrio != "NIÑO JESÚS" {
+		t.Errorf("Distrito, Barrio = %q, %q; want XML spelling %q, %q", got.Distrito, got.Barrio, "CHAMARTÍN", "NIÑO JESÚS")
+	}
+	if got.VenueID != "4703422" || got.PostalCode != "28025" || got.Price != "3 euros" {
+		t.Errorf("VenueID, PostalCode, Price = %q, %q, %q; want filled from later sources", got.VenueID, got.PostalCode, got.Price)
+	}
+	if !got.Free {
+		t.Error("Free = false, want true (from first source)")
+	}
+	if len(got.Audience) != 1 || got.Audience[0] != "Niños" {
+		t.Errorf("Audience = %q, want first source's [Niños]", got.Audience)
+	}
+	if len(got.Accessibility) != 2 {
+		t.Errorf("Accessibility = %q, want [1 6] from XML", got.Accessibility)
+	}
+	if got.Type != "TeatroPerformance" {
+		t.Errorf("Type = %q, want %q", got.Type, "TeatroPerformance")
+	}
+}
@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scheduler runs fetch tasks concurrently and records when each ran.
+// It does no throttling itself: tasks share a fetch.Client, whose
+// RequestThrottle serializes and spaces requests to the same host while
+// different hosts proceed in parallel. The build's fetch phase therefore takes
+// as long as its slowest host (the critical path), not the sum of all hosts.
+type Scheduler struct {
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	tasks []TaskTiming
+}
+
+// TaskTiming records when one scheduled task ran.
+type TaskTiming struct {
+	Name  string // E.g. "datos.madrid.es" or "weather"
+	Host  string // Upstream host the task fetches from (tasks on one host share a critical path)
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns how long the task ran.
+func (t TaskTiming) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
+// NewScheduler creates an empty scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Go runs fn in its own goroutine as the task name, fetching from host.
+func (s *Scheduler) Go(name, host string, fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		timing := TaskTiming{Name: name, Host: host, Start: time.Now()}
+		defer func() {
+			timing.End = time.Now()
+			s.mu.Lock()
+			s.tasks = append(s.tasks, timing)
+			s.mu.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// Wait blocks until every task has finished and returns their timings.
+func (s *Scheduler) Wait() Schedule {
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]TaskTiming, len(s.tasks))
+	copy(tasks, s.tasks)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].Start.Before(tasks[j].Start)
+	})
+	return Schedule{Tasks: tasks}
+}
+
+// Schedule is the timing of a completed set of scheduled tasks, ordered by start time.
+type Schedule struct {
+	Tasks []TaskTiming
+}
+
+// Wall returns the time from the first task starting to the last one finishing.
+func (s Schedule) Wall() time.Duration {
+	if len(s.Tasks) == 0 {
+		return 0
+	}
+	start, end := s.Tasks[0].Start, s.Tasks[0].End
+	for _, t := range s.Tasks[1:] {
+		if t.End.After(end) {
+			end = t.End
+		}
+	}
+	return end.Sub(start)
+}
+
+// Serial returns the sum of task durations: roughly what running the tasks one
+// after another would have taken.
+func (s Schedule) Serial() time.Duration {
+	var total time.Duration
+	for _, t := range s.Tasks {
+		total += t.Duration()
+	}
+	return total
+}
+
+// CriticalPath returns the host whose tasks finished last and the time its
+// tasks spanned (first start to last end). Speeding up any other host would
+// not shorten the fetch phase.
+func (s Schedule) CriticalPath() (host string, span time.Duration) {
+	if len(s.Tasks) == 0 {
+		return "", 0
+	}
+
+	last := s.Tasks[0]
+	for _, t := range s.Tasks[1:] {
+		if t.End.After(last.End) {
+			last = t
+		}
+	}
+
+	start, end := last.Start, last.End
+	for _, t := range s.Tasks {
+		if t.Host != last.Host {
+			continue
+		}
+		if t.Start.Before(start) {
+			start = t.Start
+		}
+		if t.End.After(end) {
+			end = t.End
+		}
+	}
+	return last.Host, end.Sub(start)
+}
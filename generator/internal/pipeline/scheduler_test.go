@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduler_RunsTasksConcurrently(t *testing.T) {
+	scheduler := NewScheduler()
+	ran := make(map[string]bool)
+	results := make(chan string, 2)
+
+	// Each task waits for the other to start: run one after the other, they
+	// would never finish
+	var started sync.WaitGroup
+	started.Add(2)
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	for _, name := range []string{"a", "b"} {
+		scheduler.Go(name, name+".example.com", func() {
+			started.Done()
+			select {
+			case <-allStarted:
+			case <-time.After(5 * time.Second):
+			}
+			results <- name
+		})
+	}
+
+	schedule := scheduler.Wait()
+	close(results)
+	for name := range results {
+		ran[name] = true
+	}
+
+	if !ran["a"] || !ran["b"] || len(schedule.Tasks) != 2 {
+		t.Fatalf("ran = %v with %d timings, want both tasks", ran, len(schedule.Tasks))
+	}
+	select {
+	case <-allStarted:
+	default:
+		t.Fatal("tasks did not run concurrently")
+	}
+	a, b := schedule.Tasks[0], schedule.Tasks[1]
+	if !a.Start.Before(b.End) || !b.Start.Before(a.End) {
+		t.Errorf("timings %+v and %+v don't overlap", a, b)
+	}
+	if schedule.Serial() < schedule.Wall() {
+		t.Errorf("Serial() = %v, want at least Wall() = %v for overlapping tasks", schedule.Serial(), schedule.Wall())
+	}
+}
+
+func TestSchedule_CriticalPath(t *testing.T) {
+	base := time.Date(2025, 10, 20, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return base.Add(d) }
+
+	tests := []struct {
+		name     string
+		tasks    []TaskTiming
+		wantHost string
+		wantSpan time.Duration
+	}{
+		{
+			name:     "empty",
+			wantHost: "",
+			wantSpan: 0,
+		},
+		{
+			name: "host finishing last",
+			tasks: []TaskTiming{
+				{Name: "datos", Host: "datos.madrid.es", Start: at(0), End: at(4 * time.Second)},
+				{Name: "esmadrid", Host: "esmadrid.com", Start: at(0), End: at(time.Second)},
+				{Name: "weather", Host: "opendata.aemet.es", Start: at(0), End: at(2 * time.Second)},
+			},
+			wantHost: "datos.madrid.es",
+			wantSpan: 4 * time.Second,
+		},
+		{
+			name: "tasks on one host form one chain",
+			tasks: []TaskTiming{
+				{Name: "first", Host: "shared.example.com", Start: at(0), End: at(2 * time.Second)},
+				{Name: "other", Host: "other.example.com", Start: at(0), End: at(3 * time.Second)},
+				{Name: "second", Host: "shared.example.com", Start: at(time.Second), End: at(5 * time.Second)},
+			},
+			wantHost: "shared.example.com",
+			wantSpan: 5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, span := Schedule{Tasks: tt.tasks}.CriticalPath()
+			if host != tt.wantHost || span != tt.wantSpan {
+				t.Errorf("CriticalPath() = (%q, %v), want (%q, %v)", host, span, tt.wantHost, tt.wantSpan)
+			}
+		})
+	}
+}
@@ -17,10 +17,64 @@ type TimeGroup struct {
 	CityCount int // Count of city events (visible by default)
 
 	// Distance-filtered counts (for dynamic count display)
-	CountPlaza  int // Events at Plaza de España (En Plaza filter)
-	CountNearby int // All nearby events (default filter, same as len(Events))
-	CityPlaza   int // City events at Plaza
-	CityNearby  int // All city events (same as CityCount)
+	CountNearby int         // All nearby events (same as len(Events))
+	CityNearby  int         // All city events (same as CityCount)
+	Zones       []ZoneCount // Events per zone (zone filters), in config order
+
+	shown map[string]bool // Events already in the group (see add)
+}
+
+// ZoneCount counts events in one zone (see filter.Zone).
+type ZoneCount struct {
+	ID   string
+	Name string
+	All  int // Events in the zone
+	City int // City events in the zone
+}
+
+// zoneCounts returns empty counts for zones.
+func zoneCounts(zones []filter.Zone) []ZoneCount {
+	counts := make([]ZoneCount, len(zones))
+	for i, z := range zones {
+		counts[i] = ZoneCount{ID: z.ID, Name: z.Name}
+	}
+	return counts
+}
+
+// ZoneTotals sums the groups' zone counts (the groups must share their zones).
+func ZoneTotals(groups ...TimeGroup) []ZoneCount {
+	var totals []ZoneCount
+	for _, g := range groups {
+		if totals == nil {
+			totals = make([]ZoneCount, len(g.Zones))
+			copy(totals, g.Zones)
+			continue
+		}
+		for i, zc := range g.Zones {
+			totals[i].All += zc.All
+			totals[i].City += zc.City
+		}
+	}
+	return totals
+}
+
+// add appends evt to the group and updates its counts, unless the group
+// already shows the event (a recurring event has an entry per session).
+func (g *TimeGroup) add(evt TemplateEvent, isCityEvent bool) {
+	key := evt.EventType + "/" + evt.IDEvento
+	if g.shown[key] {
+		return
+	}
+	if g.shown == nil {
+		g.shown = make(map[string]bool)
+	}
+	g.shown[key] = true
+
+	g.Events = append(g.Events, evt)
+	g.incrementDistanceCounts(evt, isCityEvent)
+	if isCityEvent {
+		g.CityCount++
+	}
 }
 
 // incrementDistanceCounts updates the distance-filtered counts for a time group.
@@ -31,11 +85,13 @@ func (g *TimeGroup) incrementDistanceCounts(evt TemplateEvent, isCityEvent bool)
 		g.CityNearby++
 	}
 
-	// Only events at Plaza de España count toward "En Plaza"
-	if evt.AtPlaza {
-		g.CountPlaza++
-		if isCityEvent {
-			g.CityPlaza++
+	// Events in a zone count toward its filter
+	for i := range g.Zones {
+		if evt.Zone != "" && g.Zones[i].ID == evt.Zone {
+			g.Zones[i].All++
+			if isCityEvent {
+				g.Zones[i].City++
+			}
 		}
 	}
 }
@@ -56,16 +112,18 @@ type GroupedTemplateData struct {
 	OngoingCityCount    int // Count of city events in ongoing section
 
 	// Distance-filtered counts for ongoing events
-	OngoingPlaza      int
 	OngoingNearby     int
-	OngoingCityPlaza  int
 	OngoingCityNearby int
+	OngoingZones      []ZoneCount
 
 	// Total distance-filtered counts (for filter button labels)
-	TotalPlaza      int // All events at Plaza de España
-	TotalNearby     int // All events (same as TotalEvents)
-	TotalCityPlaza  int // City events at Plaza
-	TotalCityNearby int // All city events (same as TotalCityEvents)
+	TotalNearby     int         // All events (same as TotalEvents)
+	TotalCityNearby int         // All city events (same as TotalCityEvents)
+	Zones           []ZoneCount // One filter button per zone, the first selected by default
+	ZonesCSSHash    string      // Hash of the zone filters' stylesheet (see WriteZoneCSS)
+
+	// Sources whose fetch failed and whose last good snapshot is shown instead
+	SnapshotNotices []SnapshotNotice
 }
 
 // GroupEventsByTime groups events into time-based buckets relative to now.
@@ -184,6 +242,10 @@ func GroupEventsByTime(events []event.CulturalEvent, now time.Time) (groups []Ti
 			ContentURL:        evt.DetailsURL,
 			Description:       TruncateText(evt.Description, 150),
 			EventType:         "cultural", // Default for this function
+			Free:              evt.Free,
+			Price:             TruncateText(evt.Price, 60), // Plain text (city prices can be HTML)
+			Audience:          AudienceLabels(evt.Audience),
+			Category:          ActivityTypeLabel(evt.Type),
 		}
 
 		// Classify: ongoing events (5+ days) go to separate section
@@ -266,55 +328,43 @@ func GroupCityEventsByTime(events []event.CityEvent, now time.Time) (groups []Ti
 	return GroupEventsByTime(culturalEvents, now)
 }
 
+// ongoingDaysPerWeek is how often a recurring event must happen to stay a
+// single "ongoing" entry: near-daily runs would repeat in every time group.
+const ongoingDaysPerWeek = 5
+
+// sessionsToGroup returns the entries to place in time groups for evt. A
+// recurring event that isn't near-daily becomes one copy per session in
+// [from, to), with StartTime and EndTime set to the session; anything else is
+// grouped by its whole StartTime..EndTime span as before.
+func sessionsToGroup(evt event.CulturalEvent, from, to time.Time) []event.CulturalEvent {
+	if evt.Recurrence == nil || evt.Recurrence.DaysPerWeek() >= ongoingDaysPerWeek {
+		return []event.CulturalEvent{evt}
+	}
+
+	var sessions []event.CulturalEvent
+	for _, occ := range evt.Occurrences(from, to) {
+		session := evt
+		session.StartTime, session.EndTime = occ.Start, occ.End
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
 // GroupMixedEventsByTime groups both city and cultural events into time-based buckets.
 // Events are merged and sorted chronologically (city events first on ties).
-// Cultural events are marked with EventType="cultural" for CSS filtering.
-// Returns groups, ongoing events, and counts for ongoing section.
-// Calculates and formats distance from reference point (typically Plaza de España).
+// Cultural events are marked with EventType="cultural" for CSS filtering, except
+// those merged with their city duplicate (see dedupe), which show like city events.
+// Returns groups and the ongoing events (5+ days) as a group of their own.
+// Calculates and formats distance from reference point (typically Plaza de España)
+// and places each event in its zone, if any (see filter.ZoneOf).
 // If weatherMap is provided, enriches events with weather forecasts.
-func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event.CulturalEvent, now time.Time, refLat, refLon float64, weatherMap map[string]*Weather) (groups []TimeGroup, ongoing []TemplateEvent, ongoingCityCount, ongoingPlaza, ongoingNearby, ongoingCityPlaza, ongoingCityNearby int) {
+func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event.CulturalEvent, now time.Time, refLat, refLon float64, zones []filter.Zone, weatherMap map[string]*Weather) (groups []TimeGroup, ongoing TimeGroup) {
 	// Convert both types to a common internal type with metadata
 	type eventWithType struct {
 		evt       event.CulturalEvent
 		eventType string // "city" or "cultural"
 	}
 
-	// Combine both event lists
-	allEvents := make([]eventWithType, 0, len(cityEvents)+len(culturalEvents))
-
-	for _, evt := range cityEvents {
-		allEvents = append(allEvents, eventWithType{
-			evt: event.CulturalEvent{
-				ID:          evt.ID,
-				Title:       evt.Title,
-				Description: evt.Description,
-				StartTime:   evt.StartDate,
-				EndTime:     evt.EndDate,
-				Latitude:    evt.Latitude,
-				Longitude:   evt.Longitude,
-				VenueName:   evt.Venue,
-				DetailsURL:  evt.WebURL,
-			},
-			eventType: "city",
-		})
-	}
-
-	for _, evt := range culturalEvents {
-		allEvents = append(allEvents, eventWithType{
-			evt:       evt,
-			eventType: "cultural",
-		})
-	}
-
-	// Sort: chronological order, city first on ties
-	sort.Slice(allEvents, func(i, j int) bool {
-		if allEvents[i].evt.StartTime.Equal(allEvents[j].evt.StartTime) {
-			// Tie: city events come first
-			return allEvents[i].eventType == "city" && allEvents[j].eventType == "cultural"
-		}
-		return allEvents[i].evt.StartTime.Before(allEvents[j].evt.StartTime)
-	})
-
 	// Use the same time grouping logic
 	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	endOfToday := startOfToday.Add(24 * time.Hour)
@@ -349,6 +399,51 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 	futureLimit := now.AddDate(0, 0, 30)
 	oldEventCutoff := now.AddDate(0, 0, -60)
 
+	// Combine both event lists
+	allEvents := make([]eventWithType, 0, len(cityEvents)+len(culturalEvents))
+
+	for _, evt := range cityEvents {
+		allEvents = append(allEvents, eventWithType{
+			evt: event.CulturalEvent{
+				ID:          evt.ID,
+				Title:       evt.Title,
+				Description: evt.Description,
+				StartTime:   evt.StartDate,
+				EndTime:     evt.EndDate,
+				Latitude:    evt.Latitude,
+				Longitude:   evt.Longitude,
+				VenueName:   evt.Venue,
+				DetailsURL:  evt.WebURL,
+				Price:       evt.Price,
+			},
+			eventType: "city",
+		})
+	}
+
+	for _, evt := range culturalEvents {
+		// Events both feeds list show like city events, whatever the cultural toggle
+		eventType := "cultural"
+		if len(evt.Listings) > 0 {
+			eventType = "city"
+		}
+		// Recurring events are placed on the days they happen (see sessionsToGroup)
+		for _, session := range sessionsToGroup(evt, pastWeekendStart, futureLimit) {
+			allEvents = append(allEvents, eventWithType{
+				evt:       session,
+				eventType: eventType,
+			})
+		}
+	}
+
+	// Sort: chronological order, city first on ties
+	sort.Slice(allEvents, func(i, j int) bool {
+		if allEvents[i].evt.StartTime.Equal(allEvents[j].evt.StartTime) {
+			// Tie: city events come first
+			return allEvents[i].eventType == "city" && allEvents[j].eventType == "cultural"
+		}
+		return allEvents[i].evt.StartTime.Before(allEvents[j].evt.StartTime)
+	})
+
 	// Initialize groups
 	// Icons from Bootstrap Icons (https://icons.getbootstrap.com/)
 	// Licensed under MIT License - Copyright (c) 2019-2024 The Bootstrap Authors
@@ -358,8 +453,10 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 	thisWeekend := TimeGroup{Name: "This Weekend", Icon: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16" fill="#7c3aed" aria-hidden="true"><path d="M3.612 15.443c-.386.198-.824-.149-.746-.592l.83-4.73L.173 6.765c-.329-.314-.158-.888.283-.95l4.898-.696L7.538.792c.197-.39.73-.39.927 0l2.184 4.327 4.898.696c.441.062.612.636.282.95l-3.522 3.356.83 4.73c.078.443-.36.79-.746.592L8 13.187l-4.389 2.256z"/></svg>`, Events: []TemplateEvent{}}
 	thisWeek := TimeGroup{Name: "This Week", Icon: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16" fill="#3b82f6" aria-hidden="true"><path d="M3.5 0a.5.5 0 0 1 .5.5V1h8V.5a.5.5 0 0 1 1 0V1h1a2 2 0 0 1 2 2v11a2 2 0 0 1-2 2H2a2 2 0 0 1-2-2V3a2 2 0 0 1 2-2h1V.5a.5.5 0 0 1 .5-.5zM2 2a1 1 0 0 0-1 1v1h14V3a1 1 0 0 0-1-1H2zm13 3H1v9a1 1 0 0 0 1 1h12a1 1 0 0 0 1-1V5z"/><path d="M11 7.5a.5.5 0 0 1 .5-.5h1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-1a.5.5 0 0 1-.5-.5v-1zm-3 0a.5.5 0 0 1 .5-.5h1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-1a.5.5 0 0 1-.5-.5v-1zm-2 3a.5.5 0 0 1 .5-.5h1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-1a.5.5 0 0 1-.5-.5v-1zm-3 0a.5.5 0 0 1 .5-.5h1a.5.5 0 0 1 .5.5v1a.5.5 0 0 1-.5.5h-1a.5.5 0 0 1-.5-.5v-1z"/></svg>`, Events: []TemplateEvent{}}
 	laterThisMonth := TimeGroup{Name: "Later This Month", Icon: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 16 16" fill="#10b981" aria-hidden="true"><path d="M4 .5a.5.5 0 0 0-1 0V1H2a2 2 0 0 0-2 2v1h16V3a2 2 0 0 0-2-2h-1V.5a.5.5 0 0 0-1 0V1H4V.5zM16 14V5H0v9a2 2 0 0 0 2 2h12a2 2 0 0 0 2-2zm-5.146-5.146-3 3a.5.5 0 0 1-.708 0l-1.5-1.5a.5.5 0 0 1 .708-.708L7.5 10.793l2.646-2.647a.5.5 0 0 1 .708.708z"/></svg>`, Events: []TemplateEvent{}}
-	ongoingEvents := []TemplateEvent{}
-	ongoingCityCount = 0
+	ongoing = TimeGroup{Name: "Ongoing", Events: []TemplateEvent{}}
+	for _, g := range []*TimeGroup{&pastWeekend, &happeningNow, &thisWeekend, &thisWeek, &laterThisMonth, &ongoing} {
+		g.Zones = zoneCounts(zones)
+	}
 
 	// Group events
 	for _, ewt := range allEvents {
@@ -386,22 +483,16 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 		// Calculate distance from reference point (only for valid coordinates)
 		var distanceStr string
 		var distanceMeters int
-		var atPlaza bool
 		if evt.Latitude != 0 || evt.Longitude != 0 {
 			distanceKm := filter.HaversineDistance(refLat, refLon, evt.Latitude, evt.Longitude)
 			distanceStr = FormatDistance(distanceKm)
 			distanceMeters = int(distanceKm * 1000) // Convert to meters
+		}
 
-			// Check if event is at Plaza de España (distance ~0m OR text mentions it)
-			if distanceMeters <= 50 || filter.MatchesPlazaEspana(evt.Title, evt.VenueName, evt.Address, evt.Description) {
-				atPlaza = true
-			}
-		} else {
-			// No coordinates - check all text fields for Plaza de España mentions
-			atPlaza = filter.MatchesPlazaEspana(evt.Title, evt.VenueName, evt.Address, evt.Description)
-			if atPlaza {
-				distanceMeters = 0 // Treat as 0 meters if venue name matches
-			}
+		// Zone the event is in (by coordinates, else by text), for the zone filters
+		var zoneID string
+		if z := filter.ZoneOf(zones, evt.Latitude, evt.Longitude, evt.Title, evt.VenueName, evt.Address, evt.Description); z != nil {
+			zoneID = z.ID
 		}
 
 		// Convert to template event
@@ -416,8 +507,13 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 			EventType:         ewt.eventType,
 			DistanceHuman:     distanceStr,
 			DistanceMeters:    distanceMeters,
-			AtPlaza:           atPlaza,
+			Zone:              zoneID,
 			Weather:           nil, // Will be set below if weatherMap provided
+			Free:              evt.Free,
+			Price:             TruncateText(evt.Price, 60), // Plain text (city prices can be HTML)
+			Audience:          AudienceLabels(evt.Audience),
+			Category:          ActivityTypeLabel(evt.Type),
+			Listings:          evt.Listings,
 		}
 
 		// Add weather forecast if available
@@ -428,75 +524,39 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 			}
 		}
 
+		isCityEvent := ewt.eventType == "city"
+
 		// Ongoing events (5+ days)
 		if duration >= 5*24*time.Hour {
-			ongoingEvents = append(ongoingEvents, templateEvt)
-			isCityEvent := ewt.eventType == "city"
-			if isCityEvent {
-				ongoingCityCount++
-			}
-
-			// Track distance-filtered counts for ongoing events
-			ongoingNearby++
-			if isCityEvent {
-				ongoingCityNearby++
-			}
-			if templateEvt.AtPlaza {
-				ongoingPlaza++
-				if isCityEvent {
-					ongoingCityPlaza++
-				}
-			}
-
+			ongoing.add(templateEvt, isCityEvent)
 			continue
 		}
 
 		// Assign to time groups
 		added := false
-		isCityEvent := ewt.eventType == "city"
 
 		if evt.StartTime.Before(pastWeekendEnd) && endTime.After(pastWeekendStart) {
-			pastWeekend.Events = append(pastWeekend.Events, templateEvt)
-			pastWeekend.incrementDistanceCounts(templateEvt, isCityEvent)
-			if isCityEvent {
-				pastWeekend.CityCount++
-			}
+			pastWeekend.add(templateEvt, isCityEvent)
 			added = true
 		}
 
 		if evt.StartTime.Before(endOfToday) && endTime.After(startOfToday) {
-			happeningNow.Events = append(happeningNow.Events, templateEvt)
-			happeningNow.incrementDistanceCounts(templateEvt, isCityEvent)
-			if isCityEvent {
-				happeningNow.CityCount++
-			}
+			happeningNow.add(templateEvt, isCityEvent)
 			added = true
 		}
 
 		if evt.StartTime.Before(thisWeekendEnd) && evt.StartTime.After(thisWeekendStart) {
-			thisWeekend.Events = append(thisWeekend.Events, templateEvt)
-			thisWeekend.incrementDistanceCounts(templateEvt, isCityEvent)
-			if isCityEvent {
-				thisWeekend.CityCount++
-			}
+			thisWeekend.add(templateEvt, isCityEvent)
 			added = true
 		}
 
 		if !added && evt.StartTime.Before(thisWeekEnd) && evt.StartTime.After(endOfToday) {
-			thisWeek.Events = append(thisWeek.Events, templateEvt)
-			thisWeek.incrementDistanceCounts(templateEvt, isCityEvent)
-			if isCityEvent {
-				thisWeek.CityCount++
-			}
+			thisWeek.add(templateEvt, isCityEvent)
 			added = true
 		}
 
 		if !added && evt.StartTime.Before(endOfMonth) && evt.StartTime.After(thisWeekEnd) {
-			laterThisMonth.Events = append(laterThisMonth.Events, templateEvt)
-			laterThisMonth.incrementDistanceCounts(templateEvt, isCityEvent)
-			if isCityEvent {
-				laterThisMonth.CityCount++
-			}
+			laterThisMonth.add(templateEvt, isCityEvent)
 		}
 	}
 
@@ -518,5 +578,5 @@ func GroupMixedEventsByTime(cityEvents []event.CityEvent, culturalEvents []event
 		groups = append(groups, laterThisMonth)
 	}
 
-	return groups, ongoingEvents, ongoingCityCount, ongoingPlaza, ongoingNearby, ongoingCityPlaza, ongoingCityNearby
+	return groups, ongoing
 }
@@ -0,0 +1,140 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/filter"
+)
+
+func TestGroupMixedEventsByTime_Recurrence(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, madrid) // Wednesday
+
+	// Thursdays 18:00-20:00 for six weeks: 16 Oct is this week, 23 and 30 Oct later this month
+	workshop := event.CulturalEvent{
+		ID:         "weekly",
+		Title:      "Weekly workshop",
+		StartTime:  time.Date(2025, 10, 2, 18, 0, 0, 0, madrid),
+		EndTime:    time.Date(2025, 11, 13, 20, 0, 0, 0, madrid),
+		Recurrence: &event.Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{time.Thursday}},
+	}
+	// Monday to Saturday all month: stays a single ongoing entry
+	exhibition := event.CulturalEvent{
+		ID:        "daily",
+		Title:     "Exhibition",
+		StartTime: time.Date(2025, 10, 1, 10, 0, 0, 0, madrid),
+		EndTime:   time.Date(2025, 10, 31, 20, 0, 0, 0, madrid),
+		Recurrence: &event.Recurrence{Frequency: "WEEKLY", Interval: 1, Weekdays: []time.Weekday{
+			time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}},
+	}
+
+	groups, ongoing := GroupMixedEventsByTime(nil, []event.CulturalEvent{workshop, exhibition}, now, 40.4238, -3.7122, nil, nil)
+
+	if len(ongoing.Events) != 1 || ongoing.Events[0].IDEvento != "daily" || ongoing.CountNearby != 1 {
+		t.Errorf("ongoing = %v (nearby %d), want only the exhibition", ongoing.Events, ongoing.CountNearby)
+	}
+
+	want := map[string]time.Time{
+		"This Week":        time.Date(2025, 10, 16, 18, 0, 0, 0, madrid),
+		"Later This Month": time.Date(2025, 10, 23, 18, 0, 0, 0, madrid),
+	}
+	for _, g := range groups {
+		wantStart, ok := want[g.Name]
+		if !ok {
+			t.Errorf("unexpected group %q with %d events", g.Name, len(g.Events))
+			continue
+		}
+		delete(want, g.Name)
+		if len(g.Events) != 1 || g.CountNearby != 1 {
+			t.Errorf("%s: %d events (CountNearby %d), want the workshop once", g.Name, len(g.Events), g.CountNearby)
+			continue
+		}
+		if evt := g.Events[0]; evt.IDEvento != "weekly" || !evt.StartTime.Equal(wantStart) {
+			t.Errorf("%s: event %s at %v, want weekly at %v", g.Name, evt.IDEvento, evt.StartTime, wantStart)
+		}
+	}
+	for name := range want {
+		t.Errorf("missing group %q", name)
+	}
+}
+
+func TestGroupMixedEventsByTime_Zones(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC) // Wednesday
+	today := now.Add(2 * time.Hour)
+	zones := []filter.Zone{
+		{ID: "plaza", Name: "En Plaza", Latitude: 40.4238, Longitude: -3.7122, RadiusKm: 0.05, Aliases: []string{"plaza de españa"}},
+		{ID: "debod", Name: "Templo de Debod", Areas: []filter.Area{{Name: "Templo de Debod", Polygons: []filter.Polygon{{{
+			{40.4262, -3.7170}, {40.4232, -3.7150}, {40.4222, -3.7165}, {40.4248, -3.7205},
+		}}}}}, Aliases: []string{"templo de debod"}},
+	}
+	city := []event.CityEvent{
+		{ID: "at-plaza", Title: "Concert", StartDate: today, EndDate: today.Add(time.Hour), Latitude: 40.4238, Longitude: -3.7122},
+		{ID: "far", Title: "Market", StartDate: today, EndDate: today.Add(time.Hour), Latitude: 40.4300, Longitude: -3.7000},
+	}
+	cultural := []event.CulturalEvent{
+		{ID: "at-debod", Title: "Talk", StartTime: today, EndTime: today.Add(time.Hour), Latitude: 40.4240, Longitude: -3.7175},
+		{ID: "named", Title: "Walk from PLAZA DE ESPANA", StartTime: today, EndTime: today.Add(time.Hour)},
+		{ID: "debod-show", Title: "Show", VenueName: "Templo de Debod", StartTime: today.AddDate(0, 0, -10), EndTime: today.AddDate(0, 0, 10)},
+	}
+
+	groups, ongoing := GroupMixedEventsByTime(city, cultural, now, 40.4238, -3.7122, zones, nil)
+
+	if len(groups) != 1 || groups[0].Name != "Happening Now / Today" {
+		t.Fatalf("groups = %+v, want only today", groups)
+	}
+	wantZone := map[string]string{"at-plaza": "plaza", "far": "", "at-debod": "debod", "named": "plaza"}
+	for _, evt := range groups[0].Events {
+		if evt.Zone != wantZone[evt.IDEvento] {
+			t.Errorf("%s: Zone = %q, want %q", evt.IDEvento, evt.Zone, wantZone[evt.IDEvento])
+		}
+	}
+	wantCounts := []ZoneCount{{ID: "plaza", Name: "En Plaza", All: 2, City: 1}, {ID: "debod", Name: "Templo de Debod", All: 1}}
+	if !reflect.DeepEqual(groups[0].Zones, wantCounts) {
+		t.Errorf("today's zones = %+v, want %+v", groups[0].Zones, wantCounts)
+	}
+	if len(ongoing.Events) != 1 || ongoing.Events[0].Zone != "debod" {
+		t.Errorf("ongoing = %+v, want the show at Templo de Debod", ongoing.Events)
+	}
+
+	wantTotals := []ZoneCount{{ID: "plaza", Name: "En Plaza", All: 2, City: 1}, {ID: "debod", Name: "Templo de Debod", All: 2}}
+	if got := ZoneTotals(ongoing, groups[0]); !reflect.DeepEqual(got, wantTotals) {
+		t.Errorf("ZoneTotals = %+v, want %+v", got, wantTotals)
+	}
+}
+
+func TestGroupMixedEventsByTime_Listings(t *testing.T) {
+	now := time.Date(2025, 10, 15, 12, 0, 0, 0, time.UTC)
+	today := now.Add(2 * time.Hour)
+	listings := []event.Listing{
+		{Source: "datos.madrid.es", ID: "merged", URL: "https://datos.madrid.es/merged"},
+		{Source: "esmadrid.com", ID: "e1", URL: "https://www.esmadrid.com/e1"},
+	}
+	cultural := []event.CulturalEvent{
+		{ID: "merged", Title: "Concert", StartTime: today, EndTime: today.Add(time.Hour), Listings: listings},
+		{ID: "plain", Title: "Talk", StartTime: today, EndTime: today.Add(time.Hour)},
+	}
+
+	groups, _ := GroupMixedEventsByTime(nil, cultural, now, 40.4238, -3.7122, nil, nil)
+
+	if len(groups) != 1 || len(groups[0].Events) != 2 {
+		t.Fatalf("groups = %+v, want both events today", groups)
+	}
+	for _, evt := range groups[0].Events {
+		switch evt.IDEvento {
+		case "merged":
+			if evt.EventType != "city" || !reflect.DeepEqual(evt.Listings, listings) {
+				t.Errorf("merged event = %+v, want a city event with both listings", evt)
+			}
+		case "plain":
+			if evt.EventType != "cultural" || evt.Listings != nil {
+				t.Errorf("plain event = %+v, want a cultural event without listings", evt)
+			}
+		}
+	}
+}
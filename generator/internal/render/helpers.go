@@ -4,6 +4,7 @@ import (
 	"html"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
@@ -34,3 +35,57 @@ func TruncateText(text string, maxChars int) string {
 
 	return truncated + "…"
 }
+
+// activityTypeLabels names the common datos.madrid.es activity types (TIPO).
+var activityTypeLabels = map[string]string{
+	"ActividadesCalleArteUrbano":             "Actividades en la calle",
+	"ActividadesDeportivas":                  "Deportes",
+	"CineActividadesAudiovisuales":           "Cine",
+	"CircoMagia":                             "Circo y magia",
+	"ClubesLectura":                          "Clubes de lectura",
+	"ConferenciasColoquios":                  "Conferencias",
+	"CuentacuentosTiteresMarionetas":         "Cuentacuentos y títeres",
+	"CursosTalleres":                         "Cursos y talleres",
+	"DanzaBaile":                             "Danza",
+	"ExcursionesItinerariosVisitas":          "Visitas y excursiones",
+	"Exposiciones":                           "Exposiciones",
+	"Fiestas":                                "Fiestas",
+	"Musica":                                 "Música",
+	"ProgramacionDestacadaAgendaCultura":     "Destacado",
+	"RecitalesPresentacionesActosLiterarios": "Actos literarios",
+	"TeatroPerformance":                      "Teatro",
+}
+
+// ActivityTypeLabel returns a display label for an activity type slug,
+// spacing out the CamelCase of types without a label ("CoroGospel" -> "Coro Gospel").
+func ActivityTypeLabel(slug string) string {
+	if label, ok := activityTypeLabels[slug]; ok {
+		return label
+	}
+	var b strings.Builder
+	for i, r := range slug {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// audienceLabels restores the spelling of audience slugs (AUDIENCIA).
+var audienceLabels = map[string]string{
+	"Jovenes":                "Jóvenes",
+	"PropietariosdeAnimales": "Con mascotas",
+}
+
+// AudienceLabels returns display labels for audience slugs.
+func AudienceLabels(slugs []string) []string {
+	var labels []string
+	for _, slug := range slugs {
+		if label, ok := audienceLabels[slug]; ok {
+			slug = label
+		}
+		labels = append(labels, slug)
+	}
+	return labels
+}
@@ -50,3 +50,30 @@ func TestTruncateText(t *testing.T) {
 		})
 	}
 }
+
+func TestActivityTypeLabel(t *testing.T) {
+	tests := []struct {
+		slug string
+		want string
+	}{
+		{"TeatroPerformance", "Teatro"},
+		{"Musica", "Música"},
+		{"CoroGospel", "Coro Gospel"}, // No label: CamelCase spaced out
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ActivityTypeLabel(tt.slug); got != tt.want {
+			t.Errorf("ActivityTypeLabel(%q) = %q, want %q", tt.slug, got, tt.want)
+		}
+	}
+}
+
+func TestAudienceLabels(t *testing.T) {
+	got := AudienceLabels([]string{"Niños", "Jovenes"})
+	if len(got) != 2 || got[0] != "Niños" || got[1] != "Jóvenes" {
+		t.Errorf("AudienceLabels = %q, want [Niños Jóvenes]", got)
+	}
+	if got := AudienceLabels(nil); got != nil {
+		t.Errorf("AudienceLabels(nil) = %q, want nil", got)
+	}
+}
@@ -1,6 +1,10 @@
 package render
 
-import "time"
+import (
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
 
 // TemplateData holds data for HTML template rendering.
 type TemplateData struct {
@@ -22,12 +26,17 @@ type TemplateEvent struct {
 	StartTime         time.Time // For sorting
 	NombreInstalacion string
 	ContentURL        string
-	Description       string   // Truncated description
-	EventType         string   // "city" or "cultural"
-	DistanceHuman     string   // Human-readable distance from Plaza de España (e.g., "250m", "1.2km")
-	DistanceMeters    int      // Distance in meters (for display/debugging)
-	AtPlaza           bool     // True if event is at Plaza de España (for "En Plaza" filter)
-	Weather           *Weather // Weather forecast for event date (nil if unavailable)
+	Description       string          // Truncated description
+	EventType         string          // "city" or "cultural"
+	DistanceHuman     string          // Human-readable distance from Plaza de España (e.g., "250m", "1.2km")
+	DistanceMeters    int             // Distance in meters (for display/debugging)
+	Zone              string          // ID of the zone the event is in, if any (for the zone filters)
+	Weather           *Weather        // Weather forecast for event date (nil if unavailable)
+	Free              bool            // Free entry (GRATUITO)
+	Price             string          // Price text, shown for events that aren't free
+	Audience          []string        // Audience labels (e.g. "Niños", "Familias"); empty = everyone
+	Category          string          // Activity type label (e.g. "Teatro y performance")
+	Listings          []event.Listing // Each feed's listing, for an event both feeds list (credited on its card)
 }
 
 // Weather represents weather information for a specific event date
@@ -44,6 +53,13 @@ type Weather struct {
 	IsNight         bool    // True if code ends with 'n'
 }
 
+// SnapshotNotice tells visitors that a source's data is from an earlier build
+// because its fetch failed.
+type SnapshotNotice struct {
+	Source    string // e.g. "datos.madrid.es (XML)", "AEMET"
+	FetchedAt string // When the snapshot's data was fetched, formatted like LastUpdated
+}
+
 // JSONEvent represents an event in the machine-readable JSON output.
 type JSONEvent struct {
 	ID         string `json:"id"`
@@ -52,6 +68,17 @@ type JSONEvent struct {
 	EndTime    string `json:"end_time,omitempty"`
 	VenueName  string `json:"venue_name,omitempty"`
 	DetailsURL string `json:"details_url,omitempty"`
+
+	Price         string   `json:"price,omitempty"`
+	Free          *bool    `json:"free,omitempty"` // nil when the feed has no free flag (city events)
+	Audience      []string `json:"audience,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Barrio        string   `json:"barrio,omitempty"`
+	PostalCode    string   `json:"postal_code,omitempty"`
+	VenueID       string   `json:"venue_id,omitempty"`
+	Accessibility []string `json:"accessibility,omitempty"` // Venue ACCESIBILIDAD codes as given upstream
+
+	Listings []event.Listing `json:"listings,omitempty"` // Each feed's listing, for an event both feeds list
 }
 
 // JSONOutput is the top-level structure for the JSON API output.
@@ -0,0 +1,65 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ZoneCSS returns the stylesheet for the zone filters: for each zone, the
+// rules site.css has for the "Cerca (todos)" filter, plus hiding the events
+// outside the zone. The page's Content-Security-Policy forbids inline styles,
+// so the rules go in a file of their own (see WriteZoneCSS).
+func ZoneCSS(zones []ZoneCount) string {
+	var b strings.Builder
+	b.WriteString("/* Zone filters, generated from the configured zones. Do not edit. */\n")
+	for _, z := range zones {
+		radio := "#distance-" + z.ID + ":checked"
+		fmt.Fprintf(&b, "\n/* %s */\n", strings.ReplaceAll(z.Name, "*/", ""))
+
+		// Active filter button
+		fmt.Fprintf(&b, "%s ~ header .filters-container .distance-filter label[for=\"distance-%s\"] {\n", radio, z.ID)
+		b.WriteString("  background: var(--accent);\n  border-color: var(--accent);\n  color: var(--bg);\n  font-weight: 600;\n}\n")
+
+		// Only events in the zone are shown
+		fmt.Fprintf(&b, "%s ~ main .event-card:not([data-zone=\"%s\"]) {\n  display: none;\n}\n", radio, z.ID)
+
+		// Section counts: city events only, or all with cultural events shown
+		fmt.Fprintf(&b, "%s ~ main .count-%s-city {\n  display: inline;\n}\n", radio, z.ID)
+		fmt.Fprintf(&b, "#toggle-cultural:checked ~ %s ~ main .count-%s-city {\n  display: none;\n}\n", radio, z.ID)
+		fmt.Fprintf(&b, "#toggle-cultural:checked ~ %s ~ main .count-%s-all {\n  display: inline;\n}\n", radio, z.ID)
+
+		// Sections without events in the zone sink to the bottom
+		fmt.Fprintf(&b, "#toggle-cultural:checked ~ %s ~ main .event-section[data-count-%s=\"0\"] {\n  order: 999;\n}\n", radio, z.ID)
+		fmt.Fprintf(&b, "#toggle-cultural:not(:checked) ~ %s ~ main .event-section[data-count-%s-city=\"0\"] {\n  order: 999;\n}\n", radio, z.ID)
+	}
+	return b.String()
+}
+
+// WriteZoneCSS writes ZoneCSS(zones) to dir as zones.<hash>.css, named by a
+// hash of its content like site.css (see scripts/hash-assets.sh), and returns
+// the hash. Without zones there is no stylesheet and the hash is "".
+func WriteZoneCSS(zones []ZoneCount, dir string) (string, error) {
+	if len(zones) == 0 {
+		return "", nil
+	}
+	css := ZoneCSS(zones)
+	sum := sha256.Sum256([]byte(css))
+	hash := hex.EncodeToString(sum[:])[:8]
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating assets directory: %w", err)
+	}
+	path := filepath.Join(dir, "zones."+hash+".css")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(css), 0644); err != nil {
+		return "", fmt.Errorf("writing zone stylesheet: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("renaming zone stylesheet: %w", err)
+	}
+	return hash, nil
+}
@@ -0,0 +1,46 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteZoneCSS(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "assets")
+	zones := []ZoneCount{{ID: "plaza", Name: "En Plaza"}, {ID: "debod", Name: "Templo de Debod"}}
+
+	hash, err := WriteZoneCSS(zones, dir)
+	if err != nil {
+		t.Fatalf("WriteZoneCSS failed: %v", err)
+	}
+	if len(hash) != 8 {
+		t.Fatalf("hash = %q, want 8 hex digits", hash)
+	}
+	css, err := os.ReadFile(filepath.Join(dir, "zones."+hash+".css"))
+	if err != nil {
+		t.Fatalf("reading stylesheet: %v", err)
+	}
+	for _, want := range []string{
+		`#distance-plaza:checked ~ main .event-card:not([data-zone="plaza"])`,
+		`#distance-debod:checked ~ header .filters-container .distance-filter label[for="distance-debod"]`,
+		`#toggle-cultural:checked ~ #distance-debod:checked ~ main .count-debod-all`,
+		`#toggle-cultural:not(:checked) ~ #distance-plaza:checked ~ main .event-section[data-count-plaza-city="0"]`,
+	} {
+		if !strings.Contains(string(css), want) {
+			t.Errorf("stylesheet is missing %s", want)
+		}
+	}
+
+	// Same zones, same file
+	again, err := WriteZoneCSS(zones, dir)
+	if err != nil || again != hash {
+		t.Errorf("second WriteZoneCSS = %q, %v, want %q", again, err, hash)
+	}
+
+	// No zones, no stylesheet
+	if hash, err := WriteZoneCSS(nil, dir); hash != "" || err != nil {
+		t.Errorf("WriteZoneCSS(nil) = %q, %v, want no stylesheet", hash, err)
+	}
+}
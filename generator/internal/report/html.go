@@ -2,6 +2,7 @@ package report
 
 import (
 	"fmt"
+	"html"
 	"io"
 	"strings"
 	"time"
@@ -69,16 +70,21 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
     </div>
 `)
 
+	// Publish Guard (first when it kept the previous output)
+	if r.Guard != nil {
+		writePublishGuard(&b, r.Guard)
+	}
+
 	// Pipeline Overview
 	b.WriteString(`    <h2>Pipeline Overview</h2>
     <div class="pipeline-grid">
 `)
 
-	// Cultural Pipeline Card
-	b.WriteString(fmt.Sprintf(`      <div class="pipeline-card cultural">
+	for _, p := range r.Pipelines {
+		b.WriteString(fmt.Sprintf(`      <div class="pipeline-card %s">
         <div class="pipeline-header">
           <span class="icon">%s</span>
-          <h3 class="cultural-title">%s</h3>
+          <h3 class="%s-title">%s</h3>
         </div>
         <div class="pipeline-stat">
           <span>Source</span>
@@ -93,156 +99,334 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
           <span>%s</span>
         </div>
       </div>
-`, iconTheater, r.CulturalPipeline.Name, r.CulturalPipeline.Source, r.CulturalPipeline.EventCount, formatDuration(r.CulturalPipeline.Duration)))
-
-	// City Pipeline Card
-	b.WriteString(fmt.Sprintf(`      <div class="pipeline-card city">
-        <div class="pipeline-header">
-          <span class="icon">%s</span>
-          <h3 class="city-title">%s</h3>
-        </div>
-        <div class="pipeline-stat">
-          <span>Source</span>
-          <span>%s</span>
-        </div>
-        <div class="pipeline-stat">
-          <span>Events</span>
-          <span><strong>%d</strong></span>
-        </div>
-        <div class="pipeline-stat">
-          <span>Duration</span>
-          <span>%s</span>
-        </div>
-      </div>
-`, iconCelebrate, r.CityPipeline.Name, r.CityPipeline.Source, r.CityPipeline.EventCount, formatDuration(r.CityPipeline.Duration)))
+`, p.Kind, pipelineIcon(p.Kind), p.Kind, p.Name, p.Source, p.EventCount, formatDuration(p.Duration)))
+	}
 
 	b.WriteString(`    </div>
 `)
 
-	// Cultural Events Pipeline Detailed
-	b.WriteString(fmt.Sprintf(`    <h2 class="cultural-title">%s Cultural Events Pipeline</h2>
-    <div class="section">
-      <h3>%s Data Fetching</h3>
-`, iconTheater, iconBroadcast))
+	// Per-pipeline details
+	for _, p := range r.Pipelines {
+		writePipelineDetails(&b, p)
+	}
 
-	for _, attempt := range r.CulturalPipeline.Fetching.Attempts {
+	// Weather Integration
+	if r.Weather != nil {
+		b.WriteString(`    <h2>⛅ Weather Integration</h2>
+    <div class="section">
+`)
 		statusSymbol := iconSuccess
-		if attempt.Status == "FAILED" {
-			statusSymbol = iconFailed
-		} else if attempt.Status == "SKIPPED" {
-			statusSymbol = iconSkipped
+		if r.Weather.Error != "" {
+			statusSymbol = iconWarning
+		} else if !r.Weather.APIKeyPresent {
+			statusSymbol = iconWarning
 		}
+
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>%s%s</span>
+        <span>%sStatus</span>
         <span>%s</span>
       </div>
-`, statusSymbol, attempt.Source, formatAttempt(attempt)))
-	}
+`, statusSymbol, func() string {
+			if r.Weather.Snapshot {
+				return fmt.Sprintf("Failed: %s (using snapshot from %v ago)", r.Weather.Error, r.Weather.SnapshotAge.Round(time.Minute))
+			}
+			if r.Weather.Error != "" {
+				return "Failed: " + r.Weather.Error
+			}
+			if !r.Weather.APIKeyPresent {
+				return "API key not set"
+			}
+			return "Success"
+		}()))
 
-	if r.CulturalPipeline.Merging != nil {
-		b.WriteString(fmt.Sprintf(`      <h3>%s Deduplication</h3>
-`, iconSync))
-		merge := r.CulturalPipeline.Merging
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Input events</span>
-        <span>%d</span>
+        <span>Municipality</span>
+        <span>%s</span>
       </div>
-      <div class="metric-row">
-        <span>Duplicates removed</span>
-        <span>%d (%.1f%%)</span>
+`, r.Weather.Municipality))
+
+		if r.Weather.DaysCovered > 0 {
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Forecast Days</span>
+        <span>%d days</span>
       </div>
-      <div class="metric-row">
-        <span>Unique events</span>
-        <span>%d</span>
+`, r.Weather.DaysCovered))
+		}
+
+		if r.Weather.EventsMatched > 0 || r.Weather.EventsUnmatched > 0 {
+			total := r.Weather.EventsMatched + r.Weather.EventsUnmatched
+			pct := 0
+			if total > 0 {
+				pct = (r.Weather.EventsMatched * 100) / total
+			}
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Events with Weather</span>
+        <span>%d / %d (%d%%)</span>
       </div>
-`, merge.TotalBeforeMerge, merge.Duplicates, float64(merge.Duplicates)*100.0/float64(merge.TotalBeforeMerge), merge.UniqueEvents))
-	}
+`, r.Weather.EventsMatched, total, pct))
+		}
 
-	// Cultural Filtering
-	if r.CulturalPipeline.Filtering.DistrictoFilter != nil {
-		b.WriteString(fmt.Sprintf(`      <h3>%s Distrito Filtering</h3>
-`, iconMap))
-		df := r.CulturalPipeline.Filtering.DistrictoFilter
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Allowed districts</span>
+        <span>Duration</span>
         <span>%s</span>
       </div>
+`, formatDuration(r.Weather.Duration)))
+
+		b.WriteString(`    </div>
+`)
+	}
+
+	// Upstream Requests
+	if r.Requests != nil {
+		writeRequestSummary(&b, r.Requests)
+	}
+
+	// Fetch Schedule
+	if r.Schedule != nil {
+		writeFetchSchedule(&b, r.Schedule)
+	}
+
+	// Data Quality
+	if len(r.DataQuality) > 0 {
+		writeDataQuality(&b, r.DataQuality)
+	}
+
+	// Output Files
+	b.WriteString(`    <h2>Output Files</h2>
+    <div class="section">
+`)
+	b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>HTML</span>
+        <span class="%s">%s</span>
+      </div>
       <div class="metric-row">
-        <span>Input events</span>
+        <span>JSON</span>
+        <span class="%s">%s</span>
+      </div>
+`, statusClass(r.Output.HTML.Status), r.Output.HTML.Path, statusClass(r.Output.JSON.Status), r.Output.JSON.Path))
+	b.WriteString(`    </div>
+`)
+
+	// Warnings
+	if len(r.Warnings) > 0 {
+		b.WriteString(fmt.Sprintf(`    <div class="warning-box">
+      <h3>%s Warnings</h3>
+      <ul>
+`, iconWarning))
+		for _, warning := range r.Warnings {
+			b.WriteString(fmt.Sprintf("        <li>%s</li>\n", warning))
+		}
+		b.WriteString(`      </ul>
+    </div>
+`)
+	}
+
+	// Footer
+	homeURL := "/"
+	if basePath != "" {
+		homeURL = basePath + "/"
+	}
+	b.WriteString(fmt.Sprintf(`  </main>
+
+  <footer>
+    <p>Generated by Madrid Events Site Generator</p>
+    <p><a href="%s">← Back to events</a></p>
+  </footer>
+</body>
+</html>`, homeURL))
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+// writeRequestSummary writes the upstream request counts and any requests
+// suppressed by the request budget.
+func writeRequestSummary(b *strings.Builder, rr *RequestReport) {
+	b.WriteString(`    <h2>Upstream Requests</h2>
+    <div class="section">
+`)
+	b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Total</span>
         <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Kept in district</span>
+        <span>Network</span>
         <span>%d</span>
       </div>
-`, strings.Join(df.AllowedDistricts, ", "), df.Input, df.Kept))
-	}
-
-	if r.CulturalPipeline.Filtering.GeoFilter != nil {
-		b.WriteString(fmt.Sprintf(`      <h3>%s Geographic Filtering</h3>
-`, iconTarget))
-		gf := r.CulturalPipeline.Filtering.GeoFilter
-		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Reference point</span>
-        <span>%.5f, %.5f</span>
+      <div class="metric-row">
+        <span>Cache Hits</span>
+        <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Radius</span>
-        <span>%.2f km</span>
+        <span>Not Modified (304)</span>
+        <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Input events</span>
+        <span>Retries</span>
         <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Within radius</span>
+        <span>Served Stale</span>
         <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Missing coordinates</span>
-        <span>%d (%.1f%%)</span>
+        <span>HTTP Cache</span>
+        <span>%d entries, %.1f MB (%d evicted)</span>
+      </div>
+`, rr.Total, rr.Network, rr.CacheHits, rr.NotModified, rr.Retries, rr.Stale,
+		rr.CacheEntries, float64(rr.CacheBytes)/1024/1024, rr.CacheEvicted))
+
+	if len(rr.Suppressed) > 0 {
+		b.WriteString(fmt.Sprintf(`      <h3>%s Suppressed by request budget</h3>
+`, iconWarning))
+		for _, s := range rr.Suppressed {
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>%s</span>
+        <span>served from %s</span>
       </div>
-`, gf.RefLat, gf.RefLon, gf.Radius, gf.Input, gf.Kept, gf.MissingCoords, float64(gf.MissingCoords)*100.0/float64(gf.Input)))
+`, s.URL, s.ServedFrom))
+		}
 	}
 
-	if r.CulturalPipeline.Filtering.TimeFilter != nil {
-		b.WriteString(fmt.Sprintf(`      <h3>%s Time Filtering</h3>
-`, iconClock))
-		tf := r.CulturalPipeline.Filtering.TimeFilter
+	b.WriteString(`    </div>
+`)
+}
+
+// writePublishGuard writes the publish guard's verdict and each count it
+// checked against its baseline.
+func writePublishGuard(b *strings.Builder, g *GuardReport) {
+	status := iconSuccess + "Passed"
+	switch g.Status {
+	case "BLOCKED":
+		status = iconFailed + "Blocked: previous index.html and events.json kept"
+	case "FORCED":
+		status = iconWarning + "Blocked, but published with -force-publish"
+	case "SKIPPED":
+		status = iconSkipped + "Skipped"
+	}
+	b.WriteString(fmt.Sprintf(`    <h2>Publish Guard</h2>
+    <div class="section">
+      <div class="metric-row">
+        <span>Status</span>
+        <span>%s</span>
+      </div>
+`, status))
+	if g.Reason != "" {
+		b.WriteString(fmt.Sprintf("      <p class=\"muted\">%s</p>\n", html.EscapeString(g.Reason)))
+	}
+	if g.History > 0 {
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Reference time</span>
+        <span>Compared Against</span>
+        <span>%d published builds</span>
+      </div>
+`, g.History))
+	}
+
+	for _, c := range g.Checks {
+		icon := iconSuccess
+		if c.Anomalous {
+			icon = iconFailed
+		}
+		value := fmt.Sprintf("%d (not enough history)", c.Count)
+		if c.Baseline >= 0 {
+			value = fmt.Sprintf("%d (baseline %d, %+.0f%%)", c.Count, c.Baseline, -c.Drop*100)
+		}
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>%s%s</span>
         <span>%s</span>
       </div>
-      <div class="metric-row">
-        <span>Input events</span>
+`, icon, html.EscapeString(c.Name), value))
+	}
+	b.WriteString(`    </div>
+`)
+}
+
+// writeDataQuality writes the data quality issues found in upstream data,
+// with a few examples of each.
+func writeDataQuality(b *strings.Builder, issues []DataQualityIssue) {
+	b.WriteString(`    <h2>Data Quality</h2>
+    <div class="section">
+`)
+	for _, issue := range issues {
+		icon := iconTag
+		if issue.Severity != "INFO" {
+			icon = iconWarning
+		}
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>%s %s</span>
         <span>%d</span>
       </div>
+      <p class="muted">%s</p>
+`, icon, html.EscapeString(issue.Description), issue.Count, issue.Type))
+		if len(issue.Examples) > 0 {
+			b.WriteString("      <ul>\n")
+			for _, example := range issue.Examples {
+				b.WriteString(fmt.Sprintf("        <li>%s</li>\n", html.EscapeString(example))) // Upstream venue names
+			}
+			b.WriteString("      </ul>\n")
+		}
+		if issue.Recommendation != "" {
+			b.WriteString(fmt.Sprintf("      <p class=\"muted\">%s</p>\n", html.EscapeString(issue.Recommendation)))
+		}
+	}
+	b.WriteString(`    </div>
+`)
+}
+
+// writeFetchSchedule writes the fetch phase timing: wall time against a
+// sequential fetch, the critical path, and when each task ran.
+func writeFetchSchedule(b *strings.Builder, fs *FetchSchedule) {
+	b.WriteString(`    <h2>Fetch Schedule</h2>
+    <div class="section">
+`)
+	b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Wall Time</span>
+        <span>%s</span>
+      </div>
       <div class="metric-row">
-        <span>Past events removed</span>
-        <span>%d</span>
+        <span>Sequential Time</span>
+        <span>%s</span>
       </div>
       <div class="metric-row">
-        <span>Future events kept</span>
-        <span>%d</span>
+        <span>Critical Path</span>
+        <span>%s (%s)</span>
       </div>
-`, tf.ReferenceTime.Format("2006-01-02 15:04"), tf.Input, tf.PastEvents, tf.Kept))
+`, formatDuration(fs.Wall), formatDuration(fs.Serial), fs.CriticalHost, formatDuration(fs.CriticalPath)))
+
+	for _, task := range fs.Tasks {
+		label := task.Name
+		if task.Host != task.Name {
+			label = fmt.Sprintf("%s (%s)", task.Name, task.Host)
+		}
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>%s</span>
+        <span>+%s for %s</span>
+      </div>
+`, label, formatDuration(task.Offset), formatDuration(task.Duration)))
 	}
 
 	b.WriteString(`    </div>
 `)
+}
 
-	// City Events Pipeline Detailed
-	b.WriteString(fmt.Sprintf(`    <h2 class="city-title">%s City Events Pipeline</h2>
+// writePipelineDetails writes the fetch, merge and filter sections for one pipeline.
+// Sections whose stats are nil are omitted.
+func writePipelineDetails(b *strings.Builder, p *PipelineReport) {
+	b.WriteString(fmt.Sprintf(`    <h2 class="%s-title">%s %s Pipeline</h2>
     <div class="section">
       <h3>%s Data Fetching</h3>
-`, iconCelebrate, iconBroadcast))
+`, p.Kind, pipelineIcon(p.Kind), p.Name, iconBroadcast))
 
-	for _, attempt := range r.CityPipeline.Fetching.Attempts {
+	for _, attempt := range p.Fetching.Attempts {
 		statusSymbol := iconSuccess
-		if attempt.Status == "FAILED" {
+		if attempt.Snapshot {
+			statusSymbol = iconWarning
+		} else if attempt.Status == "FAILED" {
 			statusSymbol = iconFailed
 		} else if attempt.Status == "SKIPPED" {
 			statusSymbol = iconSkipped
+		} else if attempt.Stale {
+			statusSymbol = iconWarning
 		}
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
         <span>%s%s</span>
@@ -251,12 +435,50 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
 `, statusSymbol, attempt.Source, formatAttempt(attempt)))
 	}
 
-	// City Filtering
-	if r.CityPipeline.Filtering.GeoFilter != nil {
+	if p.Merging != nil && p.Merging.TotalBeforeMerge > 0 {
+		b.WriteString(fmt.Sprintf(`      <h3>%s Deduplication</h3>
+`, iconSync))
+		merge := p.Merging
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Input events</span>
+        <span>%d</span>
+      </div>
+      <div class="metric-row">
+        <span>Duplicates removed</span>
+        <span>%d (%.1f%%)</span>
+      </div>
+      <div class="metric-row">
+        <span>Unique events</span>
+        <span>%d</span>
+      </div>
+`, merge.TotalBeforeMerge, merge.Duplicates, float64(merge.Duplicates)*100.0/float64(merge.TotalBeforeMerge), merge.UniqueEvents))
+	}
+
+	if p.Filtering.DistrictoFilter != nil {
+		b.WriteString(fmt.Sprintf(`      <h3>%s Distrito Filtering</h3>
+`, iconMap))
+		df := p.Filtering.DistrictoFilter
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Allowed districts</span>
+        <span>%s</span>
+      </div>
+      <div class="metric-row">
+        <span>Input events</span>
+        <span>%d</span>
+      </div>
+      <div class="metric-row">
+        <span>Kept in district</span>
+        <span>%d</span>
+      </div>
+`, strings.Join(df.AllowedDistricts, ", "), df.Input, df.Kept))
+	}
+
+	if p.Filtering.GeoFilter != nil {
 		b.WriteString(fmt.Sprintf(`      <h3>%s Geographic Filtering</h3>
 `, iconTarget))
-		gf := r.CityPipeline.Filtering.GeoFilter
-		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+		gf := p.Filtering.GeoFilter
+		if gf.Radius > 0 {
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
         <span>Reference point</span>
         <span>%.5f, %.5f</span>
       </div>
@@ -264,25 +486,41 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
         <span>Radius</span>
         <span>%.2f km</span>
       </div>
-      <div class="metric-row">
+`, gf.RefLat, gf.RefLon, gf.Radius))
+		}
+		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
         <span>Input events</span>
         <span>%d</span>
       </div>
       <div class="metric-row">
-        <span>Within radius</span>
+        <span>Kept</span>
         <span>%d</span>
       </div>
       <div class="metric-row">
         <span>Missing coordinates</span>
         <span>%d (%.1f%%)</span>
       </div>
-`, gf.RefLat, gf.RefLon, gf.Radius, gf.Input, gf.Kept, gf.MissingCoords, float64(gf.MissingCoords)*100.0/float64(gf.Input)))
+`, gf.Input, gf.Kept, gf.MissingCoords, percent(gf.MissingCoords, gf.Input)))
+		if gf.OutsideArea > 0 {
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>Outside geofence</span>
+        <span>%d</span>
+      </div>
+`, gf.OutsideArea))
+		}
+		for _, area := range gf.Areas {
+			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
+        <span>In %s</span>
+        <span>%d (%d kept)</span>
+      </div>
+`, html.EscapeString(area.Name), area.Events, area.Kept))
+		}
 	}
 
-	if r.CityPipeline.Filtering.CategoryFilter != nil {
+	if p.Filtering.CategoryFilter != nil {
 		b.WriteString(fmt.Sprintf(`      <h3>%s Category Filtering</h3>
 `, iconTag))
-		cf := r.CityPipeline.Filtering.CategoryFilter
+		cf := p.Filtering.CategoryFilter
 		if len(cf.AllowedCategories) > 0 {
 			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
         <span>Allowed categories</span>
@@ -307,10 +545,10 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
 `, cf.Input, cf.Kept))
 	}
 
-	if r.CityPipeline.Filtering.TimeFilter != nil {
+	if p.Filtering.TimeFilter != nil {
 		b.WriteString(fmt.Sprintf(`      <h3>%s Time Filtering</h3>
 `, iconClock))
-		tf := r.CityPipeline.Filtering.TimeFilter
+		tf := p.Filtering.TimeFilter
 		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
         <span>Reference time</span>
         <span>%s</span>
@@ -332,116 +570,22 @@ func (r *BuildReport) WriteHTML(w io.Writer, cssHash string, basePath string) er
 
 	b.WriteString(`    </div>
 `)
+}
 
-	// Weather Integration
-	if r.Weather != nil {
-		b.WriteString(`    <h2>⛅ Weather Integration</h2>
-    <div class="section">
-`)
-		statusSymbol := iconSuccess
-		if r.Weather.Error != "" {
-			statusSymbol = iconWarning
-		} else if !r.Weather.APIKeyPresent {
-			statusSymbol = iconWarning
-		}
-
-		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>%sStatus</span>
-        <span>%s</span>
-      </div>
-`, statusSymbol, func() string {
-			if r.Weather.Error != "" {
-				return "Failed: " + r.Weather.Error
-			}
-			if !r.Weather.APIKeyPresent {
-				return "API key not set"
-			}
-			return "Success"
-		}()))
-
-		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Municipality</span>
-        <span>%s</span>
-      </div>
-`, r.Weather.Municipality))
-
-		if r.Weather.DaysCovered > 0 {
-			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Forecast Days</span>
-        <span>%d days</span>
-      </div>
-`, r.Weather.DaysCovered))
-		}
-
-		if r.Weather.EventsMatched > 0 || r.Weather.EventsUnmatched > 0 {
-			total := r.Weather.EventsMatched + r.Weather.EventsUnmatched
-			pct := 0
-			if total > 0 {
-				pct = (r.Weather.EventsMatched * 100) / total
-			}
-			b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Events with Weather</span>
-        <span>%d / %d (%d%%)</span>
-      </div>
-`, r.Weather.EventsMatched, total, pct))
-		}
-
-		b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>Duration</span>
-        <span>%s</span>
-      </div>
-`, formatDuration(r.Weather.Duration)))
-
-		b.WriteString(`    </div>
-`)
-	}
-
-	// Output Files
-	b.WriteString(`    <h2>Output Files</h2>
-    <div class="section">
-`)
-	b.WriteString(fmt.Sprintf(`      <div class="metric-row">
-        <span>HTML</span>
-        <span class="%s">%s</span>
-      </div>
-      <div class="metric-row">
-        <span>JSON</span>
-        <span class="%s">%s</span>
-      </div>
-`, statusClass(r.Output.HTML.Status), r.Output.HTML.Path, statusClass(r.Output.JSON.Status), r.Output.JSON.Path))
-	b.WriteString(`    </div>
-`)
-
-	// Warnings
-	if len(r.Warnings) > 0 {
-		b.WriteString(fmt.Sprintf(`    <div class="warning-box">
-      <h3>%s Warnings</h3>
-      <ul>
-`, iconWarning))
-		for _, warning := range r.Warnings {
-			b.WriteString(fmt.Sprintf("        <li>%s</li>\n", warning))
-		}
-		b.WriteString(`      </ul>
-    </div>
-`)
+// pipelineIcon returns the emoji for a pipeline kind.
+func pipelineIcon(kind string) string {
+	if kind == "city" {
+		return iconCelebrate
 	}
+	return iconTheater
+}
 
-	// Footer
-	homeURL := "/"
-	if basePath != "" {
-		homeURL = basePath + "/"
+// percent returns part as a percentage of total (0 when total is 0).
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
 	}
-	b.WriteString(fmt.Sprintf(`  </main>
-
-  <footer>
-    <p>Generated by Madrid Events Site Generator</p>
-    <p><a href="%s">← Back to events</a></p>
-  </footer>
-</body>
-</html>`, homeURL))
-
-	_, err := w.Write([]byte(b.String()))
-	return err
+	return float64(part) * 100.0 / float64(total)
 }
 
 // formatDuration formats a duration for display.
@@ -465,11 +609,21 @@ func statusClass(status string) string {
 
 // formatAttempt formats a fetch attempt for display.
 func formatAttempt(a FetchAttempt) string {
+	if a.Status == "SUCCESS" && a.Stale {
+		return fmt.Sprintf("%d events (%s, stale cache from %s ago: %s)",
+			a.EventCount, formatDuration(a.Duration), formatDuration(a.StaleAge), a.Error)
+	}
 	if a.Status == "SUCCESS" {
 		return fmt.Sprintf("%d events (%s)", a.EventCount, formatDuration(a.Duration))
 	}
+	if a.Snapshot && a.Status == "SKIPPED" {
+		return fmt.Sprintf("Skipped (%s); using %d events from snapshot (%v old)", a.Error, a.EventCount, a.SnapshotAge.Round(time.Minute))
+	}
 	if a.Status == "SKIPPED" {
 		return "Skipped"
 	}
+	if a.Snapshot {
+		return fmt.Sprintf("Failed: %s; using %d events from snapshot (%v old)", a.Error, a.EventCount, a.SnapshotAge.Round(time.Minute))
+	}
 	return fmt.Sprintf("Failed: %s", a.Error)
 }
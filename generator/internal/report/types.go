@@ -11,15 +11,23 @@ type BuildReport struct {
 	Duration   time.Duration
 	ExitStatus string // "SUCCESS", "FAILED", "PARTIAL"
 
-	// Dual pipeline tracking
-	CulturalPipeline PipelineReport
-	CityPipeline     PipelineReport
+	// One pipeline per registered source, in run order
+	Pipelines []*PipelineReport
 
-	TotalEvents int // Sum of both pipelines
+	TotalEvents int // Sum of all pipelines
 
 	// Weather integration
 	Weather *WeatherReport
 
+	// Upstream request summary (from the fetch client's audit trail)
+	Requests *RequestReport
+
+	// Fetch phase timing (sources and weather run concurrently, one chain per host)
+	Schedule *FetchSchedule
+
+	// Publish guard verdict on the build's event counts
+	Guard *GuardReport
+
 	DataQuality []DataQualityIssue
 	Output      OutputReport
 
@@ -27,10 +35,11 @@ type BuildReport struct {
 	Recommendations []string
 }
 
-// PipelineReport tracks a single data pipeline (cultural or city events).
+// PipelineReport tracks a single data pipeline (one registered source).
 type PipelineReport struct {
 	Name       string // "Cultural Events" or "City Events"
 	Source     string // "datos.madrid.es" or "esmadrid.com"
+	Kind       string // "cultural" or "city" (selects icon and styling)
 	Fetching   PipelineFetchReport
 	Merging    *MergeStats // Only for cultural events (3 sources)
 	Filtering  PipelineFilterReport
@@ -63,6 +72,10 @@ type FetchAttempt struct {
 	ContentType string
 	Size        int64
 	EventCount  int
+	Stale       bool          // Served from expired cache after an upstream failure
+	StaleAge    time.Duration // Stale only: age of the cached body when served
+	Snapshot    bool          // FAILED, and substituted by the source's last good snapshot
+	SnapshotAge time.Duration // Snapshot only: how long ago the snapshot's data was fetched
 }
 
 // MergeStats tracks multi-source merging and deduplication (cultural events only).
@@ -91,11 +104,20 @@ type GeoFilterStats struct {
 	Input          int
 	MissingCoords  int
 	OutsideRadius  int
+	OutsideArea    int `json:"outside_area,omitempty"` // Outside the polygon rules' areas
 	Kept           int
-	MultiVenueKept int `json:"multi_venue_kept,omitempty"` // City events only: kept via Plaza de España text match
+	MultiVenueKept int         `json:"multi_venue_kept,omitempty"` // Kept by a text rule (e.g. a Plaza de España mention)
+	Areas          []AreaStats `json:"areas,omitempty"`            // Per geofence area
 	Duration       time.Duration
 }
 
+// AreaStats counts the events inside one geofence area.
+type AreaStats struct {
+	Name   string
+	Events int // Inside the area
+	Kept   int // Inside the area and kept
+}
+
 // TimeFilterStats tracks time-based filtering.
 type TimeFilterStats struct {
 	ReferenceTime time.Time
@@ -161,9 +183,70 @@ type WeatherReport struct {
 	CacheHit        bool
 	APIKeyPresent   bool
 	Error           string
+	Snapshot        bool          // The fetch failed (Error) and the last good forecast was used
+	SnapshotAge     time.Duration // Snapshot only: how long ago that forecast was fetched
 	Duration        time.Duration
 }
 
+// RequestReport summarizes upstream requests made (or avoided) during the build.
+type RequestReport struct {
+	Total       int // All fetches through the client, including cache hits
+	Network     int // Requests that reached the upstream server
+	CacheHits   int // Served from fresh cache without a request
+	NotModified int // Expired cache entries revalidated with a 304
+	Retries     int // Attempts after the first for the same request (see fetch.RetryPolicy)
+	Stale       int // Expired cache served after an upstream failure (stale-if-error)
+	Suppressed  []SuppressedRequest
+
+	// HTTP cache after pruning ([fetch.cache])
+	CacheEntries int
+	CacheBytes   int64
+	CacheEvicted int // Entries removed by age or to fit the size limit
+}
+
+// FetchSchedule times the concurrent fetch phase. Hosts are fetched in
+// parallel and requests to one host are serialized, so the phase lasts as long
+// as the critical path: the host whose tasks finished last.
+type FetchSchedule struct {
+	Wall         time.Duration // First task start to last task end
+	Serial       time.Duration // Sum of task durations (a sequential fetch phase)
+	CriticalHost string
+	CriticalPath time.Duration // Span of CriticalHost's tasks
+	Tasks        []ScheduledTask
+}
+
+// ScheduledTask is one fetch task in the schedule, e.g. a source or the weather forecast.
+type ScheduledTask struct {
+	Name     string
+	Host     string
+	Offset   time.Duration // Start relative to the first task
+	Duration time.Duration
+}
+
+// SuppressedRequest is a request blocked by the persisted request budget.
+type SuppressedRequest struct {
+	URL        string
+	ServedFrom string // "cache" or "none" (caller fell back, e.g. to a snapshot)
+}
+
+// GuardReport records the publish guard's check of the build's event counts
+// against recent published builds.
+type GuardReport struct {
+	Status  string // "PASSED", "BLOCKED" (previous output kept), "FORCED" (blocked but published) or "SKIPPED"
+	Reason  string // Why the guard blocked, or was skipped
+	History int    // Published builds the counts were compared against
+	Checks  []GuardCheck
+}
+
+// GuardCheck is one event count checked by the publish guard.
+type GuardCheck struct {
+	Name      string // e.g. "datos.madrid.es fetched", "cultural kept"
+	Count     int
+	Baseline  int     // Median over the history; -1 if too little history to check
+	Drop      float64 // Fraction of the baseline lost
+	Anomalous bool
+}
+
 // NewBuildReport creates a new report initialized with defaults.
 func NewBuildReport() *BuildReport {
 	return &BuildReport{
@@ -172,6 +255,13 @@ func NewBuildReport() *BuildReport {
 	}
 }
 
+// AddPipeline appends an empty pipeline report and returns it for filling in.
+func (r *BuildReport) AddPipeline() *PipelineReport {
+	p := &PipelineReport{}
+	r.Pipelines = append(r.Pipelines, p)
+	return p
+}
+
 // AddWarning adds a warning message to the report.
 func (r *BuildReport) AddWarning(format string, args ...interface{}) {
 	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
@@ -0,0 +1,215 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// generationsDir is the directory, within the per-source snapshots directory,
+// of snapshot history: one subdirectory per build.
+const generationsDir = "generations"
+
+// generationIDFormat names a generation after its build time (UTC).
+const generationIDFormat = "20060102T150405Z"
+
+// Generation is the set of snapshots one build saved, completed with the
+// latest snapshots of the sources it saved none of (see CompleteGeneration).
+type Generation struct {
+	ID      string    // Build time, e.g. "20251020T120000Z"
+	Time    time.Time // Build time
+	Sources []string  // Names of the snapshots it holds, sorted
+}
+
+// generationDir returns the directory of generation id.
+func (m *Manager) generationDir(id string) string {
+	return filepath.Join(m.dataDir, sourcesDir, generationsDir, id)
+}
+
+// Generations returns the snapshot history, newest first.
+func (m *Manager) Generations() ([]Generation, error) {
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, sourcesDir, generationsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshot generations: %w", err)
+	}
+
+	var generations []Generation
+	for _, entry := range entries {
+		t, err := time.Parse(generationIDFormat, entry.Name())
+		if !entry.IsDir() || err != nil {
+			continue // Not a generation
+		}
+		gen := Generation{ID: entry.Name(), Time: t}
+		files, err := os.ReadDir(m.generationDir(gen.ID))
+		if err != nil {
+			return nil, fmt.Errorf("listing snapshot generation %s: %w", gen.ID, err)
+		}
+		for _, f := range files {
+			if name, ok := strings.CutSuffix(f.Name(), ".json"); ok && !f.IsDir() {
+				gen.Sources = append(gen.Sources, name)
+			}
+		}
+		generations = append(generations, gen)
+	}
+
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Time.After(generations[j].Time)
+	})
+	return generations, nil
+}
+
+// Generation returns generation id.
+func (m *Manager) Generation(id string) (Generation, error) {
+	generations, err := m.Generations()
+	if err != nil {
+		return Generation{}, err
+	}
+	for _, gen := range generations {
+		if gen.ID == id {
+			return gen, nil
+		}
+	}
+	return Generation{}, fmt.Errorf("no snapshot generation %q", id)
+}
+
+// CompleteGeneration copies into the build's generation the latest snapshot of
+// every source the build saved none of (it failed, or its snapshot stood in
+// for it), so that pinning or restoring the generation doesn't drop a source.
+// The copies keep their own fetch time and build. Does nothing if the build
+// saved no snapshots (or m is pinned). Returns the names copied.
+func (m *Manager) CompleteGeneration() ([]string, error) {
+	if m.pinned != "" || m.build.Time.IsZero() {
+		return nil, nil
+	}
+	id := m.build.Time.UTC().Format(generationIDFormat)
+	if _, err := os.Stat(m.generationDir(id)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	missing, err := m.Missing(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range missing {
+		data, err := os.ReadFile(m.latestPath(name))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s snapshot: %w", name, err)
+		}
+		if err := writeAtomic(filepath.Join(m.generationDir(id), name+".json"), data); err != nil {
+			return nil, err
+		}
+	}
+	return missing, nil
+}
+
+// Missing returns the names of the latest snapshots generation id has none
+// of, sorted: sources a build pinned to it would go without.
+func (m *Manager) Missing(id string) ([]string, error) {
+	gen, err := m.Generation(id)
+	if err != nil {
+		return nil, err
+	}
+	has := make(map[string]bool, len(gen.Sources))
+	for _, name := range gen.Sources {
+		has[name] = true
+	}
+
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, sourcesDir))
+	if err != nil {
+		return nil, fmt.Errorf("listing snapshots: %w", err)
+	}
+	var missing []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() && !has[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// Pin makes LoadSource read from generation id instead of the latest
+// snapshots, for a build pinned to that generation. A pinned manager doesn't
+// save snapshots or read last_success.json.
+func (m *Manager) Pin(id string) error {
+	if _, err := m.Generation(id); err != nil {
+		return err
+	}
+	m.pinned = id
+	return nil
+}
+
+// Pinned returns the generation m is pinned to, or "".
+func (m *Manager) Pinned() string {
+	return m.pinned
+}
+
+// Restore makes generation id's snapshots the latest ones, so builds fall back
+// to them. Sources the generation has no snapshot of keep their latest one.
+// Returns the names restored.
+func (m *Manager) Restore(id string) ([]string, error) {
+	gen, err := m.Generation(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range gen.Sources {
+		data, err := os.ReadFile(filepath.Join(m.generationDir(id), name+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s snapshot: %w", name, err)
+		}
+		if err := writeAtomic(m.latestPath(name), data); err != nil {
+			return nil, err
+		}
+	}
+	return gen.Sources, nil
+}
+
+// Prune removes the generations a retention policy doesn't keep: the newest
+// generation of each of the last keepHourly hours and of each of the last
+// keepDaily days (UTC) that have one, as with "keep hourly/daily" backup
+// rotation. The newest generation is always kept. Returns the IDs removed.
+func (m *Manager) Prune(keepHourly, keepDaily int) ([]string, error) {
+	generations, err := m.Generations()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	if len(generations) > 0 {
+		keep[generations[0].ID] = true
+	}
+	keepNewestPer := func(period func(time.Time) time.Time, n int) {
+		seen := make(map[time.Time]bool)
+		for _, gen := range generations { // Newest first
+			p := period(gen.Time)
+			if seen[p] {
+				continue
+			}
+			if len(seen) == n {
+				return
+			}
+			seen[p] = true
+			keep[gen.ID] = true
+		}
+	}
+	keepNewestPer(func(t time.Time) time.Time { return t.Truncate(time.Hour) }, keepHourly)
+	keepNewestPer(func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}, keepDaily)
+
+	var removed []string
+	for _, gen := range generations {
+		if keep[gen.ID] {
+			continue
+		}
+		if err := os.RemoveAll(m.generationDir(gen.ID)); err != nil {
+			return removed, fmt.Errorf("removing snapshot generation %s: %w", gen.ID, err)
+		}
+		removed = append(removed, gen.ID)
+	}
+	return removed, nil
+}
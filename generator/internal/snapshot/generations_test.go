@@ -0,0 +1,231 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// saveGeneration saves one snapshot per name as the build at buildTime.
+func saveGeneration(t *testing.T, mgr *Manager, buildTime time.Time, data map[string][]string) {
+	t.Helper()
+	mgr.SetBuild(Build{Time: buildTime, Version: "2.0.0"})
+	for name, items := range data {
+		if err := mgr.SaveSource(name, buildTime, items); err != nil {
+			t.Fatalf("SaveSource failed: %v", err)
+		}
+	}
+}
+
+func loadStrings(t *testing.T, mgr *Manager, name string) []string {
+	t.Helper()
+	snap, err := mgr.LoadSource(name)
+	if err != nil {
+		t.Fatalf("LoadSource(%s) failed: %v", name, err)
+	}
+	var data []string
+	if err := snap.Decode(&data); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	return data
+}
+
+func TestManager_Generations(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	if gens, err := mgr.Generations(); err != nil || len(gens) != 0 {
+		t.Fatalf("Generations() = %v, %v, want none", gens, err)
+	}
+
+	first := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	saveGeneration(t, mgr, first, map[string][]string{"esmadrid.com": {"a"}, "aemet": {"sun"}})
+	saveGeneration(t, mgr, first.Add(time.Hour), map[string][]string{"esmadrid.com": {"b"}})
+
+	gens, err := mgr.Generations()
+	if err != nil {
+		t.Fatalf("Generations() failed: %v", err)
+	}
+	want := []Generation{
+		{ID: "20251020T130000Z", Time: first.Add(time.Hour), Sources: []string{"esmadrid.com"}},
+		{ID: "20251020T120000Z", Time: first, Sources: []string{"aemet", "esmadrid.com"}},
+	}
+	if !reflect.DeepEqual(gens, want) {
+		t.Errorf("Generations() = %+v, want %+v", gens, want)
+	}
+
+	snap, err := mgr.LoadGeneration("20251020T120000Z", "esmadrid.com")
+	if err != nil || snap.Build.Version != "2.0.0" {
+		t.Errorf("LoadGeneration = %+v, %v, want the first build's snapshot", snap, err)
+	}
+	if _, err := mgr.Generation("20251019T000000Z"); err == nil {
+		t.Error("Generation(unknown) succeeded, want error")
+	}
+}
+
+func TestManager_PinAndRestore(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	first := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	saveGeneration(t, mgr, first, map[string][]string{"esmadrid.com": {"a"}, "aemet": {"sun"}})
+	saveGeneration(t, mgr, first.Add(time.Hour), map[string][]string{"esmadrid.com": {"b"}})
+
+	// Pinned: reads come from the generation, saves are ignored
+	pinned := NewManager(mgr.dataDir)
+	if err := pinned.Pin("20251019T000000Z"); err == nil {
+		t.Error("Pin(unknown) succeeded, want error")
+	}
+	if err := pinned.Pin("20251020T120000Z"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if got := loadStrings(t, pinned, "esmadrid.com"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("pinned esmadrid.com = %v, want [a]", got)
+	}
+	if err := pinned.SaveSource("esmadrid.com", time.Now(), []string{"c"}); err != nil {
+		t.Fatalf("pinned SaveSource failed: %v", err)
+	}
+	if got := loadStrings(t, mgr, "esmadrid.com"); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("latest esmadrid.com = %v after a pinned save, want [b]", got)
+	}
+	if _, err := pinned.LoadLegacy(time.UTC); err == nil {
+		t.Error("pinned LoadLegacy succeeded, want error")
+	}
+
+	// Restore: the generation's snapshots become the latest ones
+	restored, err := mgr.Restore("20251020T120000Z")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored, []string{"aemet", "esmadrid.com"}) {
+		t.Errorf("Restore = %v, want [aemet esmadrid.com]", restored)
+	}
+	if got := loadStrings(t, mgr, "esmadrid.com"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("latest esmadrid.com = %v after restore, want [a]", got)
+	}
+}
+
+func TestManager_CompleteGeneration(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	first := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	saveGeneration(t, mgr, first, map[string][]string{"esmadrid.com": {"a"}, "aemet": {"sun"}})
+
+	// The next build's weather fetch failed: it saved esmadrid.com only
+	second := first.Add(time.Hour)
+	saveGeneration(t, mgr, second, map[string][]string{"esmadrid.com": {"b"}})
+	if missing, err := mgr.Missing("20251020T130000Z"); err != nil || !reflect.DeepEqual(missing, []string{"aemet"}) {
+		t.Fatalf("Missing = %v, %v, want [aemet]", missing, err)
+	}
+
+	copied, err := mgr.CompleteGeneration()
+	if err != nil {
+		t.Fatalf("CompleteGeneration failed: %v", err)
+	}
+	if !reflect.DeepEqual(copied, []string{"aemet"}) {
+		t.Errorf("CompleteGeneration = %v, want [aemet]", copied)
+	}
+	if missing, _ := mgr.Missing("20251020T130000Z"); len(missing) != 0 {
+		t.Errorf("Missing after CompleteGeneration = %v, want none", missing)
+	}
+
+	// Pinned to it, the build still has weather: the first build's
+	snap, err := mgr.LoadGeneration("20251020T130000Z", "aemet")
+	if err != nil || !snap.Build.Time.Equal(first) {
+		t.Errorf("copied aemet snapshot = %+v, %v, want the first build's", snap, err)
+	}
+	pinned := NewManager(mgr.dataDir)
+	if err := pinned.Pin("20251020T130000Z"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if got := loadStrings(t, pinned, "aemet"); !reflect.DeepEqual(got, []string{"sun"}) {
+		t.Errorf("pinned aemet = %v, want [sun]", got)
+	}
+	if copied, err := pinned.CompleteGeneration(); err != nil || copied != nil {
+		t.Errorf("pinned CompleteGeneration = %v, %v, want nothing", copied, err)
+	}
+}
+
+func TestManager_HoldDiscard(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	first := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	saveGeneration(t, mgr, first, map[string][]string{"esmadrid.com": {"a"}})
+
+	// A build the publish guard blocks: its truncated feed is never saved
+	blocked := NewManager(mgr.dataDir)
+	blocked.Hold()
+	saveGeneration(t, blocked, first.Add(time.Hour), map[string][]string{"esmadrid.com": {}})
+	if got := loadStrings(t, blocked, "esmadrid.com"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("latest esmadrid.com = %v while held, want [a]", got)
+	}
+	if dropped := blocked.Discard(); !reflect.DeepEqual(dropped, []string{"esmadrid.com"}) {
+		t.Errorf("Discard = %v, want [esmadrid.com]", dropped)
+	}
+	if got := loadStrings(t, mgr, "esmadrid.com"); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("latest esmadrid.com = %v after a blocked build, want the previous [a]", got)
+	}
+	if gens, _ := mgr.Generations(); len(gens) != 1 || gens[0].ID != "20251020T120000Z" {
+		t.Errorf("Generations() = %+v, want only the previous build's", gens)
+	}
+
+	// A build that publishes commits its snapshots
+	published := NewManager(mgr.dataDir)
+	published.Hold()
+	saveGeneration(t, published, first.Add(2*time.Hour), map[string][]string{"esmadrid.com": {"b"}})
+	if saved, err := published.Commit(); err != nil || !reflect.DeepEqual(saved, []string{"esmadrid.com"}) {
+		t.Fatalf("Commit = %v, %v, want [esmadrid.com]", saved, err)
+	}
+	if got := loadStrings(t, mgr, "esmadrid.com"); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("latest esmadrid.com = %v after commit, want [b]", got)
+	}
+	if gens, _ := mgr.Generations(); len(gens) != 2 || gens[0].ID != "20251020T140000Z" {
+		t.Errorf("Generations() = %+v, want the published build's too", gens)
+	}
+}
+
+func TestManager_Prune(t *testing.T) {
+	base := time.Date(2025, 10, 20, 12, 30, 0, 0, time.UTC)
+	builds := []time.Time{
+		base,                        // newest
+		base.Add(-10 * time.Minute), // same hour as newest
+		base.Add(-time.Hour),        // previous hour
+		base.Add(-2 * time.Hour),    // two hours back
+		base.Add(-24 * time.Hour),   // previous day
+		base.Add(-25 * time.Hour),   // previous day, earlier
+		base.Add(-72 * time.Hour),   // three days back
+	}
+	id := func(i int) string { return builds[i].Format(generationIDFormat) }
+
+	tests := []struct {
+		name                  string
+		keepHourly, keepDaily int
+		want                  []int // Indexes of builds kept
+	}{
+		{"hourly only", 2, 0, []int{0, 2}},
+		{"daily only", 0, 3, []int{0, 4, 6}},
+		{"hourly and daily", 3, 2, []int{0, 2, 3, 4}},
+		{"nothing configured keeps newest", 0, 0, []int{0}},
+		{"generous", 100, 100, []int{0, 2, 3, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mgr := NewManager(t.TempDir())
+			for _, b := range builds {
+				saveGeneration(t, mgr, b, map[string][]string{"esmadrid.com": {b.String()}})
+			}
+
+			if _, err := mgr.Prune(tt.keepHourly, tt.keepDaily); err != nil {
+				t.Fatalf("Prune failed: %v", err)
+			}
+			gens, err := mgr.Generations()
+			if err != nil {
+				t.Fatalf("Generations() failed: %v", err)
+			}
+			var got, want []string
+			for _, gen := range gens {
+				got = append(got, gen.ID)
+			}
+			for _, i := range tt.want {
+				want = append(want, id(i))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("kept %v, want %v", got, want)
+			}
+		})
+	}
+}
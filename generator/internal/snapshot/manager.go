@@ -5,13 +5,42 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/fetch"
 )
 
+// FormatVersion is the snapshot file format written by this build.
+//
+// Versions:
+//
+//	0: per-source files without version or build (source, fetched_at, data)
+//	1: last_success.json, the whole datos.madrid.es merge as []fetch.RawEvent
+//	2: per-source files with version and build
+const FormatVersion = 2
+
 // Manager handles saving and loading event snapshots for fallback resilience.
+//
+// Each source (or format of a multi-format source) keeps its own snapshot of
+// its last good fetch in snapshots/<name>.json, so a source that fails can be
+// substituted on its own. Each build's snapshots are also kept as a
+// generation (see Generations). last_success.json is the older whole-merge
+// snapshot, still read (LoadLegacy) but no longer written.
+//
+// A held manager (see Hold) keeps the snapshots it is given in memory until
+// Commit, so a build whose data turns out bad doesn't replace the last good
+// snapshots.
 type Manager struct {
 	dataDir string
+	build   Build
+	pinned  string // Generation ID (see Pin)
+
+	mu      sync.Mutex        // Guards held and pending: sources save concurrently
+	held    bool              // See Hold
+	pending map[string][]byte // Name -> encoded snapshot, awaiting Commit
 }
 
 // NewManager creates a snapshot manager for the given data directory.
@@ -19,45 +48,303 @@ func NewManager(dataDir string) *Manager {
 	return &Manager{dataDir: dataDir}
 }
 
-// SaveSnapshot saves events to last_success.json.
-func (m *Manager) SaveSnapshot(events []fetch.RawEvent) error {
-	if err := os.MkdirAll(m.dataDir, 0755); err != nil {
-		return fmt.Errorf("creating data directory: %w", err)
+// Build identifies the build that wrote a snapshot.
+type Build struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version,omitempty"`
+	Commit  string    `json:"commit,omitempty"`
+}
+
+// SetBuild sets the build recorded in the snapshots m saves. With a build
+// time set, snapshots are also saved in the build's generation.
+func (m *Manager) SetBuild(build Build) {
+	m.build = build
+}
+
+// Snapshot is one source's last good data.
+type Snapshot struct {
+	// Version is the format the snapshot was read from. Older formats are
+	// migrated on read, so Data is always in the current layout.
+	Version   int             `json:"version"`
+	Source    string          `json:"source"`
+	Build     Build           `json:"build"` // Zero for snapshots from before version 2
+	FetchedAt time.Time       `json:"fetched_at"`
+	Data      json.RawMessage `json:"data"` // The source's canonical data, e.g. []event.CulturalEvent
+}
+
+// Decode decodes the snapshot's data into v. JSON keeps each time's instant
+// and offset but not its *time.Location: event times should be moved back to
+// the source's location (event.CulturalEvent.In).
+func (s *Snapshot) Decode(v any) error {
+	if err := json.Unmarshal(s.Data, v); err != nil {
+		return fmt.Errorf("decoding %s snapshot: %w", s.Source, err)
+	}
+	return nil
+}
+
+// sourcesDir is the directory, within the data directory, of per-source snapshots.
+const sourcesDir = "snapshots"
+
+// SaveSource saves a source's last good data (any JSON-encodable value) and
+// when it was fetched, replacing the source's previous snapshot (on Commit,
+// if m is held). Pinned managers save nothing.
+func (m *Manager) SaveSource(name string, fetchedAt time.Time, data any) error {
+	if m.pinned != "" {
+		return nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding %s snapshot: %w", name, err)
+	}
+	snap, err := json.MarshalIndent(Snapshot{
+		Version:   FormatVersion,
+		Source:    name,
+		Build:     m.build,
+		FetchedAt: fetchedAt,
+		Data:      encoded,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s snapshot: %w", name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.held {
+		m.pending[name] = snap
+		return nil
+	}
+	return m.write(name, snap)
+}
+
+// write writes an encoded snapshot as the latest one of source name and into
+// the build's generation.
+func (m *Manager) write(name string, snap []byte) error {
+	if err := writeAtomic(m.latestPath(name), snap); err != nil {
+		return err
+	}
+	if m.build.Time.IsZero() {
+		return nil
+	}
+	id := m.build.Time.UTC().Format(generationIDFormat)
+	return writeAtomic(filepath.Join(m.generationDir(id), name+".json"), snap)
+}
+
+// Hold makes SaveSource keep snapshots in memory until Commit (or Discard),
+// leaving the latest snapshots and the generations as they are meanwhile.
+func (m *Manager) Hold() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.held = true
+	m.pending = make(map[string][]byte)
+}
+
+// Commit writes the snapshots held since Hold and stops holding. Returns the
+// names written, sorted.
+func (m *Manager) Commit() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.pending))
+	for name := range m.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := m.write(name, m.pending[name]); err != nil {
+			return nil, err
+		}
+	}
+	m.held, m.pending = false, nil
+	return names, nil
+}
+
+// Discard drops the snapshots held since Hold and stops holding. Returns the
+// names dropped, sorted.
+func (m *Manager) Discard() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.pending))
+	for name := range m.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m.held, m.pending = false, nil
+	return names
+}
+
+// LoadSource loads a source's last good data (from the pinned generation if
+// m is pinned), migrating older formats.
+func (m *Manager) LoadSource(name string) (*Snapshot, error) {
+	if m.pinned != "" {
+		return m.LoadGeneration(m.pinned, name)
+	}
+	return loadFile(m.latestPath(name), name)
+}
+
+// LoadGeneration loads a source's snapshot as saved in generation id.
+func (m *Manager) LoadGeneration(id, name string) (*Snapshot, error) {
+	return loadFile(filepath.Join(m.generationDir(id), name+".json"), name)
+}
+
+// loadFile loads the snapshot of source name from path, migrating older formats.
+func loadFile(path, name string) (*Snapshot, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s snapshot: %w", name, err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return nil, fmt.Errorf("decoding %s snapshot: %w", name, err)
 	}
 
+	switch snap.Version {
+	case 0, FormatVersion:
+		// Version 0 has the same layout, just without version and build
+	default:
+		return nil, fmt.Errorf("%s snapshot has unsupported format version %d (this build reads up to %d)",
+			name, snap.Version, FormatVersion)
+	}
+	if snap.Source == "" {
+		snap.Source = name
+	}
+	return &snap, nil
+}
+
+func (m *Manager) latestPath(name string) string {
+	return filepath.Join(m.dataDir, sourcesDir, name+".json")
+}
+
+// LoadLegacy loads last_success.json, the whole-merge datos.madrid.es snapshot
+// of format version 1, migrated to a snapshot of []event.CulturalEvent. That
+// format only kept dates as strings, so times are read in loc; it had no
+// Distrito or per-format sources, so events are marked as from "SNAPSHOT".
+// FetchedAt is the file's modification time.
+func (m *Manager) LoadLegacy(loc *time.Location) (*Snapshot, error) {
+	if m.pinned != "" {
+		return nil, fmt.Errorf("pinned to snapshot generation %s", m.pinned)
+	}
 	snapshotPath := filepath.Join(m.dataDir, "last_success.json")
-	tmpPath := snapshotPath + ".tmp"
 
-	data, err := json.MarshalIndent(events, "", "  ")
+	data, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var raw []fetch.RawEvent
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+
+	events := make([]event.CulturalEvent, 0, len(raw))
+	for _, r := range raw {
+		startTime, err := time.ParseInLocation("2006-01-02 15:04", r.Fecha+" "+r.Hora, loc)
+		if err != nil {
+			// Try without time if parsing fails
+			startTime, err = time.ParseInLocation("2006-01-02", r.Fecha, loc)
+			if err != nil {
+				continue
+			}
+		}
+		endTime, err := time.ParseInLocation("2006-01-02", r.FechaFin, loc)
+		if err != nil {
+			// Use start time if end time parsing fails
+			endTime = startTime
+		}
+
+		events = append(events, event.CulturalEvent{
+			ID:          r.IDEvento,
+			Title:       r.Titulo,
+			Description: r.Descripcion,
+			StartTime:   startTime,
+			EndTime:     endTime,
+			VenueName:   r.NombreInstalacion,
+			Address:     r.Direccion,
+			DetailsURL:  r.ContentURL,
+			Latitude:    r.Lat,
+			Longitude:   r.Lon,
+			Sources:     []string{"SNAPSHOT"},
+		})
+	}
+
+	encoded, err := json.Marshal(events)
 	if err != nil {
-		return fmt.Errorf("encoding snapshot: %w", err)
+		return nil, fmt.Errorf("encoding migrated snapshot: %w", err)
 	}
+	return &Snapshot{
+		Version:   1,
+		Source:    "last_success.json",
+		FetchedAt: info.ModTime(),
+		Data:      encoded,
+	}, nil
+}
 
-	// Atomic write: write to temp file, then rename
+// writeAtomic writes data to path via a temp file and rename, creating the
+// directory if needed.
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating data directory: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
 	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
 		return fmt.Errorf("writing temp snapshot: %w", err)
 	}
 
-	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+	if err := os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("renaming snapshot: %w", err)
 	}
 
 	return nil
 }
 
-// LoadSnapshot loads events from last_success.json.
-func (m *Manager) LoadSnapshot() ([]fetch.RawEvent, error) {
-	snapshotPath := filepath.Join(m.dataDir, "last_success.json")
+// CopyTo copies the current snapshots (per-source and last_success.json) into
+// dir (e.g. a record/replay bundle). Copying when no snapshot exists yet is
+// not an error.
+func (m *Manager) CopyTo(dir string) error {
+	if err := copyFile(filepath.Join(m.dataDir, "last_success.json"), filepath.Join(dir, "last_success.json")); err != nil {
+		return err
+	}
 
-	data, err := os.ReadFile(snapshotPath)
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, sourcesDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("reading snapshot: %w", err)
+		return fmt.Errorf("listing snapshots: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		src := filepath.Join(m.dataDir, sourcesDir, entry.Name())
+		if err := copyFile(src, filepath.Join(dir, sourcesDir, entry.Name())); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	var events []fetch.RawEvent
-	if err := json.Unmarshal(data, &events); err != nil {
-		return nil, fmt.Errorf("decoding snapshot: %w", err)
+// copyFile copies a snapshot file, creating dst's directory. A missing src is
+// not an error.
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
 	}
 
-	return events, nil
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("creating snapshot copy directory: %w", err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("writing snapshot copy: %w", err)
+	}
+	return nil
 }
@@ -1,55 +1,241 @@
 package snapshot
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/ericphanson/plazaespana.info/internal/event"
 	"github.com/ericphanson/plazaespana.info/internal/fetch"
 )
 
-func TestManager_SaveAndLoad(t *testing.T) {
+// writeLegacySnapshot writes last_success.json in format version 1.
+func writeLegacySnapshot(t *testing.T, dataDir string, events []fetch.RawEvent) {
+	t.Helper()
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatalf("encoding legacy snapshot: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "last_success.json"), data, 0644); err != nil {
+		t.Fatalf("writing legacy snapshot: %v", err)
+	}
+}
+
+func TestManager_LoadLegacy(t *testing.T) {
 	tmpDir := t.TempDir()
 	mgr := NewManager(tmpDir)
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
 
-	events := []fetch.RawEvent{
-		{IDEvento: "SNAP-001", Titulo: "Snapshot Event"},
-		{IDEvento: "SNAP-002", Titulo: "Another Event"},
+	writeLegacySnapshot(t, tmpDir, []fetch.RawEvent{
+		{IDEvento: "SNAP-001", Titulo: "Snapshot Event", Fecha: "2025-11-01", FechaFin: "2025-11-02", Hora: "19:00"},
+		{IDEvento: "SNAP-002", Titulo: "Another Event", Fecha: "2025-11-03"},
+		{IDEvento: "SNAP-003", Titulo: "No date"},
+	})
+
+	snap, err := mgr.LoadLegacy(madrid)
+	if err != nil {
+		t.Fatalf("LoadLegacy failed: %v", err)
+	}
+	if snap.Version != 1 || snap.FetchedAt.IsZero() {
+		t.Errorf("Version = %d, FetchedAt = %v, want 1 and the file's modification time", snap.Version, snap.FetchedAt)
 	}
 
-	// Save snapshot
-	err := mgr.SaveSnapshot(events)
+	var loaded []event.CulturalEvent
+	if err := snap.Decode(&loaded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("Expected 2 events (undated one skipped), got %d", len(loaded))
+	}
+	want := time.Date(2025, 11, 1, 19, 0, 0, 0, madrid)
+	if loaded[0].ID != "SNAP-001" || !loaded[0].StartTime.Equal(want) {
+		t.Errorf("loaded[0] = %s at %v, want SNAP-001 at %v", loaded[0].ID, loaded[0].StartTime, want)
+	}
+	if !loaded[1].EndTime.Equal(loaded[1].StartTime) {
+		t.Errorf("loaded[1] EndTime = %v, want its start time", loaded[1].EndTime)
+	}
+	if len(loaded[0].Sources) != 1 || loaded[0].Sources[0] != "SNAPSHOT" {
+		t.Errorf("Sources = %v, want [SNAPSHOT]", loaded[0].Sources)
+	}
+}
+
+func TestManager_LoadLegacy_NotExists(t *testing.T) {
+	tmpDir := t.TempDir()
+	mgr := NewManager(tmpDir)
+
+	_, err := mgr.LoadLegacy(time.UTC)
+	if err == nil {
+		t.Error("Expected error when loading non-existent snapshot")
+	}
+}
+
+func TestManager_SaveAndLoadSource(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	fetchedAt := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	build := Build{Time: fetchedAt.Add(time.Minute), Version: "2.0.0", Commit: "abc1234"}
+	mgr.SetBuild(build)
+
+	if err := mgr.SaveSource("datos.madrid.es-xml", fetchedAt, []string{"a", "b"}); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	if err := mgr.SaveSource("esmadrid.com", fetchedAt.Add(time.Hour), []string{"c"}); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+
+	snap, err := mgr.LoadSource("datos.madrid.es-xml")
 	if err != nil {
-		t.Fatalf("SaveSnapshot failed: %v", err)
+		t.Fatalf("LoadSource failed: %v", err)
+	}
+	if snap.Version != FormatVersion || snap.Source != "datos.madrid.es-xml" || snap.Build != build {
+		t.Errorf("snapshot = version %d, source %q, build %+v, want %d, datos.madrid.es-xml, %+v",
+			snap.Version, snap.Source, snap.Build, FormatVersion, build)
+	}
+	var xml []string
+	if err := snap.Decode(&xml); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !snap.FetchedAt.Equal(fetchedAt) || len(xml) != 2 || xml[1] != "b" {
+		t.Errorf("LoadSource = %v fetched %v, want [a b] fetched %v", xml, snap.FetchedAt, fetchedAt)
 	}
 
-	// Verify file exists
-	snapshotPath := filepath.Join(tmpDir, "last_success.json")
-	if _, err := os.Stat(snapshotPath); os.IsNotExist(err) {
-		t.Fatal("Snapshot file was not created")
+	// Each source keeps its own snapshot and fetch time
+	snap, err = mgr.LoadSource("esmadrid.com")
+	if err != nil {
+		t.Fatalf("LoadSource failed: %v", err)
+	}
+	if !snap.FetchedAt.Equal(fetchedAt.Add(time.Hour)) {
+		t.Errorf("esmadrid.com fetched %v, want an hour later", snap.FetchedAt)
+	}
+
+	if _, err := mgr.LoadSource("aemet"); err == nil {
+		t.Error("Expected error when loading a source without a snapshot")
 	}
+}
 
-	// Load snapshot
-	loaded, err := mgr.LoadSnapshot()
+func TestManager_LoadSource_CulturalEventsLossless(t *testing.T) {
+	mgr := NewManager(t.TempDir())
+	madrid, err := time.LoadLocation("Europe/Madrid")
 	if err != nil {
-		t.Fatalf("LoadSnapshot failed: %v", err)
+		t.Fatalf("loading timezone: %v", err)
 	}
 
-	if len(loaded) != 2 {
-		t.Fatalf("Expected 2 events, got %d", len(loaded))
+	events := []event.CulturalEvent{{
+		ID:        "50093233",
+		Title:     "Taller de cerámica",
+		StartTime: time.Date(2025, 10, 2, 18, 0, 0, 0, madrid),
+		EndTime:   time.Date(2025, 11, 13, 20, 0, 0, 0, madrid),
+		Recurrence: &event.Recurrence{
+			Frequency: "WEEKLY",
+			Interval:  1,
+			Weekdays:  []time.Weekday{time.Thursday},
+			Excluded:  []time.Time{time.Date(2025, 10, 16, 0, 0, 0, 0, madrid)},
+		},
+		Latitude:      40.42338,
+		Longitude:     -3.71217,
+		UTMX:          439631,
+		UTMY:          4474470,
+		VenueName:     "Centro Cultural",
+		Distrito:      "MONCLOA-ARAVACA",
+		Barrio:        "ARGÜELLES",
+		PostalCode:    "28008",
+		Free:          true,
+		Audience:      []string{"Familias"},
+		Accessibility: []string{"1", "6"},
+		Sources:       []string{"JSON", "XML"},
+	}}
+	if err := mgr.SaveSource("datos.madrid.es-xml", time.Now(), events); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
 	}
 
-	if loaded[0].IDEvento != "SNAP-001" {
-		t.Errorf("Expected IDEvento 'SNAP-001', got '%s'", loaded[0].IDEvento)
+	snap, err := mgr.LoadSource("datos.madrid.es-xml")
+	if err != nil {
+		t.Fatalf("LoadSource failed: %v", err)
+	}
+	var loaded []event.CulturalEvent
+	if err := snap.Decode(&loaded); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("loaded %d events, want 1", len(loaded))
+	}
+	if got := loaded[0].In(madrid); !reflect.DeepEqual(got, events[0]) {
+		t.Errorf("round trip = %+v, want %+v", got, events[0])
 	}
 }
 
-func TestManager_LoadSnapshot_NotExists(t *testing.T) {
-	tmpDir := t.TempDir()
-	mgr := NewManager(tmpDir)
+func TestManager_LoadSource_Versions(t *testing.T) {
+	dataDir := t.TempDir()
+	mgr := NewManager(dataDir)
+	write := func(content string) {
+		t.Helper()
+		path := filepath.Join(dataDir, "snapshots", "esmadrid.com.json")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating snapshot dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing snapshot: %v", err)
+		}
+	}
+
+	// Version 0: same layout without version and build
+	write(`{"source": "esmadrid.com", "fetched_at": "2025-10-20T12:00:00Z", "data": ["c"]}`)
+	snap, err := mgr.LoadSource("esmadrid.com")
+	if err != nil {
+		t.Fatalf("LoadSource(version 0) failed: %v", err)
+	}
+	var data []string
+	if err := snap.Decode(&data); err != nil || len(data) != 1 || snap.Version != 0 || !snap.Build.Time.IsZero() {
+		t.Errorf("version 0 snapshot = %+v (%v), want its data and no build", snap, err)
+	}
+
+	// Versions from the future are refused rather than misread
+	write(`{"version": 99, "source": "esmadrid.com", "data": {}}`)
+	if _, err := mgr.LoadSource("esmadrid.com"); err == nil {
+		t.Error("LoadSource(version 99) succeeded, want an unsupported version error")
+	}
+}
+
+func TestManager_CopyTo(t *testing.T) {
+	dataDir := t.TempDir()
+	mgr := NewManager(dataDir)
+	copyDir := filepath.Join(t.TempDir(), "bundle")
+
+	// Nothing to copy yet is fine
+	if err := mgr.CopyTo(copyDir); err != nil {
+		t.Fatalf("CopyTo without snapshot failed: %v", err)
+	}
+
+	writeLegacySnapshot(t, dataDir, []fetch.RawEvent{{IDEvento: "SNAP-001", Fecha: "2025-11-01"}})
+	if err := mgr.SaveSource("esmadrid.com", time.Now(), []string{"c"}); err != nil {
+		t.Fatalf("SaveSource failed: %v", err)
+	}
+	if err := mgr.CopyTo(copyDir); err != nil {
+		t.Fatalf("CopyTo failed: %v", err)
+	}
+
+	copied := NewManager(copyDir)
+	snap, err := copied.LoadLegacy(time.UTC)
+	if err != nil {
+		t.Fatalf("LoadLegacy from copy failed: %v", err)
+	}
+	var loaded []event.CulturalEvent
+	if err := snap.Decode(&loaded); err != nil || len(loaded) != 1 || loaded[0].ID != "SNAP-001" {
+		t.Errorf("copied snapshot = %+v (%v), want SNAP-001", loaded, err)
+	}
 
-	_, err := mgr.LoadSnapshot()
+	// Per-source snapshots are copied too
+	var city []string
+	snap, err = copied.LoadSource("esmadrid.com")
 	if err == nil {
-		t.Error("Expected error when loading non-existent snapshot")
+		err = snap.Decode(&city)
+	}
+	if err != nil || len(city) != 1 {
+		t.Errorf("copied source snapshot = %v (%v), want [c]", city, err)
 	}
 }
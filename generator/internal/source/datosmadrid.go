@@ -0,0 +1,329 @@
+package source
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/pipeline"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+	"github.com/ericphanson/plazaespana.info/internal/validate"
+)
+
+// DatosMadridName is the registry key for the datos.madrid.es cultural agenda.
+const DatosMadridName = "datos.madrid.es"
+
+func init() {
+	Register(DatosMadridName, newDatosMadrid)
+}
+
+// datosMadrid adapts pipeline.Pipeline (JSON + XML + CSV) to the Source interface.
+type datosMadrid struct {
+	cfg       config.CulturalEventsConfig
+	pipe      *pipeline.Pipeline
+	snapshots *snapshot.Manager
+	loc       *time.Location
+}
+
+func newDatosMadrid(cfg *config.Config, deps Deps) (Source, error) {
+	if deps.Client == nil {
+		return nil, fmt.Errorf("fetch client is required")
+	}
+	c := cfg.CulturalEvents
+	return &datosMadrid{
+		cfg:       c,
+		pipe:      pipeline.NewPipeline(c.JSONURL, c.XMLURL, c.CSVURL, deps.Client, deps.Location),
+		snapshots: deps.Snapshots,
+		loc:       deps.Location,
+	}, nil
+}
+
+func (s *datosMadrid) Name() string { return DatosMadridName }
+
+func (s *datosMadrid) Kind() Kind { return KindCultural }
+
+// Host returns the JSON feed's host; the XML and CSV feeds share it.
+func (s *datosMadrid) Host() string { return fetch.HostOf(s.cfg.JSONURL) }
+
+// Fetch fetches all three formats, substitutes each format that failed with
+// its last good snapshot, and merges them. The whole-merge last_success.json
+// snapshot is only used when every format failed and none has a snapshot.
+// A build pinned to a snapshot generation uses that generation's snapshots
+// without fetching.
+func (s *datosMadrid) Fetch() Result {
+	var result Result
+	start := time.Now()
+
+	var pipeResult pipeline.PipelineResult
+	if id := pinnedGeneration(s.snapshots); id != "" {
+		log.Printf("Pinned to snapshot generation %s, not fetching JSON, XML or CSV", id)
+		result.Attempts = []report.FetchAttempt{
+			pinnedAttempt("JSON", s.cfg.JSONURL, id),
+			pinnedAttempt("XML", s.cfg.XMLURL, id),
+			pinnedAttempt("CSV", s.cfg.CSVURL, id),
+		}
+	} else {
+		// Fetch from all three sources independently
+		log.Println("Fetching from all three sources (JSON, XML, CSV)...")
+		pipeResult = s.pipe.FetchAll()
+
+		// Track individual fetch attempts
+		result.Attempts = []report.FetchAttempt{
+			createFetchAttempt("JSON", s.cfg.JSONURL, pipeResult.JSONEvents, pipeResult.JSONErrors, pipeResult.JSONStale),
+			createFetchAttempt("XML", s.cfg.XMLURL, pipeResult.XMLEvents, pipeResult.XMLErrors, pipeResult.XMLStale),
+			createFetchAttempt("CSV", s.cfg.CSVURL, pipeResult.CSVEvents, pipeResult.CSVErrors, pipeResult.CSVStale),
+		}
+	}
+	result.FetchDuration = time.Since(start)
+	substituted := s.syncSnapshots(&pipeResult, result.Attempts, start)
+	result.Warnings = append(result.Warnings, staleWarnings(DatosMadridName, result.Attempts)...)
+	result.Warnings = append(result.Warnings, snapshotWarnings(DatosMadridName, result.Attempts)...)
+
+	log.Printf("JSON: %d events, %d errors", len(pipeResult.JSONEvents), len(pipeResult.JSONErrors))
+	log.Printf("XML: %d events, %d errors", len(pipeResult.XMLEvents), len(pipeResult.XMLErrors))
+	log.Printf("CSV: %d events, %d errors", len(pipeResult.CSVEvents), len(pipeResult.CSVErrors))
+
+	result.Errors = append(result.Errors, pipeResult.JSONErrors...)
+	result.Errors = append(result.Errors, pipeResult.XMLErrors...)
+	result.Errors = append(result.Errors, pipeResult.CSVErrors...)
+
+	// Merge and deduplicate
+	mergeStart := time.Now()
+	merged := s.pipe.Merge(pipeResult)
+	markSnapshotSources(merged, substituted)
+	result.Merging = mergeStatsFor(pipeResult, merged, time.Since(mergeStart))
+
+	if result.Merging.TotalBeforeMerge > 0 {
+		log.Printf("After merge: %d unique events from %d total (%.1f%% deduplication)",
+			len(merged),
+			result.Merging.TotalBeforeMerge,
+			float64(result.Merging.Duplicates)*100.0/float64(result.Merging.TotalBeforeMerge))
+	}
+
+	// Fill missing coordinates from UTM and cross-check the rest
+	result.DataQuality = append(result.DataQuality, reconcileCoordinates(merged)...)
+
+	// Fall back to the whole-merge snapshot if ALL sources failed without
+	// a snapshot of their own
+	if len(merged) == 0 && allSourcesFailed(pipeResult) {
+		merged = s.loadSnapshot(&result)
+	}
+
+	result.Cultural = merged
+	result.Duration = time.Since(start)
+	return result
+}
+
+// syncSnapshots saves a snapshot of each format fetched fresh (stale cache
+// bodies were snapshotted when first fetched) and substitutes each format
+// that failed (or was skipped) with its snapshot, marking its attempt. Returns the formats
+// substituted.
+func (s *datosMadrid) syncSnapshots(pipeResult *pipeline.PipelineResult, attempts []report.FetchAttempt, fetchedAt time.Time) map[string]bool {
+	if s.snapshots == nil {
+		return nil
+	}
+	formats := []*[]event.SourcedEvent{&pipeResult.JSONEvents, &pipeResult.XMLEvents, &pipeResult.CSVEvents}
+
+	substituted := make(map[string]bool)
+	for i, events := range formats {
+		attempt := &attempts[i]
+		name := snapshotName(DatosMadridName, attempt.Source)
+		switch {
+		case attempt.Status == "SUCCESS" && !attempt.Stale:
+			snap := make([]event.CulturalEvent, len(*events))
+			for j, sourced := range *events {
+				snap[j] = sourced.Event
+			}
+			if err := s.snapshots.SaveSource(name, fetchedAt, snap); err != nil {
+				log.Printf("Warning: failed to save snapshot: %v", err)
+			}
+
+		case needsSnapshot(*attempt):
+			snap, err := s.snapshots.LoadSource(name)
+			var cultural []event.CulturalEvent
+			if err == nil {
+				cultural, err = culturalSnapshot(snap, s.loc)
+			}
+			if err != nil {
+				log.Printf("Warning: %s has no usable snapshot: %v", attempt.Source, err)
+				continue
+			}
+			log.Printf("Using %s snapshot from %s (%s, %d events)",
+				attempt.Source, snap.FetchedAt.Format(time.RFC3339), snapshotProvenance(snap), len(cultural))
+			*events = make([]event.SourcedEvent, len(cultural))
+			for j, evt := range cultural {
+				(*events)[j] = event.SourcedEvent{Event: evt, Source: attempt.Source}
+			}
+			markSnapshot(attempt, snap.FetchedAt, len(cultural))
+			substituted[attempt.Source] = true
+		}
+	}
+	return substituted
+}
+
+// markSnapshotSources relabels the substituted formats in each event's
+// Sources (see snapshotLabel). A substituted format fetched nothing live, so
+// every mention of it is the snapshot's.
+func markSnapshotSources(events []event.CulturalEvent, substituted map[string]bool) {
+	if len(substituted) == 0 {
+		return
+	}
+	for i := range events {
+		for j, format := range events[i].Sources {
+			if substituted[format] {
+				events[i].Sources[j] = snapshotLabel(format)
+			}
+		}
+	}
+}
+
+// loadSnapshot loads the whole-merge snapshot (last_success.json) that
+// predates per-format snapshots.
+func (s *datosMadrid) loadSnapshot(result *Result) []event.CulturalEvent {
+	log.Println("All sources failed without snapshots of their own, attempting to load last_success.json...")
+	if s.snapshots == nil {
+		result.Warnings = append(result.Warnings, "All fetch sources failed and no snapshot available")
+		return nil
+	}
+
+	snap, err := s.snapshots.LoadLegacy(s.loc)
+	var snapshotEvents []event.CulturalEvent
+	if err == nil {
+		snapshotEvents, err = culturalSnapshot(snap, s.loc)
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to load snapshot: %v", err)
+		result.Warnings = append(result.Warnings, "All fetch sources failed and no snapshot available")
+		return nil
+	}
+
+	log.Printf("Loaded snapshot with %d events", len(snapshotEvents))
+	result.Warnings = append(result.Warnings,
+		fmt.Sprintf("Using snapshot data - all fetch attempts failed (snapshot has %d events)", len(snapshotEvents)))
+	return snapshotEvents
+}
+
+func (s *datosMadrid) Report(result Result, pr *report.PipelineReport) {
+	pr.Name = "Cultural Events"
+	pr.Source = DatosMadridName
+	pr.Kind = string(KindCultural)
+	pr.Fetching.Attempts = result.Attempts
+	pr.Fetching.TotalDuration = result.FetchDuration
+	pr.Merging = result.Merging
+}
+
+// reconcileCoordinates fills the latitude/longitude of events that only have
+// UTM coordinates (COORDENADA-X/Y), and reports those along with events whose
+// latitude/longitude and UTM coordinates disagree by more than
+// validate.CoordinateTolerance.
+func reconcileCoordinates(events []event.CulturalEvent) []report.DataQualityIssue {
+	var filled, mismatched []string
+	for i := range events {
+		evt := &events[i]
+		if validate.FillCoordinatesFromUTM(evt) {
+			filled = append(filled, evt.ID)
+			continue
+		}
+		if offset, ok := validate.UTMOffset(*evt); ok && offset > validate.CoordinateTolerance {
+			mismatched = append(mismatched, fmt.Sprintf("%s (%s): %.0f m apart", evt.ID, evt.VenueName, offset))
+		}
+	}
+	if len(filled) > 0 {
+		log.Printf("Filled coordinates of %d events from UTM", len(filled))
+	}
+	if len(mismatched) > 0 {
+		log.Printf("Warning: %d events have latitude/longitude more than %.0f m from their UTM coordinates", len(mismatched), validate.CoordinateTolerance)
+	}
+
+	var issues []report.DataQualityIssue
+	if len(filled) > 0 {
+		issues = append(issues, report.DataQualityIssue{
+			Type:        "COORDINATES_FROM_UTM",
+			Severity:    "INFO",
+			Count:       len(filled),
+			Description: "Events without latitude/longitude, located from COORDENADA-X/Y (UTM zone 30N)",
+			Examples:    firstN(filled, 5),
+		})
+	}
+	if len(mismatched) > 0 {
+		issues = append(issues, report.DataQualityIssue{
+			Type:     "COORDINATE_MISMATCH",
+			Severity: "WARNING",
+			Count:    len(mismatched),
+			Description: fmt.Sprintf("Events whose latitude/longitude are more than %.0f m from their COORDENADA-X/Y",
+				validate.CoordinateTolerance),
+			Examples:       firstN(mismatched, 5),
+			Recommendation: "Latitude/longitude are used as given; check the venue on a map if it matters for filtering",
+		})
+	}
+	return issues
+}
+
+// firstN returns up to n items.
+func firstN(items []string, n int) []string {
+	if len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// mergeStatsFor calculates merge stats for the three-format pipeline.
+func mergeStatsFor(pipeResult pipeline.PipelineResult, merged []event.CulturalEvent, d time.Duration) *report.MergeStats {
+	total := len(pipeResult.JSONEvents) + len(pipeResult.XMLEvents) + len(pipeResult.CSVEvents)
+	stats := &report.MergeStats{
+		JSONEvents:       len(pipeResult.JSONEvents),
+		XMLEvents:        len(pipeResult.XMLEvents),
+		CSVEvents:        len(pipeResult.CSVEvents),
+		TotalBeforeMerge: total,
+		UniqueEvents:     len(merged),
+		Duplicates:       total - len(merged),
+		Duration:         d,
+	}
+
+	// Calculate source coverage
+	for _, evt := range merged {
+		switch len(evt.Sources) {
+		case 3:
+			stats.InAllThree++
+		case 2:
+			stats.InTwoSources++
+		case 1:
+			stats.InOneSource++
+		}
+	}
+	return stats
+}
+
+// createFetchAttempt creates a FetchAttempt from pipeline results.
+func createFetchAttempt(source, url string, events []event.SourcedEvent, errors []event.ParseError, stale *event.StaleData) report.FetchAttempt {
+	attempt := report.FetchAttempt{
+		Source: source,
+		URL:    url,
+	}
+	markStale(&attempt, stale)
+
+	if len(events) > 0 {
+		attempt.Status = "SUCCESS"
+		attempt.EventCount = len(events)
+		if !attempt.Stale {
+			attempt.HTTPStatus = 200
+		}
+	} else if len(errors) > 0 {
+		attempt.Status = "FAILED"
+		attempt.Error = errors[0].Error.Error()
+	} else {
+		attempt.Status = "FAILED"
+		attempt.Error = "no events parsed"
+	}
+
+	return attempt
+}
+
+// allSourcesFailed returns true if all three sources failed to fetch events.
+func allSourcesFailed(result pipeline.PipelineResult) bool {
+	return len(result.JSONEvents) == 0 && len(result.XMLEvents) == 0 && len(result.CSVEvents) == 0
+}
@@ -0,0 +1,146 @@
+package source
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+)
+
+// EsmadridName is the registry key for the esmadrid.com city agenda.
+const EsmadridName = "esmadrid.com"
+
+func init() {
+	Register(EsmadridName, newEsmadrid)
+}
+
+// esmadrid adapts fetch.Client.FetchEsmadrid to the Source interface.
+type esmadrid struct {
+	url       string
+	client    *fetch.Client
+	snapshots *snapshot.Manager
+	loc       *time.Location
+}
+
+func newEsmadrid(cfg *config.Config, deps Deps) (Source, error) {
+	if deps.Client == nil {
+		return nil, fmt.Errorf("fetch client is required")
+	}
+	return &esmadrid{url: cfg.CityEvents.XMLURL, client: deps.Client, snapshots: deps.Snapshots, loc: deps.Location}, nil
+}
+
+func (s *esmadrid) Name() string { return EsmadridName }
+
+func (s *esmadrid) Kind() Kind { return KindCity }
+
+func (s *esmadrid) Host() string { return fetch.HostOf(s.url) }
+
+// Fetch fetches the esmadrid agenda XML through the shared client, falling
+// back to its last good snapshot if the fetch fails (or the build is pinned
+// to a snapshot generation).
+func (s *esmadrid) Fetch() Result {
+	var result Result
+	start := time.Now()
+
+	var attempt report.FetchAttempt
+	if id := pinnedGeneration(s.snapshots); id != "" {
+		log.Printf("Pinned to snapshot generation %s, not fetching ESMadrid events", id)
+		attempt = pinnedAttempt("XML", s.url, id)
+	} else {
+		log.Printf("Fetching ESMadrid events from: %s", s.url)
+		cityResult := s.client.FetchEsmadrid(s.url)
+		result.FetchDuration = time.Since(start)
+
+		result.City = cityResult.Events
+		result.Errors = cityResult.Errors
+		log.Printf("Parsed %d city events (%d parse errors)", len(result.City), len(result.Errors))
+
+		attempt = createCityFetchAttempt(s.url, cityResult)
+		attempt.Duration = result.FetchDuration
+		if attempt.Status == "FAILED" {
+			log.Printf("Warning: Failed to fetch ESMadrid events: %s", attempt.Error)
+		}
+	}
+	s.syncSnapshot(&result, &attempt, start)
+	result.Attempts = []report.FetchAttempt{attempt}
+	result.Warnings = append(result.Warnings, staleWarnings(EsmadridName, result.Attempts)...)
+	result.Warnings = append(result.Warnings, snapshotWarnings(EsmadridName, result.Attempts)...)
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// syncSnapshot saves a snapshot of events fetched fresh, or substitutes a
+// failed (or skipped) fetch with the last snapshot and marks attempt.
+func (s *esmadrid) syncSnapshot(result *Result, attempt *report.FetchAttempt, fetchedAt time.Time) {
+	if s.snapshots == nil {
+		return
+	}
+	switch {
+	case attempt.Status == "SUCCESS" && !attempt.Stale && len(result.City) > 0:
+		if err := s.snapshots.SaveSource(EsmadridName, fetchedAt, result.City); err != nil {
+			log.Printf("Warning: failed to save snapshot: %v", err)
+		}
+
+	case needsSnapshot(*attempt):
+		snap, err := s.snapshots.LoadSource(EsmadridName)
+		var city []event.CityEvent
+		if err == nil {
+			err = snap.Decode(&city)
+		}
+		if err != nil {
+			log.Printf("Warning: ESMadrid has no usable snapshot: %v", err)
+			return
+		}
+		for i := range city {
+			city[i] = city[i].In(s.loc)
+		}
+		log.Printf("Using ESMadrid snapshot from %s (%s, %d events)",
+			snap.FetchedAt.Format(time.RFC3339), snapshotProvenance(snap), len(city))
+		result.City = city
+		markSnapshot(attempt, snap.FetchedAt, len(city))
+	}
+}
+
+func (s *esmadrid) Report(result Result, pr *report.PipelineReport) {
+	pr.Name = "City Events"
+	pr.Source = EsmadridName
+	pr.Kind = string(KindCity)
+	pr.Fetching.Attempts = result.Attempts
+	pr.Fetching.TotalDuration = result.FetchDuration
+}
+
+// createCityFetchAttempt creates a FetchAttempt from the esmadrid feed result.
+func createCityFetchAttempt(url string, result event.CityParseResult) report.FetchAttempt {
+	attempt := report.FetchAttempt{
+		Source: "XML",
+		URL:    url,
+	}
+	markStale(&attempt, result.Stale)
+
+	if len(result.Events) > 0 {
+		attempt.Status = "SUCCESS"
+		attempt.EventCount = len(result.Events)
+		if !attempt.Stale {
+			attempt.HTTPStatus = 200
+		}
+		// Add note if some services failed to parse (keeping the stale reason)
+		if len(result.Errors) > 0 && !attempt.Stale {
+			attempt.Error = fmt.Sprintf("Parsed %d/%d services successfully",
+				len(result.Events), len(result.Events)+len(result.Errors))
+		}
+	} else if len(result.Errors) > 0 {
+		attempt.Status = "FAILED"
+		attempt.Error = result.Errors[0].Error.Error()
+	} else {
+		attempt.Status = "SUCCESS"
+		attempt.HTTPStatus = 200
+	}
+
+	return attempt
+}
@@ -0,0 +1,225 @@
+// Package source defines the pluggable upstream event feeds.
+//
+// Each feed (datos.madrid.es, esmadrid.com, ...) is an adapter implementing
+// Source. Adapters register a factory under the name used in config.toml's
+// [sources] section; main builds the enabled sources from the registry and
+// runs them without knowing which feeds exist.
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+)
+
+// Kind identifies which canonical event model a source produces.
+type Kind string
+
+const (
+	// KindCultural sources produce event.CulturalEvent (datos.madrid.es).
+	KindCultural Kind = "cultural"
+
+	// KindCity sources produce event.CityEvent (esmadrid.com).
+	KindCity Kind = "city"
+)
+
+// Source is one upstream event feed.
+type Source interface {
+	// Name is the registry key used in config.toml (e.g. "datos.madrid.es").
+	Name() string
+
+	// Kind reports which event model Fetch populates.
+	Kind() Kind
+
+	// Host is the upstream host Fetch requests from (see pipeline.Scheduler).
+	Host() string
+
+	// Fetch downloads and parses the feed into canonical events.
+	// Failures are recorded in Result.Errors rather than returned.
+	Fetch() Result
+
+	// Report records the source's fetch and merge stats in its pipeline report.
+	Report(result Result, pr *report.PipelineReport)
+}
+
+// Result holds the canonical events produced by one Source.Fetch call.
+type Result struct {
+	Cultural []event.CulturalEvent // Populated by KindCultural sources
+	City     []event.CityEvent     // Populated by KindCity sources
+	Errors   []event.ParseError    // Per-item and per-feed failures
+
+	Attempts []report.FetchAttempt // One per upstream document
+	Merging  *report.MergeStats    // Only for multi-format sources
+	Warnings []string              // Added to the build report as-is
+
+	DataQuality []report.DataQualityIssue // Added to the build report as-is
+
+	FetchDuration time.Duration
+	Duration      time.Duration // Fetch + parse + merge
+}
+
+// EventCount returns the number of canonical events in the result.
+func (r Result) EventCount() int {
+	return len(r.Cultural) + len(r.City)
+}
+
+// Deps holds the shared services available to source factories.
+type Deps struct {
+	Client    *fetch.Client
+	Location  *time.Location
+	Snapshots *snapshot.Manager
+}
+
+// Factory builds a Source from configuration.
+type Factory func(cfg *config.Config, deps Deps) (Source, error)
+
+var registry = map[string]Factory{}
+
+// Register makes a source available under name. It panics on duplicates,
+// since registration happens in init functions.
+func Register(name string, factory Factory) {
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("source: %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of all registered sources, sorted.
+func Registered() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build constructs the sources listed in cfg.Sources.Enabled, in order.
+func Build(cfg *config.Config, deps Deps) ([]Source, error) {
+	sources := make([]Source, 0, len(cfg.Sources.Enabled))
+	for _, name := range cfg.Sources.Enabled {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source %q (registered: %s)", name, strings.Join(Registered(), ", "))
+		}
+		src, err := factory(cfg, deps)
+		if err != nil {
+			return nil, fmt.Errorf("creating source %q: %w", name, err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// markStale records on attempt that its body came from stale cache.
+// The upstream failure goes in Error so the report can explain why.
+func markStale(attempt *report.FetchAttempt, stale *event.StaleData) {
+	if stale == nil {
+		return
+	}
+	attempt.Stale = true
+	attempt.StaleAge = time.Since(stale.FetchedAt)
+	attempt.Error = stale.Reason
+}
+
+// staleWarnings returns a build warning for each attempt served from stale cache.
+func staleWarnings(source string, attempts []report.FetchAttempt) []string {
+	var warnings []string
+	for _, a := range attempts {
+		if a.Stale {
+			warnings = append(warnings, fmt.Sprintf("%s %s served from stale cache (%s old) after upstream error: %s",
+				source, a.Source, a.StaleAge.Round(time.Minute), a.Error))
+		}
+	}
+	return warnings
+}
+
+// snapshotName is the snapshot.Manager name of one format of a source
+// ("datos.madrid.es-xml"). Single-format sources use their Name.
+func snapshotName(source, format string) string {
+	return source + "-" + strings.ToLower(format)
+}
+
+// snapshotLabel is how a format substituted by its snapshot appears in
+// event.CulturalEvent.Sources: "XML" -> "XML (snapshot)".
+func snapshotLabel(format string) string {
+	return format + " (snapshot)"
+}
+
+// culturalSnapshot decodes a snapshot of cultural events, with their times
+// back in loc.
+func culturalSnapshot(snap *snapshot.Snapshot, loc *time.Location) ([]event.CulturalEvent, error) {
+	var events []event.CulturalEvent
+	if err := snap.Decode(&events); err != nil {
+		return nil, err
+	}
+	for i := range events {
+		events[i] = events[i].In(loc)
+	}
+	return events, nil
+}
+
+// snapshotProvenance describes which build saved a snapshot, for logs.
+func snapshotProvenance(snap *snapshot.Snapshot) string {
+	if snap.Build.Time.IsZero() {
+		return fmt.Sprintf("format version %d", snap.Version)
+	}
+	return fmt.Sprintf("saved by build %s (%s %s)", snap.Build.Time.Format(time.RFC3339), snap.Build.Version, snap.Build.Commit)
+}
+
+// pinnedGeneration returns the snapshot generation the build is pinned to
+// (see snapshot.Manager.Pin), or "" if it fetches normally.
+func pinnedGeneration(snapshots *snapshot.Manager) string {
+	if snapshots == nil {
+		return ""
+	}
+	return snapshots.Pinned()
+}
+
+// pinnedAttempt records an upstream document not fetched because the build
+// is pinned to snapshot generation id. Its snapshot then stands in for it.
+func pinnedAttempt(format, url, id string) report.FetchAttempt {
+	return report.FetchAttempt{
+		Source: format,
+		URL:    url,
+		Status: "SKIPPED",
+		Error:  fmt.Sprintf("pinned to snapshot generation %s", id),
+	}
+}
+
+// needsSnapshot reports whether attempt fetched nothing, so its snapshot
+// should stand in for it.
+func needsSnapshot(attempt report.FetchAttempt) bool {
+	return attempt.Status == "FAILED" || attempt.Status == "SKIPPED"
+}
+
+// markSnapshot records on a failed (or skipped) attempt that the source's last good
+// snapshot, fetched at fetchedAt, stands in for it with count events.
+func markSnapshot(attempt *report.FetchAttempt, fetchedAt time.Time, count int) {
+	attempt.Snapshot = true
+	attempt.SnapshotAge = time.Since(fetchedAt)
+	attempt.EventCount = count
+}
+
+// snapshotWarnings returns a build warning for each attempt substituted by its snapshot.
+func snapshotWarnings(source string, attempts []report.FetchAttempt) []string {
+	var warnings []string
+	for _, a := range attempts {
+		if a.Snapshot {
+			verb := "failed"
+			if a.Status == "SKIPPED" {
+				verb = "was skipped"
+			}
+			warnings = append(warnings, fmt.Sprintf("%s %s %s, using its snapshot from %s ago (%d events): %s",
+				source, a.Source, verb, a.SnapshotAge.Round(time.Minute), a.EventCount, a.Error))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,391 @@
+package source
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ericphanson/plazaespana.info/internal/config"
+	"github.com/ericphanson/plazaespana.info/internal/event"
+	"github.com/ericphanson/plazaespana.info/internal/fetch"
+	"github.com/ericphanson/plazaespana.info/internal/report"
+	"github.com/ericphanson/plazaespana.info/internal/snapshot"
+)
+
+// newTestDeps creates a client without inter-request delays so tests run fast.
+func newTestDeps(t *testing.T) Deps {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("loading timezone: %v", err)
+	}
+	modeConfig := fetch.ModeConfig{Mode: fetch.DevelopmentMode, CacheTTL: time.Hour}
+	client, err := fetch.NewClient(5*time.Second, modeConfig, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	return Deps{
+		Client:    client,
+		Location:  loc,
+		Snapshots: snapshot.NewManager(t.TempDir()),
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	names := Registered()
+	for _, want := range []string{DatosMadridName, EsmadridName} {
+		found := false
+		for _, name := range names {
+			if name == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Registered() = %v, missing %q", names, want)
+		}
+	}
+}
+
+func TestBuild_Order(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sources.Enabled = []string{EsmadridName, DatosMadridName}
+
+	sources, err := Build(cfg, newTestDeps(t))
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if len(sources) != 2 {
+		t.Fatalf("Build returned %d sources, want 2", len(sources))
+	}
+	if sources[0].Name() != EsmadridName || sources[0].Kind() != KindCity {
+		t.Errorf("sources[0] = %s (%s), want %s (city)", sources[0].Name(), sources[0].Kind(), EsmadridName)
+	}
+	if sources[1].Name() != DatosMadridName || sources[1].Kind() != KindCultural {
+		t.Errorf("sources[1] = %s (%s), want %s (cultural)", sources[1].Name(), sources[1].Kind(), DatosMadridName)
+	}
+	if sources[0].Host() != "www.esmadrid.com" || sources[1].Host() != "datos.madrid.es" {
+		t.Errorf("hosts = %q, %q, want the feeds' hosts", sources[0].Host(), sources[1].Host())
+	}
+}
+
+func TestBuild_UnknownSource(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Sources.Enabled = []string{"example.org"}
+
+	_, err := Build(cfg, newTestDeps(t))
+	if err == nil {
+		t.Fatal("Build succeeded, want error for unknown source")
+	}
+	if !strings.Contains(err.Error(), "example.org") {
+		t.Errorf("error = %v, want it to name the unknown source", err)
+	}
+}
+
+func TestEsmadridSource_FetchAndReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agenda.xml")
+	xmlData := `<?xml version="1.0"?><serviceList>
+<service id="1"><basicData><title>Uno</title></basicData><extradata><fechas><rango><inicio>01/11/2025</inicio></rango></fechas></extradata></service>
+<service id="2"><basicData><title>Sin fecha</title></basicData></service>
+</serviceList>`
+	if err := os.WriteFile(path, []byte(xmlData), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.CityEvents.XMLURL = "file://" + path
+	src, err := newEsmadrid(cfg, newTestDeps(t))
+	if err != nil {
+		t.Fatalf("newEsmadrid failed: %v", err)
+	}
+
+	result := src.Fetch()
+	if len(result.City) != 1 {
+		t.Errorf("City events = %d, want 1", len(result.City))
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("Errors = %d, want 1", len(result.Errors))
+	}
+
+	var pr report.PipelineReport
+	src.Report(result, &pr)
+	if pr.Source != EsmadridName || pr.Kind != "city" {
+		t.Errorf("report source/kind = %q/%q, want %q/city", pr.Source, pr.Kind, EsmadridName)
+	}
+	if len(pr.Fetching.Attempts) != 1 || pr.Fetching.Attempts[0].Status != "SUCCESS" {
+		t.Errorf("Attempts = %+v, want one SUCCESS attempt", pr.Fetching.Attempts)
+	}
+}
+
+func TestDatosMadridSource_SnapshotFallback(t *testing.T) {
+	// A data dir from before per-format snapshots, with only last_success.json
+	deps := newTestDeps(t)
+	dataDir := t.TempDir()
+	deps.Snapshots = snapshot.NewManager(dataDir)
+	legacy, err := json.Marshal([]fetch.RawEvent{{
+		IDEvento: "SNAP-1",
+		Titulo:   "Evento guardado",
+		Fecha:    "2025-11-01",
+		FechaFin: "2025-11-02",
+		Hora:     "19:00",
+	}})
+	if err != nil {
+		t.Fatalf("encoding legacy snapshot: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "last_success.json"), legacy, 0644); err != nil {
+		t.Fatalf("writing legacy snapshot: %v", err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.CulturalEvents = config.CulturalEventsConfig{
+		JSONURL: "file:///nonexistent/events.json",
+		XMLURL:  "file:///nonexistent/events.xml",
+		CSVURL:  "file:///nonexistent/events.csv",
+	}
+	src, err := newDatosMadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newDatosMadrid failed: %v", err)
+	}
+
+	result := src.Fetch()
+	if len(result.Cultural) != 1 || result.Cultural[0].ID != "SNAP-1" {
+		t.Fatalf("Cultural = %+v, want snapshot event SNAP-1", result.Cultural)
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("expected a warning about snapshot use")
+	}
+
+	var pr report.PipelineReport
+	src.Report(result, &pr)
+	if len(pr.Fetching.Attempts) != 3 {
+		t.Fatalf("Attempts = %d, want 3", len(pr.Fetching.Attempts))
+	}
+	for _, attempt := range pr.Fetching.Attempts {
+		if attempt.Status != "FAILED" {
+			t.Errorf("%s attempt status = %s, want FAILED", attempt.Source, attempt.Status)
+		}
+	}
+}
+
+// fixtureURL returns the file:// URL of a testdata fixture.
+func fixtureURL(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("..", "..", "testdata", "fixtures", name))
+	if err != nil {
+		t.Fatalf("resolving fixture %s: %v", name, err)
+	}
+	return "file://" + path
+}
+
+func TestDatosMadridSource_PerFormatSnapshot(t *testing.T) {
+	deps := newTestDeps(t)
+	cfg := config.DefaultConfig()
+	cfg.CulturalEvents = config.CulturalEventsConfig{
+		JSONURL: fixtureURL(t, "madrid-events.json"),
+		XMLURL:  fixtureURL(t, "madrid-events.xml"),
+		CSVURL:  fixtureURL(t, "madrid-events.csv"),
+	}
+	src, err := newDatosMadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newDatosMadrid failed: %v", err)
+	}
+	first := src.Fetch()
+	distritos := make(map[string]string)
+	for _, evt := range first.Cultural {
+		distritos[evt.ID] = evt.Distrito
+	}
+
+	// Only the XML fails: it's substituted from its snapshot, the others are live
+	cfg.CulturalEvents.XMLURL = "file:///nonexistent/events.xml"
+	src, err = newDatosMadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newDatosMadrid failed: %v", err)
+	}
+	result := src.Fetch()
+
+	if len(result.Cultural) != len(first.Cultural) {
+		t.Errorf("Cultural = %d events, want %d as with a live XML", len(result.Cultural), len(first.Cultural))
+	}
+	for _, evt := range result.Cultural {
+		if evt.StartTime.Location() != deps.Location {
+			t.Errorf("%s StartTime in %v, want %v", evt.ID, evt.StartTime.Location(), deps.Location)
+			break
+		}
+	}
+	for _, evt := range result.Cultural {
+		if evt.Distrito != distritos[evt.ID] {
+			t.Errorf("%s Distrito = %q, want %q as with a live XML", evt.ID, evt.Distrito, distritos[evt.ID])
+			break
+		}
+	}
+	for _, evt := range result.Cultural {
+		for _, s := range evt.Sources {
+			if s == "XML" {
+				t.Fatalf("%s Sources = %v, want the XML marked as a snapshot", evt.ID, evt.Sources)
+			}
+		}
+	}
+	if result.Merging.InAllThree == 0 {
+		t.Error("InAllThree = 0, want the snapshot XML merged with the live JSON and CSV")
+	}
+
+	xml := result.Attempts[1]
+	if xml.Status != "FAILED" || !xml.Snapshot || xml.EventCount == 0 {
+		t.Errorf("XML attempt = %+v, want FAILED with snapshot events", xml)
+	}
+	if result.Attempts[0].Snapshot || result.Attempts[2].Snapshot {
+		t.Errorf("JSON/CSV attempts = %+v, %+v, want live", result.Attempts[0], result.Attempts[2])
+	}
+	found := false
+	for _, w := range result.Warnings {
+		found = found || strings.Contains(w, "XML failed, using its snapshot")
+	}
+	if !found {
+		t.Errorf("Warnings = %v, want one about the XML snapshot", result.Warnings)
+	}
+}
+
+func TestEsmadridSource_SnapshotFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agenda.xml")
+	xmlData := `<?xml version="1.0"?><serviceList>
+<service id="1"><basicData><title>Uno</title></basicData><extradata><fechas><rango><inicio>01/11/2025</inicio></rango></fechas></extradata></service>
+</serviceList>`
+	if err := os.WriteFile(path, []byte(xmlData), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	deps := newTestDeps(t)
+	cfg := config.DefaultConfig()
+	cfg.CityEvents.XMLURL = "file://" + path
+	src, err := newEsmadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newEsmadrid failed: %v", err)
+	}
+	if result := src.Fetch(); len(result.City) != 1 {
+		t.Fatalf("City events = %d, want 1", len(result.City))
+	}
+
+	cfg.CityEvents.XMLURL = "file:///nonexistent/agenda.xml"
+	src, err = newEsmadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newEsmadrid failed: %v", err)
+	}
+	result := src.Fetch()
+	if len(result.City) != 1 || result.City[0].Title != "Uno" {
+		t.Errorf("City = %+v, want the snapshot event", result.City)
+	}
+	if attempt := result.Attempts[0]; attempt.Status != "FAILED" || !attempt.Snapshot || attempt.EventCount != 1 {
+		t.Errorf("attempt = %+v, want FAILED with 1 snapshot event", attempt)
+	}
+	if len(result.Warnings) != 1 {
+		t.Errorf("Warnings = %v, want one about the snapshot", result.Warnings)
+	}
+}
+
+func TestEsmadridSource_PinnedGeneration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agenda.xml")
+	xmlData := `<?xml version="1.0"?><serviceList>
+<service id="1"><basicData><title>Uno</title></basicData><extradata><fechas><rango><inicio>01/11/2025</inicio></rango></fechas></extradata></service>
+</serviceList>`
+	if err := os.WriteFile(path, []byte(xmlData), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+
+	deps := newTestDeps(t)
+	dataDir := t.TempDir()
+	deps.Snapshots = snapshot.NewManager(dataDir)
+	buildTime := time.Date(2025, 10, 20, 12, 0, 0, 0, time.UTC)
+	deps.Snapshots.SetBuild(snapshot.Build{Time: buildTime})
+	cfg := config.DefaultConfig()
+	cfg.CityEvents.XMLURL = "file://" + path
+	src, err := newEsmadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newEsmadrid failed: %v", err)
+	}
+	if result := src.Fetch(); len(result.City) != 1 {
+		t.Fatalf("City events = %d, want 1", len(result.City))
+	}
+
+	// Pinned to that build's generation: the feed isn't fetched, even though it changed
+	if err := os.WriteFile(path, []byte(`<?xml version="1.0"?><serviceList></serviceList>`), 0644); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+	deps.Snapshots = snapshot.NewManager(dataDir)
+	if err := deps.Snapshots.Pin("20251020T120000Z"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	src, err = newEsmadrid(cfg, deps)
+	if err != nil {
+		t.Fatalf("newEsmadrid failed: %v", err)
+	}
+	result := src.Fetch()
+	if len(result.City) != 1 || result.City[0].Title != "Uno" {
+		t.Errorf("City = %+v, want the generation's event", result.City)
+	}
+	if attempt := result.Attempts[0]; attempt.Status != "SKIPPED" || !attempt.Snapshot || attempt.HTTPStatus != 0 {
+		t.Errorf("attempt = %+v, want SKIPPED with snapshot events", attempt)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "was skipped") {
+		t.Errorf("Warnings = %v, want one about the skipped fetch", result.Warnings)
+	}
+}
+
+func TestResult_EventCount(t *testing.T) {
+	r := Result{
+		Cultural: make([]event.CulturalEvent, 2),
+		City:     make([]event.CityEvent, 3),
+	}
+	if r.EventCount() != 5 {
+		t.Errorf("EventCount() = %d, want 5", r.EventCount())
+	}
+}
+
+func TestCreateCityFetchAttempt_Stale(t *testing.T) {
+	result := event.CityParseResult{
+		Events: make([]event.CityEvent, 2),
+		Stale:  &event.StaleData{FetchedAt: time.Now().Add(-2 * time.Hour), Reason: "HTTP 503: 503 Service Unavailable"},
+	}
+
+	attempt := createCityFetchAttempt("https://example.com/agenda.xml", result)
+	if attempt.Status != "SUCCESS" || !attempt.Stale {
+		t.Errorf("attempt = %+v, want stale SUCCESS", attempt)
+	}
+	if attempt.StaleAge < 2*time.Hour || attempt.Error != result.Stale.Reason {
+		t.Errorf("StaleAge = %v, Error = %q, want >= 2h and the upstream error", attempt.StaleAge, attempt.Error)
+	}
+
+	warnings := staleWarnings(EsmadridName, []report.FetchAttempt{attempt})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "stale cache") {
+		t.Errorf("warnings = %v, want one stale cache warning", warnings)
+	}
+}
+
+func TestReconcileCoordinates(t *testing.T) {
+	events := []event.CulturalEvent{
+		{ID: "utm-only", UTMX: 440946, UTMY: 4471422},
+		{ID: "agreeing", Latitude: 40.39130985242181, Longitude: -3.6958028442054074, UTMX: 440946, UTMY: 4471422},
+		{ID: "mismatch", VenueName: "Auditorio", Latitude: 40.44810636195716, Longitude: -3.6762309620097398, UTMX: 442766, UTMY: 4477922},
+		{ID: "no-location"},
+	}
+
+	issues := reconcileCoordinates(events)
+
+	if events[0].Latitude == 0 || events[0].Longitude == 0 {
+		t.Errorf("utm-only coordinates = %v, %v, want filled from UTM", events[0].Latitude, events[0].Longitude)
+	}
+	if events[1].Latitude != 40.39130985242181 {
+		t.Errorf("agreeing Latitude = %v, want unchanged", events[1].Latitude)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("issues = %+v, want a fill and a mismatch issue", issues)
+	}
+	if issues[0].Type != "COORDINATES_FROM_UTM" || issues[0].Count != 1 || issues[0].Examples[0] != "utm-only" {
+		t.Errorf("issues[0] = %+v, want 1 COORDINATES_FROM_UTM for utm-only", issues[0])
+	}
+	if issues[1].Type != "COORDINATE_MISMATCH" || issues[1].Severity != "WARNING" || issues[1].Count != 1 ||
+		!strings.HasPrefix(issues[1].Examples[0], "mismatch (Auditorio): 236 m") {
+		t.Errorf("issues[1] = %+v, want 1 COORDINATE_MISMATCH warning for mismatch", issues[1])
+	}
+}
@@ -0,0 +1,83 @@
+package validate
+
+import "math"
+
+// CoordinateFix describes what CoordinateChecker.Check did to a pair of coordinates.
+type CoordinateFix string
+
+const (
+	CoordinatesOK          CoordinateFix = ""                   // Usable as given (or absent)
+	CoordinatesSwapped     CoordinateFix = "swapped"            // Latitude and longitude were swapped; corrected
+	CoordinatesSentinel    CoordinateFix = "sentinel value"     // A filler value (a zero or whole-number coordinate, out of range); cleared
+	CoordinatesOutOfBounds CoordinateFix = "outside Madrid"     // Outside the bounding box; cleared
+	CoordinatesPlaceholder CoordinateFix = "municipal centroid" // A geocoder's placeholder for the whole city; cleared
+)
+
+// placeholderTolerance is how close (in degrees, ~0.1 m) coordinates must be
+// to a placeholder to count as one.
+const placeholderTolerance = 1e-6
+
+// CoordinateChecker detects unusable coordinates of events of either type.
+// An empty bounding box (all zero) skips the bounds and swap checks.
+type CoordinateChecker struct {
+	MinLatitude, MaxLatitude   float64
+	MinLongitude, MaxLongitude float64
+	Placeholders               [][]float64 // [latitude, longitude] pairs
+}
+
+// Check returns the coordinates to use in place of lat, lon and the fix it
+// applied. Unusable coordinates come back as 0, 0 (no coordinates), so
+// filtering falls back to distrito and text matching. Missing coordinates
+// (0, 0) are returned unchanged.
+//
+// Checks run in order: sentinel values, swapped latitude/longitude (only when
+// the swapped pair is inside the bounding box and the given one isn't), the
+// bounding box, then placeholders.
+func (c CoordinateChecker) Check(lat, lon float64) (float64, float64, CoordinateFix) {
+	if lat == 0 && lon == 0 {
+		return 0, 0, CoordinatesOK
+	}
+	if isSentinel(lat, lon) {
+		return 0, 0, CoordinatesSentinel
+	}
+
+	fix := CoordinatesOK
+	if c.hasBounds() && !c.inBounds(lat, lon) {
+		if !c.inBounds(lon, lat) {
+			return 0, 0, CoordinatesOutOfBounds
+		}
+		lat, lon = lon, lat
+		fix = CoordinatesSwapped
+	}
+
+	for _, p := range c.Placeholders {
+		if len(p) == 2 && math.Abs(lat-p[0]) < placeholderTolerance && math.Abs(lon-p[1]) < placeholderTolerance {
+			return 0, 0, CoordinatesPlaceholder
+		}
+	}
+	return lat, lon, fix
+}
+
+func (c CoordinateChecker) hasBounds() bool {
+	return c.MinLatitude != 0 || c.MaxLatitude != 0 || c.MinLongitude != 0 || c.MaxLongitude != 0
+}
+
+func (c CoordinateChecker) inBounds(lat, lon float64) bool {
+	return lat >= c.MinLatitude && lat <= c.MaxLatitude && lon >= c.MinLongitude && lon <= c.MaxLongitude
+}
+
+// isSentinel reports whether coordinates are a filler rather than a position:
+// NaN or out of range, one of them exactly zero (null island's half-missing
+// cousin), or both whole numbers (-1/-1, 999/999).
+func isSentinel(lat, lon float64) bool {
+	switch {
+	case math.IsNaN(lat) || math.IsNaN(lon):
+		return true
+	case lat < -90 || lat > 90 || lon < -180 || lon > 180:
+		return true
+	case lat == 0 || lon == 0:
+		return true
+	default:
+		return lat == math.Trunc(lat) && lon == math.Trunc(lon)
+	}
+}
@@ -0,0 +1,50 @@
+package validate
+
+import "testing"
+
+func TestCoordinateChecker_Check(t *testing.T) {
+	checker := CoordinateChecker{
+		MinLatitude:  40.30,
+		MaxLatitude:  40.65,
+		MinLongitude: -3.90,
+		MaxLongitude: -3.50,
+		Placeholders: [][]float64{{40.4167754, -3.7037902}},
+	}
+
+	tests := []struct {
+		name             string
+		lat, lon         float64
+		wantLat, wantLon float64
+		wantFix          CoordinateFix
+	}{
+		{"Plaza de España", 40.42338, -3.71217, 40.42338, -3.71217, CoordinatesOK},
+		{"missing", 0, 0, 0, 0, CoordinatesOK},
+		{"swapped", -3.71217, 40.42338, 40.42338, -3.71217, CoordinatesSwapped},
+		{"Barcelona", 41.38879, 2.15899, 0, 0, CoordinatesOutOfBounds},
+		{"swapped and outside Madrid", 2.15899, 41.38879, 0, 0, CoordinatesOutOfBounds},
+		{"null island latitude", 0, -3.71217, 0, 0, CoordinatesSentinel},
+		{"null island longitude", 40.42338, 0, 0, 0, CoordinatesSentinel},
+		{"whole-number filler", -1, -1, 0, 0, CoordinatesSentinel},
+		{"out of range", 999, 999.5, 0, 0, CoordinatesSentinel},
+		{"municipal centroid", 40.4167754, -3.7037902, 0, 0, CoordinatesPlaceholder},
+		{"near the centroid", 40.4168, -3.7038, 40.4168, -3.7038, CoordinatesOK},
+	}
+	for _, tt := range tests {
+		lat, lon, fix := checker.Check(tt.lat, tt.lon)
+		if lat != tt.wantLat || lon != tt.wantLon || fix != tt.wantFix {
+			t.Errorf("%s: Check(%v, %v) = %v, %v, %q, want %v, %v, %q",
+				tt.name, tt.lat, tt.lon, lat, lon, fix, tt.wantLat, tt.wantLon, tt.wantFix)
+		}
+	}
+}
+
+func TestCoordinateChecker_NoBounds(t *testing.T) {
+	// Without a bounding box only sentinels and placeholders are caught
+	var checker CoordinateChecker
+	if lat, lon, fix := checker.Check(41.38879, 2.15899); fix != CoordinatesOK || lat != 41.38879 || lon != 2.15899 {
+		t.Errorf("Check(Barcelona) = %v, %v, %q, want unchanged", lat, lon, fix)
+	}
+	if _, _, fix := checker.Check(40.42338, 0); fix != CoordinatesSentinel {
+		t.Errorf("Check(40.42338, 0) fix = %q, want %q", fix, CoordinatesSentinel)
+	}
+}
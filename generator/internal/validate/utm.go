@@ -0,0 +1,87 @@
+package validate
+
+import (
+	"math"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+// MadridUTMZone is the UTM zone of datos.madrid.es COORDENADA-X/Y (ETRS89 / UTM 30N).
+const MadridUTMZone = 30
+
+// CoordinateTolerance is how far apart (in meters) an event's latitude/longitude
+// and its converted UTM coordinates may be before they count as a mismatch.
+// Upstream rounds UTM to whole meters, so agreeing pairs are within ~2 m.
+const CoordinateTolerance = 100.0
+
+// GRS80 ellipsoid (ETRS89) and UTM projection constants.
+const (
+	grs80A          = 6378137.0
+	grs80F          = 1 / 298.257222101
+	utmScale        = 0.9996
+	utmFalseEast    = 500000.0
+	metersPerDegree = 111320.0 // Length of a degree of latitude, near enough for tolerances
+)
+
+// UTMToLatLon converts northern-hemisphere UTM coordinates (meters, ETRS89)
+// in the given zone to latitude/longitude in degrees. It uses the series
+// expansion of the inverse transverse Mercator projection (Snyder, "Map
+// Projections: A Working Manual"), accurate to well under a meter within a zone.
+func UTMToLatLon(easting, northing float64, zone int) (lat, lon float64) {
+	e2 := grs80F * (2 - grs80F) // First eccentricity squared
+	ep2 := e2 / (1 - e2)        // Second eccentricity squared
+	e1 := (1 - math.Sqrt(1-e2)) / (1 + math.Sqrt(1-e2))
+
+	// Footpoint latitude
+	m := northing / utmScale
+	mu := m / (grs80A * (1 - e2/4 - 3*e2*e2/64 - 5*e2*e2*e2/256))
+	phi1 := mu +
+		(3*e1/2-27*math.Pow(e1, 3)/32)*math.Sin(2*mu) +
+		(21*e1*e1/16-55*math.Pow(e1, 4)/32)*math.Sin(4*mu) +
+		(151*math.Pow(e1, 3)/96)*math.Sin(6*mu) +
+		(1097*math.Pow(e1, 4)/512)*math.Sin(8*mu)
+
+	sin, cos, tan := math.Sin(phi1), math.Cos(phi1), math.Tan(phi1)
+	n1 := grs80A / math.Sqrt(1-e2*sin*sin)
+	t1 := tan * tan
+	c1 := ep2 * cos * cos
+	r1 := grs80A * (1 - e2) / math.Pow(1-e2*sin*sin, 1.5)
+	d := (easting - utmFalseEast) / (n1 * utmScale)
+
+	latRad := phi1 - (n1*tan/r1)*(d*d/2-
+		(5+3*t1+10*c1-4*c1*c1-9*ep2)*math.Pow(d, 4)/24+
+		(61+90*t1+298*c1+45*t1*t1-252*ep2-3*c1*c1)*math.Pow(d, 6)/720)
+	lonRad := (d -
+		(1+2*t1+c1)*math.Pow(d, 3)/6 +
+		(5-2*c1+28*t1-3*c1*c1+8*ep2+24*t1*t1)*math.Pow(d, 5)/120) / cos
+
+	centralMeridian := float64(zone*6 - 183)
+	return latRad * 180 / math.Pi, centralMeridian + lonRad*180/math.Pi
+}
+
+// FillCoordinatesFromUTM sets an event's latitude/longitude from its UTM
+// coordinates when it has UTM but no latitude/longitude. Reports whether it did.
+func FillCoordinatesFromUTM(evt *event.CulturalEvent) bool {
+	if evt.Latitude != 0 || evt.Longitude != 0 || !hasUTM(*evt) {
+		return false
+	}
+	evt.Latitude, evt.Longitude = UTMToLatLon(evt.UTMX, evt.UTMY, MadridUTMZone)
+	return true
+}
+
+// UTMOffset returns the distance in meters between an event's latitude/longitude
+// and its converted UTM coordinates. ok is false unless the event has both.
+func UTMOffset(evt event.CulturalEvent) (meters float64, ok bool) {
+	if (evt.Latitude == 0 && evt.Longitude == 0) || !hasUTM(evt) {
+		return 0, false
+	}
+	lat, lon := UTMToLatLon(evt.UTMX, evt.UTMY, MadridUTMZone)
+	dy := (lat - evt.Latitude) * metersPerDegree
+	dx := (lon - evt.Longitude) * metersPerDegree * math.Cos(evt.Latitude*math.Pi/180)
+	return math.Hypot(dx, dy), true
+}
+
+// hasUTM reports whether the event has usable UTM coordinates.
+func hasUTM(evt event.CulturalEvent) bool {
+	return evt.UTMX > 0 && evt.UTMY > 0
+}
@@ -0,0 +1,70 @@
+package validate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ericphanson/plazaespana.info/internal/event"
+)
+
+func TestUTMToLatLon(t *testing.T) {
+	// COORDENADA-X/Y and LATITUD/LONGITUD pairs from datos.madrid.es (UTM is
+	// rounded to whole meters, so allow ~2 m of error)
+	tests := []struct {
+		name     string
+		x, y     float64
+		lat, lon float64
+	}{
+		{"Matadero Madrid", 440946, 4471422, 40.39130985242181, -3.6958028442054074},
+		{"event 50078857", 448357, 4472920, 40.40529229274289, -3.6086152959929514},
+		{"central meridian", 500000, 4427757, 40.0, -3.0},
+	}
+	for _, tt := range tests {
+		lat, lon := UTMToLatLon(tt.x, tt.y, MadridUTMZone)
+		dy := (lat - tt.lat) * metersPerDegree
+		dx := (lon - tt.lon) * metersPerDegree * math.Cos(tt.lat*math.Pi/180)
+		if d := math.Hypot(dx, dy); d > 2 {
+			t.Errorf("%s: UTMToLatLon(%v, %v) = %.7f, %.7f, want %.7f, %.7f (%.1f m off)",
+				tt.name, tt.x, tt.y, lat, lon, tt.lat, tt.lon, d)
+		}
+	}
+}
+
+func TestFillCoordinatesFromUTM(t *testing.T) {
+	evt := event.CulturalEvent{UTMX: 440946, UTMY: 4471422}
+	if !FillCoordinatesFromUTM(&evt) {
+		t.Fatal("FillCoordinatesFromUTM() = false for an event with only UTM")
+	}
+	if math.Abs(evt.Latitude-40.3913) > 0.0001 || math.Abs(evt.Longitude+3.6958) > 0.0001 {
+		t.Errorf("coordinates = %v, %v, want about 40.3913, -3.6958", evt.Latitude, evt.Longitude)
+	}
+
+	// Existing coordinates are kept, and events without UTM are left alone
+	if FillCoordinatesFromUTM(&evt) {
+		t.Error("FillCoordinatesFromUTM() = true for an event with latitude/longitude")
+	}
+	if none := (event.CulturalEvent{}); FillCoordinatesFromUTM(&none) {
+		t.Error("FillCoordinatesFromUTM() = true for an event without UTM")
+	}
+}
+
+func TestUTMOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		evt     event.CulturalEvent
+		wantOK  bool
+		wantMin float64
+		wantMax float64
+	}{
+		{"agreeing", event.CulturalEvent{Latitude: 40.39130985242181, Longitude: -3.6958028442054074, UTMX: 440946, UTMY: 4471422}, true, 0, 2},
+		{"venue moved 236 m", event.CulturalEvent{Latitude: 40.44810636195716, Longitude: -3.6762309620097398, UTMX: 442766, UTMY: 4477922}, true, 230, 240},
+		{"no UTM", event.CulturalEvent{Latitude: 40.4, Longitude: -3.7}, false, 0, 0},
+		{"no latitude/longitude", event.CulturalEvent{UTMX: 440946, UTMY: 4471422}, false, 0, 0},
+	}
+	for _, tt := range tests {
+		got, ok := UTMOffset(tt.evt)
+		if ok != tt.wantOK || got < tt.wantMin || got > tt.wantMax {
+			t.Errorf("%s: UTMOffset() = %.1f, %v, want %v-%v m, %v", tt.name, got, ok, tt.wantMin, tt.wantMax, tt.wantOK)
+		}
+	}
+}
@@ -46,6 +46,9 @@ func SanitizeEvent(evt *event.CulturalEvent) {
 	evt.ID = strings.TrimSpace(evt.ID)
 	evt.Title = strings.TrimSpace(evt.Title)
 	evt.VenueName = strings.TrimSpace(evt.VenueName)
+	evt.Barrio = strings.TrimSpace(evt.Barrio)
+	evt.PostalCode = strings.TrimSpace(evt.PostalCode)
+	evt.Price = strings.TrimSpace(evt.Price)
 
 	// Fix end time if missing (use start time)
 	if evt.EndTime.IsZero() && !evt.StartTime.IsZero() {
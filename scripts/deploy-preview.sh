@@ -47,6 +47,7 @@ scp public/events.json "$NFSN_USER@$NFSN_HOST:$REMOTE_DIR/events.json"
 echo "📤 Uploading CSS assets..."
 scp public/assets/site.*.css "$NFSN_USER@$NFSN_HOST:$REMOTE_DIR/assets/"
 scp public/assets/build-report.*.css "$NFSN_USER@$NFSN_HOST:$REMOTE_DIR/assets/"
+scp public/assets/zones.*.css "$NFSN_USER@$NFSN_HOST:$REMOTE_DIR/assets/" 2>/dev/null || echo "⚠️  No zone stylesheet found (no zones configured)"
 
 # Upload weather icons
 echo "📤 Uploading weather icons..."